@@ -31,6 +31,13 @@ type AgentGroupSpec struct {
 	Description string `json:"description,omitempty"`
 	// Tags for the agent group
 	Tags []string `json:"tags,omitempty"`
+	// NodeSelector selects the nodes whose node agents join this agent group
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// PodSelector selects Pods in the namespace of the agent group whose agents join this agent group:
+	// the injected sidecar of the Pod, or the node agent on the node the Pod runs on
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
 	// Configs that should be applied to this agent group
 	Configs []string `json:"configs,omitempty"`
 }
@@ -45,6 +52,21 @@ type AgentGroupStatus struct {
 	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
 	// AppliedConfigs is the list of configs that have been applied to this agent group
 	AppliedConfigs []string `json:"appliedConfigs,omitempty"`
+	// AppliedName is the name the agent group was last synced to Config-Server as
+	// +optional
+	AppliedName string `json:"appliedName,omitempty"`
+	// Tags is the full tag set pushed to Config-Server, including the tags resolved from the selectors
+	Tags []string `json:"tags,omitempty"`
+	// MissingConfigs lists the configs referenced in spec.configs that have no Pipeline
+	MissingConfigs []string `json:"missingConfigs,omitempty"`
+	// ObservedGeneration is the generation of the spec last processed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions represent the latest available observations of the agent group
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
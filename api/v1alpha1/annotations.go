@@ -0,0 +1,97 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// Well-known annotations and labels used by the operator.
+const (
+	// AnnotationLogs declares the logs to collect for a workload or Pod, as a JSON array, e.g.
+	// `[{"path":"/app/*.log","format":"json"}]`
+	AnnotationLogs = "loongcollector.infraflow.co/logs"
+	// AnnotationAgentGroup binds the generated Pipelines to an agent group
+	AnnotationAgentGroup = "loongcollector.infraflow.co/agent-group"
+	// AnnotationFlusher overrides the flusher of the generated Pipelines, as a JSON object
+	AnnotationFlusher = "loongcollector.infraflow.co/flusher"
+
+	// LabelLogsEnabled opts a namespace in to annotation driven Pipelines
+	LabelLogsEnabled = "loongcollector.infraflow.co/logs-enabled"
+	// LabelSourceKind records the kind of the workload a Pipeline was generated from
+	LabelSourceKind = "loongcollector.infraflow.co/source-kind"
+	// LabelSourceName records the name of the workload a Pipeline was generated from
+	LabelSourceName = "loongcollector.infraflow.co/source-name"
+	// LabelManagedBy marks objects generated by the operator
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	// ManagedByWorkload is the LabelManagedBy value of Pipelines generated from annotations
+	ManagedByWorkload = "loongcollector-workload"
+)
+
+// Sidecar injection annotations.
+const (
+	// LabelSidecarInjection opts a namespace in to sidecar injection when set to "enabled". The webhook is only
+	// called for Pods of such namespaces, which must also set AnnotationInject
+	LabelSidecarInjection = "loongcollector.infraflow.co/sidecar-injection"
+	// AnnotationInject requests the LoongCollector sidecar to be injected into a Pod
+	AnnotationInject = "loongcollector.infraflow.co/inject"
+	// AnnotationInjectionProfile selects the InjectionProfile used for the Pod, defaults to DefaultInjectionProfile
+	AnnotationInjectionProfile = "loongcollector.infraflow.co/injection-profile"
+	// AnnotationInjected is set on Pods the sidecar has been injected into
+	AnnotationInjected = "loongcollector.infraflow.co/injected"
+	// AnnotationSidecarTag is the agent tag the injected sidecar reports, set by the webhook. A Pod may request its own tag,
+	// which is only honored under the Pod's namespace, as SidecarTagPrefix + <namespace> + "." + <name>
+	AnnotationSidecarTag = "loongcollector.infraflow.co/sidecar-tag"
+	// SidecarTagPrefix prefixes the agent tag of injected sidecars
+	SidecarTagPrefix = "sidecar-"
+	// DefaultInjectionProfile is the InjectionProfile used when a Pod does not select one
+	DefaultInjectionProfile = "default"
+)
+
+// NodeTagPrefix prefixes the agent tag reported by the node agent, followed by the node name.
+// The LoongCollector DaemonSet must report it, see config/samples/loongcollector.yaml.
+const NodeTagPrefix = "node-"
+
+// Adoption annotations.
+const (
+	// AnnotationAdopt on a Namespace requests the configs and agent groups of the Config-Server to be adopted
+	// into Pipelines and AgentGroups in that namespace when set to "true". The operator removes it once done.
+	AnnotationAdopt = "loongcollector.infraflow.co/adopt"
+	// AnnotationAdopted marks Pipelines and AgentGroups adopted from Config-Server, its value is the Config-Server address.
+	// They are not pushed back to Config-Server until their spec is edited, and deleting them before that leaves
+	// Config-Server untouched.
+	AnnotationAdopted = "loongcollector.infraflow.co/adopted"
+	// ManagedByAdoption is the LabelManagedBy value of adopted Pipelines and AgentGroups
+	ManagedByAdoption = "loongcollector-adoption"
+)
+
+// Migration labels and annotations.
+const (
+	// AnnotationMigratedFrom marks Pipelines and AgentGroups generated from Alibaba Cloud AliyunPipelineConfig or
+	// AliyunLogConfig objects, its value is the source object as <kind>/<namespace>/<name>
+	AnnotationMigratedFrom = "loongcollector.infraflow.co/migrated-from"
+	// ManagedByMigration is the LabelManagedBy value of migrated Pipelines and AgentGroups
+	ManagedByMigration = "loongcollector-migration"
+)
+
+// Backup labels and annotations.
+const (
+	// LabelBackup records the Backup a backup Job was created for
+	LabelBackup = "loongcollector.infraflow.co/backup"
+	// AnnotationBackupArchive records the path of the bundle a backup Job writes, relative to the volume
+	AnnotationBackupArchive = "loongcollector.infraflow.co/backup-archive"
+)
+
+// AnnotationResync on a Pipeline forces it to be applied to Config-Server again, even if nothing changed.
+// Any new value triggers one resync, e.g. the current time; status.resyncToken records the last handled value.
+const AnnotationResync = "loongcollector.infraflow.co/resync"
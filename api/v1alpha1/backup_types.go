@@ -0,0 +1,108 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupSpec defines how often the Pipelines and AgentGroups are exported and where the bundles are kept.
+type BackupSpec struct {
+	// Interval between two backups
+	// +kubebuilder:default="24h"
+	// +optional
+	Interval metav1.Duration `json:"interval,omitempty"`
+	// ClaimName is the PersistentVolumeClaim the bundles are written to, in the namespace of the Backup
+	ClaimName string `json:"claimName"`
+	// Path is the directory of the bundles in the volume
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Namespaces to export, all namespaces if empty
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Keep is the number of bundles and Jobs kept
+	// +kubebuilder:default=7
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Keep int32 `json:"keep,omitempty"`
+	// Image running the export, defaults to the image of the operator
+	// +optional
+	Image string `json:"image,omitempty"`
+	// ServiceAccountName of the export Job. It must be allowed to list Pipelines and AgentGroups in the exported namespaces
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// Suspend stops scheduling new backups
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// BackupRevision is one run of a Backup.
+type BackupRevision struct {
+	// Job running the export
+	Job string `json:"job"`
+	// Archive is the path of the bundle in the volume
+	Archive string `json:"archive"`
+	// Phase is Running, Succeeded or Failed
+	Phase string `json:"phase"`
+	// StartTime of the Job
+	StartTime metav1.Time `json:"startTime"`
+	// CompletionTime of the Job
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// BackupStatus defines the observed state of Backup.
+type BackupStatus struct {
+	// LastScheduleTime is the last time a backup Job was created
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is the completion time of the last successful backup
+	// +optional
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// History of the kept backups, newest first
+	// +optional
+	History []BackupRevision `json:"history,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Interval",type=string,JSONPath=`.spec.interval`
+// +kubebuilder:printcolumn:name="Last Success",type=date,JSONPath=`.status.lastSuccessfulTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Backup is the Schema for the backups API.
+// It periodically exports the Pipelines and AgentGroups as a bundle to a PersistentVolumeClaim.
+type Backup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackupSpec   `json:"spec,omitempty"`
+	Status BackupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BackupList contains a list of Backup.
+type BackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Backup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Backup{}, &BackupList{})
+}
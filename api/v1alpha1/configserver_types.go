@@ -0,0 +1,64 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConfigServerSpec defines a Config-Server Pipelines can be fanned out to.
+type ConfigServerSpec struct {
+	// Endpoint is the address of the Config-Server, e.g. http://config-server.cluster-a.example.com:8899
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Endpoint string `json:"endpoint"`
+	// Description of the Config-Server, e.g. the cluster it serves
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+// ConfigServerStatus defines the observed state of ConfigServer.
+type ConfigServerStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.spec.endpoint`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ConfigServer is the Schema for the configservers API.
+// Pipelines with a configServerSelector are applied to every ConfigServer matching the selector.
+type ConfigServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ConfigServerSpec   `json:"spec,omitempty"`
+	Status ConfigServerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ConfigServerList contains a list of ConfigServer.
+type ConfigServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ConfigServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ConfigServer{}, &ConfigServerList{})
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// FlusherProfileSpec defines flushers shared by Pipelines.
+type FlusherProfileSpec struct {
+	// Description of the profile, e.g. the destination
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Flushers are LoongCollector flusher plugins, appended in order to the flushers of the referencing Pipelines
+	// +kubebuilder:validation:MinItems=1
+	Flushers []runtime.RawExtension `json:"flushers"`
+}
+
+// FlusherProfileStatus defines the observed state of FlusherProfile.
+type FlusherProfileStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// FlusherProfile is the Schema for the flusherprofiles API.
+// Pipelines in the same namespace reference it in spec.flusherProfileRefs.
+type FlusherProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FlusherProfileSpec   `json:"spec,omitempty"`
+	Status FlusherProfileStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// FlusherProfileList contains a list of FlusherProfile.
+type FlusherProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []FlusherProfile `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterFlusherProfile is the Schema for the clusterflusherprofiles API.
+// Pipelines in any namespace reference it in spec.flusherProfileRefs.
+type ClusterFlusherProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FlusherProfileSpec   `json:"spec,omitempty"`
+	Status FlusherProfileStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterFlusherProfileList contains a list of ClusterFlusherProfile.
+type ClusterFlusherProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterFlusherProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&FlusherProfile{}, &FlusherProfileList{}, &ClusterFlusherProfile{}, &ClusterFlusherProfileList{})
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InjectionProfileSpec defines how the LoongCollector sidecar is injected into Pods.
+type InjectionProfileSpec struct {
+	// Image of the LoongCollector sidecar
+	Image string `json:"image"`
+	// ImagePullPolicy of the sidecar image
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	// Resources of the sidecar container
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// ConfigServerURL is the Config-Server address the sidecar registers to
+	// +optional
+	ConfigServerURL string `json:"configServerURL,omitempty"`
+	// LogMountPath is where the shared log volume is mounted in the application containers and the sidecar
+	// +kubebuilder:default=/var/log/app
+	// +optional
+	LogMountPath string `json:"logMountPath,omitempty"`
+	// TagEnvName is the environment variable the sidecar reads its agent tags from
+	// +kubebuilder:default=ilogtail_tags
+	// +optional
+	TagEnvName string `json:"tagEnvName,omitempty"`
+	// ConfigServerEnvName is the environment variable the sidecar reads the Config-Server address from
+	// +kubebuilder:default=ilogtail_configserver_address
+	// +optional
+	ConfigServerEnvName string `json:"configServerEnvName,omitempty"`
+	// Env is appended to the environment of the sidecar
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// InjectionProfileStatus defines the observed state of InjectionProfile.
+type InjectionProfileStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// InjectionProfile is the Schema for the injectionprofiles API.
+type InjectionProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   InjectionProfileSpec   `json:"spec,omitempty"`
+	Status InjectionProfileStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// InjectionProfileList contains a list of InjectionProfile.
+type InjectionProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []InjectionProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&InjectionProfile{}, &InjectionProfileList{})
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -25,6 +27,7 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // PipelineSpec defines the desired state of Pipeline.
+// +kubebuilder:validation:XValidation:rule="(has(self.content) ? 1 : 0) + (has(self.contentFrom) ? 1 : 0) + (has(self.templateRef) ? 1 : 0) == 1",message="exactly one of content, contentFrom and templateRef must be set"
 type PipelineSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
@@ -32,12 +35,45 @@ type PipelineSpec struct {
 	// Name of the pipeline
 	Name string `json:"name"`
 	// content is the pipeline configuration
-	Content string `json:"content"`
+	// +optional
+	Content string `json:"content,omitempty"`
+	// ContentFrom sources the pipeline configuration from ConfigMaps, Secrets or other Pipelines instead of content.
+	// Changes to the referenced objects are applied like changes to content
+	// +optional
+	ContentFrom *PipelineContentSource `json:"contentFrom,omitempty"`
+	// TemplateRef renders the pipeline configuration from a PipelineTemplate instead of content
+	// +optional
+	TemplateRef *PipelineTemplateReference `json:"templateRef,omitempty"`
+	// SourceFormat is the format of the configuration given by content, contentFrom or templateRef.
+	// Fluent Bit, Filebeat and Vector configurations are converted into a LoongCollector pipeline, defaults to LoongCollector
+	// +optional
+	SourceFormat PipelineSourceFormat `json:"sourceFormat,omitempty"`
+
+	// ProcessorChainRefs splice the processors of ProcessorChains and ClusterProcessorChains into the configuration, in order
+	// +optional
+	ProcessorChainRefs []ProcessorChainReference `json:"processorChainRefs,omitempty"`
+	// FlusherProfileRefs append the flushers of FlusherProfiles and ClusterFlusherProfiles to the configuration, in order
+	// +optional
+	FlusherProfileRefs []FlusherProfileReference `json:"flusherProfileRefs,omitempty"`
+	// UpdatePolicy controls when changes to the referenced ProcessorChains and FlusherProfiles are applied, defaults to Auto
+	// +optional
+	UpdatePolicy PipelineUpdatePolicy `json:"updatePolicy,omitempty"`
 
 	// AgentGroup specifies the agent group to which this pipeline should be applied
 	// +optional
 	AgentGroup string `json:"agentGroup,omitempty"`
 
+	// ConfigServerSelector selects the ConfigServers the pipeline is applied to.
+	// If unset, the pipeline is applied to the Config-Server configured by the config-server-config ConfigMap
+	// +optional
+	ConfigServerSelector *metav1.LabelSelector `json:"configServerSelector,omitempty"`
+
+	// DryRun renders and validates the pipeline and writes the changes applying it would make to Config-Server
+	// into status.plan, without changing Config-Server. Dry-run pipelines are not associated with AgentGroups
+	// and deleting them leaves Config-Server untouched
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
 	// 支持logtail
 	// https://help.aliyun.com/zh/sls/user-guide/recommend-use-aliyunpipelineconfig-to-manage-collection-configurations?spm=a2c4g.11186623.help-menu-28958.d_2_1_1_3_2_0.3b56694e44bSyR&scm=20140722.H_2833390._.OR_help-T_cn~zh-V_1#770941e164v6h
 	// Project defines the SLS project configuration
@@ -58,6 +94,125 @@ type PipelineSpec struct {
 	EnableUpgradeOverride bool `json:"enableUpgradeOverride,omitempty"`
 }
 
+// PipelineContentSource selects the pipeline configuration, exactly one of its fields must be set
+// +kubebuilder:validation:XValidation:rule="(has(self.configMapKeyRef) ? 1 : 0) + (has(self.secretKeyRef) ? 1 : 0) + (has(self.fragments) ? 1 : 0) == 1",message="exactly one of configMapKeyRef, secretKeyRef and fragments must be set"
+type PipelineContentSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap in the pipeline namespace
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// SecretKeyRef selects a key of a Secret in the pipeline namespace
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// Fragments are merged in order into the configuration: mappings are merged, lists such as inputs and
+	// processors are appended to, and other values are replaced by later fragments
+	// +kubebuilder:validation:MinItems=1
+	// +optional
+	Fragments []PipelineContentFragment `json:"fragments,omitempty"`
+}
+
+// PipelineContentFragment is a part of the pipeline configuration, exactly one of its fields must be set
+// +kubebuilder:validation:XValidation:rule="(has(self.content) ? 1 : 0) + (has(self.configMapKeyRef) ? 1 : 0) + (has(self.secretKeyRef) ? 1 : 0) + (has(self.pipelineRef) ? 1 : 0) == 1",message="exactly one of content, configMapKeyRef, secretKeyRef and pipelineRef must be set"
+type PipelineContentFragment struct {
+	// Content is an inline fragment
+	// +optional
+	Content string `json:"content,omitempty"`
+	// ConfigMapKeyRef selects a key of a ConfigMap in the pipeline namespace
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+	// SecretKeyRef selects a key of a Secret in the pipeline namespace
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+	// PipelineRef uses the configuration of another Pipeline in the pipeline namespace
+	// +optional
+	PipelineRef *corev1.LocalObjectReference `json:"pipelineRef,omitempty"`
+}
+
+// PipelineTemplateReference selects a PipelineTemplate and the values of its parameters
+type PipelineTemplateReference struct {
+	// Name of the PipelineTemplate
+	Name string `json:"name"`
+	// Parameters are the values of the template parameters, by name
+	// +optional
+	Parameters map[string]apiextensionsv1.JSON `json:"parameters,omitempty"`
+}
+
+// PipelineTemplateRenderStatus records the PipelineTemplate a pipeline configuration was rendered from
+type PipelineTemplateRenderStatus struct {
+	// Name of the PipelineTemplate
+	Name string `json:"name"`
+	// Version is spec.version of the template
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Generation of the template
+	Generation int64 `json:"generation"`
+}
+
+// ProcessorChainReference references a ProcessorChain in the pipeline namespace or a ClusterProcessorChain
+type ProcessorChainReference struct {
+	// Kind of the chain, defaults to ProcessorChain
+	// +kubebuilder:validation:Enum=ProcessorChain;ClusterProcessorChain
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Name of the chain
+	Name string `json:"name"`
+	// Position of the processors of the chain relative to the processors of the configuration, defaults to Before
+	// +kubebuilder:validation:Enum=Before;After
+	// +optional
+	Position string `json:"position,omitempty"`
+}
+
+// FlusherProfileReference references a FlusherProfile in the pipeline namespace or a ClusterFlusherProfile
+type FlusherProfileReference struct {
+	// Kind of the profile, defaults to FlusherProfile
+	// +kubebuilder:validation:Enum=FlusherProfile;ClusterFlusherProfile
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Name of the profile
+	Name string `json:"name"`
+}
+
+// PipelineUpdatePolicy controls when changes to the ProcessorChains and FlusherProfiles referenced by a pipeline are applied
+// +kubebuilder:validation:Enum=Auto;Manual
+type PipelineUpdatePolicy string
+
+const (
+	// UpdatePolicyAuto applies changes to referenced profiles as soon as they are made
+	UpdatePolicyAuto PipelineUpdatePolicy = "Auto"
+	// UpdatePolicyManual keeps the last applied configuration until the pipeline spec changes or a resync is requested
+	UpdatePolicyManual PipelineUpdatePolicy = "Manual"
+)
+
+// PipelineSourceFormat is the format a pipeline configuration is written in
+// +kubebuilder:validation:Enum=LoongCollector;FluentBit;Filebeat;Vector
+type PipelineSourceFormat string
+
+const (
+	// SourceFormatLoongCollector is a LoongCollector pipeline configuration
+	SourceFormatLoongCollector PipelineSourceFormat = "LoongCollector"
+	// SourceFormatFluentBit is a Fluent Bit classic configuration with [INPUT], [FILTER], [OUTPUT] and [PARSER] sections
+	SourceFormatFluentBit PipelineSourceFormat = "FluentBit"
+	// SourceFormatFilebeat is a Filebeat YAML configuration
+	SourceFormatFilebeat PipelineSourceFormat = "Filebeat"
+	// SourceFormatVector is a Vector TOML configuration
+	SourceFormatVector PipelineSourceFormat = "Vector"
+)
+
+// Position of the processors of a ProcessorChain
+const (
+	PositionBefore = "Before"
+	PositionAfter  = "After"
+)
+
+// PipelineProfileStatus is a ProcessorChain or FlusherProfile rendered into the configuration
+type PipelineProfileStatus struct {
+	// Kind of the profile
+	Kind string `json:"kind"`
+	// Name of the profile
+	Name string `json:"name"`
+	// ContentHash is the sha256 of the plugins of the profile
+	ContentHash string `json:"contentHash"`
+}
+
 // PipelineStatus defines the observed state of Pipeline.
 type PipelineStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -71,11 +226,135 @@ type PipelineStatus struct {
 	LastUpdateTime metav1.Time `json:"LastUpdateTime,omitempty"`
 	// LastAppliedConfig is the last applied configuration of the pipeline
 	LastAppliedConfig LastAppliedConfig `json:"lastAppliedConfig,omitempty"`
+	// ObservedGeneration is the generation of the spec last processed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions represent the latest available observations of the pipeline
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Targets is the state of the pipeline on each ConfigServer selected by spec.configServerSelector
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Targets []PipelineTargetStatus `json:"targets,omitempty"`
+	// History of the last applied revisions, newest first
+	// +optional
+	History []PipelineRevision `json:"history,omitempty"`
+	// ResyncToken is the value of the loongcollector.infraflow.co/resync annotation handled by the last apply
+	// +optional
+	ResyncToken string `json:"resyncToken,omitempty"`
+	// ContentHash is the sha256 of the configuration last applied, resolved from content or contentFrom
+	// +optional
+	ContentHash string `json:"contentHash,omitempty"`
+	// ContentFromSecret is set when the configuration includes values read from a Secret through spec.contentFrom.
+	// The configuration is then left out of lastAppliedConfig and plan diffs, and only contentHash identifies it
+	// +optional
+	ContentFromSecret bool `json:"contentFromSecret,omitempty"`
+	// Template is the PipelineTemplate version the configuration was last rendered from, set with spec.templateRef
+	// +optional
+	Template *PipelineTemplateRenderStatus `json:"template,omitempty"`
+	// Profiles are the ProcessorChains and FlusherProfiles accepted into the configuration. With updatePolicy Manual,
+	// changes to them are applied once the spec changes or a resync is requested
+	// +optional
+	Profiles []PipelineProfileStatus `json:"profiles,omitempty"`
+	// Plan is the change applying the pipeline would make to Config-Server, set when spec.dryRun is true
+	// +optional
+	Plan *PipelinePlan `json:"plan,omitempty"`
+}
+
+// PlanAction is the change applying a pipeline would make to a config in Config-Server
+// +kubebuilder:validation:Enum=Create;Update;Unchanged
+type PlanAction string
+
+const (
+	PlanActionCreate    PlanAction = "Create"
+	PlanActionUpdate    PlanAction = "Update"
+	PlanActionUnchanged PlanAction = "Unchanged"
+)
+
+// PipelinePlan is the change applying a dry-run pipeline would make to Config-Server
+type PipelinePlan struct {
+	// ObservedGeneration of the spec the plan was computed for
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// ComputedTime is when the plan was computed
+	ComputedTime metav1.Time `json:"computedTime"`
+	// Warnings reported by the validation of the content
+	// +optional
+	Warnings []string `json:"warnings,omitempty"`
+	// Targets is the plan for each Config-Server the pipeline would be applied to
+	// +optional
+	Targets []PipelineTargetPlan `json:"targets,omitempty"`
+}
+
+// PipelineTargetPlan is the change applying a dry-run pipeline would make to one Config-Server
+type PipelineTargetPlan struct {
+	// Name of the ConfigServer, empty for the Config-Server configured by the config-server-config ConfigMap
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Endpoint of the Config-Server
+	Endpoint string `json:"endpoint"`
+	// Config is the change to the config
+	Config PlanAction `json:"config"`
+	// Diff between the config in Config-Server and the pipeline content, empty when unchanged.
+	// Redacted when the content includes values read from a Secret
+	// +optional
+	Diff string `json:"diff,omitempty"`
+	// CreateGroups are the agent groups that would be created
+	// +optional
+	CreateGroups []string `json:"createGroups,omitempty"`
+	// AddGroups are the agent groups the config would be associated with
+	// +optional
+	AddGroups []string `json:"addGroups,omitempty"`
+	// RemoveGroups are the agent groups the config would be removed from
+	// +optional
+	RemoveGroups []string `json:"removeGroups,omitempty"`
+}
+
+// PipelineRevision is a revision of the pipeline applied to Config-Server
+type PipelineRevision struct {
+	// Generation of the applied spec
+	Generation int64 `json:"generation"`
+	// ContentHash is the sha256 of the applied content
+	ContentHash string `json:"contentHash"`
+	// Endpoint the revision was applied to, empty when applied to the ConfigServers selected by configServerSelector
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+	// AppliedTime is when the revision was applied
+	AppliedTime metav1.Time `json:"appliedTime"`
+}
+
+// PipelineTargetStatus is the state of a pipeline on one ConfigServer
+type PipelineTargetStatus struct {
+	// Name of the ConfigServer
+	Name string `json:"name"`
+	// Endpoint the pipeline was applied to
+	Endpoint string `json:"endpoint"`
+	// Success indicates whether the pipeline is applied to the ConfigServer
+	Success bool `json:"success"`
+	// Message is the last error, if any
+	// +optional
+	Message string `json:"message,omitempty"`
+	// ObservedGeneration is the generation of the spec last applied to the ConfigServer
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastUpdateTime is the last time the target was applied or failed
+	// +optional
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
 }
 
 type LastAppliedConfig struct {
 	AppliedTime metav1.Time `json:"appliedTime,omitempty"`
 	Content     string      `json:"content,omitempty"`
+	// Endpoint is the Config-Server the content was applied to
+	Endpoint string `json:"endpoint,omitempty"`
+	// Name is the config name the content was applied as
+	// +optional
+	Name string `json:"name,omitempty"`
+	// AgentGroup is the agent group the config was associated with
+	// +optional
+	AgentGroup string `json:"agentGroup,omitempty"`
 }
 
 // +kubebuilder:object:root=true
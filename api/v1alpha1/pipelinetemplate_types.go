@@ -0,0 +1,116 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PipelineTemplateSpec defines a parameterized pipeline configuration.
+type PipelineTemplateSpec struct {
+	// Description of the template, e.g. the logs it collects
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Version of the template set by its authors, recorded in the status of the Pipelines it renders
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Parameters declares the values Pipelines pass to the template
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Parameters []PipelineTemplateParameter `json:"parameters,omitempty"`
+	// Content is a Go text/template of the pipeline configuration. Parameters are available as {{ .Params.<name> }},
+	// the Pipeline as {{ .Pipeline.Name }}, {{ .Pipeline.Namespace }} and {{ .Pipeline.ConfigName }}
+	Content string `json:"content"`
+}
+
+// ParameterType is the type of the value of a template parameter
+// +kubebuilder:validation:Enum=string;integer;number;boolean;array
+type ParameterType string
+
+const (
+	ParameterTypeString  ParameterType = "string"
+	ParameterTypeInteger ParameterType = "integer"
+	ParameterTypeNumber  ParameterType = "number"
+	ParameterTypeBoolean ParameterType = "boolean"
+	ParameterTypeArray   ParameterType = "array"
+)
+
+// PipelineTemplateParameter is a typed parameter of a PipelineTemplate
+type PipelineTemplateParameter struct {
+	// Name of the parameter
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+	// Description of the parameter
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Type of the value, defaults to string
+	// +optional
+	Type ParameterType `json:"type,omitempty"`
+	// Required parameters without a default must be set by the Pipelines
+	// +optional
+	Required bool `json:"required,omitempty"`
+	// Default value used when the Pipeline does not set the parameter
+	// +optional
+	Default *apiextensionsv1.JSON `json:"default,omitempty"`
+	// Enum lists the allowed values of a string parameter
+	// +optional
+	Enum []string `json:"enum,omitempty"`
+	// Pattern is a regular expression a string parameter must match
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+	// Minimum value of an integer or number parameter
+	// +optional
+	Minimum *int64 `json:"minimum,omitempty"`
+	// Maximum value of an integer or number parameter
+	// +optional
+	Maximum *int64 `json:"maximum,omitempty"`
+}
+
+// PipelineTemplateStatus defines the observed state of PipelineTemplate.
+type PipelineTemplateStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PipelineTemplate is the Schema for the pipelinetemplates API.
+// Pipelines in any namespace render their configuration from it with spec.templateRef.
+type PipelineTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PipelineTemplateSpec   `json:"spec,omitempty"`
+	Status PipelineTemplateStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PipelineTemplateList contains a list of PipelineTemplate.
+type PipelineTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PipelineTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PipelineTemplate{}, &PipelineTemplateList{})
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PipelineTestSpec defines sample logs for a Pipeline and the records its processors are expected to produce from them.
+// +kubebuilder:validation:XValidation:rule="has(self.input) || has(self.inputRecords)",message="input or inputRecords must be set"
+type PipelineTestSpec struct {
+	// PipelineRef is the Pipeline under test, in the namespace of the PipelineTest
+	PipelineRef corev1.LocalObjectReference `json:"pipelineRef"`
+	// Input is the content of the sample logs, one log each. A log spans several lines for split processors
+	// +optional
+	Input []string `json:"input,omitempty"`
+	// InputRecords are sample logs with their fields, processed after the logs of input
+	// +optional
+	InputRecords []map[string]string `json:"inputRecords,omitempty"`
+	// Expected records produced by the processors of the Pipeline from the input, in order.
+	// Without assertions, an empty list expects every log to be dropped
+	// +optional
+	Expected []map[string]string `json:"expected,omitempty"`
+	// Assertions checked on the output records. With assertions and no expected records, only the assertions are checked
+	// +optional
+	Assertions []PipelineTestAssertion `json:"assertions,omitempty"`
+	// BlockOnFailure prevents the Pipeline from being applied to Config-Server while the test fails
+	// +optional
+	BlockOnFailure bool `json:"blockOnFailure,omitempty"`
+}
+
+// PipelineTestAssertion checks the number of output records, or a field of the output records.
+// +kubebuilder:validation:XValidation:rule="has(self.count) != has(self.field)",message="exactly one of count and field must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.field) || (has(self.equals) ? 1 : 0) + (has(self.matches) ? 1 : 0) + (has(self.exists) ? 1 : 0) == 1",message="a field assertion needs exactly one of equals, matches and exists"
+type PipelineTestAssertion struct {
+	// Count is the expected number of output records
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Count *int32 `json:"count,omitempty"`
+	// Record is the index of the output record checked, every record when unset
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Record *int32 `json:"record,omitempty"`
+	// Field of the record checked
+	// +optional
+	Field string `json:"field,omitempty"`
+	// Equals is the expected value of the field
+	// +optional
+	Equals *string `json:"equals,omitempty"`
+	// Matches is a regular expression the whole value of the field must match
+	// +optional
+	Matches string `json:"matches,omitempty"`
+	// Exists checks whether the record has the field
+	// +optional
+	Exists *bool `json:"exists,omitempty"`
+}
+
+// PipelineTestStatus defines the observed state of PipelineTest.
+type PipelineTestStatus struct {
+	// ObservedGeneration is the generation of the spec last evaluated by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ContentHash is the hash of the Pipeline configuration last evaluated
+	// +optional
+	ContentHash string `json:"contentHash,omitempty"`
+	// LastRunTime is the last time the status of the test changed
+	// +optional
+	LastRunTime *metav1.Time `json:"lastRunTime,omitempty"`
+	// Output is the records produced by the processors of the Pipeline
+	// +optional
+	Output []map[string]string `json:"output,omitempty"`
+	// Failures lists the differences between the output and the expected records, and the failed assertions
+	// +optional
+	Failures []string `json:"failures,omitempty"`
+	// Notes lists the effects of processors that are not simulated
+	// +optional
+	Notes []string `json:"notes,omitempty"`
+	// Conditions represent the latest available observations of the test
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Pipeline",type=string,JSONPath=`.spec.pipelineRef.name`
+// +kubebuilder:printcolumn:name="Passed",type=string,JSONPath=`.status.conditions[?(@.type=="Passed")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Passed")].reason`
+// +kubebuilder:printcolumn:name="Blocking",type=boolean,JSONPath=`.spec.blockOnFailure`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PipelineTest is the Schema for the pipelinetests API.
+// The operator runs the processors of the referenced Pipeline over the sample input and checks the output against the expected
+// records and assertions, and with spec.blockOnFailure stops the Pipeline from being applied while the test fails.
+type PipelineTest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PipelineTestSpec   `json:"spec,omitempty"`
+	Status PipelineTestStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PipelineTestList contains a list of PipelineTest.
+type PipelineTestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PipelineTest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PipelineTest{}, &PipelineTestList{})
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ProcessorChainSpec defines processors shared by Pipelines.
+type ProcessorChainSpec struct {
+	// Description of the chain, e.g. what it parses
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Processors are LoongCollector processor plugins, spliced in order into the processors of the referencing Pipelines
+	// +kubebuilder:validation:MinItems=1
+	Processors []runtime.RawExtension `json:"processors"`
+}
+
+// ProcessorChainStatus defines the observed state of ProcessorChain.
+type ProcessorChainStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ProcessorChain is the Schema for the processorchains API.
+// Pipelines in the same namespace reference it in spec.processorChainRefs.
+type ProcessorChain struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProcessorChainSpec   `json:"spec,omitempty"`
+	Status ProcessorChainStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ProcessorChainList contains a list of ProcessorChain.
+type ProcessorChainList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProcessorChain `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Description",type=string,JSONPath=`.spec.description`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterProcessorChain is the Schema for the clusterprocessorchains API.
+// Pipelines in any namespace reference it in spec.processorChainRefs.
+type ClusterProcessorChain struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProcessorChainSpec   `json:"spec,omitempty"`
+	Status ProcessorChainStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterProcessorChainList contains a list of ClusterProcessorChain.
+type ClusterProcessorChainList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterProcessorChain `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProcessorChain{}, &ProcessorChainList{}, &ClusterProcessorChain{}, &ClusterProcessorChainList{})
+}
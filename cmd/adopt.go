@@ -0,0 +1,95 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/adopt"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+// runAdopt implements the adopt subcommand: it lists the configs and agent groups of a Config-Server and prints
+// Pipeline and AgentGroup manifests marked as adopted, or creates them with --apply.
+func runAdopt(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("adopt", flag.ContinueOnError)
+	endpoint := fs.String("endpoint", "http://config-server:8899", "The Config-Server to adopt configs and agent groups from.")
+	namespace := fs.String("namespace", "default", "The namespace of the generated Pipelines and AgentGroups.")
+	apply := fs.Bool("apply", false,
+		"If set, the Pipelines and AgentGroups are created in the cluster of the current kubeconfig instead of printed.")
+	skipExisting := fs.Bool("skip-existing", true,
+		"If set, configs and agent groups already declared by a Pipeline or AgentGroup in the cluster are skipped. "+
+			"Requires access to the cluster.")
+	timeout := fs.Duration("timeout", 5*time.Minute, "The timeout of the whole adoption.")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	src, err := adopt.Fetch(ctx, configserver.NewConfigServerClient(*endpoint, nil, *namespace), *endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to read Config-Server: %w", err)
+	}
+
+	opts := adopt.Options{Namespace: *namespace}
+	var c client.Client
+	if *apply || *skipExisting {
+		if c, err = newCLIClient(); err != nil {
+			return err
+		}
+	}
+	if *skipExisting {
+		if opts.ExistingConfigs, opts.ExistingGroups, err = adopt.Existing(ctx, c); err != nil {
+			return fmt.Errorf("failed to list existing Pipelines and AgentGroups: %w", err)
+		}
+	}
+
+	result, err := adopt.Build(src, opts)
+	if err != nil {
+		return err
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "skipped %s: already declared in the cluster\n", skipped)
+	}
+
+	if !*apply {
+		manifests, err := adopt.Manifests(result)
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(manifests)
+		return err
+	}
+	created, err := adopt.Apply(ctx, c, result)
+	for _, name := range created {
+		fmt.Fprintf(stdout, "%s created\n", name)
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/bundle"
+)
+
+const bundleUsage = `Usage:
+  manager bundle export [--namespace ns1,ns2] (--output FILE | --dir DIR) [--prefix NAME] [--keep N] [--include-secrets]
+  manager bundle restore --input FILE [--namespace NS] [--dry-run]`
+
+// runBundle implements the bundle subcommand: export snapshots Pipelines and AgentGroups into a portable archive,
+// restore creates or updates them in the cluster of the current kubeconfig.
+func runBundle(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(bundleUsage)
+	}
+	switch args[0] {
+	case "export":
+		return runBundleExport(args[1:], stdout)
+	case "restore":
+		return runBundleRestore(args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown bundle command %q\n%s", args[0], bundleUsage)
+	}
+}
+
+func runBundleExport(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("bundle export", flag.ContinueOnError)
+	namespaces := fs.String("namespace", "", "Comma separated namespaces to export. Empty means all namespaces.")
+	output := fs.String("output", "", "The archive to write. Mutually exclusive with --dir.")
+	dir := fs.String("dir", "", "The directory to write a timestamped archive to. Mutually exclusive with --output.")
+	prefix := fs.String("prefix", "bundle", "The file name prefix of archives written to --dir.")
+	keep := fs.Int("keep", 0,
+		"If positive, only the newest N archives named like --dir would name them with --prefix are kept in the directory of the archive.")
+	includeSecrets := fs.Bool("include-secrets", false,
+		"If set, the data of Secrets referenced by Pipelines is exported too, and the archive holds it in plain text. "+
+			"Otherwise only the referenced keys are recorded and the Secrets must be created before restoring.")
+	timeout := fs.Duration("timeout", 5*time.Minute, "The timeout of the whole export.")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if (*output == "") == (*dir == "") {
+		return errors.New("exactly one of --output and --dir must be set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	c, err := newCLIClient()
+	if err != nil {
+		return err
+	}
+	var scope []string
+	if *namespaces != "" {
+		scope = strings.Split(*namespaces, ",")
+	}
+	b, err := bundle.Export(ctx, c, bundle.ExportOptions{Namespaces: scope, IncludeSecrets: *includeSecrets})
+	if err != nil {
+		return err
+	}
+	for _, warning := range b.Warnings {
+		fmt.Fprintf(stdout, "warning: %s\n", warning)
+	}
+
+	name := *output
+	if name == "" {
+		name = filepath.Join(*dir, bundle.FileName(*prefix, b.CreatedAt))
+	}
+	if err := bundle.WriteFile(name, b); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	fmt.Fprintf(stdout, "exported %d pipelines and %d agent groups to %s\n", len(b.Pipelines), len(b.AgentGroups), name)
+
+	if *keep > 0 {
+		removed, err := bundle.Prune(filepath.Dir(name), *prefix, *keep)
+		for _, name := range removed {
+			fmt.Fprintf(stdout, "removed %s\n", name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to prune old archives: %w", err)
+		}
+	}
+	return nil
+}
+
+func runBundleRestore(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("bundle restore", flag.ContinueOnError)
+	input := fs.String("input", "", "The archive to restore.")
+	namespace := fs.String("namespace", "", "If set, all resources are restored into this namespace.")
+	dryRun := fs.Bool("dry-run", false, "If set, only prints what would change, with a diff of updated specs.")
+	timeout := fs.Duration("timeout", 5*time.Minute, "The timeout of the whole restore.")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if *input == "" {
+		return errors.New("--input is required")
+	}
+
+	b, err := bundle.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	c, err := newCLIClient()
+	if err != nil {
+		return err
+	}
+	changes, err := bundle.Restore(ctx, c, b, bundle.RestoreOptions{Namespace: *namespace, DryRun: *dryRun})
+	suffix := ""
+	if *dryRun {
+		suffix = " (dry run)"
+	}
+	for _, change := range changes {
+		fmt.Fprintf(stdout, "%s %s/%s %s%s\n", change.Kind, change.Namespace, change.Name, change.Action, suffix)
+		if *dryRun && change.Diff != "" {
+			fmt.Fprint(stdout, change.Diff)
+		}
+	}
+	return err
+}
+
+// newCLIClient creates a client for the cluster of the current kubeconfig.
+func newCLIClient() (client.Client, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
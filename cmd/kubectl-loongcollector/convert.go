@@ -0,0 +1,89 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/convert"
+)
+
+func newConvertCommand(o *options) *cobra.Command {
+	var file, from, name string
+	var strict bool
+	cmd := &cobra.Command{
+		Use:   "convert --from FORMAT -f FILE",
+		Short: "Convert a Fluent Bit, Filebeat or Vector config into a LoongCollector pipeline",
+		Long: `Convert a Fluent Bit classic config, a Filebeat YAML config or a Vector TOML config into a LoongCollector
+pipeline config, with the converter the operator uses for spec.sourceFormat.
+
+Constructs without a LoongCollector equivalent are skipped and reported on stderr.`,
+		Example: `  kubectl loongcollector convert --from fluentbit -f fluent-bit.conf
+  kubectl loongcollector convert --from vector -f vector.toml --name app -n logging | kubectl apply -f -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := convert.ParseFormat(from)
+			if err != nil {
+				return err
+			}
+			if file == "" {
+				return fmt.Errorf("no config given, pass -f")
+			}
+			source, err := readFile(file)
+			if err != nil {
+				return err
+			}
+			result, err := convert.Convert(format, string(source))
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			for _, u := range result.Unsupported {
+				fmt.Fprintf(cmd.ErrOrStderr(), "unsupported: %s\n", u)
+			}
+			if strict && len(result.Unsupported) > 0 {
+				return fmt.Errorf("%d unsupported constructs", len(result.Unsupported))
+			}
+
+			out := []byte(result.Content)
+			if name != "" {
+				// Without --namespace the Pipeline is created in the namespace it is applied to
+				metadata := map[string]interface{}{"name": name}
+				if o.namespace != "" {
+					metadata["namespace"] = o.namespace
+				}
+				manifest := map[string]interface{}{
+					"apiVersion": v1alpha1.GroupVersion.String(),
+					"kind":       "Pipeline",
+					"metadata":   metadata,
+					"spec":       map[string]interface{}{"name": name, "content": result.Content},
+				}
+				if out, err = yaml.Marshal(manifest); err != nil {
+					return err
+				}
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&file, "filename", "f", "", "The config to convert, - for stdin.")
+	cmd.Flags().StringVar(&from, "from", "", "The format of the config: fluentbit, filebeat or vector.")
+	cmd.Flags().StringVar(&name, "name", "", "Print a Pipeline with this name holding the converted config instead of the config alone.")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail when a construct cannot be converted.")
+	return cmd
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/textdiff"
+)
+
+// errDifferent makes the command exit with 1 without a message, like kubectl diff.
+var errDifferent = errors.New("differences found")
+
+func newDiffCommand(o *options) *cobra.Command {
+	var files []string
+	cmd := &cobra.Command{
+		Use:   "diff [PIPELINE...] [-f FILE...]",
+		Short: "Compare Pipelines with the configs in Config-Server",
+		Long: `Compare the content of Pipelines with the configs currently stored in Config-Server.
+
+Each Pipeline is compared with every Config-Server it is applied to. The command exits with 1 when
+differences are found.`,
+		Example: `  kubectl loongcollector diff -n logging nginx-access
+  kubectl loongcollector diff -f pipeline.yaml -n logging`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pipelines, err := o.loadPipelines(files, args)
+			if err != nil {
+				return err
+			}
+			// --config-server给出地址时不需要读取集群
+			var c client.Client
+			if o.configServer == "" {
+				if c, err = o.client(); err != nil {
+					return err
+				}
+			}
+			ctx, cancel := o.commandContext()
+			defer cancel()
+
+			out := cmd.OutOrStdout()
+			different := false
+			for _, pipeline := range pipelines {
+				local, err := render.Canonical(pipeline.Spec.Content)
+				if err != nil {
+					return fmt.Errorf("%s: %w", displayName(pipeline), err)
+				}
+				targets, err := o.targets(ctx, c, pipeline)
+				if err != nil {
+					return err
+				}
+				for _, target := range targets {
+					remote := ""
+					config, err := target.Client.GetConfig(ctx, pipeline.Spec.Name)
+					var csErr *configserver.Error
+					switch {
+					case errors.As(err, &csErr) && csErr.StatusCode == 404:
+						fmt.Fprintf(out, "config %s not found in %s\n", pipeline.Spec.Name, target)
+					case err != nil:
+						return fmt.Errorf("%s: %w", target, err)
+					default:
+						if remote, err = render.Canonical(config.Content); err != nil {
+							return fmt.Errorf("%s: config %s: %w", target, pipeline.Spec.Name, err)
+						}
+					}
+					if diff := textdiff.Unified(target.Endpoint+"/"+pipeline.Spec.Name, displayName(pipeline), remote, local, 3); diff != "" {
+						different = true
+						fmt.Fprint(out, diff)
+					}
+				}
+			}
+			if different {
+				return errDifferent
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "Files holding Pipelines or raw pipeline configs, - for stdin.")
+	return cmd
+}
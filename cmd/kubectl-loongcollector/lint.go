@@ -0,0 +1,117 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+func newLintCommand(o *options) *cobra.Command {
+	var files []string
+	var strict bool
+	cmd := &cobra.Command{
+		Use:   "lint [PIPELINE...] [-f FILE...]",
+		Short: "Validate the content of Pipelines",
+		Long: `Validate the content of Pipelines with the checks the operator runs before applying them.
+
+Files are checked offline: they may hold Pipeline manifests or raw LoongCollector pipeline configs.
+Names are Pipelines read from the cluster.`,
+		Example: `  kubectl loongcollector lint -f pipeline.yaml
+  kubectl loongcollector lint -n logging nginx-access`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pipelines, err := o.loadPipelines(files, args)
+			if err != nil {
+				return err
+			}
+			errs, warnings := 0, 0
+			out := cmd.OutOrStdout()
+			for _, pipeline := range pipelines {
+				for _, problem := range render.Lint(pipeline.Spec.Content) {
+					fmt.Fprintf(out, "%s: %s\n", displayName(pipeline), problem)
+					if problem.Severity == render.SeverityError {
+						errs++
+					} else {
+						warnings++
+					}
+				}
+			}
+			if errs > 0 || (strict && warnings > 0) {
+				return fmt.Errorf("%d errors, %d warnings", errs, warnings)
+			}
+			fmt.Fprintf(out, "%d pipelines checked, %d warnings\n", len(pipelines), warnings)
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "Files to check, - for stdin.")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail on warnings too.")
+	return cmd
+}
+
+// anyRendersFromCluster reports whether the configuration of any of the pipelines depends on the cluster.
+func anyRendersFromCluster(pipelines []*v1alpha1.Pipeline) bool {
+	for _, pipeline := range pipelines {
+		if rendersFromCluster(pipeline) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPipelines reads the pipelines of files, then the pipelines named by names from the cluster.
+// The content of pipelines using contentFrom, templates or profiles is rendered from the cluster,
+// and content in another format is converted.
+func (o *options) loadPipelines(files, names []string) ([]*v1alpha1.Pipeline, error) {
+	if len(files) == 0 && len(names) == 0 {
+		return nil, fmt.Errorf("no pipeline given, pass names or -f")
+	}
+	pipelines, err := readPipelines(files)
+	if err != nil {
+		return nil, err
+	}
+	// 只转换格式时不需要访问集群
+	var c client.Client
+	if len(names) > 0 || anyRendersFromCluster(pipelines) {
+		if c, err = o.client(); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := o.commandContext()
+	defer cancel()
+	for _, name := range names {
+		pipeline, err := o.getPipeline(ctx, c, name)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+	for _, pipeline := range pipelines {
+		if rendersFromCluster(pipeline) && pipeline.Namespace == "" {
+			if pipeline.Namespace, err = o.targetNamespace(); err != nil {
+				return nil, err
+			}
+		}
+		if err := resolveContent(ctx, c, pipeline); err != nil {
+			return nil, err
+		}
+	}
+	return pipelines, nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command kubectl-loongcollector is a kubectl plugin to lint, render, diff, inspect, resync and convert Pipelines.
+// Install it on the PATH and run it as `kubectl loongcollector`.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		if !errors.Is(err, errDifferent) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	o := &options{}
+	root := &cobra.Command{
+		Use:   "kubectl-loongcollector",
+		Short: "Inspect and operate LoongCollector Pipelines",
+		Long: `Inspect and operate LoongCollector Pipelines with the validation and rendering code of the operator.
+
+Config-Server is reached through the Kubernetes API server service proxy, so only access to the cluster is needed.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	o.addFlags(root)
+	root.AddCommand(
+		newLintCommand(o),
+		newRenderCommand(o),
+		newDiffCommand(o),
+		newStatusCommand(o),
+		newTriggerCommand(o),
+		newConvertCommand(o),
+		newSimulateCommand(o),
+		newTestCommand(o),
+	)
+	return root
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/contentfrom"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+)
+
+// options are the flags shared by all subcommands.
+type options struct {
+	kubeconfig   string
+	context      string
+	namespace    string
+	configServer string
+	direct       bool
+	timeout      time.Duration
+
+	clientConfig clientcmd.ClientConfig
+}
+
+func (o *options) addFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	flags.StringVar(&o.context, "context", "", "The kubeconfig context to use.")
+	flags.StringVarP(&o.namespace, "namespace", "n", "", "The namespace of the Pipelines. Defaults to the namespace of the context.")
+	flags.StringVar(&o.configServer, "config-server", "",
+		"The Config-Server address. Defaults to the address configured for the operator, or the ConfigServers a Pipeline fans out to.")
+	flags.BoolVar(&o.direct, "direct", false,
+		"Connect to Config-Server directly instead of through the Kubernetes API server service proxy.")
+	flags.DurationVar(&o.timeout, "timeout", time.Minute, "The timeout of the command.")
+}
+
+func (o *options) loadClientConfig() clientcmd.ClientConfig {
+	if o.clientConfig == nil {
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		rules.ExplicitPath = o.kubeconfig
+		o.clientConfig = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules,
+			&clientcmd.ConfigOverrides{CurrentContext: o.context})
+	}
+	return o.clientConfig
+}
+
+func (o *options) restConfig() (*rest.Config, error) {
+	cfg, err := o.loadClientConfig().ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	return cfg, nil
+}
+
+// targetNamespace returns --namespace or the namespace of the kubeconfig context.
+func (o *options) targetNamespace() (string, error) {
+	if o.namespace != "" {
+		return o.namespace, nil
+	}
+	ns, _, err := o.loadClientConfig().Namespace()
+	return ns, err
+}
+
+func (o *options) client() (client.Client, error) {
+	cfg, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(cfg, client.Options{Scheme: scheme})
+}
+
+func (o *options) commandContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), o.timeout)
+}
+
+func (o *options) getPipeline(ctx context.Context, c client.Client, name string) (*v1alpha1.Pipeline, error) {
+	ns, err := o.targetNamespace()
+	if err != nil {
+		return nil, err
+	}
+	pipeline := &v1alpha1.Pipeline{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, pipeline); err != nil {
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+// resolveContent replaces the content of a pipeline using contentFrom, a template, ProcessorChains, FlusherProfiles
+// or another source format with the configuration the operator renders for it. c is only used when the pipeline
+// renders from the cluster.
+func resolveContent(ctx context.Context, c client.Client, pipeline *v1alpha1.Pipeline) error {
+	if !rendersFromCluster(pipeline) && pipeline.Spec.SourceFormat == "" {
+		return nil
+	}
+	content, err := contentfrom.Resolve(ctx, c, pipeline)
+	if err == nil {
+		content, _, err = profile.Render(ctx, c, pipeline, content)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName(pipeline), err)
+	}
+	pipeline.Spec.Content = content
+	return nil
+}
+
+// rendersFromCluster reports whether the configuration of the pipeline depends on other objects of the cluster.
+func rendersFromCluster(pipeline *v1alpha1.Pipeline) bool {
+	return pipeline.Spec.ContentFrom != nil || pipeline.Spec.TemplateRef != nil ||
+		len(pipeline.Spec.ProcessorChainRefs) > 0 || len(pipeline.Spec.FlusherProfileRefs) > 0
+}
+
+// target is a Config-Server a Pipeline is applied to.
+type target struct {
+	// Name is the ConfigServer name, empty for the Config-Server of the operator
+	Name     string
+	Endpoint string
+	Client   *configserver.ConfigServerClient
+}
+
+func (t target) String() string {
+	if t.Name == "" {
+		return t.Endpoint
+	}
+	return fmt.Sprintf("%s (%s)", t.Name, t.Endpoint)
+}
+
+// targets returns the Config-Servers the pipeline is applied to: --config-server, the ConfigServers in
+// status.targets when the pipeline fans out, or the Config-Server configured for the operator.
+func (o *options) targets(ctx context.Context, c client.Client, pipeline *v1alpha1.Pipeline) ([]target, error) {
+	var targets []target
+	switch {
+	case o.configServer != "":
+		targets = append(targets, target{Endpoint: o.configServer})
+	case pipeline.Spec.ConfigServerSelector != nil:
+		for _, status := range pipeline.Status.Targets {
+			targets = append(targets, target{Name: status.Name, Endpoint: status.Endpoint})
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("pipeline %s has not been applied to any ConfigServer yet", pipeline.Name)
+		}
+	default:
+		endpoint, err := configserver.LookupURL(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the Config-Server address, set --config-server: %w", err)
+		}
+		targets = append(targets, target{Endpoint: endpoint})
+	}
+
+	for i := range targets {
+		csClient, err := o.configServerClient(targets[i].Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		targets[i].Client = csClient
+	}
+	return targets, nil
+}
+
+// configServerClient returns a client for endpoint, through the API server service proxy unless --direct is set.
+func (o *options) configServerClient(endpoint string) (*configserver.ConfigServerClient, error) {
+	if o.direct {
+		return configserver.NewConfigServerClient(endpoint, nil, ""), nil
+	}
+	cfg, err := o.restConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := serviceProxyURL(cfg.Host, endpoint, configserver.ConfigMapNamespace)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return configserver.NewConfigServerClientFor(proxy, httpClient), nil
+}
+
+// serviceProxyURL maps an in-cluster Service address such as http://config-server.ns:8899 to its
+// API server proxy URL. Services without a namespace in the host are looked up in defaultNamespace.
+func serviceProxyURL(apiServer, endpoint, defaultNamespace string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid Config-Server address %q: %w", endpoint, err)
+	}
+	host := u.Hostname()
+	if host == "" || net.ParseIP(host) != nil {
+		return "", fmt.Errorf("address %q of Config-Server is not a Service, use --direct", endpoint)
+	}
+	parts := strings.Split(host, ".")
+	service, namespace := parts[0], defaultNamespace
+	if len(parts) > 1 {
+		namespace = parts[1]
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if u.Scheme == "https" {
+		service = "https:" + service
+	}
+	if !strings.Contains(apiServer, "://") {
+		apiServer = "https://" + apiServer
+	}
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s:%s/proxy%s",
+		strings.TrimSuffix(apiServer, "/"), namespace, service, port, strings.TrimSuffix(u.Path, "/")), nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/pipelinetest"
+)
+
+func newTestCommand(o *options) *cobra.Command {
+	var files []string
+	var verbose bool
+	cmd := &cobra.Command{
+		Use:   "test [TEST...] [-f FILE...]",
+		Short: "Run PipelineTests against their Pipelines",
+		Long: `Run PipelineTests with the processor simulator of the operator and print whether each one passes.
+Nothing is sent to Config-Server or to the agents.
+
+Files may hold PipelineTests and the Pipelines they reference, so that tests kept in Git next to their Pipelines
+run offline in CI. Pipelines not found in the files, and PipelineTests given by name, are read from the cluster.
+The command exits with 1 when a test fails.`,
+		Example: `  kubectl loongcollector test -f pipeline.yaml -f pipeline-test.yaml
+  kubectl loongcollector test -n logging nginx-access-parsing`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(files) == 0 && len(args) == 0 {
+				return fmt.Errorf("no pipeline test given, pass names or -f")
+			}
+			tests, err := readPipelineTests(files)
+			if err != nil {
+				return err
+			}
+			pipelines, err := readPipelines(files)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := o.commandContext()
+			defer cancel()
+			var c client.Client
+			connect := func() (client.Client, error) {
+				if c == nil {
+					c, err = o.client()
+				}
+				return c, err
+			}
+			for _, name := range args {
+				test, err := o.getPipelineTest(ctx, connect, name)
+				if err != nil {
+					return err
+				}
+				tests = append(tests, test)
+			}
+			if len(tests) == 0 {
+				return fmt.Errorf("no PipelineTest found in %v", files)
+			}
+
+			failed := 0
+			out := cmd.OutOrStdout()
+			for _, test := range tests {
+				outcome, err := o.runTest(ctx, connect, pipelines, test)
+				if err != nil {
+					outcome = &pipelinetest.Outcome{Failures: []string{err.Error()}}
+				}
+				if outcome.Passed() {
+					fmt.Fprintf(out, "PASS %s: %d records\n", testName(test), len(outcome.Output))
+				} else {
+					failed++
+					fmt.Fprintf(out, "FAIL %s\n", testName(test))
+				}
+				for _, failure := range outcome.Failures {
+					fmt.Fprintf(out, "    %s\n", failure)
+				}
+				if verbose {
+					for _, note := range outcome.Notes {
+						fmt.Fprintf(out, "    note: %s\n", note)
+					}
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d pipeline tests failed", failed, len(tests))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "Files holding PipelineTests and Pipelines, - for stdin.")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Print the effects of processors the simulator does not reproduce.")
+	return cmd
+}
+
+func (o *options) getPipelineTest(ctx context.Context, connect func() (client.Client, error),
+	name string) (*v1alpha1.PipelineTest, error) {
+	c, err := connect()
+	if err != nil {
+		return nil, err
+	}
+	ns, err := o.targetNamespace()
+	if err != nil {
+		return nil, err
+	}
+	test := &v1alpha1.PipelineTest{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, test); err != nil {
+		return nil, err
+	}
+	return test, nil
+}
+
+// runTest runs a PipelineTest against the Pipeline it references, taken from pipelines when one of them matches
+// and read from the cluster otherwise.
+func (o *options) runTest(ctx context.Context, connect func() (client.Client, error), pipelines []*v1alpha1.Pipeline,
+	test *v1alpha1.PipelineTest) (*pipelinetest.Outcome, error) {
+	var pipeline *v1alpha1.Pipeline
+	for _, p := range pipelines {
+		if p.Name == test.Spec.PipelineRef.Name && (p.Namespace == "" || test.Namespace == "" || p.Namespace == test.Namespace) {
+			pipeline = p.DeepCopy()
+			break
+		}
+	}
+
+	var c client.Client
+	var err error
+	if pipeline == nil || rendersFromCluster(pipeline) {
+		if c, err = connect(); err != nil {
+			return nil, err
+		}
+	}
+	ns := test.Namespace
+	if ns == "" {
+		if ns, err = o.targetNamespace(); err != nil {
+			return nil, err
+		}
+	}
+	if pipeline == nil {
+		pipeline = &v1alpha1.Pipeline{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: test.Spec.PipelineRef.Name}, pipeline); err != nil {
+			return nil, err
+		}
+	}
+	if pipeline.Namespace == "" {
+		pipeline.Namespace = ns
+	}
+	if err := resolveContent(ctx, c, pipeline); err != nil {
+		return nil, err
+	}
+
+	outcome, err := pipelinetest.Run(pipeline.Spec.Content, &test.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", displayName(pipeline), err)
+	}
+	return outcome, nil
+}
+
+// testName names a PipelineTest in the output.
+func testName(test *v1alpha1.PipelineTest) string {
+	if test.Namespace == "" {
+		return "pipelinetest/" + test.Name
+	}
+	return fmt.Sprintf("pipelinetest/%s/%s", test.Namespace, test.Name)
+}
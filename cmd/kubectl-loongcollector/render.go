@@ -0,0 +1,74 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+func newRenderCommand(o *options) *cobra.Command {
+	var files []string
+	var output string
+	cmd := &cobra.Command{
+		Use:   "render [PIPELINE...] [-f FILE...]",
+		Short: "Print the request the operator sends to Config-Server for Pipelines",
+		Long:  `Print the CreateConfig request body the operator sends to Config-Server for each Pipeline.`,
+		Example: `  kubectl loongcollector render -f pipeline.yaml
+  kubectl loongcollector render -n logging nginx-access -o yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "json" && output != "yaml" {
+				return fmt.Errorf("unsupported output %q, want json or yaml", output)
+			}
+			pipelines, err := o.loadPipelines(files, args)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			for i, pipeline := range pipelines {
+				payload, err := render.Payload(pipeline.Spec.Name, pipeline.Spec.Content)
+				if err != nil {
+					return fmt.Errorf("%s: %w", displayName(pipeline), err)
+				}
+				var data []byte
+				if output == "yaml" {
+					if i > 0 {
+						fmt.Fprintln(out, "---")
+					}
+					data, err = yaml.Marshal(payload)
+				} else {
+					data, err = json.MarshalIndent(payload, "", "  ")
+					data = append(data, '\n')
+				}
+				if err != nil {
+					return err
+				}
+				if _, err := out.Write(data); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "Files holding Pipelines or raw pipeline configs, - for stdin.")
+	cmd.Flags().StringVarP(&output, "output", "o", "json", "Output format, json or yaml.")
+	return cmd
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/simulate"
+)
+
+func newSimulateCommand(o *options) *cobra.Command {
+	var files, logs []string
+	var input string
+	var trace bool
+	cmd := &cobra.Command{
+		Use:   "simulate [PIPELINE] [-f FILE] (--input FILE | --log LINE...)",
+		Short: "Run the processors of a Pipeline over sample logs",
+		Long: `Run the processors of a Pipeline over sample logs with a local implementation of the common LoongCollector
+processors, and print the resulting records as JSON, one per line. Nothing is sent to Config-Server or to the agents.
+
+The sample file is split as the input of the Pipeline reads it: one log per line, lines merged by the Multiline
+setting of the input, or the whole file as one log when the Pipeline has split processors.
+Pipelines using processors the simulator does not implement are rejected.`,
+		Example: `  kubectl loongcollector simulate -f pipeline.yaml --input app.log
+  kubectl loongcollector simulate -n logging nginx-access --log '127.0.0.1 - - "GET / HTTP/1.1" 200' --trace`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return fmt.Errorf("simulate takes a single pipeline")
+			}
+			if (input == "") == (len(logs) == 0) {
+				return fmt.Errorf("pass sample logs with either --input or --log")
+			}
+			pipelines, err := o.loadPipelines(files, args)
+			if err != nil {
+				return err
+			}
+			if len(pipelines) != 1 {
+				return fmt.Errorf("simulate takes a single pipeline, got %d", len(pipelines))
+			}
+			pipeline := pipelines[0]
+
+			if input != "" {
+				text, err := readFile(input)
+				if err != nil {
+					return err
+				}
+				if logs, err = simulate.Inputs(pipeline.Spec.Content, string(text)); err != nil {
+					return fmt.Errorf("%s: %w", displayName(pipeline), err)
+				}
+			}
+			result, err := simulate.Run(pipeline.Spec.Content, logs)
+			if err != nil {
+				return fmt.Errorf("%s: %w", displayName(pipeline), err)
+			}
+			for _, note := range result.Notes {
+				fmt.Fprintf(cmd.ErrOrStderr(), "note: %s\n", note)
+			}
+
+			out := cmd.OutOrStdout()
+			if !trace {
+				return writeRecords(out, result.Records)
+			}
+			for _, step := range result.Steps {
+				fmt.Fprintf(out, "# %s: %d records\n", step.Processor, len(step.Records))
+				if err := writeRecords(out, step.Records); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "File holding the Pipeline or the raw pipeline config, - for stdin.")
+	cmd.Flags().StringVar(&input, "input", "", "File of sample logs, - for stdin.")
+	cmd.Flags().StringArrayVar(&logs, "log", nil, "A sample log, may be repeated.")
+	cmd.Flags().BoolVar(&trace, "trace", false, "Print the records after each processor.")
+	return cmd
+}
+
+// writeRecords prints the records as JSON lines with sorted keys.
+func writeRecords(out io.Writer, records []simulate.Record) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// readPipelines reads the Pipelines of the YAML files, "-" being stdin. A document without a kind is
+// taken as the raw content of a pipeline named after the file.
+func readPipelines(files []string) ([]*v1alpha1.Pipeline, error) {
+	var pipelines []*v1alpha1.Pipeline
+	for _, file := range files {
+		err := readDocuments(file, func(i int, kind string, doc []byte) error {
+			switch kind {
+			case "":
+				pipelines = append(pipelines, &v1alpha1.Pipeline{Spec: v1alpha1.PipelineSpec{
+					Name:    rawName(file, i),
+					Content: string(doc),
+				}})
+			case "Pipeline":
+				pipeline := &v1alpha1.Pipeline{}
+				if err := yaml.UnmarshalStrict(doc, pipeline); err != nil {
+					return err
+				}
+				pipelines = append(pipelines, pipeline)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return pipelines, nil
+}
+
+// readPipelineTests reads the PipelineTests of the YAML files, "-" being stdin.
+func readPipelineTests(files []string) ([]*v1alpha1.PipelineTest, error) {
+	var tests []*v1alpha1.PipelineTest
+	for _, file := range files {
+		err := readDocuments(file, func(_ int, kind string, doc []byte) error {
+			if kind != "PipelineTest" {
+				return nil
+			}
+			test := &v1alpha1.PipelineTest{}
+			if err := yaml.UnmarshalStrict(doc, test); err != nil {
+				return err
+			}
+			tests = append(tests, test)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return tests, nil
+}
+
+// readDocuments calls fn with the index, the kind and the content of each non-empty document of a YAML file.
+func readDocuments(file string, fn func(i int, kind string, doc []byte) error) error {
+	data, err := readFile(file)
+	if err != nil {
+		return err
+	}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		var meta struct {
+			Kind string `json:"kind"`
+		}
+		// 原始配置不一定能解析为对象，解析失败时按原始配置处理，由lint报告错误
+		_ = yaml.Unmarshal(doc, &meta)
+		if err := fn(i, meta.Kind, doc); err != nil {
+			return err
+		}
+	}
+}
+
+// stdin holds standard input once read, as a command may read the same files more than once.
+var stdin []byte
+
+func readFile(file string) ([]byte, error) {
+	if file == "-" {
+		if stdin == nil {
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return nil, err
+			}
+			stdin = data
+		}
+		return stdin, nil
+	}
+	return os.ReadFile(file)
+}
+
+func rawName(file string, doc int) string {
+	if doc == 0 {
+		return file
+	}
+	return fmt.Sprintf("%s#%d", file, doc)
+}
+
+// displayName names a pipeline in the output.
+func displayName(pipeline *v1alpha1.Pipeline) string {
+	if pipeline.Name == "" {
+		return pipeline.Spec.Name
+	}
+	if pipeline.Namespace == "" {
+		return "pipeline/" + pipeline.Name
+	}
+	return fmt.Sprintf("pipeline/%s/%s", pipeline.Namespace, pipeline.Name)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+func newStatusCommand(o *options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status PIPELINE",
+		Short: "Show where a Pipeline is applied, down to every agent",
+		Long: `Show the status of a Pipeline, the agent groups it is associated with in each Config-Server,
+and the status of the config reported by every agent of those groups.`,
+		Example: `  kubectl loongcollector status -n logging nginx-access`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := o.commandContext()
+			defer cancel()
+
+			pipeline, err := o.getPipeline(ctx, c, args[0])
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			printPipelineStatus(out, pipeline)
+
+			groups, err := pipelineGroups(ctx, c, pipeline)
+			if err != nil {
+				return err
+			}
+			targets, err := o.targets(ctx, c, pipeline)
+			if err != nil {
+				return err
+			}
+			for _, target := range targets {
+				fmt.Fprintf(out, "\nConfig-Server %s:\n", target)
+				if err := printAgents(ctx, out, target.Client, pipeline.Spec.Name, groups); err != nil {
+					fmt.Fprintf(out, "  error: %v\n", err)
+				}
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+func printPipelineStatus(out io.Writer, pipeline *v1alpha1.Pipeline) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Pipeline:\t%s/%s\n", pipeline.Namespace, pipeline.Name)
+	fmt.Fprintf(w, "Config:\t%s\n", pipeline.Spec.Name)
+	fmt.Fprintf(w, "Generation:\t%d (observed %d)\n", pipeline.Generation, pipeline.Status.ObservedGeneration)
+	if cond := meta.FindStatusCondition(pipeline.Status.Conditions, emus.PipelineConditionReady); cond != nil {
+		fmt.Fprintf(w, "Ready:\t%s (%s) %s\n", cond.Status, cond.Reason, cond.Message)
+	}
+	if applied := pipeline.Status.LastAppliedConfig; !applied.AppliedTime.IsZero() {
+		fmt.Fprintf(w, "Last applied:\t%s to %s\n", applied.AppliedTime.Format("2006-01-02 15:04:05"), applied.Endpoint)
+	}
+	if tpl := pipeline.Status.Template; tpl != nil {
+		fmt.Fprintf(w, "Template:\t%s version %q (generation %d)\n", tpl.Name, tpl.Version, tpl.Generation)
+	}
+	if cond := meta.FindStatusCondition(pipeline.Status.Conditions, emus.PipelineConditionProfilesUpToDate); cond != nil {
+		fmt.Fprintf(w, "Profiles up to date:\t%s (%s) %s\n", cond.Status, cond.Reason, cond.Message)
+	}
+	if token := pipeline.Annotations[v1alpha1.AnnotationResync]; token != "" && token != pipeline.Status.ResyncToken {
+		fmt.Fprintf(w, "Resync:\trequested at %s, pending\n", token)
+	}
+	_ = w.Flush()
+
+	if len(pipeline.Status.Targets) > 0 {
+		fmt.Fprintln(out, "\nTargets:")
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  NAME\tENDPOINT\tSUCCESS\tGENERATION\tMESSAGE")
+		for _, t := range pipeline.Status.Targets {
+			fmt.Fprintf(w, "  %s\t%s\t%t\t%d\t%s\n", t.Name, t.Endpoint, t.Success, t.ObservedGeneration, t.Message)
+		}
+		_ = w.Flush()
+	}
+	if len(pipeline.Status.Profiles) > 0 {
+		fmt.Fprintln(out, "\nProfiles:")
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  KIND\tNAME\tCONTENT")
+		for _, p := range pipeline.Status.Profiles {
+			fmt.Fprintf(w, "  %s\t%s\t%.12s\n", p.Kind, p.Name, p.ContentHash)
+		}
+		_ = w.Flush()
+	}
+	if plan := pipeline.Status.Plan; plan != nil {
+		fmt.Fprintf(out, "\nDry run plan (generation %d):\n", plan.ObservedGeneration)
+		for _, warning := range plan.Warnings {
+			fmt.Fprintf(out, "  %s\n", warning)
+		}
+		for _, tp := range plan.Targets {
+			fmt.Fprintf(out, "  %s: config %s\n", target{Name: tp.Name, Endpoint: tp.Endpoint}, tp.Config)
+			for _, change := range []struct {
+				label  string
+				groups []string
+			}{{"create groups", tp.CreateGroups}, {"associate with", tp.AddGroups}, {"remove from", tp.RemoveGroups}} {
+				if len(change.groups) > 0 {
+					fmt.Fprintf(out, "    %s: %s\n", change.label, strings.Join(change.groups, ", "))
+				}
+			}
+			if tp.Diff != "" {
+				fmt.Fprint(out, indent(tp.Diff, "    "))
+			}
+		}
+	}
+	if len(pipeline.Status.History) > 0 {
+		fmt.Fprintln(out, "\nHistory:")
+		w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "  GENERATION\tCONTENT\tENDPOINT\tAPPLIED")
+		for _, r := range pipeline.Status.History {
+			fmt.Fprintf(w, "  %d\t%.12s\t%s\t%s\n", r.Generation, r.ContentHash, r.Endpoint, r.AppliedTime.Format("2006-01-02 15:04:05"))
+		}
+		_ = w.Flush()
+	}
+}
+
+// pipelineGroups returns spec.agentGroup and the agent groups of the AgentGroups referencing the pipeline.
+func pipelineGroups(ctx context.Context, c client.Client, pipeline *v1alpha1.Pipeline) ([]string, error) {
+	var groups []string
+	if pipeline.Spec.AgentGroup != "" {
+		groups = append(groups, pipeline.Spec.AgentGroup)
+	}
+	var list v1alpha1.AgentGroupList
+	if err := c.List(ctx, &list); err != nil {
+		return nil, fmt.Errorf("failed to list agent groups: %w", err)
+	}
+	for _, group := range list.Items {
+		if slices.Contains(group.Spec.Configs, pipeline.Spec.Name) && !slices.Contains(groups, group.Spec.Name) {
+			groups = append(groups, group.Spec.Name)
+		}
+	}
+	slices.Sort(groups)
+	return groups, nil
+}
+
+// printAgents prints, for every group, whether the config is associated in Config-Server and its status on each agent.
+func printAgents(ctx context.Context, out io.Writer, csClient *configserver.ConfigServerClient, config string, groups []string) error {
+	if len(groups) == 0 {
+		fmt.Fprintln(out, "  not associated with any agent group")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  GROUP\tASSOCIATED\tAGENT\tHOST\tIP\tRUNNING\tCONFIG STATUS")
+	for _, group := range groups {
+		applied, err := csClient.GetAppliedConfigsForAgentGroup(ctx, group)
+		if err != nil {
+			return err
+		}
+		associated := slices.Contains(applied, config)
+		agents, err := csClient.ListAgents(ctx, group)
+		if err != nil {
+			return err
+		}
+		if len(agents) == 0 {
+			fmt.Fprintf(w, "  %s\t%t\t-\t-\t-\t-\t-\n", group, associated)
+			continue
+		}
+		for _, agent := range agents {
+			fmt.Fprintf(w, "  %s\t%t\t%s\t%s\t%s\t%s\t%s\n", group, associated, agent.InstanceID,
+				orDash(agent.Hostname), orDash(agent.IP), orDash(agent.RunningStatus), agentConfigStatus(agent, config, associated))
+		}
+	}
+	return w.Flush()
+}
+
+// agentConfigStatus returns the status of config reported by agent, or pending when the agent has not reported it yet.
+func agentConfigStatus(agent configserver.Agent, config string, associated bool) string {
+	for _, status := range agent.Configs {
+		if status.Name != config {
+			continue
+		}
+		parts := []string{orDash(status.Status)}
+		if status.Version != 0 {
+			parts = append(parts, fmt.Sprintf("v%d", status.Version))
+		}
+		if status.Message != "" {
+			parts = append(parts, status.Message)
+		}
+		return strings.Join(parts, " ")
+	}
+	if associated {
+		return "pending"
+	}
+	return "-"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func indent(text, prefix string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+func newTriggerCommand(o *options) *cobra.Command {
+	var all, waitApplied bool
+	cmd := &cobra.Command{
+		Use:   "trigger (PIPELINE... | --all)",
+		Short: "Apply Pipelines to Config-Server again now",
+		Long: `Ask the operator to apply Pipelines to Config-Server again immediately, even if nothing changed,
+by setting the ` + v1alpha1.AnnotationResync + ` annotation.`,
+		Example: `  kubectl loongcollector trigger -n logging nginx-access --wait
+  kubectl loongcollector trigger -n logging --all`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if all == (len(args) > 0) {
+				return fmt.Errorf("pass either pipeline names or --all")
+			}
+			c, err := o.client()
+			if err != nil {
+				return err
+			}
+			ctx, cancel := o.commandContext()
+			defer cancel()
+
+			ns, err := o.targetNamespace()
+			if err != nil {
+				return err
+			}
+			names := args
+			if all {
+				var list v1alpha1.PipelineList
+				if err := c.List(ctx, &list, client.InNamespace(ns)); err != nil {
+					return err
+				}
+				for _, pipeline := range list.Items {
+					names = append(names, pipeline.Name)
+				}
+			}
+
+			token := time.Now().UTC().Format(time.RFC3339Nano)
+			out := cmd.OutOrStdout()
+			for _, name := range names {
+				pipeline := &v1alpha1.Pipeline{}
+				if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, pipeline); err != nil {
+					return err
+				}
+				patch := client.MergeFrom(pipeline.DeepCopy())
+				if pipeline.Annotations == nil {
+					pipeline.Annotations = map[string]string{}
+				}
+				pipeline.Annotations[v1alpha1.AnnotationResync] = token
+				if err := c.Patch(ctx, pipeline, patch); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "pipeline/%s resync requested\n", name)
+			}
+			if !waitApplied {
+				return nil
+			}
+			for _, name := range names {
+				if err := waitResynced(ctx, c, client.ObjectKey{Namespace: ns, Name: name}, token); err != nil {
+					return fmt.Errorf("pipeline/%s: %w", name, err)
+				}
+				fmt.Fprintf(out, "pipeline/%s applied\n", name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "Trigger all Pipelines of the namespace.")
+	cmd.Flags().BoolVar(&waitApplied, "wait", false, "Wait until the operator has applied the Pipelines again.")
+	return cmd
+}
+
+// waitResynced waits until the operator records token in status.resyncToken.
+func waitResynced(ctx context.Context, c client.Client, key client.ObjectKey, token string) error {
+	return wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		pipeline := &v1alpha1.Pipeline{}
+		if err := c.Get(ctx, key, pipeline); err != nil {
+			return false, err
+		}
+		return pipeline.Status.ResyncToken == token, nil
+	})
+}
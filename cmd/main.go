@@ -17,10 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,12 +33,20 @@ import (
 
 	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
 	"github.com/infraflows/loongcollector-operator/internal/controller"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/metrics"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/sharding"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/tracing"
+	webhookv1 "github.com/infraflows/loongcollector-operator/internal/webhook/v1"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -54,8 +67,25 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
+// subcommands run instead of the manager when named by the first argument.
+var subcommands = map[string]func(args []string, stdout io.Writer) error{
+	"adopt":   runAdopt,
+	"bundle":  runBundle,
+	"migrate": runMigrate,
+}
+
 // nolint:gocyclo
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var metricsAddr string
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
@@ -63,6 +93,18 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var enableSidecarInjection bool
+	var configServerFailureThreshold int
+	var configServerProbeInterval time.Duration
+	var configServerRefreshInterval time.Duration
+	var configServerIdleTimeout time.Duration
+	var tracingOpts tracing.Options
+	var batchSyncConcurrency int
+	var batchSyncQPS float64
+	var batchSyncInterval time.Duration
+	var enableSharding bool
+	var shardOpts sharding.Options
+	var backupImage string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -81,6 +123,46 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.BoolVar(&enableSidecarInjection, "enable-sidecar-injection", false,
+		"If set, the Pod mutating webhook injecting the LoongCollector sidecar is served. "+
+			"Requires the webhook certificates, see config/webhook.")
+	flag.IntVar(&configServerFailureThreshold, "configserver-failure-threshold", configserver.DefaultFailureThreshold,
+		"Number of consecutive Config-Server failures that open the circuit breaker.")
+	flag.DurationVar(&configServerProbeInterval, "configserver-probe-interval", configserver.DefaultProbeInterval,
+		"Interval between Config-Server probes while the circuit breaker is open.")
+	flag.DurationVar(&configServerRefreshInterval, "configserver-cache-refresh-interval", configserver.DefaultRefreshInterval,
+		"Interval at which the cached Config-Server agent groups and configs are refreshed.")
+	flag.DurationVar(&configServerIdleTimeout, "configserver-idle-timeout", configserver.DefaultIdleTimeout,
+		"Time after which the client and circuit breaker of an unused ConfigServer endpoint are released.")
+	flag.IntVar(&batchSyncConcurrency, "batch-sync-concurrency", controller.DefaultBatchSyncConcurrency,
+		"Number of concurrent Config-Server requests when batch syncing Pipelines.")
+	flag.Float64Var(&batchSyncQPS, "batch-sync-qps", controller.DefaultBatchSyncQPS,
+		"Maximum Config-Server requests per second when batch syncing Pipelines, 0 for no limit.")
+	flag.DurationVar(&batchSyncInterval, "batch-sync-interval", 0,
+		"Interval between batch syncs of all Pipelines. The batch sync runs once on startup if 0.")
+	flag.StringVar(&tracingOpts.Endpoint, "tracing-endpoint", "",
+		"The OTLP gRPC endpoint traces are exported to, e.g. otel-collector:4317. Tracing is disabled if empty.")
+	flag.BoolVar(&tracingOpts.Insecure, "tracing-insecure", false,
+		"If set, the OTLP endpoint is reached without TLS.")
+	flag.Float64Var(&tracingOpts.SampleRatio, "tracing-sample-ratio", 1,
+		"The ratio of reconciles that are traced, between 0 and 1.")
+	flag.StringVar(&tracingOpts.ServiceName, "tracing-service-name", tracing.DefaultServiceName,
+		"The service.name reported with the traces.")
+	flag.BoolVar(&enableSharding, "enable-sharding", false,
+		"If set, Pipelines and AgentGroups are split between all replicas by a consistent hash instead of "+
+			"being reconciled by the leader only. Replicas coordinate through Leases.")
+	flag.StringVar(&shardOpts.Identity, "shard-identity", defaultShardIdentity(),
+		"The identity of this replica in the shard ring. Defaults to $POD_NAME or the hostname.")
+	flag.StringVar(&shardOpts.Namespace, "shard-namespace", os.Getenv("POD_NAMESPACE"),
+		"The namespace of the shard Leases. Defaults to $POD_NAMESPACE.")
+	flag.StringVar(&shardOpts.Group, "shard-group", "default",
+		"The replicas sharing the Pipelines and AgentGroups. Replicas of different groups do not coordinate.")
+	flag.DurationVar(&shardOpts.LeaseDuration, "shard-lease-duration", sharding.DefaultLeaseDuration,
+		"Duration after which a replica that stopped renewing its Lease leaves the shard ring.")
+	flag.DurationVar(&shardOpts.RenewInterval, "shard-renew-interval", sharding.DefaultRenewInterval,
+		"Interval between shard Lease renewals and membership refreshes.")
+	flag.StringVar(&backupImage, "backup-image", "",
+		"The image running the export Jobs of Backups without spec.image. Defaults to the image of this Pod.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -133,14 +215,26 @@ func main() {
 		TLSOpts: webhookTLSOpts,
 	})
 
+	configServers := configserver.NewRegistry(configserver.RegistryOptions{
+		FailureThreshold: configServerFailureThreshold,
+		ProbeInterval:    configServerProbeInterval,
+		RefreshInterval:  configServerRefreshInterval,
+		IdleTimeout:      configServerIdleTimeout,
+	})
+
 	// Metrics endpoint is enabled in 'config/default/kustomization.yaml'. The Metrics options configure the server.
 	// More info:
 	// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.20.4/pkg/metrics/server
 	// - https://book.kubebuilder.io/reference/metrics.html
+	// The breaker state of the default Config-Server is served next to the metrics instead of /readyz, so a Config-Server
+	// outage does not take the webhook Service endpoints down.
 	metricsServerOptions := metricsserver.Options{
 		BindAddress:   metricsAddr,
 		SecureServing: secureMetrics,
 		TLSOpts:       tlsOpts,
+		ExtraHandlers: map[string]http.Handler{
+			"/configserver/healthz": healthz.CheckHandler{Checker: configServers.HealthCheck},
+		},
 	}
 
 	if secureMetrics {
@@ -183,8 +277,11 @@ func main() {
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "29d18c79.co.infraflow",
+		// Secrets are only watched by metadata; their data is read from the API server when a Pipeline
+		// references it, so the operator does not keep every Secret of the cluster in memory.
+		Client:           client.Options{Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}}}},
+		LeaderElection:   enableLeaderElection,
+		LeaderElectionID: "29d18c79.co.infraflow",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -202,22 +299,112 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controller.PipelineReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Pipeline"),
-		Event:  mgr.GetEventRecorderFor("Pipeline"),
-	}).SetupWithManager(mgr); err != nil {
+	if err = mgr.Add(configServers); err != nil {
+		setupLog.Error(err, "unable to add Config-Server client registry to manager")
+		os.Exit(1)
+	}
+
+	var shard *sharding.Coordinator
+	if enableSharding {
+		shard, err = sharding.NewCoordinator(mgr.GetClient(), mgr.GetAPIReader(),
+			ctrl.Log.WithName("sharding"), shardOpts)
+		if err != nil {
+			setupLog.Error(err, "unable to create shard coordinator")
+			os.Exit(1)
+		}
+		if err = mgr.Add(shard); err != nil {
+			setupLog.Error(err, "unable to add shard coordinator to manager")
+			os.Exit(1)
+		}
+	}
+
+	if err = metrics.RegisterStateCollector(mgr.GetClient()); err != nil {
+		setupLog.Error(err, "unable to register metrics collector")
+		os.Exit(1)
+	}
+	if err = controller.SetupIndexes(context.Background(), mgr); err != nil {
+		setupLog.Error(err, "unable to set up field indexes")
+		os.Exit(1)
+	}
+	initialSync := controller.NewSyncGate()
+	pipelineReconciler := &controller.PipelineReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Log:           ctrl.Log.WithName("controllers").WithName("Pipeline"),
+		Event:         mgr.GetEventRecorderFor("Pipeline"),
+		ConfigServers: configServers,
+		Shard:         shard,
+		InitialSync:   initialSync,
+	}
+	if err = pipelineReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Pipeline")
 		os.Exit(1)
 	}
 	if err = (&controller.AgentGroupReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Log:           ctrl.Log.WithName("controllers").WithName("AgentGroup"),
+		Event:         mgr.GetEventRecorderFor("AgentGroup"),
+		ConfigServers: configServers,
+		Shard:         shard,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "AgentGroup")
+		os.Exit(1)
+	}
+	if err = mgr.Add(&controller.PipelineBatchSyncer{
+		Client:        mgr.GetClient(),
+		Cache:         mgr.GetCache(),
+		Log:           ctrl.Log.WithName("controllers").WithName("PipelineBatchSync"),
+		ConfigServers: configServers,
+		Shard:         shard,
+		Concurrency:   batchSyncConcurrency,
+		QPS:           batchSyncQPS,
+		Interval:      batchSyncInterval,
+		InitialSync:   initialSync,
+		Reconciler:    pipelineReconciler,
+	}); err != nil {
+		setupLog.Error(err, "unable to add pipeline batch sync")
+		os.Exit(1)
+	}
+	if err = (&controller.AdoptionReconciler{
+		Client:        mgr.GetClient(),
+		Log:           ctrl.Log.WithName("controllers").WithName("Adoption"),
+		Event:         mgr.GetEventRecorderFor("Adoption"),
+		ConfigServers: configServers,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Adoption")
+		os.Exit(1)
+	}
+	if err = (&controller.BackupReconciler{
 		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("Backup"),
 		Scheme: mgr.GetScheme(),
+		Event:  mgr.GetEventRecorderFor("Backup"),
+		Image:  backupImage,
+		Pod:    types.NamespacedName{Namespace: os.Getenv("POD_NAMESPACE"), Name: os.Getenv("POD_NAME")},
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "AgentGroup")
+		setupLog.Error(err, "unable to create controller", "controller", "Backup")
+		os.Exit(1)
+	}
+	if err = (&controller.PipelineTestReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("PipelineTest"),
+		Event:  mgr.GetEventRecorderFor("PipelineTest"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PipelineTest")
+		os.Exit(1)
+	}
+	if err = controller.SetupWorkloadPipelineControllers(mgr,
+		ctrl.Log.WithName("controllers").WithName("WorkloadPipeline")); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WorkloadPipeline")
 		os.Exit(1)
 	}
+	if enableSidecarInjection {
+		if err = webhookv1.SetupPodWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -245,9 +432,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingOpts)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
+}
+
+// defaultShardIdentity 优先使用Downward API注入的Pod名称
+func defaultShardIdentity() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	hostname, _ := os.Hostname()
+	return hostname
 }
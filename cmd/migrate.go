@@ -0,0 +1,126 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/adopt"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/aliyun"
+)
+
+// runMigrate implements the migrate subcommand: it converts AliyunPipelineConfig, ClusterAliyunPipelineConfig and
+// AliyunLogConfig objects read from files or the cluster into Pipelines and AgentGroups, prints their manifests or
+// creates them with --apply, and writes a report of the field mappings and lossy conversions.
+func runMigrate(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	filenames := fs.String("filename", "", "Comma separated YAML files holding the objects to migrate, - for stdin.")
+	fromCluster := fs.Bool("from-cluster", false, "If set, the objects are read from the cluster of the current kubeconfig.")
+	namespace := fs.String("namespace", "",
+		"The namespace of the generated Pipelines and AgentGroups. Defaults to the namespace of each object, "+
+			"default for cluster scoped ones.")
+	apply := fs.Bool("apply", false,
+		"If set, the Pipelines and AgentGroups are created in the cluster of the current kubeconfig instead of printed.")
+	report := fs.String("report", "", "The file the migration report is written to. Defaults to stderr.")
+	reportFormat := fs.String("report-format", "text", "The format of the migration report: text or json.")
+	timeout := fs.Duration("timeout", 5*time.Minute, "The timeout of the whole migration.")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if (*filenames == "") == !*fromCluster {
+		return fmt.Errorf("exactly one of --filename and --from-cluster must be set")
+	}
+	if *reportFormat != "text" && *reportFormat != "json" {
+		return fmt.Errorf("unknown report format %q, must be text or json", *reportFormat)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var objs []unstructured.Unstructured
+	var notes []string
+	var err error
+	if *filenames != "" {
+		objs, err = aliyun.ReadFiles(strings.Split(*filenames, ","))
+	} else {
+		c, cerr := newCLIClient()
+		if cerr != nil {
+			return cerr
+		}
+		objs, notes, err = aliyun.List(ctx, c)
+	}
+	if err != nil {
+		return err
+	}
+
+	result, err := aliyun.Convert(objs, aliyun.Options{Namespace: *namespace})
+	if err != nil {
+		return err
+	}
+	result.Report.Notes = append(notes, result.Report.Notes...)
+	if err := writeReport(&result.Report, *report, *reportFormat); err != nil {
+		return err
+	}
+
+	generated := adopt.Result{Pipelines: result.Pipelines, AgentGroups: result.AgentGroups}
+	if !*apply {
+		manifests, err := adopt.Manifests(generated)
+		if err != nil {
+			return err
+		}
+		_, err = stdout.Write(manifests)
+		return err
+	}
+	c, err := newCLIClient()
+	if err != nil {
+		return err
+	}
+	created, err := adopt.Apply(ctx, c, generated)
+	for _, name := range created {
+		fmt.Fprintf(stdout, "%s created\n", name)
+	}
+	return err
+}
+
+// writeReport writes the migration report to file, or to stderr when file is empty.
+func writeReport(report *aliyun.Report, file, format string) error {
+	out := []byte(report.Text())
+	if format == "json" {
+		var err error
+		if out, err = report.JSON(); err != nil {
+			return err
+		}
+		out = append(out, '\n')
+	}
+	if file == "" {
+		_, err := os.Stderr.Write(out)
+		return err
+	}
+	return os.WriteFile(file, out, 0o644)
+}
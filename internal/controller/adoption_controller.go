@@ -0,0 +1,127 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/adopt"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+// adoptedUnchanged 已接管且spec未被修改过的资源不下发到Config-Server，删除时也不清理Config-Server
+func adoptedUnchanged(obj client.Object) bool {
+	return obj.GetAnnotations()[v1alpha1.AnnotationAdopted] != "" && obj.GetGeneration() <= 1
+}
+
+// AdoptionReconciler adopts the configs and agent groups of the Config-Server into a Namespace annotated with
+// loongcollector.infraflow.co/adopt: "true", then removes the annotation.
+type AdoptionReconciler struct {
+	client.Client
+	Log   logr.Logger
+	Event record.EventRecorder
+	// ConfigServers shares one client and circuit breaker per Config-Server endpoint
+	ConfigServers *configserver.Registry
+	retrier
+}
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelines;agentgroups,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *AdoptionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("namespace", req.Name)
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, req.NamespacedName, ns); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if ns.Annotations[v1alpha1.AnnotationAdopt] != "true" || ns.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	created, skipped, err := r.adopt(ctx, ns.Name)
+	if err != nil {
+		result := r.retryResult(ctx, ns, err)
+		logRetry(log, err, "Failed to adopt Config-Server configs", "requeueAfter", result.RequeueAfter)
+		r.Event.Event(ns, corev1.EventTypeWarning, "AdoptionFailed", err.Error())
+		return result, nil
+	}
+	r.retrySucceeded(ns)
+
+	msg := fmt.Sprintf("adopted %d resources, skipped %d configs and groups already declared", len(created), len(skipped))
+	log.Info("Adopted Config-Server configs", "created", created, "skipped", skipped)
+	r.Event.Event(ns, corev1.EventTypeNormal, "Adopted", msg)
+
+	patch := client.MergeFrom(ns.DeepCopy())
+	delete(ns.Annotations, v1alpha1.AnnotationAdopt)
+	return ctrl.Result{}, r.Patch(ctx, ns, patch)
+}
+
+// adopt 读取Config-Server并创建未被声明的配置与Agent组对应的资源
+func (r *AdoptionReconciler) adopt(ctx context.Context, namespace string) ([]string, []string, error) {
+	endpoint, err := lookupConfigServerURL(ctx, r.Client, r.ConfigServers)
+	if err != nil {
+		return nil, nil, err
+	}
+	csClient := configServerClient(r.ConfigServers, endpoint)
+	if err := csClient.Refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+	src, err := adopt.Fetch(ctx, csClient, endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	configs, groups, err := adopt.Existing(ctx, r.Client)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := adopt.Build(src, adopt.Options{
+		Namespace:       namespace,
+		ExistingConfigs: configs,
+		ExistingGroups:  groups,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	created, err := adopt.Apply(ctx, r.Client, result)
+	return created, result.Skipped, err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AdoptionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("adoption").
+		For(&corev1.Namespace{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetAnnotations()[v1alpha1.AnnotationAdopt] == "true"
+		}))).
+		Complete(r)
+}
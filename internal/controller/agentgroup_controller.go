@@ -18,20 +18,33 @@ package controller
 
 import (
 	"context"
-	"time"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/agenttag"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/association"
 	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/sharding"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/tracing"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
@@ -44,21 +57,33 @@ type AgentGroupReconciler struct {
 	Scheme  *runtime.Scheme
 	Event   record.EventRecorder
 	BaseURL string
+	// ConfigServers shares one client and circuit breaker per Config-Server endpoint
+	ConfigServers *configserver.Registry
+	// Shard restricts the reconciler to the AgentGroups owned by this replica, nil reconciles all of them
+	Shard *sharding.Coordinator
+	retrier
 }
 
 const (
 	agentGroupFinalizer = "agentgroup.finalizers.infraflow.co"
 )
 
-// +kubebuilder:rbac:groups=infraflow.co,resources=agentgroups,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=infraflow.co,resources=agentgroups/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=infraflow.co,resources=agentgroups/finalizers,verbs=update
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=agentgroups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=agentgroups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=agentgroups/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes;pods,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *AgentGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("agentgroup", req.NamespacedName)
+	if !ownsKey(r.Shard, req.NamespacedName) {
+		log.V(1).Info("AgentGroup is owned by another replica, skipping")
+		return reconcile.Result{}, nil
+	}
+	ctx, span := tracing.StartReconcile(ctx, "AgentGroup", req.NamespacedName)
+	defer span.End()
 
 	if err := r.getConfigServerURL(ctx); err != nil {
 		log.Error(err, "Failed to get ConfigServer URL")
@@ -98,53 +123,72 @@ func (r *AgentGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
-	agentClient := configserver.NewConfigServerClient(r.BaseURL, &r.Client, agentGroup.Namespace)
+	tags, err := agenttag.Resolve(ctx, r.Client, agentGroup)
+	if err != nil {
+		log.Error(err, "Failed to resolve agent group tags")
+		return reconcile.Result{}, err
+	}
+
+	agentClient := configServerClient(r.ConfigServers, r.BaseURL)
 	group := &configserver.AgentGroup{
 		Name:        agentGroup.Spec.Name,
 		Description: agentGroup.Spec.Description,
-		Tags:        agentGroup.Spec.Tags,
-	}
-
-	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		// Try to create the agent group
-		if err := agentClient.CreateAgentGroup(ctx, group); err != nil {
-			// If the group already exists, try to update it
-			if err := agentClient.UpdateAgentGroup(ctx, group); err != nil {
-				lastErr = err
-				log.Error(err, "Failed to create/update agent group, retrying", "attempt", i+1, "maxAttempts", maxRetries)
-				time.Sleep(retryDelay)
-				continue
-			}
-		}
+		Tags:        tags,
+	}
 
-		// Apply configs to the agent group
-		for _, configName := range agentGroup.Spec.Configs {
-			if err := agentClient.ApplyConfigToAgentGroup(ctx, configName, agentGroup.Spec.Name); err != nil {
-				lastErr = err
-				log.Error(err, "Failed to apply config to agent group, retrying", "config", configName, "attempt", i+1, "maxAttempts", maxRetries)
-				time.Sleep(retryDelay)
-				continue
-			}
-		}
+	graph, err := r.computeAssociations(ctx, agentGroup)
+	if err != nil {
+		log.Error(err, "Failed to compute agent group associations")
+		return reconcile.Result{}, err
+	}
 
-		lastErr = nil
-		break
+	// 已接管的AgentGroup在spec与绑定的Pipeline都未变化前不下发
+	if adoptedUnchanged(agentGroup) && sets.New(graph.Desired...).Equal(sets.New(agentGroup.Spec.Configs...)) {
+		return reconcile.Result{}, r.recordAdopted(ctx, agentGroup)
 	}
 
-	if lastErr != nil {
-		log.Error(lastErr, "Failed to manage agent group after retries")
+	applied, err := r.syncAgentGroup(ctx, agentClient, group, graph.Desired)
+
+	agentGroup.Status.Tags = tags
+	agentGroup.Status.MissingConfigs = graph.Missing
+	agentGroup.Status.ObservedGeneration = agentGroup.Generation
+	setAssociationConditions(agentGroup, graph)
+
+	if err != nil {
+		// 不可重试的错误不再重新入队，等待spec或关联的Pipeline变化后再次处理
+		result := r.retryResult(ctx, agentGroup, err)
+		tracing.RecordError(span, err)
+		logRetry(log, err, "Failed to sync agent group", "requeueAfter", result.RequeueAfter)
 		agentGroup.Status.Success = false
 		agentGroup.Status.Message = emus.AgentGroupStatusFailed
-		r.Event.Event(agentGroup, corev1.EventTypeWarning, "FailedToManageAgentGroup", lastErr.Error())
+		meta.SetStatusCondition(&agentGroup.Status.Conditions,
+			failureCondition(emus.AgentGroupConditionSynced, agentGroup.Generation, err))
+		if !stderrors.Is(err, configserver.ErrCircuitOpen) {
+			r.Event.Event(agentGroup, corev1.EventTypeWarning, "FailedToManageAgentGroup", err.Error())
+		}
 		agentGroup.Status.LastUpdateTime = metav1.Now()
 		_ = r.Status().Update(ctx, agentGroup)
-		return reconcile.Result{}, lastErr
+		return result, nil
+	}
+
+	// 改名后删除旧名称的Agent组，删除失败时下次调和重试
+	if err := r.removeRenamed(ctx, agentClient, agentGroup); err != nil {
+		log.Error(err, "Failed to delete agent group of previous name", "previous", agentGroup.Status.AppliedName)
+		return reconcile.Result{}, err
 	}
 
+	r.retrySucceeded(agentGroup)
 	agentGroup.Status.Success = true
 	agentGroup.Status.Message = emus.AgentGroupStatusSuccess
-	agentGroup.Status.AppliedConfigs = agentGroup.Spec.Configs
+	agentGroup.Status.AppliedConfigs = applied
+	agentGroup.Status.AppliedName = agentGroup.Spec.Name
+	meta.SetStatusCondition(&agentGroup.Status.Conditions, metav1.Condition{
+		Type:               emus.AgentGroupConditionSynced,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: agentGroup.Generation,
+		Reason:             "Synced",
+		Message:            fmt.Sprintf("%d configs applied", len(applied)),
+	})
 	r.Event.Event(agentGroup, corev1.EventTypeNormal, "SuccessfulManageAgentGroup", agentGroup.Status.Message)
 	agentGroup.Status.LastUpdateTime = metav1.Now()
 	if err := r.Status().Update(ctx, agentGroup); err != nil {
@@ -156,32 +200,198 @@ func (r *AgentGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{RequeueAfter: syncInterval}, nil
 }
 
-// getConfigServerURL gets the ConfigServer URL from ConfigMap
-func (r *AgentGroupReconciler) getConfigServerURL(ctx context.Context) error {
-	configMap := &corev1.ConfigMap{}
-	err := r.Get(ctx, client.ObjectKey{Namespace: configMapNamespace, Name: configMapName}, configMap)
+// computeAssociations 计算AgentGroup的期望关联：spec.configs与绑定到该组的Pipeline的并集
+func (r *AgentGroupReconciler) computeAssociations(ctx context.Context, agentGroup *v1alpha1.AgentGroup) (association.Result, error) {
+	var bound v1alpha1.PipelineList
+	if err := r.List(ctx, &bound, client.MatchingFields{pipelineAgentGroupField: agentGroup.Spec.Name}); err != nil {
+		return association.Result{}, err
+	}
+
+	// 只需要期望关联的配置名对应的Pipeline，同名的Pipeline用于冲突检测
+	names := map[string]struct{}{}
+	for _, name := range agentGroup.Spec.Configs {
+		names[name] = struct{}{}
+	}
+	for i := range bound.Items {
+		if p, ok := associatedPipeline(&bound.Items[i]); ok && p.Spec.AgentGroup == agentGroup.Spec.Name {
+			names[p.Spec.Name] = struct{}{}
+		}
+	}
+	// 设置了configServerSelector的Pipeline不下发到本集群的Config-Server，不参与关联；
+	// dryRun的Pipeline按最后一次下发的配置名与Agent组参与关联
+	var pipelines []v1alpha1.Pipeline
+	for name := range names {
+		var list v1alpha1.PipelineList
+		if err := r.List(ctx, &list, client.MatchingFields{pipelineNameField: name}); err != nil {
+			return association.Result{}, err
+		}
+		for i := range list.Items {
+			if p, ok := associatedPipeline(&list.Items[i]); ok && p.Spec.Name == name {
+				pipelines = append(pipelines, p)
+			}
+		}
+	}
+
+	var groups v1alpha1.AgentGroupList
+	if err := r.List(ctx, &groups, client.MatchingFields{agentGroupNameField: agentGroup.Spec.Name}); err != nil {
+		return association.Result{}, err
+	}
+	return association.Compute(agentGroup, pipelines, groups.Items), nil
+}
+
+// syncAgentGroup 创建或更新Agent组，并按期望关联新增与移除配置，返回同步后的关联
+func (r *AgentGroupReconciler) syncAgentGroup(ctx context.Context, agentClient *configserver.ConfigServerClient,
+	group *configserver.AgentGroup, desired []string) ([]string, error) {
+	log := r.Log.WithValues("group", group.Name)
+
+	// Try to create the agent group, if the group already exists, try to update it
+	if err := agentClient.CreateAgentGroup(ctx, group); err != nil {
+		if err := agentClient.UpdateAgentGroup(ctx, group); err != nil {
+			return nil, err
+		}
+	}
+
+	actual, err := agentClient.GetAppliedConfigsForAgentGroup(ctx, group.Name)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			r.BaseURL = defaultBaseURL
-			return nil
+		return nil, err
+	}
+
+	add, remove := association.Diff(desired, actual)
+	for _, configName := range add {
+		if err := agentClient.ApplyConfigToAgentGroup(ctx, configName, group.Name); err != nil {
+			return nil, fmt.Errorf("failed to apply config %s: %w", configName, err)
 		}
+		log.Info("Applied config to agent group", "config", configName)
+	}
+	for _, configName := range remove {
+		if err := agentClient.RemoveConfigFromAgentGroup(ctx, configName, group.Name); err != nil {
+			return nil, fmt.Errorf("failed to remove config %s: %w", configName, err)
+		}
+		log.Info("Removed config from agent group", "config", configName)
+	}
+	return desired, nil
+}
+
+// removeRenamed 删除改名前同步的Agent组，仍被其他AgentGroup声明时保留
+func (r *AgentGroupReconciler) removeRenamed(ctx context.Context, agentClient *configserver.ConfigServerClient,
+	agentGroup *v1alpha1.AgentGroup) error {
+	previous := agentGroup.Status.AppliedName
+	if previous == "" || previous == agentGroup.Spec.Name {
+		return nil
+	}
+	if owned, err := r.ownedByOthers(ctx, agentGroup, previous); err != nil || owned {
+		return err
+	}
+	if err := agentClient.DeleteAgentGroup(ctx, previous); err != nil {
 		return err
 	}
+	r.Log.Info("Deleted agent group of previous name", "agentgroup", agentGroup.Name, "previous", previous)
+	return nil
+}
+
+// ownedByOthers 检查是否有其他未删除的AgentGroup声明了Agent组name
+func (r *AgentGroupReconciler) ownedByOthers(ctx context.Context, agentGroup *v1alpha1.AgentGroup, name string) (bool, error) {
+	var groups v1alpha1.AgentGroupList
+	if err := r.List(ctx, &groups, client.MatchingFields{agentGroupNameField: name}); err != nil {
+		return false, err
+	}
+	for _, group := range groups.Items {
+		if client.ObjectKeyFromObject(&group) != client.ObjectKeyFromObject(agentGroup) && group.DeletionTimestamp == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// setAssociationConditions 将冲突与缺失的配置记录到状态中
+func setAssociationConditions(agentGroup *v1alpha1.AgentGroup, graph association.Result) {
+	conflict := metav1.Condition{
+		Type:    emus.AgentGroupConditionConflict,
+		Status:  metav1.ConditionFalse,
+		Reason:  "NoConflict",
+		Message: "",
+	}
+	if len(graph.Conflicts) > 0 {
+		conflict.Status = metav1.ConditionTrue
+		conflict.Reason = "ConflictingDeclarations"
+		conflict.Message = strings.Join(graph.Conflicts, "; ")
+	}
+	meta.SetStatusCondition(&agentGroup.Status.Conditions, conflict)
 
-	if url, ok := configMap.Data[configMapKey]; ok && url != "" {
-		r.BaseURL = url
-	} else {
-		r.BaseURL = defaultBaseURL
+	missing := metav1.Condition{
+		Type:   emus.AgentGroupConditionMissingConfigs,
+		Status: metav1.ConditionFalse,
+		Reason: "AllConfigsFound",
 	}
+	if len(graph.Missing) > 0 {
+		missing.Status = metav1.ConditionTrue
+		missing.Reason = "PipelineNotFound"
+		missing.Message = "configs without a Pipeline: " + strings.Join(graph.Missing, ", ")
+	}
+	meta.SetStatusCondition(&agentGroup.Status.Conditions, missing)
+}
+
+// getConfigServerURL gets the ConfigServer URL from ConfigMap
+func (r *AgentGroupReconciler) getConfigServerURL(ctx context.Context) error {
+	url, err := lookupConfigServerURL(ctx, r.Client, r.ConfigServers)
+	if err != nil {
+		return err
+	}
+	r.BaseURL = url
 	return nil
 }
 
+// recordAdopted 将已接管且未修改的AgentGroup记录为已同步，不请求Config-Server
+func (r *AgentGroupReconciler) recordAdopted(ctx context.Context, agentGroup *v1alpha1.AgentGroup) error {
+	if cond := meta.FindStatusCondition(agentGroup.Status.Conditions, emus.AgentGroupConditionSynced); cond != nil &&
+		cond.Reason == emus.ConditionReasonAdopted && cond.ObservedGeneration == agentGroup.Generation {
+		return nil
+	}
+	agentGroup.Status.Success = true
+	agentGroup.Status.Message = emus.AgentGroupStatusSuccess
+	agentGroup.Status.AppliedConfigs = agentGroup.Spec.Configs
+	agentGroup.Status.AppliedName = agentGroup.Spec.Name
+	agentGroup.Status.Tags = agentGroup.Spec.Tags
+	agentGroup.Status.ObservedGeneration = agentGroup.Generation
+	agentGroup.Status.LastUpdateTime = metav1.Now()
+	meta.SetStatusCondition(&agentGroup.Status.Conditions, metav1.Condition{
+		Type:               emus.AgentGroupConditionSynced,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: agentGroup.Generation,
+		Reason:             emus.ConditionReasonAdopted,
+		Message:            "agent group adopted from " + agentGroup.Annotations[v1alpha1.AnnotationAdopted] + ", changes are applied once the spec is edited",
+	})
+	return r.Status().Update(ctx, agentGroup)
+}
+
 // cleanupAgentGroup 清理AgentGroup相关的资源
 func (r *AgentGroupReconciler) cleanupAgentGroup(ctx context.Context, agentGroup *v1alpha1.AgentGroup) error {
 	log := r.Log.WithValues("agentgroup", agentGroup.Name)
 
-	agentClient := configserver.NewConfigServerClient(r.BaseURL, &r.Client, agentGroup.Namespace)
-	if err := agentClient.DeleteAgentGroup(ctx, agentGroup.Spec.Name); err != nil {
+	if adoptedUnchanged(agentGroup) {
+		log.Info("Agent group was adopted and never edited, leaving it in Config-Server")
+		return nil
+	}
+
+	// 改名尚未同步时Config-Server上仍是旧名称的Agent组
+	name := agentGroup.Status.AppliedName
+	if name == "" {
+		name = agentGroup.Spec.Name
+	}
+	owned, err := r.ownedByOthers(ctx, agentGroup, name)
+	if err != nil {
+		return err
+	}
+	if owned {
+		log.Info("Agent group is still declared by another AgentGroup, keeping it in Config-Server", "name", name)
+		return nil
+	}
+
+	if err := r.getConfigServerURL(ctx); err != nil {
+		return err
+	}
+	agentClient := configServerClient(r.ConfigServers, r.BaseURL)
+	if err := agentClient.DeleteAgentGroup(ctx, name); err != nil {
 		log.Error(err, "Failed to delete agent group from config server")
 		return err
 	}
@@ -192,7 +402,108 @@ func (r *AgentGroupReconciler) cleanupAgentGroup(ctx context.Context, agentGroup
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *AgentGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.AgentGroup{}).
-		Complete(r)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.AgentGroup{}, shardForOptions(r.Shard)...).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.mapNode),
+			builder.WithPredicates(agentMembershipPredicate())).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.mapPod),
+			builder.WithPredicates(agentMembershipPredicate())).
+		Watches(&v1alpha1.Pipeline{}, enqueueOldAndNew(r.mapPipelineToAgentGroups)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapConfigMapToAgentGroups),
+			builder.WithPredicates(configServerConfigMapPredicate()))
+	return watchShard(b, r.Shard, r.Log, r.listAgentGroupObjects).Complete(r)
+}
+
+// listAgentGroupObjects 列出全部AgentGroup，分片变化后用于重新入队
+func (r *AgentGroupReconciler) listAgentGroupObjects(ctx context.Context) ([]client.Object, error) {
+	var groups v1alpha1.AgentGroupList
+	if err := r.List(ctx, &groups); err != nil {
+		return nil, err
+	}
+	objs := make([]client.Object, 0, len(groups.Items))
+	for i := range groups.Items {
+		objs = append(objs, &groups.Items[i])
+	}
+	return objs, nil
+}
+
+// mapPipelineToAgentGroups Pipeline变化时重新处理其绑定的AgentGroup与在spec.configs中引用它的AgentGroup
+func (r *AgentGroupReconciler) mapPipelineToAgentGroups(ctx context.Context, obj client.Object) []reconcile.Request {
+	pipeline, ok := obj.(*v1alpha1.Pipeline)
+	if !ok {
+		return nil
+	}
+	requests := requestSet{}
+	var groups v1alpha1.AgentGroupList
+	if pipeline.Spec.AgentGroup != "" {
+		if err := r.List(ctx, &groups, client.MatchingFields{agentGroupNameField: pipeline.Spec.AgentGroup}); err != nil {
+			r.Log.Error(err, "Failed to list agent groups by name", "group", pipeline.Spec.AgentGroup)
+			return nil
+		}
+		for i := range groups.Items {
+			requests.add(&groups.Items[i])
+		}
+	}
+	if err := r.List(ctx, &groups, client.MatchingFields{agentGroupConfigsField: pipeline.Spec.Name}); err != nil {
+		r.Log.Error(err, "Failed to list agent groups by config", "config", pipeline.Spec.Name)
+		return nil
+	}
+	for i := range groups.Items {
+		requests.add(&groups.Items[i])
+	}
+	return requests.list()
+}
+
+// mapConfigMapToAgentGroups Config-Server地址变化时重新处理全部AgentGroup
+func (r *AgentGroupReconciler) mapConfigMapToAgentGroups(ctx context.Context, _ client.Object) []reconcile.Request {
+	return r.groupsWithSelector(ctx, "", func(*v1alpha1.AgentGroup) bool { return true })
+}
+
+// mapNode 节点变化时重新计算使用了nodeSelector的AgentGroup
+func (r *AgentGroupReconciler) mapNode(ctx context.Context, _ client.Object) []reconcile.Request {
+	return r.groupsWithSelector(ctx, "", func(group *v1alpha1.AgentGroup) bool {
+		return group.Spec.NodeSelector != nil
+	})
+}
+
+// mapPod Pod变化时重新计算同命名空间下使用了podSelector的AgentGroup
+func (r *AgentGroupReconciler) mapPod(ctx context.Context, pod client.Object) []reconcile.Request {
+	return r.groupsWithSelector(ctx, pod.GetNamespace(), func(group *v1alpha1.AgentGroup) bool {
+		return group.Spec.PodSelector != nil
+	})
+}
+
+// groupsWithSelector 列出满足条件的AgentGroup
+func (r *AgentGroupReconciler) groupsWithSelector(ctx context.Context, namespace string,
+	match func(*v1alpha1.AgentGroup) bool) []reconcile.Request {
+	var groups v1alpha1.AgentGroupList
+	if err := r.List(ctx, &groups, client.InNamespace(namespace)); err != nil {
+		r.Log.Error(err, "Failed to list agent groups")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range groups.Items {
+		if match(&groups.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&groups.Items[i])})
+		}
+	}
+	return requests
+}
+
+// agentMembershipPredicate 只关注影响标签计算的变化：创建、删除、标签变化与调度
+func agentMembershipPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !reflect.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) {
+				return true
+			}
+			oldPod, ok := e.ObjectOld.(*corev1.Pod)
+			if !ok {
+				return false
+			}
+			newPod := e.ObjectNew.(*corev1.Pod)
+			return oldPod.Spec.NodeName != newPod.Spec.NodeName
+		},
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
 }
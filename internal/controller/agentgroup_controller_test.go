@@ -23,11 +23,14 @@ import (
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	infraflowv1alpha1 "github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	csfake "github.com/infraflows/loongcollector-operator/internal/pkg/configserver/fake"
 )
 
 var _ = Describe("AgentGroup Controller", func() {
@@ -38,47 +41,80 @@ var _ = Describe("AgentGroup Controller", func() {
 
 		typeNamespacedName := types.NamespacedName{
 			Name:      resourceName,
-			Namespace: "default", // TODO(user):Modify as needed
+			Namespace: "default",
+		}
+		var controllerReconciler *AgentGroupReconciler
+
+		// reconcileLatest reconciles the AgentGroup once the cache has observed the last change
+		reconcileLatest := func() {
+			waitForCache(ctx, &infraflowv1alpha1.AgentGroup{ObjectMeta: metav1.ObjectMeta{
+				Namespace: typeNamespacedName.Namespace, Name: typeNamespacedName.Name}})
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
 		}
-		agentgroup := &infraflowv1alpha1.AgentGroup{}
 
 		BeforeEach(func() {
+			configServer.Reset()
+			Expect(configServer.AddConfig("app", remoteContent)).To(Succeed())
+			// spec.configs只关联集群中有对应Pipeline的配置
+			pipeline := remotePipeline("app", "")
+			Expect(k8sClient.Create(ctx, pipeline)).To(Succeed())
+			waitForCache(ctx, pipeline)
+			controllerReconciler = &AgentGroupReconciler{
+				Client:        cachedClient,
+				Scheme:        cachedClient.Scheme(),
+				Log:           GinkgoLogr,
+				Event:         record.NewFakeRecorder(100),
+				ConfigServers: configserver.NewRegistry(configserver.RegistryOptions{}),
+			}
+
 			By("creating the custom resource for the Kind AgentGroup")
-			err := k8sClient.Get(ctx, typeNamespacedName, agentgroup)
-			if err != nil && errors.IsNotFound(err) {
-				resource := &infraflowv1alpha1.AgentGroup{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      resourceName,
-						Namespace: "default",
-					},
-					// TODO(user): Specify other spec details if needed.
-				}
-				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			resource := &infraflowv1alpha1.AgentGroup{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: infraflowv1alpha1.AgentGroupSpec{
+					Name:        "web",
+					Description: "web nodes",
+					Tags:        []string{"env=prod"},
+					Configs:     []string{"app"},
+				},
 			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 		})
 
 		AfterEach(func() {
-			// TODO(user): Cleanup logic after each test, like removing the resource instance.
-			resource := &infraflowv1alpha1.AgentGroup{}
-			err := k8sClient.Get(ctx, typeNamespacedName, resource)
-			Expect(err).NotTo(HaveOccurred())
-
 			By("Cleanup the specific resource instance AgentGroup")
-			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			removeResource(ctx, &infraflowv1alpha1.AgentGroup{ObjectMeta: metav1.ObjectMeta{
+				Namespace: typeNamespacedName.Namespace, Name: typeNamespacedName.Name}})
+			removeResource(ctx, remotePipeline("app", ""))
 		})
-		It("should successfully reconcile the resource", func() {
-			By("Reconciling the created resource")
-			controllerReconciler := &AgentGroupReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-			}
 
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
-				NamespacedName: typeNamespacedName,
-			})
-			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+		// The full flow runs against the fake client in remote_state_test.go; this spec covers the finalizer
+		// against a real API server.
+		It("should sync the agent group and delete it when the AgentGroup is deleted", func() {
+			By("creating the agent group with its configs")
+			reconcileLatest()
+			Expect(configServer.State().Groups).To(Equal(map[string][]string{"web": {"app"}}))
+			remote, _ := configServer.AgentGroup("web")
+			Expect(remote.Description).To(Equal("web nodes"))
+			Expect(remote.Tags).To(Equal([]string{"env=prod"}))
+			agentgroup := &infraflowv1alpha1.AgentGroup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, agentgroup)).To(Succeed())
+			Expect(agentgroup.Status.Success).To(BeTrue())
+			Expect(agentgroup.Status.AppliedConfigs).To(Equal([]string{"app"}))
+
+			By("deleting the AgentGroup")
+			Expect(k8sClient.Delete(ctx, agentgroup)).To(Succeed())
+			reconcileLatest()
+			Expect(configServer.State()).To(Equal(csfake.State{
+				Configs: map[string]string{"app": expectCanonical(remoteContent)},
+				Groups:  map[string][]string{},
+			}))
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &infraflowv1alpha1.AgentGroup{}))
+			}).Should(BeTrue())
 		})
 	})
 })
@@ -0,0 +1,315 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/bundle"
+)
+
+const (
+	backupPhaseRunning   = "Running"
+	backupPhaseSucceeded = "Succeeded"
+	backupPhaseFailed    = "Failed"
+
+	// backupMountPath 备份PVC在Job中的挂载路径
+	backupMountPath = "/backup"
+)
+
+// BackupReconciler runs a Job exporting the Pipelines and AgentGroups as a bundle to a PersistentVolumeClaim
+// every spec.interval of a Backup, and keeps the last spec.keep bundles and Jobs.
+type BackupReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	Event  record.EventRecorder
+	// Image runs the export when spec.image is empty. If empty too, the image of the operator Pod is used.
+	Image string
+	// Pod is the operator Pod, used to find its image
+	Pod types.NamespacedName
+}
+
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=backups,verbs=get;list;watch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=backups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *BackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("backup", req.NamespacedName)
+
+	backup := &v1alpha1.Backup{}
+	if err := r.Get(ctx, req.NamespacedName, backup); err != nil {
+		if errors.IsNotFound(err) {
+			// Job带有ownerReference，由垃圾回收清理
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if backup.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(backup.Namespace),
+		client.MatchingLabels{v1alpha1.LabelBackup: backup.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := backup.Status.DeepCopy()
+	running, err := r.trackJobs(ctx, backup, jobs.Items, status)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	now := time.Now()
+	var requeueAfter time.Duration
+	if !backup.Spec.Suspend {
+		next := now
+		if status.LastScheduleTime != nil {
+			next = status.LastScheduleTime.Add(backupInterval(backup))
+		}
+		switch {
+		case next.After(now):
+			requeueAfter = next.Sub(now)
+		case running:
+			// 上一次备份未结束时不创建新的Job，Job结束后会再次触发调和
+			log.V(1).Info("Previous backup is still running")
+		default:
+			job, err := r.newJob(ctx, backup, now)
+			if err != nil {
+				r.Event.Event(backup, corev1.EventTypeWarning, "BackupFailed", err.Error())
+				return ctrl.Result{}, err
+			}
+			if err := r.Create(ctx, job); err != nil && !errors.IsAlreadyExists(err) {
+				r.Event.Event(backup, corev1.EventTypeWarning, "BackupFailed", err.Error())
+				return ctrl.Result{}, err
+			}
+			log.Info("Created backup Job", "job", job.Name)
+			status.LastScheduleTime = &metav1.Time{Time: now}
+			status.History = append([]v1alpha1.BackupRevision{{
+				Job:       job.Name,
+				Archive:   job.Annotations[v1alpha1.AnnotationBackupArchive],
+				Phase:     backupPhaseRunning,
+				StartTime: metav1.Time{Time: now},
+			}}, status.History...)
+			if len(status.History) > int(backupKeep(backup)) {
+				status.History = status.History[:backupKeep(backup)]
+			}
+			requeueAfter = backupInterval(backup)
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(status, &backup.Status) {
+		backup.Status = *status
+		if err := r.Status().Update(ctx, backup); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// trackJobs 根据Job的状态更新备份历史，并删除超出spec.keep的旧Job，返回是否有正在运行的Job
+func (r *BackupReconciler) trackJobs(ctx context.Context, backup *v1alpha1.Backup, jobs []batchv1.Job,
+	status *v1alpha1.BackupStatus) (bool, error) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[j].CreationTimestamp.Before(&jobs[i].CreationTimestamp)
+	})
+
+	running := false
+	history := make([]v1alpha1.BackupRevision, 0, len(jobs))
+	for i := range jobs {
+		job := &jobs[i]
+		if i >= int(backupKeep(backup)) {
+			if job.DeletionTimestamp == nil {
+				if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+					!errors.IsNotFound(err) {
+					return running, err
+				}
+			}
+			continue
+		}
+
+		revision := v1alpha1.BackupRevision{
+			Job:       job.Name,
+			Archive:   job.Annotations[v1alpha1.AnnotationBackupArchive],
+			Phase:     jobPhase(job),
+			StartTime: job.CreationTimestamp,
+		}
+		if job.Status.StartTime != nil {
+			revision.StartTime = *job.Status.StartTime
+		}
+		switch revision.Phase {
+		case backupPhaseRunning:
+			running = true
+		case backupPhaseSucceeded:
+			revision.CompletionTime = job.Status.CompletionTime
+			if revision.CompletionTime != nil &&
+				(status.LastSuccessfulTime == nil || status.LastSuccessfulTime.Before(revision.CompletionTime)) {
+				status.LastSuccessfulTime = revision.CompletionTime.DeepCopy()
+			}
+		}
+		history = append(history, revision)
+	}
+	if len(history) == 0 {
+		history = nil
+	}
+	status.History = history
+	return running, nil
+}
+
+// jobPhase 返回备份Job的阶段
+func jobPhase(job *batchv1.Job) string {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return backupPhaseSucceeded
+		case batchv1.JobFailed:
+			return backupPhaseFailed
+		}
+	}
+	return backupPhaseRunning
+}
+
+// newJob 构造now时刻的备份Job，导出到PVC中spec.path下以Backup名称为前缀的归档，并清理旧归档
+func (r *BackupReconciler) newJob(ctx context.Context, backup *v1alpha1.Backup, now time.Time) (*batchv1.Job, error) {
+	image, err := r.image(ctx, backup)
+	if err != nil {
+		return nil, err
+	}
+	archive := path.Join(backup.Spec.Path, bundle.FileName(backup.Name, now))
+	args := []string{
+		"bundle", "export",
+		"--output", path.Join(backupMountPath, archive),
+		"--prefix", backup.Name,
+		"--keep", fmt.Sprint(backupKeep(backup)),
+	}
+	if len(backup.Spec.Namespaces) > 0 {
+		args = append(args, "--namespace", strings.Join(backup.Spec.Namespaces, ","))
+	}
+
+	backoffLimit := int32(2)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   backup.Namespace,
+			Name:        fmt.Sprintf("%s-%d", backup.Name, now.Unix()),
+			Labels:      map[string]string{v1alpha1.LabelBackup: backup.Name},
+			Annotations: map[string]string{v1alpha1.AnnotationBackupArchive: archive},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{v1alpha1.LabelBackup: backup.Name},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: backup.Spec.ServiceAccountName,
+					Containers: []corev1.Container{{
+						Name:         "export",
+						Image:        image,
+						Command:      []string{"/manager"},
+						Args:         args,
+						VolumeMounts: []corev1.VolumeMount{{Name: "backup", MountPath: backupMountPath}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "backup",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: backup.Spec.ClaimName},
+						},
+					}},
+				},
+			},
+		},
+	}
+	if err := controllerutil.SetControllerReference(backup, job, r.Scheme); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// image 返回运行导出的镜像：spec.image、--backup-image，最后是operator Pod中manager容器的镜像
+func (r *BackupReconciler) image(ctx context.Context, backup *v1alpha1.Backup) (string, error) {
+	if backup.Spec.Image != "" {
+		return backup.Spec.Image, nil
+	}
+	if r.Image != "" {
+		return r.Image, nil
+	}
+	if r.Pod.Name == "" || r.Pod.Namespace == "" {
+		return "", fmt.Errorf("spec.image is required when the operator Pod is unknown")
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, r.Pod, pod); err != nil {
+		return "", fmt.Errorf("failed to get the operator Pod %s: %w", r.Pod, err)
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == "manager" {
+			return container.Image, nil
+		}
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return "", fmt.Errorf("the operator Pod %s has no containers", r.Pod)
+	}
+	return pod.Spec.Containers[0].Image, nil
+}
+
+func backupInterval(backup *v1alpha1.Backup) time.Duration {
+	if backup.Spec.Interval.Duration <= 0 {
+		return 24 * time.Hour
+	}
+	return backup.Spec.Interval.Duration
+}
+
+func backupKeep(backup *v1alpha1.Backup) int32 {
+	if backup.Spec.Keep <= 0 {
+		return 7
+	}
+	return backup.Spec.Keep
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Backup{}).
+		Owns(&batchv1.Job{}).
+		Named("backup").
+		Complete(r)
+}
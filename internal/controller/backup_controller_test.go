@@ -0,0 +1,159 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+func newBackupReconciler(t *testing.T, objs ...client.Object) *BackupReconciler {
+	t.Helper()
+	c, scheme := newFakeClient(t, objs...)
+	return &BackupReconciler{
+		Client: c,
+		Log:    logr.Discard(),
+		Scheme: scheme,
+		Event:  record.NewFakeRecorder(10),
+		Pod:    types.NamespacedName{Namespace: "loongcollector-system", Name: "operator"},
+	}
+}
+
+func TestBackupCreatesJob(t *testing.T) {
+	backup := &v1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "loongcollector-system", Name: "nightly"},
+		Spec: v1alpha1.BackupSpec{
+			Interval:   metav1.Duration{Duration: time.Hour},
+			ClaimName:  "backup",
+			Path:       "bundles",
+			Namespaces: []string{"a", "b"},
+			Keep:       2,
+		},
+	}
+	operator := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "loongcollector-system", Name: "operator"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "manager", Image: "operator:v1"}}},
+	}
+	r := newBackupReconciler(t, backup, operator)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(backup)}
+
+	result, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter != time.Hour {
+		t.Errorf("RequeueAfter = %v, want 1h", result.RequeueAfter)
+	}
+
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs); err != nil || len(jobs.Items) != 1 {
+		t.Fatalf("got %d jobs, %v, want 1", len(jobs.Items), err)
+	}
+	job := jobs.Items[0]
+	container := job.Spec.Template.Spec.Containers[0]
+	args := strings.Join(container.Args, " ")
+	if container.Image != "operator:v1" {
+		t.Errorf("image = %s, want the operator image", container.Image)
+	}
+	if !strings.Contains(args, "--output /backup/bundles/nightly-") || !strings.Contains(args, "--keep 2") ||
+		!strings.Contains(args, "--namespace a,b") {
+		t.Errorf("unexpected args %q", args)
+	}
+
+	if err := r.Get(ctx, req.NamespacedName, backup); err != nil {
+		t.Fatal(err)
+	}
+	if backup.Status.LastScheduleTime == nil || len(backup.Status.History) != 1 ||
+		backup.Status.History[0].Phase != backupPhaseRunning {
+		t.Fatalf("unexpected status %+v", backup.Status)
+	}
+
+	// 未到下一次备份时间时只更新Job的状态
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	job.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	if err := r.Status().Update(ctx, &job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.List(ctx, &jobs); err != nil || len(jobs.Items) != 1 {
+		t.Fatalf("got %d jobs, %v, want 1", len(jobs.Items), err)
+	}
+	if err := r.Get(ctx, req.NamespacedName, backup); err != nil {
+		t.Fatal(err)
+	}
+	if backup.Status.History[0].Phase != backupPhaseSucceeded || backup.Status.LastSuccessfulTime == nil {
+		t.Fatalf("unexpected status %+v", backup.Status)
+	}
+}
+
+func TestBackupPrunesOldJobs(t *testing.T) {
+	backup := &v1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "nightly"},
+		Spec:       v1alpha1.BackupSpec{ClaimName: "backup", Keep: 2, Suspend: true},
+	}
+	var objs []client.Object
+	objs = append(objs, backup)
+	start := time.Now().Add(-time.Hour)
+	for i, name := range []string{"oldest", "middle", "newest"} {
+		objs = append(objs, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "ns",
+			Name:              name,
+			Labels:            map[string]string{v1alpha1.LabelBackup: "nightly"},
+			CreationTimestamp: metav1.Time{Time: start.Add(time.Duration(i) * time.Minute)},
+		}})
+	}
+	r := newBackupReconciler(t, objs...)
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(backup)}); err != nil {
+		t.Fatal(err)
+	}
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs); err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(jobs.Items))
+	}
+	for _, job := range jobs.Items {
+		if job.Name == "oldest" {
+			t.Fatal("the oldest Job must be deleted")
+		}
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(backup), backup); err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Status.History) != 2 || backup.Status.History[0].Job != "newest" {
+		t.Fatalf("unexpected history %+v", backup.Status.History)
+	}
+}
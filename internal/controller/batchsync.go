@@ -0,0 +1,288 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/batchsync"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/sharding"
+)
+
+const (
+	// DefaultBatchSyncConcurrency 批量同步的默认并发数
+	DefaultBatchSyncConcurrency = 10
+	// DefaultBatchSyncQPS 批量同步的默认每秒请求数
+	DefaultBatchSyncQPS = 50
+	// initialSyncWait 首次批量同步完成前Pipeline重新入队的间隔
+	initialSyncWait = time.Second * 2
+)
+
+// SyncGate 在首次批量同步完成前阻止Pipeline控制器下发，避免启动时两条路径重复下发同一Pipeline且绕过批量同步的限速
+type SyncGate struct {
+	once sync.Once
+	done chan struct{}
+}
+
+// NewSyncGate 创建未打开的SyncGate
+func NewSyncGate() *SyncGate {
+	return &SyncGate{done: make(chan struct{})}
+}
+
+// Open 打开SyncGate，重复调用无影响
+func (g *SyncGate) Open() {
+	if g != nil {
+		g.once.Do(func() { close(g.done) })
+	}
+}
+
+// Opened 返回是否已打开，nil表示没有批量同步，始终打开
+func (g *SyncGate) Opened() bool {
+	if g == nil {
+		return true
+	}
+	select {
+	case <-g.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// PipelineBatchSyncer 在缓存同步后一次性比较全部Pipeline与Config-Server的实际状态，
+// 以有限并发与限速下发差异。Interval大于0时定期执行。
+// 启用分片时每个副本只同步自己负责的Pipeline，并在每次分配稳定后重新同步
+type PipelineBatchSyncer struct {
+	client.Client
+	Cache         cache.Cache
+	Log           logr.Logger
+	ConfigServers *configserver.Registry
+	Shard         *sharding.Coordinator
+	Concurrency   int
+	QPS           float64
+	Interval      time.Duration
+	// InitialSync 首次同步结束后打开，无论成功与否，失败的Pipeline由控制器按退避重试
+	InitialSync *SyncGate
+	// Reconciler 批量下发成功后清除其中Pipeline的退避记录
+	Reconciler *PipelineReconciler
+}
+
+// Start implements manager.Runnable
+func (s *PipelineBatchSyncer) Start(ctx context.Context) error {
+	if !s.Cache.WaitForCacheSync(ctx) {
+		return errors.New("failed to wait for caches to sync")
+	}
+
+	// 分片尚未确定前不负责任何Pipeline，首次同步等待分配稳定
+	rebalanced := make(chan struct{}, 1)
+	if s.Shard != nil {
+		s.Shard.OnRebalance(func(context.Context) {
+			select {
+			case rebalanced <- struct{}{}:
+			default:
+			}
+		})
+	} else {
+		s.sync(ctx)
+		s.InitialSync.Open()
+	}
+
+	var tick <-chan time.Time
+	if s.Interval > 0 {
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	} else if s.Shard == nil {
+		return nil
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick:
+			s.sync(ctx)
+		case <-rebalanced:
+			s.sync(ctx)
+			s.InitialSync.Open()
+		}
+	}
+}
+
+// sync 执行一次批量同步并记录错误
+func (s *PipelineBatchSyncer) sync(ctx context.Context) {
+	if err := s.Sync(ctx); err != nil {
+		s.Log.Error(err, "Failed to batch sync pipelines")
+	}
+}
+
+// NeedLeaderElection 未启用分片时只在leader上下发，启用分片时每个副本下发自己负责的Pipeline
+func (s *PipelineBatchSyncer) NeedLeaderElection() bool {
+	return s.Shard == nil
+}
+
+// Sync 执行一次批量同步，下发成功的Pipeline更新为已下发状态
+func (s *PipelineBatchSyncer) Sync(ctx context.Context) error {
+	endpoint, err := lookupConfigServerURL(ctx, s.Client, s.ConfigServers)
+	if err != nil {
+		return err
+	}
+
+	var all v1alpha1.PipelineList
+	if err := s.List(ctx, &all); err != nil {
+		return err
+	}
+	// 设置了configServerSelector的Pipeline由控制器逐个下发到选中的ConfigServer，dryRun的Pipeline不下发
+	var pipelines v1alpha1.PipelineList
+	for _, p := range all.Items {
+		if !syncsToDefaultConfigServer(&p) {
+			continue
+		}
+		// 配置依赖其他对象或需要转换的Pipeline渲染失败时留给控制器报告，profile有未接受的变化时沿用上次下发的配置，
+		// 上次下发的配置来自Secret、没有记录内容时保持Config-Server上的配置不变
+		if rendersFromReferences(&p) || convertsContent(&p) {
+			content, err := acceptedContent(ctx, s.Client, &p)
+			if err != nil {
+				s.Log.V(1).Info("Failed to render pipeline content, left to the reconciler", "pipeline", p.Name, "error", err.Error())
+				continue
+			}
+			p.Spec.Content = content
+		}
+		tests, err := blockingTests(ctx, s.Client, &p)
+		if err != nil {
+			return err
+		}
+		if err := runBlockingTests(p.Spec.Content, tests); err != nil {
+			s.Log.V(1).Info("Blocking pipeline tests failed, left to the reconciler", "pipeline", p.Name, "error", err.Error())
+			continue
+		}
+		pipelines.Items = append(pipelines.Items, p)
+	}
+	var groups v1alpha1.AgentGroupList
+	if err := s.List(ctx, &groups); err != nil {
+		return err
+	}
+	managed := map[string]bool{}
+	for _, group := range groups.Items {
+		if group.DeletionTimestamp == nil {
+			managed[group.Spec.Name] = true
+		}
+	}
+
+	csClient := configServerClient(s.ConfigServers, endpoint)
+	actual, err := s.actualState(ctx, csClient, endpoint, batchsync.ReferencedGroups(pipelines.Items, managed))
+	if err != nil {
+		return err
+	}
+
+	plan := batchsync.Compute(pipelines.Items, managed, actual, func(p *v1alpha1.Pipeline) bool {
+		// 改名或改组的Pipeline需要清理上次下发的配置与关联，留给控制器处理
+		return !owns(s.Shard, p) || adoptedUnchanged(p) || staleApplied(p) ||
+			terminalForGeneration(p.Status.Conditions, emus.PipelineConditionReady, p.Generation)
+	})
+	if plan.Empty() {
+		s.Log.V(1).Info("Pipelines are in sync", "pipelines", len(pipelines.Items))
+		return nil
+	}
+
+	s.Log.Info("Batch syncing pipelines", "configs", len(plan.Configs), "groups", len(plan.Groups),
+		"associations", len(plan.Associations))
+	result := batchsync.Execute(ctx, plan, batchExecutor{csClient}, batchsync.Options{
+		Concurrency: s.Concurrency,
+		QPS:         s.QPS,
+	})
+
+	for _, p := range result.Applied {
+		setPipelineApplied(p, endpoint)
+		if err := s.Status().Update(ctx, p); err != nil {
+			s.Log.V(1).Info("Failed to update pipeline status after batch sync", "pipeline", p.Name, "error", err.Error())
+		}
+		if s.Reconciler != nil {
+			s.Reconciler.retrySucceeded(p)
+		}
+	}
+	for key, err := range result.Failed {
+		s.Log.Error(err, "Batch sync operation failed, left to the reconciler", "operation", key)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d of %d batch sync operations failed", len(result.Failed),
+			len(plan.Configs)+len(plan.Groups)+len(plan.Associations))
+	}
+	return nil
+}
+
+// actualState 读取Config-Server的配置、Agent组与被引用的Agent组已关联的配置
+func (s *PipelineBatchSyncer) actualState(ctx context.Context, csClient *configserver.ConfigServerClient,
+	endpoint string, referencedGroups []string) (batchsync.State, error) {
+	state := batchsync.State{Endpoint: endpoint, Applied: map[string][]string{}}
+
+	if err := csClient.Refresh(ctx); err != nil {
+		return state, err
+	}
+	configs, err := csClient.ListConfigs(ctx)
+	if err != nil {
+		return state, err
+	}
+	for _, config := range configs {
+		state.Configs = append(state.Configs, config.Name)
+	}
+	groups, err := csClient.ListAgentGroups(ctx)
+	if err != nil {
+		return state, err
+	}
+	for _, group := range groups {
+		state.Groups = append(state.Groups, group.Name)
+	}
+	for _, group := range referencedGroups {
+		applied, err := csClient.GetAppliedConfigsForAgentGroup(ctx, group)
+		if err != nil {
+			return state, err
+		}
+		state.Applied[group] = applied
+	}
+	return state, nil
+}
+
+// batchExecutor 将批量同步的操作转换为Config-Server请求
+type batchExecutor struct {
+	client *configserver.ConfigServerClient
+}
+
+func (e batchExecutor) ApplyConfig(ctx context.Context, pipeline *v1alpha1.Pipeline) error {
+	return e.client.CreateConfig(ctx, pipeline)
+}
+
+func (e batchExecutor) CreateGroup(ctx context.Context, group string) error {
+	return e.client.CreateAgentGroup(ctx, &configserver.AgentGroup{
+		Name:        group,
+		Description: "Created automatically for pipelines bound to " + group,
+	})
+}
+
+func (e batchExecutor) Associate(ctx context.Context, config, group string) error {
+	return e.client.ApplyConfigToAgentGroup(ctx, config, group)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	csfake "github.com/infraflows/loongcollector-operator/internal/pkg/configserver/fake"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+)
+
+// syncedCache 缓存已同步，批量同步测试只需要WaitForCacheSync
+type syncedCache struct {
+	cache.Cache
+}
+
+func (syncedCache) WaitForCacheSync(context.Context) bool {
+	return true
+}
+
+func TestInitialBatchSyncGatesReconciler(t *testing.T) {
+	e := newRemoteEnv(t, remotePipeline("app", "web"))
+	gate := NewSyncGate()
+	e.pipelines.InitialSync = gate
+
+	if result := e.reconcilePipeline(t, "app"); result.RequeueAfter != initialSyncWait {
+		t.Fatalf("expected the reconciler to wait for the batch sync, got %+v", result)
+	}
+	if requests := e.server.Requests(); len(requests) != 0 {
+		t.Fatalf("the reconciler called Config-Server before the batch sync: %v", requests)
+	}
+	key := client.ObjectKeyFromObject(remotePipeline("app", "web")).String()
+	e.pipelines.backoff().Next(key)
+
+	syncer := &PipelineBatchSyncer{Client: e.Client, Cache: syncedCache{}, Log: logr.Discard(),
+		ConfigServers: e.pipelines.ConfigServers, InitialSync: gate, Reconciler: e.pipelines}
+	if err := syncer.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !gate.Opened() {
+		t.Fatal("the gate is still closed after the initial batch sync")
+	}
+	if n := e.pipelines.backoff().Failures(key); n != 0 {
+		t.Errorf("backoff failures = %d after a batch sync success, want 0", n)
+	}
+	e.expectState(t, "batch sync", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+
+	// 批量同步已下发，控制器不再重复写入
+	e.server.ResetRequests()
+	e.reconcilePipeline(t, "app")
+	for _, op := range []string{"CreateConfig", "CreateAgentGroup", "ApplyConfigToAgentGroup"} {
+		if n := e.server.Count(op); n != 0 {
+			t.Errorf("%s requests after the batch sync = %d, want 0: %v", op, n, e.server.Requests())
+		}
+	}
+}
+
+func TestBatchSyncRecordsProfilesAndTemplate(t *testing.T) {
+	kafka := &v1alpha1.ClusterFlusherProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka"},
+		Spec: v1alpha1.FlusherProfileSpec{Flushers: []runtime.RawExtension{
+			{Raw: []byte(`{"Type":"flusher_kafka_v2","Brokers":["kafka:9092"]}`)}}},
+	}
+	tpl := &v1alpha1.PipelineTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "file"},
+		Spec:       v1alpha1.PipelineTemplateSpec{Version: "1.0.0", Content: remoteContent},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sls"},
+		Data:       map[string][]byte{"pipeline.yaml": []byte(remoteUpdated)},
+	}
+	manual := func(name string) *v1alpha1.Pipeline {
+		p := remotePipeline(name, "web")
+		p.Spec.Content = ""
+		p.Spec.UpdatePolicy = v1alpha1.UpdatePolicyManual
+		p.Spec.FlusherProfileRefs = []v1alpha1.FlusherProfileReference{{Kind: profile.KindClusterFlusherProfile, Name: "kafka"}}
+		return p
+	}
+	templated, fromSecret := manual("templated"), manual("secret")
+	templated.Spec.TemplateRef = &v1alpha1.PipelineTemplateReference{Name: "file"}
+	fromSecret.Spec.ContentFrom = &v1alpha1.PipelineContentSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "sls"}, Key: "pipeline.yaml"}}
+	e := newRemoteEnv(t, kafka, tpl, secret, templated, fromSecret)
+
+	syncer := &PipelineBatchSyncer{Client: e.Client, Cache: syncedCache{}, Log: logr.Discard(),
+		ConfigServers: e.pipelines.ConfigServers, Reconciler: e.pipelines}
+	if err := syncer.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*v1alpha1.Pipeline{templated, fromSecret} {
+		if err := e.Get(context.Background(), client.ObjectKeyFromObject(p), p); err != nil {
+			t.Fatal(err)
+		}
+		if len(p.Status.Profiles) != 1 || !meta.IsStatusConditionTrue(p.Status.Conditions, emus.PipelineConditionProfilesUpToDate) {
+			t.Fatalf("%s: batch sync must record the applied profiles, got %+v", p.Name, p.Status)
+		}
+	}
+	if templated.Status.Template == nil || templated.Status.Template.Version != "1.0.0" {
+		t.Fatalf("batch sync must record the template version, got %+v", templated.Status.Template)
+	}
+
+	// 控制器把批量下发的profile视为已接受，不报告未接受的变化，也不重复下发
+	e.server.ResetRequests()
+	for _, p := range []*v1alpha1.Pipeline{templated, fromSecret} {
+		e.reconcilePipeline(t, p.Name)
+		if err := e.Get(context.Background(), client.ObjectKeyFromObject(p), p); err != nil {
+			t.Fatal(err)
+		}
+		if !meta.IsStatusConditionTrue(p.Status.Conditions, emus.PipelineConditionProfilesUpToDate) || !p.Status.Success {
+			t.Errorf("%s: status after reconcile = %+v", p.Name, p.Status)
+		}
+	}
+	if n := e.server.Count("CreateConfig"); n != 0 {
+		t.Errorf("batch synced pipelines were applied again: %v", e.server.Requests())
+	}
+}
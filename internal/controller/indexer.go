@@ -0,0 +1,159 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/contentfrom"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+)
+
+// Field indexes registered on the manager cache.
+const (
+	// pipelineAgentGroupField indexes Pipelines by spec.agentGroup, and by the agent group last applied while in dry run
+	pipelineAgentGroupField = "spec.agentGroup"
+	// pipelineNameField indexes Pipelines by spec.name, the config name in Config-Server, and by the config name
+	// last applied while in dry run
+	pipelineNameField = "spec.name"
+	// pipelineContentFromField indexes Pipelines by the objects spec.contentFrom and spec.templateRef reference, as "<kind>/<name>"
+	pipelineContentFromField = "spec.contentFrom"
+	// pipelineProfileRefField indexes Pipelines by the ProcessorChains and FlusherProfiles they reference, as "<kind>/<name>"
+	pipelineProfileRefField = "spec.profileRefs"
+	// pipelineTestPipelineField indexes PipelineTests by spec.pipelineRef.name
+	pipelineTestPipelineField = "spec.pipelineRef.name"
+	// agentGroupNameField indexes AgentGroups by spec.name, the group name in Config-Server
+	agentGroupNameField = "spec.name"
+	// agentGroupConfigsField indexes AgentGroups by every entry of spec.configs
+	agentGroupConfigsField = "spec.configs"
+)
+
+// fieldIndex is a field index of the controllers.
+type fieldIndex struct {
+	obj     client.Object
+	field   string
+	extract client.IndexerFunc
+}
+
+// fieldIndexes are the field indexes used by the controllers, shared by SetupIndexes and the tests.
+var fieldIndexes = []fieldIndex{
+	{&v1alpha1.Pipeline{}, pipelineAgentGroupField, func(obj client.Object) []string {
+		pipeline := obj.(*v1alpha1.Pipeline)
+		var groups []string
+		if group := pipeline.Spec.AgentGroup; group != "" {
+			groups = append(groups, group)
+		}
+		if p, ok := associatedPipeline(pipeline); ok && p.Spec.AgentGroup != "" && p.Spec.AgentGroup != pipeline.Spec.AgentGroup {
+			groups = append(groups, p.Spec.AgentGroup)
+		}
+		return groups
+	}},
+	{&v1alpha1.Pipeline{}, pipelineNameField, func(obj client.Object) []string {
+		pipeline := obj.(*v1alpha1.Pipeline)
+		names := []string{pipeline.Spec.Name}
+		if p, ok := associatedPipeline(pipeline); ok && p.Spec.Name != pipeline.Spec.Name {
+			names = append(names, p.Spec.Name)
+		}
+		return names
+	}},
+	{&v1alpha1.Pipeline{}, pipelineContentFromField, func(obj client.Object) []string {
+		return contentfrom.References(obj.(*v1alpha1.Pipeline))
+	}},
+	{&v1alpha1.Pipeline{}, pipelineProfileRefField, func(obj client.Object) []string {
+		return profile.References(obj.(*v1alpha1.Pipeline))
+	}},
+	{&v1alpha1.PipelineTest{}, pipelineTestPipelineField, func(obj client.Object) []string {
+		return []string{obj.(*v1alpha1.PipelineTest).Spec.PipelineRef.Name}
+	}},
+	{&v1alpha1.AgentGroup{}, agentGroupNameField, func(obj client.Object) []string {
+		return []string{obj.(*v1alpha1.AgentGroup).Spec.Name}
+	}},
+	{&v1alpha1.AgentGroup{}, agentGroupConfigsField, func(obj client.Object) []string {
+		return obj.(*v1alpha1.AgentGroup).Spec.Configs
+	}},
+}
+
+// SetupIndexes registers the field indexes used by the controllers. It must be called once before
+// the controllers are set up.
+func SetupIndexes(ctx context.Context, mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+	for _, index := range fieldIndexes {
+		if err := indexer.IndexField(ctx, index.obj, index.field, index.extract); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isConfigServerConfigMap 判断是否为保存Config-Server地址的ConfigMap
+func isConfigServerConfigMap(obj client.Object) bool {
+	return obj.GetNamespace() == configMapNamespace &&
+		(obj.GetName() == configMapName || obj.GetLabels()["app"] == "config-server")
+}
+
+// configServerConfigMapPredicate 只关注Config-Server的ConfigMap
+func configServerConfigMapPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(isConfigServerConfigMap)
+}
+
+// enqueueOldAndNew 更新事件同时按新旧对象映射，保证关联关系变化时旧的一方也被重新处理
+func enqueueOldAndNew(fn handler.MapFunc) handler.EventHandler {
+	add := func(ctx context.Context, obj client.Object, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		for _, req := range fn(ctx, obj) {
+			q.Add(req)
+		}
+	}
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			add(ctx, e.Object, q)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			add(ctx, e.ObjectOld, q)
+			add(ctx, e.ObjectNew, q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			add(ctx, e.Object, q)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			add(ctx, e.Object, q)
+		},
+	}
+}
+
+// requestSet 去重后的请求集合
+type requestSet map[reconcile.Request]struct{}
+
+func (s requestSet) add(obj client.Object) {
+	s[reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)}] = struct{}{}
+}
+
+func (s requestSet) list() []reconcile.Request {
+	requests := make([]reconcile.Request, 0, len(s))
+	for req := range s {
+		requests = append(requests, req)
+	}
+	return requests
+}
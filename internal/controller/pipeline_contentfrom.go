@@ -0,0 +1,167 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/contentfrom"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+)
+
+// rendersFromReferences 检查Pipeline的配置是否依赖其他对象，这些对象变化时Pipeline的generation不变
+func rendersFromReferences(pipeline *v1alpha1.Pipeline) bool {
+	return pipeline.Spec.ContentFrom != nil || pipeline.Spec.TemplateRef != nil || referencesProfiles(pipeline)
+}
+
+// convertsContent 检查Pipeline的配置是否需要从其他日志采集器的格式转换
+func convertsContent(pipeline *v1alpha1.Pipeline) bool {
+	format := pipeline.Spec.SourceFormat
+	return format != "" && format != v1alpha1.SourceFormatLoongCollector
+}
+
+// errContentHeld updatePolicy为Manual的Pipeline有未接受的profile变化，而上次下发的配置来自Secret、没有记录内容，本次不下发
+var errContentHeld = stderrors.New("profile changes are pending and the last applied content is not recorded")
+
+// resolveContent 把spec.contentFrom解析出或spec.templateRef渲染出、按spec.sourceFormat转换并拼接了ProcessorChain与FlusherProfile的配置
+// 写入内存中的spec.content，不修改Pipeline对象。updatePolicy为Manual且引用的profile有未接受的变化时沿用上次下发的配置，
+// profile的状态变化时立即更新，模板版本随下发结果一起更新
+func (r *PipelineReconciler) resolveContent(ctx context.Context, pipeline *v1alpha1.Pipeline) error {
+	if !rendersFromReferences(pipeline) && !convertsContent(pipeline) && pipeline.Status.Template == nil &&
+		meta.FindStatusCondition(pipeline.Status.Conditions, emus.PipelineConditionProfilesUpToDate) == nil {
+		pipeline.Status.ContentFromSecret = false
+		return nil
+	}
+	before := pipeline.Status.DeepCopy()
+	content, err := acceptedContent(ctx, r.Client, pipeline)
+	if err != nil && !stderrors.Is(err, errContentHeld) {
+		return err
+	}
+	if !equality.Semantic.DeepEqual(before.Profiles, pipeline.Status.Profiles) ||
+		!equality.Semantic.DeepEqual(meta.FindStatusCondition(before.Conditions, emus.PipelineConditionProfilesUpToDate),
+			meta.FindStatusCondition(pipeline.Status.Conditions, emus.PipelineConditionProfilesUpToDate)) {
+		if err := r.Status().Update(ctx, pipeline); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if hash := contentHash(content); pipeline.Status.ContentHash != "" && hash != pipeline.Status.ContentHash {
+		r.Log.Info("Pipeline content from referenced objects changed", "pipeline", pipeline.Name, "contentHash", hash)
+	}
+	pipeline.Spec.Content = content
+	return nil
+}
+
+// acceptedContent 渲染Pipeline本次下发的配置，并在内存中的status记录模板版本与接受的profile。
+// Pipeline控制器与批量同步共用，profile有未接受的变化时返回上次下发的配置，上次下发的配置没有记录内容时返回errContentHeld
+func acceptedContent(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline) (string, error) {
+	content, profiles, err := renderPipelineContent(ctx, c, pipeline)
+	if err != nil {
+		return "", err
+	}
+	if err := recordTemplate(ctx, c, pipeline); err != nil {
+		return "", err
+	}
+	if !recordProfiles(pipeline, profiles) {
+		return content, nil
+	}
+	// 来自Secret的配置没有记录上次下发的内容，保持Config-Server上的配置不变
+	if pipeline.Status.LastAppliedConfig.Content == "" {
+		return "", errContentHeld
+	}
+	return pipeline.Status.LastAppliedConfig.Content, nil
+}
+
+// recordTemplate 在内存中的status.template记录渲染配置的PipelineTemplate版本
+func recordTemplate(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline) error {
+	if pipeline.Spec.TemplateRef == nil {
+		pipeline.Status.Template = nil
+		return nil
+	}
+	tpl, err := contentfrom.Template(ctx, c, pipeline)
+	if err != nil {
+		return err
+	}
+	pipeline.Status.Template = &v1alpha1.PipelineTemplateRenderStatus{
+		Name:       tpl.Name,
+		Version:    tpl.Spec.Version,
+		Generation: tpl.Generation,
+	}
+	return nil
+}
+
+// renderPipelineContent 解析Pipeline的配置内容并拼接引用的profile，在内存中的status.contentFromSecret记录配置是否包含Secret中的值。
+// 无效的contentFrom、模板参数、无法转换的配置或profile返回不可重试的错误
+func renderPipelineContent(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline) (string, []v1alpha1.PipelineProfileStatus, error) {
+	content, err := contentfrom.Resolve(ctx, c, pipeline)
+	var profiles []v1alpha1.PipelineProfileStatus
+	if err == nil {
+		pipeline.Status.ContentFromSecret, err = contentfrom.ReadsSecret(ctx, c, pipeline)
+	}
+	if err == nil {
+		content, profiles, err = profile.Render(ctx, c, pipeline, content)
+	}
+	if stderrors.Is(err, contentfrom.ErrInvalid) || stderrors.Is(err, profile.ErrInvalid) {
+		return "", nil, &configserver.Error{Op: "RenderContent", Message: err.Error(), Err: err}
+	}
+	return content, profiles, err
+}
+
+// appliedContent 写入status.lastAppliedConfig的配置内容，包含Secret中的值时只记录status.contentHash
+func appliedContent(pipeline *v1alpha1.Pipeline) string {
+	if pipeline.Status.ContentFromSecret {
+		return ""
+	}
+	return pipeline.Spec.Content
+}
+
+// appliedContentEqual 检查配置内容是否与上次下发的相同，没有记录内容时比较哈希
+func appliedContentEqual(pipeline *v1alpha1.Pipeline, content string) bool {
+	if last := pipeline.Status.LastAppliedConfig.Content; last != "" {
+		return content == last
+	}
+	return pipeline.Status.ContentHash != "" && contentHash(content) == pipeline.Status.ContentHash
+}
+
+// mapContentSourceToPipelines 返回的函数把ConfigMap、Secret或Pipeline的变化映射到在spec.contentFrom中引用它的Pipeline
+func (r *PipelineReconciler) mapContentSourceToPipelines(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var pipelines v1alpha1.PipelineList
+		if err := r.List(ctx, &pipelines, client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{pipelineContentFromField: contentfrom.Reference(kind, obj.GetName())}); err != nil {
+			r.Log.Error(err, "Failed to list pipelines by contentFrom", "kind", kind, "name", obj.GetName())
+			return nil
+		}
+		requests := requestSet{}
+		for i := range pipelines.Items {
+			requests.add(&pipelines.Items[i])
+		}
+		return requests.list()
+	}
+}
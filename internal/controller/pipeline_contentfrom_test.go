@@ -0,0 +1,163 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/contentfrom"
+)
+
+func newContentFromReconciler(t *testing.T, objs ...client.Object) *PipelineReconciler {
+	t.Helper()
+	c, scheme := newFakeClient(t, objs...)
+	return &PipelineReconciler{Client: c, Log: logr.Discard(), Scheme: scheme}
+}
+
+func contentFromPipeline(ns, name string, from *v1alpha1.PipelineContentSource) *v1alpha1.Pipeline {
+	return &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec:       v1alpha1.PipelineSpec{Name: name, ContentFrom: from},
+	}
+}
+
+func TestMapContentSourceToPipelines(t *testing.T) {
+	shared := &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "shared"}, Key: "processors"}},
+		{PipelineRef: &corev1.LocalObjectReference{Name: "base"}},
+	}}
+	r := newContentFromReconciler(t,
+		contentFromPipeline("logging", "web", shared),
+		contentFromPipeline("other", "web", shared),
+		&v1alpha1.Pipeline{ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "inline"},
+			Spec: v1alpha1.PipelineSpec{Name: "inline", Content: "inputs: []\n"}},
+	)
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "shared"}}
+	requests := r.mapContentSourceToPipelines(contentfrom.KindConfigMap)(context.Background(), cm)
+	if len(requests) != 1 || requests[0].Namespace != "logging" || requests[0].Name != "web" {
+		t.Fatalf("ConfigMap must map to the referencing pipeline of its namespace, got %v", requests)
+	}
+	base := &v1alpha1.Pipeline{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "base"}}
+	if requests := r.mapContentSourceToPipelines(contentfrom.KindPipeline)(context.Background(), base); len(requests) != 1 {
+		t.Fatalf("Pipeline must map to the pipelines referencing it, got %v", requests)
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "shared"}}
+	if requests := r.mapContentSourceToPipelines(contentfrom.KindSecret)(context.Background(), secret); len(requests) != 0 {
+		t.Fatalf("a Secret named like a referenced ConfigMap must not map, got %v", requests)
+	}
+}
+
+func TestResolveContentErrors(t *testing.T) {
+	missing := contentFromPipeline("logging", "missing", &v1alpha1.PipelineContentSource{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "absent"}, Key: "pipeline"},
+	})
+	cyclic := contentFromPipeline("logging", "cyclic", &v1alpha1.PipelineContentSource{
+		Fragments: []v1alpha1.PipelineContentFragment{{PipelineRef: &corev1.LocalObjectReference{Name: "cyclic"}}},
+	})
+	r := newContentFromReconciler(t, missing, cyclic)
+
+	// 被引用的对象可能稍后创建，创建时由watch触发重新处理
+	if err := r.resolveContent(context.Background(), missing); err == nil || !configserver.IsRetryable(err) {
+		t.Fatalf("missing ConfigMap must be retryable, got %v", err)
+	}
+	if err := r.resolveContent(context.Background(), cyclic); err == nil || configserver.IsRetryable(err) {
+		t.Fatalf("pipelineRef cycle must not be retryable, got %v", err)
+	}
+}
+
+func TestResolveContentSetsSpecContent(t *testing.T) {
+	p := contentFromPipeline("logging", "web", &v1alpha1.PipelineContentSource{
+		ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "web"}, Key: "pipeline"},
+	})
+	r := newContentFromReconciler(t, p, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web"},
+		Data:       map[string]string{"pipeline": "inputs: []\n"},
+	})
+	if err := r.resolveContent(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Spec.Content != "inputs: []\n" {
+		t.Fatalf("spec.content = %q", p.Spec.Content)
+	}
+	setPipelineApplied(p, "http://config-server:8899")
+	if p.Status.ContentHash != contentHash("inputs: []\n") {
+		t.Fatalf("status.contentHash must track the resolved content, got %q", p.Status.ContentHash)
+	}
+}
+
+func TestResolveContentRecordsTemplate(t *testing.T) {
+	p := &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web"},
+		Spec:       v1alpha1.PipelineSpec{Name: "web", TemplateRef: &v1alpha1.PipelineTemplateReference{Name: "base"}},
+	}
+	tpl := &v1alpha1.PipelineTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "base"},
+		Spec:       v1alpha1.PipelineTemplateSpec{Version: "1.2.0", Content: "inputs: []\n"},
+	}
+	r := newContentFromReconciler(t, p, tpl)
+	if err := r.resolveContent(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Spec.Content != "inputs: []\n" || p.Status.Template == nil || p.Status.Template.Version != "1.2.0" {
+		t.Fatalf("spec.content = %q, status.template = %+v", p.Spec.Content, p.Status.Template)
+	}
+	if requests := r.mapContentSourceToPipelines(contentfrom.KindPipelineTemplate)(context.Background(), tpl); len(requests) != 1 {
+		t.Fatalf("PipelineTemplate must map to the pipelines instantiating it, got %v", requests)
+	}
+
+	// 改为内联配置后清除status.template
+	p.Spec.TemplateRef, p.Spec.Content = nil, "inputs: []\n"
+	if err := r.resolveContent(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status.Template != nil {
+		t.Fatalf("status.template must be cleared, got %+v", p.Status.Template)
+	}
+}
+
+func TestResolveContentConvertsSourceFormat(t *testing.T) {
+	p := &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web"},
+		Spec: v1alpha1.PipelineSpec{
+			Name:         "web",
+			SourceFormat: v1alpha1.SourceFormatVector,
+			Content:      "[sources.app]\ntype = \"file\"\ninclude = [\"/var/log/app.log\"]\n",
+		},
+	}
+	r := newContentFromReconciler(t, p)
+	if err := r.resolveContent(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(p.Spec.Content, "input_file") {
+		t.Fatalf("spec.content must be converted, got %q", p.Spec.Content)
+	}
+
+	// 无法转换的配置修改spec前重试没有意义
+	p.Spec.Content = "[sources.app]\ntype = \"socket\"\n"
+	if err := r.resolveContent(context.Background(), p); err == nil || configserver.IsRetryable(err) {
+		t.Fatalf("unsupported constructs must not be retryable, got %v", err)
+	}
+}
@@ -2,22 +2,35 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
 	"fmt"
 	"time"
 
 	"github.com/infraflows/loongcollector-operator/internal/emus"
 	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/contentfrom"
 	"github.com/infraflows/loongcollector-operator/internal/pkg/kube"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/sharding"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/tracing"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
 )
@@ -25,31 +38,48 @@ import (
 // PipelineReconciler reconciles a Pipeline object
 type PipelineReconciler struct {
 	client.Client
-	Log      logr.Logger
-	Scheme   *runtime.Scheme
-	Event    record.EventRecorder
-	BaseURL  string
-	informer *kube.PipelineInformer
+	Log     logr.Logger
+	Scheme  *runtime.Scheme
+	Event   record.EventRecorder
+	BaseURL string
+	// ConfigServers shares one client and circuit breaker per Config-Server endpoint
+	ConfigServers *configserver.Registry
+	// Shard restricts the reconciler to the Pipelines owned by this replica, nil reconciles all of them
+	Shard *sharding.Coordinator
+	// InitialSync delays applying Pipelines to the default Config-Server until the first batch sync is done, nil does not wait
+	InitialSync *SyncGate
+	informer    *kube.PipelineInformer
+	retrier
 }
 
 const (
-	defaultBaseURL     = "http://config-server:8899"
-	configMapName      = "config-server-config"
-	configMapNamespace = "loongcollector-system"
-	configMapKey       = "configServerURL"
-	maxRetries         = 3
-	retryDelay         = time.Second * 5
+	configMapName      = configserver.ConfigMapName
+	configMapNamespace = configserver.ConfigMapNamespace
+	configMapKey       = configserver.ConfigMapKey
 	pipelineFinalizer  = "pipeline.finalizers.infraflow.co"
 	syncInterval       = time.Minute * 5
+	// maxPipelineRevisions status.history保留的修订数
+	maxPipelineRevisions = 10
 )
 
-// +kubebuilder:rbac:groups=infraflow.co,resources=pipelines,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=infraflow.co,resources=pipelines/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=infraflow.co,resources=pipelines/finalizers,verbs=update
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelines,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelines/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelines/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// Secret只以元数据缓存，读取内容时直接请求API Server
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=configservers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=processorchains;clusterprocessorchains;flusherprofiles;clusterflusherprofiles;pipelinetemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelinetests,verbs=get;list;watch
 
 func (r *PipelineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("pipeline", req.NamespacedName)
+	if !ownsKey(r.Shard, req.NamespacedName) {
+		log.V(1).Info("Pipeline is owned by another replica, skipping")
+		return ctrl.Result{}, nil
+	}
+	ctx, span := tracing.StartReconcile(ctx, "Pipeline", req.NamespacedName)
+	defer span.End()
 
 	pipeline := &v1alpha1.Pipeline{}
 	if err := r.Get(ctx, req.NamespacedName, pipeline); err != nil {
@@ -62,6 +92,7 @@ func (r *PipelineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	}
 	if pipeline.DeletionTimestamp != nil {
 		err := kube.HandleFinalizerWithCleanup(ctx, r.Client, pipeline, pipelineFinalizer, r.Log, r.cleanupPipeline)
+		tracing.RecordError(span, err)
 		return ctrl.Result{}, err
 	}
 
@@ -82,28 +113,78 @@ func (r *PipelineReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}
 	}()
 
-	// 在启动时处理所有现有的 Pipeline CRD
-	go func() {
-		// 等待 informer 缓存同步
-		time.Sleep(5 * time.Second)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Pipeline{}, shardForOptions(r.Shard)...).
+		Watches(&v1alpha1.AgentGroup{}, enqueueOldAndNew(r.mapAgentGroupToPipelines)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapConfigMapToPipelines),
+			builder.WithPredicates(configServerConfigMapPredicate())).
+		Watches(&v1alpha1.ConfigServer{}, enqueueOldAndNew(r.mapConfigServerToPipelines)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.mapContentSourceToPipelines(contentfrom.KindConfigMap))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.mapContentSourceToPipelines(contentfrom.KindSecret)),
+			builder.OnlyMetadata).
+		Watches(&v1alpha1.Pipeline{}, handler.EnqueueRequestsFromMapFunc(r.mapContentSourceToPipelines(contentfrom.KindPipeline))).
+		Watches(&v1alpha1.PipelineTemplate{}, handler.EnqueueRequestsFromMapFunc(r.mapContentSourceToPipelines(contentfrom.KindPipelineTemplate))).
+		Watches(&v1alpha1.PipelineTest{}, enqueueOldAndNew(mapTestToPipeline),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&v1alpha1.ProcessorChain{}, handler.EnqueueRequestsFromMapFunc(r.mapProfileToPipelines(profile.KindProcessorChain))).
+		Watches(&v1alpha1.ClusterProcessorChain{}, handler.EnqueueRequestsFromMapFunc(r.mapProfileToPipelines(profile.KindClusterProcessorChain))).
+		Watches(&v1alpha1.FlusherProfile{}, handler.EnqueueRequestsFromMapFunc(r.mapProfileToPipelines(profile.KindFlusherProfile))).
+		Watches(&v1alpha1.ClusterFlusherProfile{}, handler.EnqueueRequestsFromMapFunc(r.mapProfileToPipelines(profile.KindClusterFlusherProfile)))
+	return watchShard(b, r.Shard, r.Log, r.listPipelineObjects).Complete(r)
+}
 
-		var pipelines v1alpha1.PipelineList
-		if err := r.List(context.Background(), &pipelines); err != nil {
-			r.Log.Error(err, "Failed to list existing pipelines")
-			return
-		}
+// listPipelineObjects 列出全部Pipeline，分片变化后用于重新入队
+func (r *PipelineReconciler) listPipelineObjects(ctx context.Context) ([]client.Object, error) {
+	var pipelines v1alpha1.PipelineList
+	if err := r.List(ctx, &pipelines); err != nil {
+		return nil, err
+	}
+	objs := make([]client.Object, 0, len(pipelines.Items))
+	for i := range pipelines.Items {
+		objs = append(objs, &pipelines.Items[i])
+	}
+	return objs, nil
+}
 
-		for _, pipeline := range pipelines.Items {
-			pipelineCopy := pipeline
-			if _, err := r.handlePipelineCreateOrUpdate(context.Background(), &pipelineCopy); err != nil {
-				r.Log.Error(err, "Failed to apply existing pipeline", "pipeline", pipeline.Name)
-			}
+// mapAgentGroupToPipelines AgentGroup变化时重新处理绑定到该组或被其spec.configs引用的Pipeline
+func (r *PipelineReconciler) mapAgentGroupToPipelines(ctx context.Context, obj client.Object) []reconcile.Request {
+	group, ok := obj.(*v1alpha1.AgentGroup)
+	if !ok {
+		return nil
+	}
+	requests := requestSet{}
+	var pipelines v1alpha1.PipelineList
+	if err := r.List(ctx, &pipelines, client.MatchingFields{pipelineAgentGroupField: group.Spec.Name}); err != nil {
+		r.Log.Error(err, "Failed to list pipelines of agent group", "group", group.Spec.Name)
+		return nil
+	}
+	for i := range pipelines.Items {
+		requests.add(&pipelines.Items[i])
+	}
+	for _, configName := range group.Spec.Configs {
+		if err := r.List(ctx, &pipelines, client.MatchingFields{pipelineNameField: configName}); err != nil {
+			r.Log.Error(err, "Failed to list pipelines by config name", "config", configName)
+			continue
 		}
-	}()
+		for i := range pipelines.Items {
+			requests.add(&pipelines.Items[i])
+		}
+	}
+	return requests.list()
+}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Pipeline{}).
-		Complete(r)
+// mapConfigMapToPipelines Config-Server地址变化时重新处理全部Pipeline
+func (r *PipelineReconciler) mapConfigMapToPipelines(ctx context.Context, _ client.Object) []reconcile.Request {
+	var pipelines v1alpha1.PipelineList
+	if err := r.List(ctx, &pipelines); err != nil {
+		r.Log.Error(err, "Failed to list pipelines")
+		return nil
+	}
+	requests := requestSet{}
+	for i := range pipelines.Items {
+		requests.add(&pipelines.Items[i])
+	}
+	return requests.list()
 }
 
 // handlePipelineCreateOrUpdate 处理Pipeline创建或更新
@@ -112,22 +193,91 @@ func (r *PipelineReconciler) handlePipelineCreateOrUpdate(ctx context.Context, p
 		return ctrl.Result{}, err
 	}
 
-	if !r.shouldUpdatePipeline(ctx, pipeline) {
+	if err := r.getConfigServerURL(ctx); err != nil {
+		return r.updateStatusFailure(ctx, pipeline, err)
+	}
+
+	// 启动时由批量同步以限速统一下发，完成后再处理，此时已下发的Pipeline不再重复请求
+	if syncsToDefaultConfigServer(pipeline) && !r.InitialSync.Opened() {
+		r.Log.V(1).Info("Waiting for the initial batch sync", "pipeline", pipeline.Name)
+		return ctrl.Result{RequeueAfter: initialSyncWait}, nil
+	}
+
+	// 关闭dryRun后去掉上一次的计划
+	if !dryRun(pipeline) && (pipeline.Status.Plan != nil ||
+		meta.FindStatusCondition(pipeline.Status.Conditions, emus.PipelineConditionPlanned) != nil) {
+		pipeline.Status.Plan = nil
+		meta.RemoveStatusCondition(&pipeline.Status.Conditions, emus.PipelineConditionPlanned)
+		if err := r.Status().Update(ctx, pipeline); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 之后的Status().Update会用API Server返回的对象覆盖内存中解析出的spec.content，只能在最后一次读取spec.content之后更新状态
+	if err := r.resolveContent(ctx, pipeline); err != nil {
+		if stderrors.Is(err, errContentHeld) {
+			r.Log.V(1).Info("Pipeline profile changes pending, keeping the applied config", "pipeline", pipeline.Name)
+			return ctrl.Result{RequeueAfter: syncInterval}, nil
+		}
+		if dryRun(pipeline) {
+			return r.updatePlanFailure(ctx, pipeline, err)
+		}
+		return r.updateStatusFailure(ctx, pipeline, err)
+	}
+
+	// 开启了blockOnFailure的PipelineTest失败时不下发，dryRun的计划也报告失败
+	tests, err := blockingTests(ctx, r.Client, pipeline)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := runBlockingTests(pipeline.Spec.Content, tests); err != nil {
+		if dryRun(pipeline) {
+			return r.updatePlanFailure(ctx, pipeline, err)
+		}
+		return r.updateStatusFailure(ctx, pipeline, err)
+	}
+
+	if dryRun(pipeline) {
+		return r.handleDryRun(ctx, pipeline)
+	}
+
+	// 接管的Pipeline被强制重新下发后按正常流程处理
+	resync := resyncRequested(pipeline)
+	if adoptedUnchanged(pipeline) && !resync && pipeline.Status.ResyncToken == "" {
+		return ctrl.Result{}, r.recordAdopted(ctx, pipeline)
+	}
+
+	// 被引用的对象与阻塞下发的PipelineTest变化时generation不变，由它们的事件触发重新处理
+	if !resync && !rendersFromReferences(pipeline) && len(tests) == 0 &&
+		terminalForGeneration(pipeline.Status.Conditions, emus.PipelineConditionReady, pipeline.Generation) {
+		r.Log.V(1).Info("Pipeline failed with a terminal error, waiting for a spec change", "pipeline", pipeline.Name)
+		return ctrl.Result{}, nil
+	}
+
+	if fansOut(pipeline) {
+		return r.handleFanOut(ctx, pipeline)
+	}
+	// 从多个ConfigServer切换回单一Config-Server时，先删除各ConfigServer上的配置
+	if len(pipeline.Status.Targets) > 0 {
+		if err := r.removeTargets(ctx, pipeline, nil); err != nil {
+			return r.updateStatusFailure(ctx, pipeline, err)
+		}
+	}
+
+	if !resync && !r.shouldUpdatePipeline(ctx, pipeline) {
 		r.Log.V(1).Info("Pipeline content unchanged, skipping update", "pipeline", pipeline.Name)
 		return ctrl.Result{RequeueAfter: syncInterval}, nil
 	}
 
 	if err := r.applyPipeline(ctx, pipeline); err != nil {
-		return r.updateStatusFailure(ctx, pipeline, emus.PipelineStatusFailed, err)
+		return r.updateStatusFailure(ctx, pipeline, err)
 	}
-
-	pipeline.Status.Success = true
-	pipeline.Status.Message = emus.PipelineStatusSuccess
-	pipeline.Status.LastUpdateTime = metav1.Now()
-	pipeline.Status.LastAppliedConfig = v1alpha1.LastAppliedConfig{
-		AppliedTime: metav1.Now(),
-		Content:     pipeline.Spec.Content,
+	if err := r.removeStale(ctx, pipeline); err != nil {
+		return r.updateStatusFailure(ctx, pipeline, err)
 	}
+
+	r.retrySucceeded(pipeline)
+	setPipelineApplied(pipeline, r.BaseURL)
 	if err := r.Status().Update(ctx, pipeline); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -137,76 +287,76 @@ func (r *PipelineReconciler) handlePipelineCreateOrUpdate(ctx context.Context, p
 
 // shouldUpdatePipeline 检查Pipeline是否需要更新
 func (r *PipelineReconciler) shouldUpdatePipeline(ctx context.Context, pipeline *v1alpha1.Pipeline) bool {
-	if pipeline.Status.LastAppliedConfig.Content == "" {
+	if !appliedContentEqual(pipeline, pipeline.Spec.Content) {
+		return true
+	}
+
+	// Config-Server地址变化后需要重新下发
+	if pipeline.Status.LastAppliedConfig.Endpoint != r.BaseURL {
 		return true
 	}
 
-	if pipeline.Spec.Content != pipeline.Status.LastAppliedConfig.Content {
+	// 改名或改组后需要下发新的配置并清理上次下发的配置与关联
+	if staleApplied(pipeline) {
 		return true
 	}
 
 	if pipeline.Spec.AgentGroup != "" {
-		// 获取当前AgentGroup的配置
-		configServerClient := configserver.NewConfigServerClient(r.BaseURL, &r.Client, pipeline.Namespace)
-		groups, err := configServerClient.ListAgentGroups(ctx)
+		// AgentGroup CR存在时关联由AgentGroup控制器维护
+		managed, err := r.agentGroupManaged(ctx, pipeline.Spec.AgentGroup)
 		if err != nil {
-			r.Log.Error(err, "Failed to list agent groups, assuming update needed", "pipeline", pipeline.Name)
+			r.Log.Error(err, "Failed to check agent group, assuming update needed", "pipeline", pipeline.Name)
 			return true
 		}
-
-		// 检查AgentGroup是否存在
-		groupExists := false
-		for _, group := range groups {
-			if group.Name == pipeline.Spec.AgentGroup {
-				// TODO: 这里需要检查Pipeline是否在group中的逻辑
-				groupExists = true
-				// 检查Pipeline是否已经在group中
-				for _, config := range group.Configs {
-					if config == pipeline.Spec.Name {
-						return false
-					}
-				}
-				return true
-			}
+		if managed {
+			return false
 		}
 
-		// 如果AgentGroup不存在，需要创建
-		if !groupExists {
+		// 检查Pipeline是否已经关联到Agent组，读取客户端缓存，Agent组不存在时返回空
+		applied, err := configServerClient(r.ConfigServers, r.BaseURL).
+			GetAppliedConfigsForAgentGroup(ctx, pipeline.Spec.AgentGroup)
+		if err != nil {
+			r.Log.Error(err, "Failed to get applied configs, assuming update needed", "pipeline", pipeline.Name)
 			return true
 		}
+		for _, config := range applied {
+			if config == pipeline.Spec.Name {
+				return false
+			}
+		}
+		return true
 	}
 
 	return false
 }
+
+// applyPipeline 下发配置并在未由AgentGroup CR管理时关联到Agent组，失败由调用方按退避重新入队
 func (r *PipelineReconciler) applyPipeline(ctx context.Context, pipeline *v1alpha1.Pipeline) error {
-	if err := r.getConfigServerURL(ctx); err != nil {
+	client := configServerClient(r.ConfigServers, r.BaseURL)
+
+	if err := client.CreateConfig(ctx, pipeline); err != nil {
 		return err
 	}
 
-	client := configserver.NewConfigServerClient(r.BaseURL, &r.Client, pipeline.Namespace)
-
-	var lastErr error
-	for i := 0; i < maxRetries; i++ {
-		if err := r.tryApplyPipeline(ctx, client, pipeline); err != nil {
-			lastErr = err
-			time.Sleep(retryDelay)
-			continue
-		}
+	agentGroup := pipeline.Spec.AgentGroup
+	if agentGroup == "" {
 		return nil
 	}
-	return lastErr
-}
 
-// tryApplyPipeline 应用Pipeline重试
-func (r *PipelineReconciler) tryApplyPipeline(ctx context.Context, client *configserver.ConfigServerClient, pipeline *v1alpha1.Pipeline) error {
-	if err := client.CreateConfig(ctx, pipeline); err != nil {
+	// AgentGroup CR存在时由AgentGroup控制器统一维护关联
+	managed, err := r.agentGroupManaged(ctx, agentGroup)
+	if err != nil {
 		return err
 	}
-
-	agentGroup := pipeline.Spec.AgentGroup
-	if agentGroup == "" {
+	if managed {
 		return nil
 	}
+	return associateAgentGroup(ctx, client, pipeline)
+}
+
+// associateAgentGroup 将Pipeline关联到spec.agentGroup，Agent组不存在时先创建
+func associateAgentGroup(ctx context.Context, client *configserver.ConfigServerClient, pipeline *v1alpha1.Pipeline) error {
+	agentGroup := pipeline.Spec.AgentGroup
 
 	// 获取已有AgentGroup列表
 	groups, err := client.ListAgentGroups(ctx)
@@ -238,49 +388,250 @@ func (r *PipelineReconciler) tryApplyPipeline(ctx context.Context, client *confi
 	return client.ApplyConfigToAgentGroup(ctx, pipeline.Spec.Name, agentGroup)
 }
 
-// updateStatusFailure 更新Pipeline状态为失败
-func (r *PipelineReconciler) updateStatusFailure(ctx context.Context, pipeline *v1alpha1.Pipeline, msg string, err error) (ctrl.Result, error) {
+// staleApplied 检查上次下发的配置名或Agent组是否与spec不同。未记录配置名的旧状态视为相同
+func staleApplied(pipeline *v1alpha1.Pipeline) bool {
+	last := pipeline.Status.LastAppliedConfig
+	return last.Name != "" && (last.Name != pipeline.Spec.Name || last.AgentGroup != pipeline.Spec.AgentGroup)
+}
+
+// removeStale 下发成功后删除改名前的配置，并把配置从改组前的Agent组中移除。
+// 上次下发到其他Config-Server时由地址变化的流程处理，AgentGroup CR管理的关联由AgentGroup控制器移除。
+// 旧配置名已被其他Pipeline使用时（例如互换名称）配置仍然有效，只清理不再被声明的关联
+func (r *PipelineReconciler) removeStale(ctx context.Context, pipeline *v1alpha1.Pipeline) error {
+	last := pipeline.Status.LastAppliedConfig
+	if !staleApplied(pipeline) || last.Endpoint != r.BaseURL {
+		return nil
+	}
+	client := configServerClient(r.ConfigServers, r.BaseURL)
+
+	owners, err := r.configOwners(ctx, pipeline, last.Name)
+	if err != nil {
+		return err
+	}
+	ownerGroups := map[string]bool{}
+	for _, owner := range owners {
+		ownerGroups[owner.Spec.AgentGroup] = true
+	}
+
+	if last.AgentGroup != "" && !ownerGroups[last.AgentGroup] &&
+		(last.AgentGroup != pipeline.Spec.AgentGroup || last.Name != pipeline.Spec.Name) {
+		managed, err := r.agentGroupManaged(ctx, last.AgentGroup)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			if err := client.RemoveConfigFromAgentGroup(ctx, last.Name, last.AgentGroup); err != nil {
+				return err
+			}
+			r.Log.Info("Removed pipeline from previous agent group", "pipeline", pipeline.Name,
+				"config", last.Name, "agentGroup", last.AgentGroup)
+		}
+	}
+	if last.Name != pipeline.Spec.Name && len(owners) == 0 {
+		if err := client.DeleteConfig(ctx, last.Name); err != nil {
+			return err
+		}
+		r.Log.Info("Deleted config of previous name", "pipeline", pipeline.Name, "config", last.Name)
+	}
+	return nil
+}
+
+// configOwners 返回除pipeline外在默认Config-Server上持有配置名name的Pipeline，dryRun的Pipeline按最后一次下发的配置计算
+func (r *PipelineReconciler) configOwners(ctx context.Context, pipeline *v1alpha1.Pipeline,
+	name string) ([]v1alpha1.Pipeline, error) {
+	var list v1alpha1.PipelineList
+	if err := r.List(ctx, &list, client.MatchingFields{pipelineNameField: name}); err != nil {
+		return nil, err
+	}
+	var owners []v1alpha1.Pipeline
+	for i := range list.Items {
+		p, ok := associatedPipeline(&list.Items[i])
+		if ok && p.Spec.Name == name && p.DeletionTimestamp == nil &&
+			client.ObjectKeyFromObject(&p) != client.ObjectKeyFromObject(pipeline) {
+			owners = append(owners, p)
+		}
+	}
+	return owners, nil
+}
+
+// agentGroupManaged 检查是否存在声明了该Agent组的AgentGroup CR
+func (r *PipelineReconciler) agentGroupManaged(ctx context.Context, groupName string) (bool, error) {
+	var groups v1alpha1.AgentGroupList
+	if err := r.List(ctx, &groups, client.MatchingFields{agentGroupNameField: groupName}); err != nil {
+		return false, err
+	}
+	for _, group := range groups.Items {
+		if group.DeletionTimestamp == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// updateStatusFailure 更新Pipeline状态为失败。可重试的错误按退避重新入队，不可重试的错误等待spec变化
+func (r *PipelineReconciler) updateStatusFailure(ctx context.Context, pipeline *v1alpha1.Pipeline, err error) (ctrl.Result, error) {
+	msg := emus.PipelineStatusFailed
+	if !configserver.IsRetryable(err) {
+		msg = emus.PipelineStatusInvalid
+	}
+	result := r.retryResult(ctx, pipeline, err)
+	tracing.RecordError(trace.SpanFromContext(ctx), err)
+	logRetry(r.Log, err, "Failed to apply pipeline", "pipeline", pipeline.Name, "requeueAfter", result.RequeueAfter)
+
 	pipeline.Status.Success = false
 	pipeline.Status.Message = msg
-	r.Event.Event(pipeline, corev1.EventTypeWarning, msg, err.Error())
+	pipeline.Status.ObservedGeneration = pipeline.Generation
+	meta.SetStatusCondition(&pipeline.Status.Conditions, failureCondition(emus.PipelineConditionReady, pipeline.Generation, err))
+	if !stderrors.Is(err, configserver.ErrCircuitOpen) {
+		r.Event.Event(pipeline, corev1.EventTypeWarning, msg, err.Error())
+	}
 	pipeline.Status.LastUpdateTime = metav1.Now()
 	_ = r.Status().Update(ctx, pipeline)
-	return ctrl.Result{}, err
+	return result, nil
 }
 
 // getConfigServerURL gets the ConfigServer URL from ConfigMap
 func (r *PipelineReconciler) getConfigServerURL(ctx context.Context) error {
-	configMap, err := kube.GetConfigMapByLabel(ctx, r.Client, configMapName,
-		configMapNamespace, map[string]string{
-			"app": "config-server",
-		})
+	url, err := lookupConfigServerURL(ctx, r.Client, r.ConfigServers)
 	if err != nil {
 		return err
 	}
+	r.BaseURL = url
+	return nil
+}
 
-	if url, ok := configMap.Data[configMapKey]; ok && url != "" {
-		r.BaseURL = url
-	} else {
-		r.BaseURL = defaultBaseURL
+// lookupConfigServerURL 从带有app: config-server标签的ConfigMap读取Config-Server地址，并记录为注册表的默认地址
+func lookupConfigServerURL(ctx context.Context, c client.Client, registry *configserver.Registry) (string, error) {
+	url, err := configserver.LookupURL(ctx, c)
+	if err != nil {
+		return "", err
 	}
-	return nil
+	configServerRegistry(registry).SetDefault(url)
+	return url, nil
+}
+
+// setPipelineApplied 将Pipeline状态更新为已下发到endpoint
+func setPipelineApplied(pipeline *v1alpha1.Pipeline, endpoint string) {
+	pipeline.Status.Success = true
+	pipeline.Status.Message = emus.PipelineStatusSuccess
+	pipeline.Status.LastUpdateTime = metav1.Now()
+	pipeline.Status.ObservedGeneration = pipeline.Generation
+	meta.SetStatusCondition(&pipeline.Status.Conditions, metav1.Condition{
+		Type:               emus.PipelineConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pipeline.Generation,
+		Reason:             "Applied",
+		Message:            "pipeline applied to " + endpoint,
+	})
+	pipeline.Status.LastAppliedConfig = v1alpha1.LastAppliedConfig{
+		AppliedTime: metav1.Now(),
+		Content:     appliedContent(pipeline),
+		Endpoint:    endpoint,
+		Name:        pipeline.Spec.Name,
+		AgentGroup:  pipeline.Spec.AgentGroup,
+	}
+	recordRevision(pipeline, endpoint)
+	pipeline.Status.ContentHash = contentHash(pipeline.Spec.Content)
+	pipeline.Status.ResyncToken = pipeline.Annotations[v1alpha1.AnnotationResync]
+}
+
+// resyncRequested 检查resync注解是否有未处理的值
+func resyncRequested(pipeline *v1alpha1.Pipeline) bool {
+	token := pipeline.Annotations[v1alpha1.AnnotationResync]
+	return token != "" && token != pipeline.Status.ResyncToken
+}
+
+// recordRevision 在status.history头部记录下发的修订，同一generation、内容与地址只记录一次
+func recordRevision(pipeline *v1alpha1.Pipeline, endpoint string) {
+	revision := v1alpha1.PipelineRevision{
+		Generation:  pipeline.Generation,
+		ContentHash: contentHash(pipeline.Spec.Content),
+		Endpoint:    endpoint,
+		AppliedTime: metav1.Now(),
+	}
+	if history := pipeline.Status.History; len(history) > 0 {
+		last := history[0]
+		if last.Generation == revision.Generation && last.ContentHash == revision.ContentHash && last.Endpoint == endpoint {
+			return
+		}
+	}
+	history := append([]v1alpha1.PipelineRevision{revision}, pipeline.Status.History...)
+	if len(history) > maxPipelineRevisions {
+		history = history[:maxPipelineRevisions]
+	}
+	pipeline.Status.History = history
+}
+
+// contentHash 配置内容的sha256
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordAdopted 将已接管且未修改的Pipeline记录为已下发，不请求Config-Server
+func (r *PipelineReconciler) recordAdopted(ctx context.Context, pipeline *v1alpha1.Pipeline) error {
+	if cond := meta.FindStatusCondition(pipeline.Status.Conditions, emus.PipelineConditionReady); cond != nil &&
+		cond.Reason == emus.ConditionReasonAdopted && cond.ObservedGeneration == pipeline.Generation {
+		return nil
+	}
+	endpoint := pipeline.Annotations[v1alpha1.AnnotationAdopted]
+	setPipelineApplied(pipeline, endpoint)
+	meta.SetStatusCondition(&pipeline.Status.Conditions, metav1.Condition{
+		Type:               emus.PipelineConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pipeline.Generation,
+		Reason:             emus.ConditionReasonAdopted,
+		Message:            "pipeline adopted from " + endpoint + ", changes are applied once the spec is edited",
+	})
+	return r.Status().Update(ctx, pipeline)
 }
 
 // cleanupPipeline 清理Pipeline相关的资源
 func (r *PipelineReconciler) cleanupPipeline(ctx context.Context, pipeline *v1alpha1.Pipeline) error {
 	log := r.Log.WithValues("pipeline", pipeline.Name)
 
-	// 如果指定了AgentGroup，从AgentGroup中移除Pipeline
-	if pipeline.Spec.AgentGroup != "" {
-		configServerClient := configserver.NewConfigServerClient(r.BaseURL, &r.Client, pipeline.Namespace)
-		if err := configServerClient.RemoveConfigFromAgentGroup(ctx, pipeline.Spec.Name, pipeline.Spec.AgentGroup); err != nil {
+	if adoptedUnchanged(pipeline) {
+		log.Info("Pipeline was adopted and never edited, leaving the config in Config-Server")
+		return nil
+	}
+
+	if fansOut(pipeline) || len(pipeline.Status.Targets) > 0 {
+		if err := r.removeTargets(ctx, pipeline, nil); err != nil {
+			log.Error(err, "Failed to delete pipeline from config servers")
+			return err
+		}
+	}
+
+	// 按最后一次下发的配置清理，dryRun或改名未下发时Config-Server上仍是旧配置
+	last := pipeline.Status.LastAppliedConfig
+	if last.Endpoint == "" {
+		log.Info("Pipeline was never applied to the default config server, nothing to clean up")
+		return nil
+	}
+	configServerClient := configServerClient(r.ConfigServers, last.Endpoint)
+
+	// 其他Pipeline持有同名配置时保留配置及其所在组的关联
+	owners, err := r.configOwners(ctx, pipeline, last.Name)
+	if err != nil {
+		return err
+	}
+	ownerGroups := map[string]bool{}
+	for _, owner := range owners {
+		ownerGroups[owner.Spec.AgentGroup] = true
+	}
+
+	if last.AgentGroup != "" && !ownerGroups[last.AgentGroup] {
+		if err := configServerClient.RemoveConfigFromAgentGroup(ctx, last.Name, last.AgentGroup); err != nil {
 			log.Error(err, "Failed to remove pipeline from agent group")
 			return err
 		}
 	}
 
-	configServerClient := configserver.NewConfigServerClient(r.BaseURL, &r.Client, pipeline.Namespace)
-	if err := configServerClient.DeleteConfig(ctx, pipeline.Spec.Name); err != nil {
+	if len(owners) > 0 {
+		log.Info("Config is still owned by other pipelines, keeping it in Config-Server", "config", last.Name)
+		return nil
+	}
+	if err := configServerClient.DeleteConfig(ctx, last.Name); err != nil {
 		log.Error(err, "Failed to delete pipeline from agent")
 		return err
 	}
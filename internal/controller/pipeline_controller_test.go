@@ -23,13 +23,48 @@ import (
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	csfake "github.com/infraflows/loongcollector-operator/internal/pkg/configserver/fake"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
 )
 
+// expectCanonical returns the canonical form the in-memory Config-Server reports for content.
+func expectCanonical(content string) string {
+	out, err := render.Canonical(content)
+	Expect(err).NotTo(HaveOccurred())
+	return out
+}
+
+// waitForCache waits until the cached client has observed the latest version of obj.
+func waitForCache(ctx context.Context, obj client.Object) {
+	key := client.ObjectKeyFromObject(obj)
+	latest := obj.DeepCopyObject().(client.Object)
+	Expect(k8sClient.Get(ctx, key, latest)).To(Succeed())
+	Eventually(func(g Gomega) {
+		cached := obj.DeepCopyObject().(client.Object)
+		g.Expect(cachedClient.Get(ctx, key, cached)).To(Succeed())
+		g.Expect(cached.GetResourceVersion()).To(Equal(latest.GetResourceVersion()))
+	}).Should(Succeed())
+}
+
+// removeResource deletes obj and its finalizers so that each spec starts from a clean namespace.
+func removeResource(ctx context.Context, obj client.Object) {
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		return
+	}
+	obj.SetFinalizers(nil)
+	Expect(client.IgnoreNotFound(k8sClient.Update(ctx, obj))).To(Succeed())
+	Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, obj))).To(Succeed())
+}
+
 var _ = Describe("Pipeline Controller", func() {
 	Context("When reconciling a resource", func() {
 		const resourceName = "test-resource"
@@ -38,47 +73,74 @@ var _ = Describe("Pipeline Controller", func() {
 
 		typeNamespacedName := types.NamespacedName{
 			Name:      resourceName,
-			Namespace: "default", // TODO(user):Modify as needed
+			Namespace: "default",
+		}
+		var controllerReconciler *PipelineReconciler
+
+		// reconcileLatest reconciles the Pipeline once the cache has observed the last change
+		reconcileLatest := func() {
+			waitForCache(ctx, &v1alpha1.Pipeline{ObjectMeta: metav1.ObjectMeta{
+				Namespace: typeNamespacedName.Namespace, Name: typeNamespacedName.Name}})
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
 		}
-		pipeline := &v1alpha1.Pipeline{}
 
 		BeforeEach(func() {
+			configServer.Reset()
+			controllerReconciler = &PipelineReconciler{
+				Client:        cachedClient,
+				Scheme:        cachedClient.Scheme(),
+				Log:           GinkgoLogr,
+				Event:         record.NewFakeRecorder(100),
+				ConfigServers: configserver.NewRegistry(configserver.RegistryOptions{}),
+			}
+
 			By("creating the custom resource for the Kind Pipeline")
-			err := k8sClient.Get(ctx, typeNamespacedName, pipeline)
-			if err != nil && errors.IsNotFound(err) {
-				resource := &v1alpha1.Pipeline{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      resourceName,
-						Namespace: "default",
-					},
-					// TODO(user): Specify other spec details if needed.
-				}
-				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			resource := &v1alpha1.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: v1alpha1.PipelineSpec{
+					Name:       "app",
+					Content:    remoteContent,
+					AgentGroup: "web",
+				},
 			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 		})
 
 		AfterEach(func() {
-			// TODO(user): Cleanup logic after each test, like removing the resource instance.
-			resource := &v1alpha1.Pipeline{}
-			err := k8sClient.Get(ctx, typeNamespacedName, resource)
-			Expect(err).NotTo(HaveOccurred())
-
 			By("Cleanup the specific resource instance Pipeline")
-			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			removeResource(ctx, &v1alpha1.Pipeline{ObjectMeta: metav1.ObjectMeta{
+				Namespace: typeNamespacedName.Namespace, Name: typeNamespacedName.Name}})
 		})
-		It("should successfully reconcile the resource", func() {
-			By("Reconciling the created resource")
-			controllerReconciler := &PipelineReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
-			}
 
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
-				NamespacedName: typeNamespacedName,
-			})
-			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+		// The full create, update, rename, regroup and delete flow and the fault handling run against the fake
+		// client in remote_state_test.go; this spec covers the finalizer against a real API server.
+		It("should apply the config and remove it when the Pipeline is deleted", func() {
+			By("creating the config and the agent group")
+			reconcileLatest()
+			Expect(configServer.State()).To(Equal(csfake.State{
+				Configs: map[string]string{"app": expectCanonical(remoteContent)},
+				Groups:  map[string][]string{"web": {"app"}},
+			}))
+			pipeline := &v1alpha1.Pipeline{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, pipeline)).To(Succeed())
+			Expect(pipeline.Finalizers).To(ContainElement(pipelineFinalizer))
+			Expect(pipeline.Status.Success).To(BeTrue())
+			Expect(pipeline.Status.LastAppliedConfig.Endpoint).To(Equal(configServer.URL))
+
+			By("deleting the Pipeline")
+			Expect(k8sClient.Delete(ctx, pipeline)).To(Succeed())
+			reconcileLatest()
+			Expect(configServer.State()).To(Equal(csfake.State{
+				Configs: map[string]string{},
+				Groups:  map[string][]string{"web": {}},
+			}))
+			Eventually(func() bool {
+				return errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, &v1alpha1.Pipeline{}))
+			}).Should(BeTrue())
 		})
 	})
 })
@@ -0,0 +1,292 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/textdiff"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/tracing"
+)
+
+// maxPlanDiffBytes status.plan中每个目标的diff上限，避免超出对象大小限制
+const maxPlanDiffBytes = 16 * 1024
+
+// redactedDiff 配置包含Secret中的值时代替diff写入status.plan
+const redactedDiff = "(diff redacted: the content includes values read from a Secret)\n"
+
+// dryRun 判断Pipeline是否只计算下发计划
+func dryRun(pipeline *v1alpha1.Pipeline) bool {
+	return pipeline.Spec.DryRun
+}
+
+// syncsToDefaultConfigServer 判断Pipeline是否下发到ConfigMap配置的Config-Server并参与AgentGroup关联与批量同步：
+// 扇出的Pipeline由控制器逐个下发到选中的ConfigServer，dryRun的Pipeline不下发
+func syncsToDefaultConfigServer(pipeline *v1alpha1.Pipeline) bool {
+	return !fansOut(pipeline) && !dryRun(pipeline)
+}
+
+// associatedPipeline 返回Pipeline在默认Config-Server上持有的配置名与Agent组，写在返回副本的spec中。
+// dryRun的Pipeline不下发新的变化，但在退出dryRun前保留最后一次下发的关联，避免AgentGroup控制器解绑仍在运行的配置
+func associatedPipeline(pipeline *v1alpha1.Pipeline) (v1alpha1.Pipeline, bool) {
+	p := *pipeline
+	if fansOut(pipeline) {
+		return p, false
+	}
+	if dryRun(pipeline) {
+		last := pipeline.Status.LastAppliedConfig
+		if last.Endpoint == "" || last.Name == "" {
+			return p, false
+		}
+		p.Spec.Name, p.Spec.AgentGroup = last.Name, last.AgentGroup
+	}
+	return p, true
+}
+
+// handleDryRun 计算下发Pipeline会对Config-Server做出的变化并写入status.plan，只读取Config-Server
+func (r *PipelineReconciler) handleDryRun(ctx context.Context, pipeline *v1alpha1.Pipeline) (ctrl.Result, error) {
+	plan, err := r.planPipeline(ctx, pipeline)
+	if err != nil {
+		return r.updatePlanFailure(ctx, pipeline, err)
+	}
+	r.retrySucceeded(pipeline)
+
+	previous := pipeline.Status.DeepCopy()
+	meta.SetStatusCondition(&pipeline.Status.Conditions, metav1.Condition{
+		Type:               emus.PipelineConditionPlanned,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pipeline.Generation,
+		Reason:             emus.ConditionReasonDryRun,
+		Message:            planSummary(plan),
+	})
+	pipeline.Status.ObservedGeneration = pipeline.Generation
+	// 计划未变化时不更新状态，避免只因计算时间变化而反复触发调和
+	if planEqual(previous.Plan, plan) && equality.Semantic.DeepEqual(previous.Conditions, pipeline.Status.Conditions) &&
+		previous.ObservedGeneration == pipeline.Status.ObservedGeneration {
+		return ctrl.Result{RequeueAfter: syncInterval}, nil
+	}
+	pipeline.Status.Plan = plan
+	if err := r.Status().Update(ctx, pipeline); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: syncInterval}, nil
+}
+
+// planPipeline 校验并渲染配置，再与每个目标Config-Server的当前状态比较
+func (r *PipelineReconciler) planPipeline(ctx context.Context, pipeline *v1alpha1.Pipeline) (*v1alpha1.PipelinePlan, error) {
+	if _, err := render.Payload(pipeline.Spec.Name, pipeline.Spec.Content); err != nil {
+		// 配置内容无效时修改spec前结果不会变化，不再重试
+		return nil, &configserver.Error{Op: "DryRun", Message: err.Error(), Err: err}
+	}
+	local, err := render.Canonical(pipeline.Spec.Content)
+	if err != nil {
+		return nil, &configserver.Error{Op: "DryRun", Message: err.Error(), Err: err}
+	}
+
+	plan := &v1alpha1.PipelinePlan{
+		ObservedGeneration: pipeline.Generation,
+		ComputedTime:       metav1.Now(),
+	}
+	for _, problem := range render.Lint(pipeline.Spec.Content) {
+		plan.Warnings = append(plan.Warnings, problem.String())
+	}
+
+	var groups v1alpha1.AgentGroupList
+	if err := r.List(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	if fansOut(pipeline) {
+		targets, err := r.selectConfigServers(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			// 扇出时只关联spec.agentGroup，不移除已有的关联
+			desired := sets.New[string]()
+			if pipeline.Spec.AgentGroup != "" {
+				desired.Insert(pipeline.Spec.AgentGroup)
+			}
+			tp, err := planTarget(ctx, configServerClient(r.ConfigServers, target.Spec.Endpoint), pipeline, local,
+				desired, sets.New[string]())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", target.Name, err)
+			}
+			tp.Name = target.Name
+			tp.Endpoint = target.Spec.Endpoint
+			plan.Targets = append(plan.Targets, tp)
+		}
+		return plan, nil
+	}
+
+	// 单一Config-Server时AgentGroup CR维护其组的关联：spec.configs引用该配置的组会关联，其余由CR管理的组会移除
+	desired, managed := sets.New[string](), sets.New[string]()
+	if pipeline.Spec.AgentGroup != "" {
+		desired.Insert(pipeline.Spec.AgentGroup)
+	}
+	for _, group := range groups.Items {
+		if group.DeletionTimestamp != nil {
+			continue
+		}
+		managed.Insert(group.Spec.Name)
+		for _, config := range group.Spec.Configs {
+			if config == pipeline.Spec.Name {
+				desired.Insert(group.Spec.Name)
+			}
+		}
+	}
+	tp, err := planTarget(ctx, configServerClient(r.ConfigServers, r.BaseURL), pipeline, local, desired, managed)
+	if err != nil {
+		return nil, err
+	}
+	tp.Endpoint = r.BaseURL
+	plan.Targets = append(plan.Targets, tp)
+	return plan, nil
+}
+
+// planTarget 计算一个Config-Server上配置与关联的变化，只有managed中的组会被移除关联
+func planTarget(ctx context.Context, csClient *configserver.ConfigServerClient, pipeline *v1alpha1.Pipeline, local string,
+	desired, managed sets.Set[string]) (v1alpha1.PipelineTargetPlan, error) {
+	tp := v1alpha1.PipelineTargetPlan{Config: v1alpha1.PlanActionCreate}
+	name := pipeline.Spec.Name
+
+	configs, err := csClient.ListConfigs(ctx)
+	if err != nil {
+		return tp, err
+	}
+	remote := ""
+	for _, config := range configs {
+		if config.Name != name {
+			continue
+		}
+		detail, err := csClient.GetConfig(ctx, name)
+		if err != nil {
+			return tp, err
+		}
+		if remote, err = render.Canonical(detail.Content); err != nil {
+			// Config-Server上的内容无法解析时按原样比较
+			remote = fmt.Sprint(detail.Content)
+		}
+		tp.Config = v1alpha1.PlanActionUpdate
+		if remote == local {
+			tp.Config = v1alpha1.PlanActionUnchanged
+		}
+		break
+	}
+	if tp.Config != v1alpha1.PlanActionUnchanged && pipeline.Status.ContentFromSecret {
+		// 配置包含Secret中的值，不写入status
+		tp.Diff = redactedDiff
+	} else if tp.Config != v1alpha1.PlanActionUnchanged {
+		tp.Diff = truncateDiff(textdiff.Unified("configserver/"+name, "pipeline/"+pipeline.Namespace+"/"+pipeline.Name,
+			remote, local, 3))
+	}
+
+	groups, err := csClient.ListAgentGroups(ctx)
+	if err != nil {
+		return tp, err
+	}
+	existing, current := sets.New[string](), sets.New[string]()
+	for _, group := range groups {
+		existing.Insert(group.Name)
+		applied, err := csClient.GetAppliedConfigsForAgentGroup(ctx, group.Name)
+		if err != nil {
+			return tp, err
+		}
+		for _, config := range applied {
+			if config == name {
+				current.Insert(group.Name)
+			}
+		}
+	}
+	tp.CreateGroups = sets.List(desired.Difference(existing))
+	tp.AddGroups = sets.List(desired.Difference(current))
+	tp.RemoveGroups = sets.List(current.Difference(desired).Intersection(managed))
+	return tp, nil
+}
+
+// truncateDiff 截断过长的diff
+func truncateDiff(diff string) string {
+	if len(diff) <= maxPlanDiffBytes {
+		return diff
+	}
+	cut := strings.LastIndex(diff[:maxPlanDiffBytes], "\n") + 1
+	return diff[:cut] + "... (truncated)\n"
+}
+
+// planSummary 计划的简要说明，写入Planned Condition
+func planSummary(plan *v1alpha1.PipelinePlan) string {
+	if len(plan.Targets) == 0 {
+		return "dry run: no ConfigServer selected"
+	}
+	parts := make([]string, 0, len(plan.Targets))
+	for _, tp := range plan.Targets {
+		target := tp.Endpoint
+		if tp.Name != "" {
+			target = tp.Name
+		}
+		part := fmt.Sprintf("%s: config %s", target, strings.ToLower(string(tp.Config)))
+		if n := len(tp.AddGroups); n > 0 {
+			part += fmt.Sprintf(", %d associations added", n)
+		}
+		if n := len(tp.RemoveGroups); n > 0 {
+			part += fmt.Sprintf(", %d associations removed", n)
+		}
+		parts = append(parts, part)
+	}
+	return "dry run: " + strings.Join(parts, "; ")
+}
+
+// planEqual 比较两个计划，忽略计算时间
+func planEqual(a, b *v1alpha1.PipelinePlan) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	a, b = a.DeepCopy(), b.DeepCopy()
+	a.ComputedTime, b.ComputedTime = metav1.Time{}, metav1.Time{}
+	return equality.Semantic.DeepEqual(a, b)
+}
+
+// updatePlanFailure 记录计划计算失败，可重试的错误按退避重新入队
+func (r *PipelineReconciler) updatePlanFailure(ctx context.Context, pipeline *v1alpha1.Pipeline, err error) (ctrl.Result, error) {
+	result := r.retryResult(ctx, pipeline, err)
+	tracing.RecordError(trace.SpanFromContext(ctx), err)
+	logRetry(r.Log, err, "Failed to plan pipeline", "pipeline", pipeline.Name, "requeueAfter", result.RequeueAfter)
+
+	pipeline.Status.ObservedGeneration = pipeline.Generation
+	pipeline.Status.Plan = nil
+	meta.SetStatusCondition(&pipeline.Status.Conditions, failureCondition(emus.PipelineConditionPlanned, pipeline.Generation, err))
+	if !stderrors.Is(err, configserver.ErrCircuitOpen) {
+		r.Event.Event(pipeline, corev1.EventTypeWarning, "DryRunFailed", err.Error())
+	}
+	_ = r.Status().Update(ctx, pipeline)
+	return result, nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+// planServer 只读的Config-Server：nginx配置关联到web与old两个组；收到写请求时测试失败
+func planServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("dry run must not call %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/User/ListConfigs":
+			fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":[{"name":"nginx"}]}`)
+		case "/User/GetConfig/nginx":
+			fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":{"name":"nginx","content":{"inputs":[{"Type":"input_file"}]}}}`)
+		case "/User/ListAgentGroups":
+			fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":[{"name":"web"},{"name":"old"},{"name":"manual"}]}`)
+		case "/User/GetAppliedConfigsForAgentGroup/web", "/User/GetAppliedConfigsForAgentGroup/old",
+			"/User/GetAppliedConfigsForAgentGroup/manual":
+			fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":["nginx"]}`)
+		default:
+			fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":[]}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPlanTarget(t *testing.T) {
+	server := planServer(t)
+	csClient := configserver.NewConfigServerClient(server.URL, nil, "")
+	ctx := context.Background()
+
+	pipeline := &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "nginx"},
+		Spec: v1alpha1.PipelineSpec{
+			Name:       "nginx",
+			Content:    "inputs:\n  - Type: input_file\nflushers:\n  - Type: flusher_stdout\n",
+			AgentGroup: "new",
+		},
+	}
+	local, err := render.Canonical(pipeline.Spec.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// web与old由AgentGroup CR管理，manual不由CR管理
+	tp, err := planTarget(ctx, csClient, pipeline, local, sets.New("new", "web"), sets.New("web", "old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp.Config != v1alpha1.PlanActionUpdate || !strings.Contains(tp.Diff, "+flushers:") {
+		t.Errorf("config = %s, diff:\n%s", tp.Config, tp.Diff)
+	}
+	if want := []string{"new"}; !reflect.DeepEqual(tp.CreateGroups, want) || !reflect.DeepEqual(tp.AddGroups, want) {
+		t.Errorf("create = %v, add = %v, want %v", tp.CreateGroups, tp.AddGroups, want)
+	}
+	if want := []string{"old"}; !reflect.DeepEqual(tp.RemoveGroups, want) {
+		t.Errorf("remove = %v, want %v", tp.RemoveGroups, want)
+	}
+
+	pipeline.Spec.Name = "other"
+	tp, err = planTarget(ctx, csClient, pipeline, local, sets.New[string](), sets.New[string]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp.Config != v1alpha1.PlanActionCreate {
+		t.Errorf("config = %s, want Create", tp.Config)
+	}
+}
+
+func TestPlanEqualIgnoresComputedTime(t *testing.T) {
+	a := &v1alpha1.PipelinePlan{ObservedGeneration: 1, ComputedTime: metav1.Now(),
+		Targets: []v1alpha1.PipelineTargetPlan{{Endpoint: "http://a", Config: v1alpha1.PlanActionCreate}}}
+	b := a.DeepCopy()
+	b.ComputedTime = metav1.NewTime(a.ComputedTime.Add(1e9))
+	if !planEqual(a, b) {
+		t.Error("plans differing only by computed time must be equal")
+	}
+	b.Targets[0].Config = v1alpha1.PlanActionUnchanged
+	if planEqual(a, b) || planEqual(a, nil) {
+		t.Error("different plans must not be equal")
+	}
+	if got := planSummary(a); got != "dry run: http://a: config create" {
+		t.Errorf("planSummary = %q", got)
+	}
+}
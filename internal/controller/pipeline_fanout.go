@@ -0,0 +1,347 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+// fanOutConcurrency 同时下发的ConfigServer数量
+const fanOutConcurrency = 10
+
+// fansOut 设置了configServerSelector的Pipeline下发到选中的ConfigServer，而不是ConfigMap配置的Config-Server
+func fansOut(pipeline *v1alpha1.Pipeline) bool {
+	return pipeline.Spec.ConfigServerSelector != nil
+}
+
+// handleFanOut 将Pipeline下发到全部选中的ConfigServer。部分失败时保留成功的目标，只重试失败的目标
+func (r *PipelineReconciler) handleFanOut(ctx context.Context, pipeline *v1alpha1.Pipeline) (ctrl.Result, error) {
+	targets, err := r.selectConfigServers(ctx, pipeline)
+	if err != nil {
+		return r.updateStatusFailure(ctx, pipeline, err)
+	}
+
+	var errs []error
+	// 从单一Config-Server切换到多个ConfigServer时，删除原Config-Server上的配置
+	if endpoint := pipeline.Status.LastAppliedConfig.Endpoint; endpoint != "" {
+		if !targetsEndpoint(targets, endpoint) {
+			err = deleteFromConfigServer(ctx, configServerClient(r.ConfigServers, endpoint), pipeline)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+		} else {
+			pipeline.Status.LastAppliedConfig.Endpoint = ""
+		}
+	}
+
+	statuses, applyErrs := r.applyTargets(ctx, pipeline, targets)
+	errs = append(errs, applyErrs...)
+
+	if err := r.removeTargets(ctx, pipeline, targets); err != nil {
+		errs = append(errs, err)
+	}
+	// removeTargets只保留了选中的目标与删除失败的目标，选中的目标以本次下发的结果为准
+	selected := map[string]bool{}
+	for _, target := range targets {
+		selected[target.Name] = true
+	}
+	for _, status := range pipeline.Status.Targets {
+		if !selected[status.Name] {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	pipeline.Status.Targets = statuses
+
+	if err := joinTargetErrors(errs, len(targets)); err != nil {
+		return r.updateStatusFailure(ctx, pipeline, err)
+	}
+
+	r.retrySucceeded(pipeline)
+	setPipelineFannedOut(pipeline, len(targets))
+	if err := r.Status().Update(ctx, pipeline); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: syncInterval}, nil
+}
+
+// selectConfigServers 返回selector选中且未在删除中的ConfigServer，按名称排序
+func (r *PipelineReconciler) selectConfigServers(ctx context.Context, pipeline *v1alpha1.Pipeline) ([]v1alpha1.ConfigServer, error) {
+	selector, err := metav1.LabelSelectorAsSelector(pipeline.Spec.ConfigServerSelector)
+	if err != nil {
+		// 无效的selector修改spec前不会变化，不再重试
+		return nil, &configserver.Error{Op: "SelectConfigServers", Message: err.Error(), Err: err}
+	}
+	var list v1alpha1.ConfigServerList
+	if err := r.List(ctx, &list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	var targets []v1alpha1.ConfigServer
+	for _, target := range list.Items {
+		if target.DeletionTimestamp == nil {
+			targets = append(targets, target)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Name < targets[j].Name })
+	return targets, nil
+}
+
+// applyTargets 以有限并发下发到各ConfigServer，已下发当前generation与内容且配置仍存在的目标跳过
+func (r *PipelineReconciler) applyTargets(ctx context.Context, pipeline *v1alpha1.Pipeline,
+	targets []v1alpha1.ConfigServer) ([]v1alpha1.PipelineTargetStatus, []error) {
+	previous := map[string]v1alpha1.PipelineTargetStatus{}
+	for _, status := range pipeline.Status.Targets {
+		previous[status.Name] = status
+	}
+
+	statuses := make([]v1alpha1.PipelineTargetStatus, len(targets))
+	errs := make([]error, len(targets))
+	var g errgroup.Group
+	g.SetLimit(fanOutConcurrency)
+	for i := range targets {
+		target := targets[i]
+		prev, ok := previous[target.Name]
+		g.Go(func() error {
+			csClient := configServerClient(r.ConfigServers, target.Spec.Endpoint)
+			if ok && prev.Endpoint != target.Spec.Endpoint {
+				// ConfigServer地址变化，旧地址未被其他ConfigServer选中时尽力删除旧地址上的配置
+				if !targetsEndpoint(targets, prev.Endpoint) {
+					if err := deleteFromConfigServer(ctx, configServerClient(r.ConfigServers, prev.Endpoint), pipeline); err != nil {
+						r.Log.Error(err, "Failed to delete pipeline from previous config server endpoint",
+							"pipeline", pipeline.Name, "configServer", target.Name, "endpoint", prev.Endpoint)
+					}
+				}
+			} else if ok && prev.Success && prev.ObservedGeneration == pipeline.Generation && !resyncRequested(pipeline) &&
+				appliedContentEqual(pipeline, pipeline.Spec.Content) && configExists(ctx, csClient, pipeline.Spec.Name) {
+				statuses[i] = prev
+				return nil
+			}
+
+			status := v1alpha1.PipelineTargetStatus{
+				Name:               target.Name,
+				Endpoint:           target.Spec.Endpoint,
+				ObservedGeneration: pipeline.Generation,
+				LastUpdateTime:     metav1.Now(),
+			}
+			if err := applyToConfigServer(ctx, csClient, pipeline); err != nil {
+				status.Message = err.Error()
+				errs[i] = fmt.Errorf("%s: %w", target.Name, err)
+			} else {
+				status.Success = true
+			}
+			statuses[i] = status
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return statuses, failed
+}
+
+// removeTargets 从不再选中的ConfigServer删除配置，targets为nil时删除全部目标。
+// 地址仍被选中的目标只移出status，删除失败的目标保留在status中以便重试
+func (r *PipelineReconciler) removeTargets(ctx context.Context, pipeline *v1alpha1.Pipeline,
+	targets []v1alpha1.ConfigServer) error {
+	selected := map[string]bool{}
+	for _, target := range targets {
+		selected[target.Name] = true
+	}
+	var remaining []v1alpha1.PipelineTargetStatus
+	var errs []error
+	for _, status := range pipeline.Status.Targets {
+		if selected[status.Name] {
+			remaining = append(remaining, status)
+			continue
+		}
+		if targetsEndpoint(targets, status.Endpoint) {
+			continue
+		}
+		if err := deleteFromConfigServer(ctx, configServerClient(r.ConfigServers, status.Endpoint), pipeline); err != nil {
+			status.Success = false
+			status.Message = "failed to delete: " + err.Error()
+			status.LastUpdateTime = metav1.Now()
+			remaining = append(remaining, status)
+			errs = append(errs, fmt.Errorf("%s: %w", status.Name, err))
+			continue
+		}
+		r.Log.Info("Deleted pipeline from config server", "pipeline", pipeline.Name,
+			"configServer", status.Name, "endpoint", status.Endpoint)
+	}
+	pipeline.Status.Targets = remaining
+	return joinTargetErrors(errs, len(errs))
+}
+
+// mapConfigServerToPipelines ConfigServer变化时重新处理selector匹配新旧标签或曾下发到该ConfigServer的Pipeline
+func (r *PipelineReconciler) mapConfigServerToPipelines(ctx context.Context, obj client.Object) []reconcile.Request {
+	var pipelines v1alpha1.PipelineList
+	if err := r.List(ctx, &pipelines); err != nil {
+		r.Log.Error(err, "Failed to list pipelines")
+		return nil
+	}
+	requests := requestSet{}
+	for i := range pipelines.Items {
+		pipeline := &pipelines.Items[i]
+		if targetsConfigServer(pipeline, obj) {
+			requests.add(pipeline)
+		}
+	}
+	return requests.list()
+}
+
+// targetsConfigServer 判断Pipeline是否选中或曾经下发到该ConfigServer
+func targetsConfigServer(pipeline *v1alpha1.Pipeline, obj client.Object) bool {
+	for _, status := range pipeline.Status.Targets {
+		if status.Name == obj.GetName() {
+			return true
+		}
+	}
+	if !fansOut(pipeline) {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pipeline.Spec.ConfigServerSelector)
+	return err == nil && selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// applyToConfigServer 下发配置并关联到spec.agentGroup。其他集群的Config-Server不受本集群AgentGroup CR管理，由Pipeline自行关联
+func applyToConfigServer(ctx context.Context, csClient *configserver.ConfigServerClient, pipeline *v1alpha1.Pipeline) error {
+	if err := csClient.CreateConfig(ctx, pipeline); err != nil {
+		return err
+	}
+	if pipeline.Spec.AgentGroup == "" {
+		return nil
+	}
+	return associateAgentGroup(ctx, csClient, pipeline)
+}
+
+// deleteFromConfigServer 解除与Agent组的关联并删除配置
+func deleteFromConfigServer(ctx context.Context, csClient *configserver.ConfigServerClient, pipeline *v1alpha1.Pipeline) error {
+	if pipeline.Spec.AgentGroup != "" {
+		if err := csClient.RemoveConfigFromAgentGroup(ctx, pipeline.Spec.Name, pipeline.Spec.AgentGroup); err != nil {
+			return err
+		}
+	}
+	return csClient.DeleteConfig(ctx, pipeline.Spec.Name)
+}
+
+// configExists 读取客户端缓存判断配置是否仍在Config-Server上，读取失败时视为不存在以重新下发
+func configExists(ctx context.Context, csClient *configserver.ConfigServerClient, name string) bool {
+	configs, err := csClient.ListConfigs(ctx)
+	if err != nil {
+		return false
+	}
+	for _, config := range configs {
+		if config.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// targetsEndpoint 判断endpoint是否为选中的ConfigServer之一
+func targetsEndpoint(targets []v1alpha1.ConfigServer, endpoint string) bool {
+	for _, target := range targets {
+		if target.Spec.Endpoint == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// joinTargetErrors 合并各目标的错误。可重试的错误排在前面，只要有目标可重试就按退避重新入队
+func joinTargetErrors(errs []error, total int) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return configserver.IsRetryable(errs[i]) && !configserver.IsRetryable(errs[j])
+	})
+	return &targetsError{errs: errs, total: total}
+}
+
+// targetsError 部分ConfigServer失败的错误
+type targetsError struct {
+	errs  []error
+	total int
+}
+
+func (e *targetsError) Error() string {
+	msgs := make([]string, 0, len(e.errs))
+	for _, err := range e.errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d of %d config servers failed: %s", len(e.errs), e.total, strings.Join(msgs, "; "))
+}
+
+func (e *targetsError) Unwrap() []error {
+	return e.errs
+}
+
+// setPipelineFannedOut 将Pipeline状态更新为已下发到全部选中的ConfigServer
+func setPipelineFannedOut(pipeline *v1alpha1.Pipeline, targets int) {
+	now := metav1.Now()
+	pipeline.Status.LastUpdateTime = now
+	pipeline.Status.ObservedGeneration = pipeline.Generation
+	if targets == 0 {
+		pipeline.Status.Success = false
+		pipeline.Status.Message = emus.PipelineStatusFailed
+		meta.SetStatusCondition(&pipeline.Status.Conditions, metav1.Condition{
+			Type:               emus.PipelineConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: pipeline.Generation,
+			Reason:             emus.ConditionReasonNoTargets,
+			Message:            "no ConfigServer matches configServerSelector",
+		})
+		return
+	}
+	pipeline.Status.Success = true
+	pipeline.Status.Message = emus.PipelineStatusSuccess
+	meta.SetStatusCondition(&pipeline.Status.Conditions, metav1.Condition{
+		Type:               emus.PipelineConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: pipeline.Generation,
+		Reason:             "Applied",
+		Message:            fmt.Sprintf("pipeline applied to %d config servers", targets),
+	})
+	pipeline.Status.LastAppliedConfig = v1alpha1.LastAppliedConfig{
+		AppliedTime: now,
+		Content:     appliedContent(pipeline),
+	}
+	recordRevision(pipeline, "")
+	pipeline.Status.ContentHash = contentHash(pipeline.Spec.Content)
+	pipeline.Status.ResyncToken = pipeline.Annotations[v1alpha1.AnnotationResync]
+}
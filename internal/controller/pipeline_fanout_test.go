@@ -0,0 +1,81 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+func TestJoinTargetErrors(t *testing.T) {
+	if err := joinTargetErrors(nil, 3); err != nil {
+		t.Fatalf("joinTargetErrors(nil) = %v", err)
+	}
+
+	terminal := &configserver.Error{Op: "CreateConfig", StatusCode: 400, Message: "bad"}
+	retryable := &configserver.Error{Op: "CreateConfig", StatusCode: 503, Message: "down", Retryable: true}
+	err := joinTargetErrors([]error{terminal, retryable}, 3)
+	if !configserver.IsRetryable(err) {
+		t.Error("a partial failure with a retryable target must be retried")
+	}
+	if !strings.HasPrefix(err.Error(), "2 of 3 config servers failed") {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if !errors.Is(err, terminal) {
+		t.Error("the target errors must be unwrappable")
+	}
+
+	if configserver.IsRetryable(joinTargetErrors([]error{terminal}, 3)) {
+		t.Error("terminal failures only must not be retried")
+	}
+}
+
+func TestTargetsConfigServer(t *testing.T) {
+	server := &v1alpha1.ConfigServer{ObjectMeta: metav1.ObjectMeta{Name: "cluster-a", Labels: map[string]string{"env": "prod"}}}
+	pipeline := func(selector *metav1.LabelSelector, targets ...string) *v1alpha1.Pipeline {
+		p := &v1alpha1.Pipeline{Spec: v1alpha1.PipelineSpec{ConfigServerSelector: selector}}
+		for _, name := range targets {
+			p.Status.Targets = append(p.Status.Targets, v1alpha1.PipelineTargetStatus{Name: name})
+		}
+		return p
+	}
+	prod := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	staging := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "staging"}}
+
+	tests := []struct {
+		name     string
+		pipeline *v1alpha1.Pipeline
+		want     bool
+	}{
+		{"selected", pipeline(prod), true},
+		{"not selected", pipeline(staging), false},
+		{"previously applied", pipeline(staging, "cluster-a"), true},
+		{"single config server", pipeline(nil), false},
+		{"switched back to a single config server", pipeline(nil, "cluster-a"), true},
+	}
+	for _, tt := range tests {
+		if got := targetsConfigServer(tt.pipeline, server); got != tt.want {
+			t.Errorf("%s: targetsConfigServer() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+)
+
+// referencesProfiles 检查Pipeline是否引用了ProcessorChain或FlusherProfile
+func referencesProfiles(pipeline *v1alpha1.Pipeline) bool {
+	return len(pipeline.Spec.ProcessorChainRefs) > 0 || len(pipeline.Spec.FlusherProfileRefs) > 0
+}
+
+// profileUpdatePending 检查updatePolicy为Manual的Pipeline引用的profile是否有尚未接受的变化。
+// spec修改或请求resync时接受profile的当前内容
+func profileUpdatePending(pipeline *v1alpha1.Pipeline, profiles []v1alpha1.PipelineProfileStatus) bool {
+	if pipeline.Spec.UpdatePolicy != v1alpha1.UpdatePolicyManual {
+		return false
+	}
+	if pipeline.Generation != pipeline.Status.ObservedGeneration || resyncRequested(pipeline) ||
+		(pipeline.Status.LastAppliedConfig.Content == "" && pipeline.Status.ContentHash == "") {
+		return false
+	}
+	return !equality.Semantic.DeepEqual(pipeline.Status.Profiles, profiles)
+}
+
+// recordProfiles 没有未接受的变化时在内存中把profiles记录为已接受，并维护ProfilesUpToDate条件。
+// 返回是否有未接受的profile变化
+func recordProfiles(pipeline *v1alpha1.Pipeline, profiles []v1alpha1.PipelineProfileStatus) bool {
+	pending := profileUpdatePending(pipeline, profiles)
+	if !pending {
+		pipeline.Status.Profiles = profiles
+	}
+	setProfilesCondition(pipeline, profiles, pending)
+	return pending
+}
+
+// setProfilesCondition 设置ProfilesUpToDate条件，没有引用profile时去掉该条件
+func setProfilesCondition(pipeline *v1alpha1.Pipeline, profiles []v1alpha1.PipelineProfileStatus, pending bool) {
+	if len(profiles) == 0 {
+		meta.RemoveStatusCondition(&pipeline.Status.Conditions, emus.PipelineConditionProfilesUpToDate)
+		return
+	}
+	if !pending {
+		meta.SetStatusCondition(&pipeline.Status.Conditions, metav1.Condition{
+			Type:               emus.PipelineConditionProfilesUpToDate,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: pipeline.Generation,
+			Reason:             "UpToDate",
+			Message:            fmt.Sprintf("%d profiles rendered into the configuration", len(profiles)),
+		})
+		return
+	}
+	accepted := map[string]string{}
+	for _, p := range pipeline.Status.Profiles {
+		accepted[profile.Reference(p.Kind, p.Name)] = p.ContentHash
+	}
+	var changed []string
+	for _, p := range profiles {
+		if ref := profile.Reference(p.Kind, p.Name); accepted[ref] != p.ContentHash {
+			changed = append(changed, ref)
+		}
+	}
+	meta.SetStatusCondition(&pipeline.Status.Conditions, metav1.Condition{
+		Type:               emus.PipelineConditionProfilesUpToDate,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: pipeline.Generation,
+		Reason:             emus.ConditionReasonUpdatePending,
+		Message: fmt.Sprintf("changes to %s are applied once the spec changes or the %s annotation is set",
+			strings.Join(changed, ", "), v1alpha1.AnnotationResync),
+	})
+}
+
+// mapProfileToPipelines 返回的函数把ProcessorChain或FlusherProfile的变化映射到引用它的Pipeline，
+// 命名空间级的profile只映射同一命名空间的Pipeline
+func (r *PipelineReconciler) mapProfileToPipelines(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		opts := []client.ListOption{client.MatchingFields{pipelineProfileRefField: profile.Reference(kind, obj.GetName())}}
+		if profile.Namespaced(kind) {
+			opts = append(opts, client.InNamespace(obj.GetNamespace()))
+		}
+		var pipelines v1alpha1.PipelineList
+		if err := r.List(ctx, &pipelines, opts...); err != nil {
+			r.Log.Error(err, "Failed to list pipelines by profile", "kind", kind, "name", obj.GetName())
+			return nil
+		}
+		requests := requestSet{}
+		for i := range pipelines.Items {
+			requests.add(&pipelines.Items[i])
+		}
+		return requests.list()
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+)
+
+func newProfileReconciler(t *testing.T, objs ...client.Object) *PipelineReconciler {
+	t.Helper()
+	c, scheme := newFakeClient(t, objs...)
+	return &PipelineReconciler{Client: c, Log: logr.Discard(), Scheme: scheme}
+}
+
+func manualPipeline(accepted string) *v1alpha1.Pipeline {
+	return &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web", Generation: 2},
+		Spec: v1alpha1.PipelineSpec{
+			Name:               "web",
+			Content:            "inputs: []\n",
+			UpdatePolicy:       v1alpha1.UpdatePolicyManual,
+			FlusherProfileRefs: []v1alpha1.FlusherProfileReference{{Kind: profile.KindClusterFlusherProfile, Name: "kafka"}},
+		},
+		Status: v1alpha1.PipelineStatus{
+			ObservedGeneration: 2,
+			LastAppliedConfig:  v1alpha1.LastAppliedConfig{Content: "applied"},
+			Profiles:           []v1alpha1.PipelineProfileStatus{{Kind: profile.KindClusterFlusherProfile, Name: "kafka", ContentHash: accepted}},
+		},
+	}
+}
+
+func TestProfileUpdatePending(t *testing.T) {
+	current := []v1alpha1.PipelineProfileStatus{{Kind: profile.KindClusterFlusherProfile, Name: "kafka", ContentHash: "new"}}
+	if !profileUpdatePending(manualPipeline("old"), current) {
+		t.Fatal("changed profile of a Manual pipeline must be pending")
+	}
+	if profileUpdatePending(manualPipeline("new"), current) {
+		t.Fatal("unchanged profile must not be pending")
+	}
+
+	auto := manualPipeline("old")
+	auto.Spec.UpdatePolicy = v1alpha1.UpdatePolicyAuto
+	edited := manualPipeline("old")
+	edited.Generation = 3
+	resync := manualPipeline("old")
+	resync.Annotations = map[string]string{v1alpha1.AnnotationResync: "now"}
+	neverApplied := manualPipeline("old")
+	neverApplied.Status.LastAppliedConfig = v1alpha1.LastAppliedConfig{}
+	for name, p := range map[string]*v1alpha1.Pipeline{"auto": auto, "edited": edited, "resync": resync, "neverApplied": neverApplied} {
+		if profileUpdatePending(p, current) {
+			t.Errorf("%s: profile change must be accepted", name)
+		}
+	}
+}
+
+func TestResolveContentKeepsAcceptedProfilesWhilePending(t *testing.T) {
+	p := manualPipeline("old")
+	kafka := &v1alpha1.ClusterFlusherProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka"},
+		Spec: v1alpha1.FlusherProfileSpec{Flushers: []runtime.RawExtension{
+			{Raw: []byte(`{"Type":"flusher_kafka_v2","Brokers":["kafka:9092"]}`)}}},
+	}
+	r := newProfileReconciler(t, p, kafka)
+
+	if err := r.resolveContent(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Spec.Content != "applied" {
+		t.Fatalf("pending profile changes must keep the applied content, got %q", p.Spec.Content)
+	}
+	stored := &v1alpha1.Pipeline{}
+	if err := r.Get(context.Background(), client.ObjectKeyFromObject(p), stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status.Profiles[0].ContentHash != "old" {
+		t.Fatalf("accepted profiles must be kept, got %+v", stored.Status.Profiles)
+	}
+	cond := meta.FindStatusCondition(stored.Status.Conditions, emus.PipelineConditionProfilesUpToDate)
+	if cond == nil || cond.Reason != emus.ConditionReasonUpdatePending {
+		t.Fatalf("expected UpdatePending condition, got %+v", cond)
+	}
+
+	stored.Spec.UpdatePolicy = v1alpha1.UpdatePolicyAuto
+	if err := r.resolveContent(context.Background(), stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status.Profiles[0].ContentHash == "old" ||
+		!meta.IsStatusConditionTrue(stored.Status.Conditions, emus.PipelineConditionProfilesUpToDate) {
+		t.Fatalf("profiles must be accepted, got %+v", stored.Status)
+	}
+	if !strings.Contains(stored.Spec.Content, "flusher_kafka_v2") {
+		t.Fatalf("accepted profiles must be rendered, got %q", stored.Spec.Content)
+	}
+}
+
+func TestMapProfileToPipelines(t *testing.T) {
+	shared := func(ns string) *v1alpha1.Pipeline {
+		return &v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "web"},
+			Spec: v1alpha1.PipelineSpec{
+				Name:               "web",
+				Content:            "inputs: []\n",
+				ProcessorChainRefs: []v1alpha1.ProcessorChainReference{{Name: "json"}},
+				FlusherProfileRefs: []v1alpha1.FlusherProfileReference{{Kind: profile.KindClusterFlusherProfile, Name: "kafka"}},
+			},
+		}
+	}
+	r := newProfileReconciler(t, shared("a"), shared("b"))
+
+	chain := &v1alpha1.ProcessorChain{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "json"}}
+	if requests := r.mapProfileToPipelines(profile.KindProcessorChain)(context.Background(), chain); len(requests) != 1 ||
+		requests[0].Namespace != "a" {
+		t.Fatalf("ProcessorChain must map to the pipelines of its namespace, got %v", requests)
+	}
+	flushers := &v1alpha1.ClusterFlusherProfile{ObjectMeta: metav1.ObjectMeta{Name: "kafka"}}
+	if requests := r.mapProfileToPipelines(profile.KindClusterFlusherProfile)(context.Background(), flushers); len(requests) != 2 {
+		t.Fatalf("ClusterFlusherProfile must map to pipelines of all namespaces, got %v", requests)
+	}
+}
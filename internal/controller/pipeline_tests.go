@@ -0,0 +1,80 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/pipelinetest"
+)
+
+// blockingTests 返回引用Pipeline且开启了blockOnFailure的PipelineTest
+func blockingTests(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline) ([]*v1alpha1.PipelineTest, error) {
+	var tests v1alpha1.PipelineTestList
+	if err := c.List(ctx, &tests, client.InNamespace(pipeline.Namespace)); err != nil {
+		return nil, err
+	}
+	var referencing []v1alpha1.PipelineTest
+	for _, test := range tests.Items {
+		if test.Spec.PipelineRef.Name == pipeline.Name {
+			referencing = append(referencing, test)
+		}
+	}
+	return pipelinetest.Blocking(referencing), nil
+}
+
+// runBlockingTests 用将要下发的配置运行阻塞下发的PipelineTest，有测试失败时返回不可重试的错误，
+// 等待PipelineTest或Pipeline变化后重新处理
+func runBlockingTests(content string, tests []*v1alpha1.PipelineTest) error {
+	var failed []string
+	for _, test := range tests {
+		outcome, err := pipelinetest.Run(content, &test.Spec)
+		switch {
+		case err != nil:
+			failed = append(failed, fmt.Sprintf("%s: %v", test.Name, err))
+		case !outcome.Passed():
+			failed = append(failed, fmt.Sprintf("%s: %s", test.Name, outcome.Failures[0]))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &configserver.Error{
+		Op:      "RunPipelineTests",
+		Message: fmt.Sprintf("%d blocking pipeline tests failed: %s", len(failed), strings.Join(failed, "; ")),
+	}
+}
+
+// mapTestToPipeline 阻塞下发的PipelineTest变化时重新处理它引用的Pipeline
+func mapTestToPipeline(_ context.Context, obj client.Object) []reconcile.Request {
+	test, ok := obj.(*v1alpha1.PipelineTest)
+	if !ok || !test.Spec.BlockOnFailure {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: test.Namespace,
+		Name:      test.Spec.PipelineRef.Name,
+	}}}
+}
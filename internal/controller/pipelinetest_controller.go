@@ -0,0 +1,179 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/pipelinetest"
+)
+
+// PipelineTestReconciler runs the processors of the Pipeline referenced by a PipelineTest over its sample input
+// and reports whether the output matches the expected records and assertions. Config-Server is never contacted.
+type PipelineTestReconciler struct {
+	client.Client
+	Log   logr.Logger
+	Event record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelinetests,verbs=get;list;watch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelinetests/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelines,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *PipelineTestReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("pipelinetest", req.NamespacedName)
+
+	test := &v1alpha1.PipelineTest{}
+	if err := r.Get(ctx, req.NamespacedName, test); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if test.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	status := test.Status.DeepCopy()
+	cond, err := r.evaluate(ctx, test, status)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status.ObservedGeneration = test.Generation
+	cond.Type = emus.PipelineTestConditionPassed
+	cond.ObservedGeneration = test.Generation
+	previous := meta.FindStatusCondition(test.Status.Conditions, emus.PipelineTestConditionPassed)
+	meta.SetStatusCondition(&status.Conditions, cond)
+
+	if equality.Semantic.DeepEqual(status, &test.Status) {
+		return ctrl.Result{}, nil
+	}
+	now := metav1.Now()
+	status.LastRunTime = &now
+	if cond.Status == metav1.ConditionFalse && (previous == nil || previous.Status != metav1.ConditionFalse ||
+		previous.Reason != cond.Reason) {
+		r.Event.Event(test, corev1.EventTypeWarning, "PipelineTestFailed", cond.Message)
+	}
+	log.Info("Evaluated pipeline test", "passed", cond.Status, "reason", cond.Reason)
+	test.Status = *status
+	return ctrl.Result{}, r.Status().Update(ctx, test)
+}
+
+// evaluate 用引用的Pipeline的处理插件处理样例日志并检查期望记录与断言，结果写入status，返回Passed条件。
+// 无法渲染的配置与无法模拟的处理插件是测试失败，只有访问API Server的错误需要重试
+func (r *PipelineTestReconciler) evaluate(ctx context.Context, test *v1alpha1.PipelineTest,
+	status *v1alpha1.PipelineTestStatus) (metav1.Condition, error) {
+	status.Output, status.Failures, status.Notes = nil, nil, nil
+
+	pipeline := &v1alpha1.Pipeline{}
+	key := types.NamespacedName{Namespace: test.Namespace, Name: test.Spec.PipelineRef.Name}
+	if err := r.Get(ctx, key, pipeline); err != nil {
+		if errors.IsNotFound(err) {
+			status.ContentHash = ""
+			return metav1.Condition{
+				Status:  metav1.ConditionFalse,
+				Reason:  emus.ConditionReasonPipelineNotFound,
+				Message: fmt.Sprintf("Pipeline %s not found", key.Name),
+			}, nil
+		}
+		return metav1.Condition{}, err
+	}
+
+	content, _, err := renderPipelineContent(ctx, r.Client, pipeline)
+	if err != nil {
+		var renderErr *configserver.Error
+		if !stderrors.As(err, &renderErr) {
+			return metav1.Condition{}, err
+		}
+		status.ContentHash = ""
+		return simulationFailed(err), nil
+	}
+	status.ContentHash = contentHash(content)
+
+	outcome, err := pipelinetest.Run(content, &test.Spec)
+	if err != nil {
+		return simulationFailed(err), nil
+	}
+	for _, record := range outcome.Output {
+		status.Output = append(status.Output, record)
+	}
+	status.Notes = outcome.Notes
+	status.Failures = outcome.Failures
+	if !outcome.Passed() {
+		return metav1.Condition{
+			Status:  metav1.ConditionFalse,
+			Reason:  emus.ConditionReasonOutputMismatch,
+			Message: fmt.Sprintf("%d failures, first: %s", len(status.Failures), status.Failures[0]),
+		}, nil
+	}
+	return metav1.Condition{
+		Status:  metav1.ConditionTrue,
+		Reason:  emus.ConditionReasonOutputMatched,
+		Message: fmt.Sprintf("%d records match the expected records and assertions", len(outcome.Output)),
+	}, nil
+}
+
+func simulationFailed(err error) metav1.Condition {
+	return metav1.Condition{
+		Status:  metav1.ConditionFalse,
+		Reason:  emus.ConditionReasonSimulationFailed,
+		Message: err.Error(),
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PipelineTestReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.PipelineTest{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// 引用的ConfigMap、模板与profile变化时Pipeline的generation不变，status中的contentHash会变化，因此关注所有更新
+		Watches(&v1alpha1.Pipeline{}, handler.EnqueueRequestsFromMapFunc(r.mapPipelineToTests)).
+		Named("pipelinetest").
+		Complete(r)
+}
+
+// mapPipelineToTests Pipeline变化时重新运行引用它的PipelineTest
+func (r *PipelineTestReconciler) mapPipelineToTests(ctx context.Context, obj client.Object) []reconcile.Request {
+	var tests v1alpha1.PipelineTestList
+	if err := r.List(ctx, &tests, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{pipelineTestPipelineField: obj.GetName()}); err != nil {
+		r.Log.Error(err, "Failed to list pipeline tests by pipelineRef", "pipeline", obj.GetName())
+		return nil
+	}
+	requests := requestSet{}
+	for i := range tests.Items {
+		requests.add(&tests.Items[i])
+	}
+	return requests.list()
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+func newPipelineTestReconciler(t *testing.T, objs ...client.Object) *PipelineTestReconciler {
+	t.Helper()
+	c, _ := newFakeClient(t, objs...)
+	return &PipelineTestReconciler{
+		Client: c,
+		Log:    logr.Discard(),
+		Event:  record.NewFakeRecorder(10),
+	}
+}
+
+const pipelineTestContent = `inputs:
+  - Type: input_file
+    FilePaths: [/var/log/app.log]
+processors:
+  - Type: processor_parse_regex_native
+    SourceKey: content
+    Regex: (\w+) (.*)
+    Keys: [level, msg]
+  - Type: processor_filter_regex_native
+    Include:
+      level: ERROR|WARN
+flushers:
+  - Type: flusher_stdout
+`
+
+// reconcilePipelineTest 调和PipelineTest并返回更新后的对象与Passed条件
+func reconcilePipelineTest(t *testing.T, r *PipelineTestReconciler, test *v1alpha1.PipelineTest) (*v1alpha1.PipelineTest,
+	*metav1.Condition) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(test)}); err != nil {
+		t.Fatal(err)
+	}
+	got := &v1alpha1.PipelineTest{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(test), got); err != nil {
+		t.Fatal(err)
+	}
+	cond := meta.FindStatusCondition(got.Status.Conditions, emus.PipelineTestConditionPassed)
+	if cond == nil {
+		t.Fatal("no Passed condition")
+	}
+	return got, cond
+}
+
+func TestPipelineTestPasses(t *testing.T) {
+	pipeline := &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "app"},
+		Spec:       v1alpha1.PipelineSpec{Name: "app", Content: pipelineTestContent},
+	}
+	test := &v1alpha1.PipelineTest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "app-errors"},
+		Spec: v1alpha1.PipelineTestSpec{
+			PipelineRef: corev1.LocalObjectReference{Name: "app"},
+			Input:       []string{"INFO started", "ERROR failed to connect"},
+			Expected:    []map[string]string{{"level": "ERROR", "msg": "failed to connect"}},
+		},
+	}
+	r := newPipelineTestReconciler(t, pipeline, test)
+
+	got, cond := reconcilePipelineTest(t, r, test)
+	if cond.Status != metav1.ConditionTrue || cond.Reason != emus.ConditionReasonOutputMatched {
+		t.Fatalf("condition = %+v", cond)
+	}
+	if len(got.Status.Output) != 1 || got.Status.ContentHash != contentHash(pipelineTestContent) || got.Status.LastRunTime == nil {
+		t.Errorf("status = %+v", got.Status)
+	}
+
+	// Pipeline修改后重新运行，输出与期望不一致
+	pipeline.Spec.Content = strings.Replace(pipelineTestContent, "ERROR|WARN", "WARN", 1)
+	if err := r.Update(context.Background(), pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if requests := r.mapPipelineToTests(context.Background(), pipeline); len(requests) != 1 {
+		t.Fatalf("pipeline maps to %v", requests)
+	}
+	got, cond = reconcilePipelineTest(t, r, got)
+	if cond.Status != metav1.ConditionFalse || cond.Reason != emus.ConditionReasonOutputMismatch {
+		t.Fatalf("condition = %+v", cond)
+	}
+	if len(got.Status.Output) != 0 || len(got.Status.Failures) != 1 || !strings.Contains(got.Status.Failures[0], "record 0: missing") {
+		t.Errorf("status = %+v", got.Status)
+	}
+}
+
+func TestPipelineTestFailures(t *testing.T) {
+	unsupported := &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "masked"},
+		Spec: v1alpha1.PipelineSpec{Name: "masked",
+			Content: "inputs:\n  - Type: input_file\nprocessors:\n  - Type: processor_desensitize\n"},
+	}
+	for _, tc := range []struct {
+		pipeline string
+		reason   string
+	}{
+		{"missing", emus.ConditionReasonPipelineNotFound},
+		{"masked", emus.ConditionReasonSimulationFailed},
+	} {
+		test := &v1alpha1.PipelineTest{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: tc.pipeline},
+			Spec: v1alpha1.PipelineTestSpec{
+				PipelineRef: corev1.LocalObjectReference{Name: tc.pipeline},
+				Input:       []string{"x"},
+			},
+		}
+		r := newPipelineTestReconciler(t, unsupported, test)
+		_, cond := reconcilePipelineTest(t, r, test)
+		if cond.Status != metav1.ConditionFalse || cond.Reason != tc.reason {
+			t.Errorf("%s: condition = %+v", tc.pipeline, cond)
+		}
+	}
+}
+
+func TestBlockingPipelineTests(t *testing.T) {
+	pipeline := &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "app"},
+		Spec:       v1alpha1.PipelineSpec{Name: "app", Content: pipelineTestContent},
+	}
+	newTest := func(name, pipeline string, block bool, level string) *v1alpha1.PipelineTest {
+		return &v1alpha1.PipelineTest{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: name},
+			Spec: v1alpha1.PipelineTestSpec{
+				PipelineRef:    corev1.LocalObjectReference{Name: pipeline},
+				InputRecords:   []map[string]string{{"content": "ERROR failed"}},
+				Assertions:     []v1alpha1.PipelineTestAssertion{{Field: "level", Equals: &level}},
+				BlockOnFailure: block,
+			},
+		}
+	}
+	r := newPipelineTestReconciler(t, pipeline,
+		newTest("passes", "app", true, "ERROR"),
+		newTest("fails", "app", true, "WARN"),
+		newTest("not-blocking", "app", false, "WARN"),
+		newTest("other", "web", true, "WARN"))
+
+	tests, err := blockingTests(context.Background(), r.Client, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tests) != 2 || tests[0].Name != "fails" || tests[1].Name != "passes" {
+		t.Fatalf("blocking tests = %v", tests)
+	}
+	err = runBlockingTests(pipeline.Spec.Content, tests)
+	if err == nil || configserver.IsRetryable(err) || !strings.Contains(err.Error(), `1 blocking pipeline tests failed: fails: assertions[0]`) {
+		t.Errorf("err = %v", err)
+	}
+	if err := runBlockingTests(pipeline.Spec.Content, tests[1:]); err != nil {
+		t.Errorf("passing tests: %v", err)
+	}
+
+	if requests := mapTestToPipeline(context.Background(), tests[0]); len(requests) != 1 || requests[0].Name != "app" {
+		t.Errorf("blocking test maps to %v", requests)
+	}
+	if requests := mapTestToPipeline(context.Background(), newTest("x", "app", false, "")); len(requests) != 0 {
+		t.Errorf("non-blocking test maps to %v", requests)
+	}
+}
@@ -0,0 +1,530 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	csfake "github.com/infraflows/loongcollector-operator/internal/pkg/configserver/fake"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+// remoteEnv 在fake客户端与内存中的Config-Server上运行Pipeline与AgentGroup控制器
+type remoteEnv struct {
+	client.Client
+	server    *csfake.Server
+	pipelines *PipelineReconciler
+	groups    *AgentGroupReconciler
+}
+
+func newRemoteEnv(t *testing.T, objs ...client.Object) *remoteEnv {
+	t.Helper()
+	server := csfake.NewServer()
+	t.Cleanup(server.Close)
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: configMapNamespace, Name: configMapName,
+			Labels: map[string]string{"app": "config-server"}},
+		Data: map[string]string{configMapKey: server.URL},
+	}
+	c, scheme := newFakeClient(t, append(objs, configMap)...)
+
+	// 每个测试使用独立的注册表，避免共享熔断器与缓存
+	registry := configserver.NewRegistry(configserver.RegistryOptions{})
+	return &remoteEnv{
+		Client: c,
+		server: server,
+		pipelines: &PipelineReconciler{Client: c, Log: logr.Discard(), Scheme: scheme,
+			Event: record.NewFakeRecorder(100), ConfigServers: registry},
+		groups: &AgentGroupReconciler{Client: c, Log: logr.Discard(), Scheme: scheme,
+			Event: record.NewFakeRecorder(100), ConfigServers: registry},
+	}
+}
+
+func (e *remoteEnv) reconcilePipeline(t *testing.T, name string) ctrl.Result {
+	t.Helper()
+	result, err := e.pipelines.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{
+		Namespace: "default", Name: name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func (e *remoteEnv) reconcileAgentGroup(t *testing.T, name string) {
+	t.Helper()
+	if _, err := e.groups.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{
+		Namespace: "default", Name: name}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// update 读取最新的对象，修改后写回
+func (e *remoteEnv) update(t *testing.T, obj client.Object, mutate func()) {
+	t.Helper()
+	ctx := context.Background()
+	if err := e.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		t.Fatal(err)
+	}
+	mutate()
+	if err := e.Update(ctx, obj); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *remoteEnv) expectState(t *testing.T, step string, want csfake.State) {
+	t.Helper()
+	if got := e.server.State(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s: remote state = %#v, want %#v", step, got, want)
+	}
+}
+
+func canonical(t *testing.T, content string) string {
+	t.Helper()
+	out, err := render.Canonical(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+const (
+	remoteContent = "inputs:\n  - Type: input_file\n    FilePaths: [/var/log/app.log]\nflushers:\n  - Type: flusher_stdout\n"
+	remoteUpdated = "inputs:\n  - Type: input_file\n    FilePaths: [/var/log/app/*.log]\nflushers:\n  - Type: flusher_stdout\n"
+)
+
+func remotePipeline(name, agentGroup string) *v1alpha1.Pipeline {
+	return &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec:       v1alpha1.PipelineSpec{Name: name, Content: remoteContent, AgentGroup: agentGroup},
+	}
+}
+
+func TestPipelineRemoteState(t *testing.T) {
+	pipeline := remotePipeline("app", "web")
+	e := newRemoteEnv(t, pipeline)
+
+	e.reconcilePipeline(t, "app")
+	e.expectState(t, "create", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+
+	// 未变化时不请求写接口
+	e.server.ResetRequests()
+	e.reconcilePipeline(t, "app")
+	if n := e.server.Count("CreateConfig"); n != 0 {
+		t.Errorf("unchanged pipeline sent %d CreateConfig requests: %v", n, e.server.Requests())
+	}
+
+	e.update(t, pipeline, func() { pipeline.Spec.Content = remoteUpdated })
+	e.reconcilePipeline(t, "app")
+	e.expectState(t, "update", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteUpdated)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+
+	e.update(t, pipeline, func() { pipeline.Spec.Name = "app-v2" })
+	e.reconcilePipeline(t, "app")
+	e.expectState(t, "rename", csfake.State{
+		Configs: map[string]string{"app-v2": canonical(t, remoteUpdated)},
+		Groups:  map[string][]string{"web": {"app-v2"}},
+	})
+
+	e.update(t, pipeline, func() { pipeline.Spec.AgentGroup = "db" })
+	e.reconcilePipeline(t, "app")
+	e.expectState(t, "regroup", csfake.State{
+		Configs: map[string]string{"app-v2": canonical(t, remoteUpdated)},
+		Groups:  map[string][]string{"web": {}, "db": {"app-v2"}},
+	})
+	if err := e.Get(context.Background(), client.ObjectKeyFromObject(pipeline), pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if last := pipeline.Status.LastAppliedConfig; last.Name != "app-v2" || last.AgentGroup != "db" || !pipeline.Status.Success {
+		t.Errorf("status = %+v", pipeline.Status)
+	}
+
+	if err := e.Delete(context.Background(), pipeline); err != nil {
+		t.Fatal(err)
+	}
+	e.reconcilePipeline(t, "app")
+	e.expectState(t, "delete", csfake.State{
+		Configs: map[string]string{},
+		Groups:  map[string][]string{"web": {}, "db": {}},
+	})
+}
+
+func TestPipelineDryRunDeleteRemovesAppliedConfig(t *testing.T) {
+	pipeline := remotePipeline("app", "web")
+	e := newRemoteEnv(t, pipeline)
+	e.reconcilePipeline(t, "app")
+
+	// dryRun期间改名改组不会下发，删除时清理的是最后一次下发的配置
+	e.update(t, pipeline, func() {
+		pipeline.Spec.DryRun = true
+		pipeline.Spec.Name = "app-v2"
+		pipeline.Spec.AgentGroup = "db"
+	})
+	e.reconcilePipeline(t, "app")
+	e.expectState(t, "dry run", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+
+	if err := e.Delete(context.Background(), pipeline); err != nil {
+		t.Fatal(err)
+	}
+	e.reconcilePipeline(t, "app")
+	e.expectState(t, "delete", csfake.State{
+		Configs: map[string]string{},
+		Groups:  map[string][]string{"web": {}},
+	})
+}
+
+func TestPipelineRenameKeepsConfigsOfOtherPipelines(t *testing.T) {
+	app, api := remotePipeline("app", "web"), remotePipeline("api", "web")
+	api.Spec.Content = remoteUpdated
+	e := newRemoteEnv(t, app, api)
+	e.reconcilePipeline(t, "app")
+	e.reconcilePipeline(t, "api")
+
+	// 互换配置名，旧名称仍被另一个Pipeline使用，不能删除
+	e.update(t, app, func() { app.Spec.Name = "api" })
+	e.update(t, api, func() { api.Spec.Name = "app" })
+	e.reconcilePipeline(t, "app")
+	e.reconcilePipeline(t, "api")
+	e.expectState(t, "swap", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteUpdated), "api": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"api", "app"}},
+	})
+	if n := e.server.Count("DeleteConfig") + e.server.Count("RemoveConfigFromAgentGroup"); n != 0 {
+		t.Errorf("renaming removed configs of other pipelines: %v", e.server.Requests())
+	}
+}
+
+func TestPipelineDeleteKeepsConfigOfOtherPipelines(t *testing.T) {
+	web, db := remotePipeline("web", "web"), remotePipeline("db", "db")
+	web.Spec.Name, db.Spec.Name = "shared", "shared"
+	e := newRemoteEnv(t, web, db)
+	e.reconcilePipeline(t, "web")
+	e.reconcilePipeline(t, "db")
+	e.expectState(t, "create", csfake.State{
+		Configs: map[string]string{"shared": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"shared"}, "db": {"shared"}},
+	})
+
+	// 另一个Pipeline仍持有同名配置，只解除被删除Pipeline所在组的关联
+	if err := e.Delete(context.Background(), web); err != nil {
+		t.Fatal(err)
+	}
+	e.reconcilePipeline(t, "web")
+	e.expectState(t, "delete shared", csfake.State{
+		Configs: map[string]string{"shared": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {}, "db": {"shared"}},
+	})
+
+	if err := e.Delete(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	e.reconcilePipeline(t, "db")
+	e.expectState(t, "delete last owner", csfake.State{
+		Configs: map[string]string{},
+		Groups:  map[string][]string{"web": {}, "db": {}},
+	})
+}
+
+func TestPipelineSecretContentStaysOutOfStatus(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sls"},
+		Data:       map[string][]byte{"pipeline.yaml": []byte(remoteContent)},
+	}
+	pipeline := remotePipeline("app", "web")
+	pipeline.Spec.Content = ""
+	pipeline.Spec.ContentFrom = &v1alpha1.PipelineContentSource{SecretKeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "sls"}, Key: "pipeline.yaml"}}
+	e := newRemoteEnv(t, secret, pipeline)
+
+	e.reconcilePipeline(t, "app")
+	e.expectState(t, "create", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+	if err := e.Get(context.Background(), client.ObjectKeyFromObject(pipeline), pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if !pipeline.Status.ContentFromSecret || pipeline.Status.LastAppliedConfig.Content != "" ||
+		pipeline.Status.ContentHash != contentHash(remoteContent) {
+		t.Fatalf("status must only record the hash of secret content: %+v", pipeline.Status)
+	}
+
+	// 只记录了哈希时仍能判断配置未变化
+	e.server.ResetRequests()
+	e.reconcilePipeline(t, "app")
+	if n := e.server.Count("CreateConfig"); n != 0 {
+		t.Errorf("unchanged secret content was applied again: %v", e.server.Requests())
+	}
+
+	e.update(t, secret, func() { secret.Data["pipeline.yaml"] = []byte(remoteUpdated) })
+	e.update(t, pipeline, func() { pipeline.Spec.DryRun = true })
+	e.reconcilePipeline(t, "app")
+	if err := e.Get(context.Background(), client.ObjectKeyFromObject(pipeline), pipeline); err != nil {
+		t.Fatal(err)
+	}
+	plan := pipeline.Status.Plan
+	if plan == nil || len(plan.Targets) != 1 || plan.Targets[0].Config != v1alpha1.PlanActionUpdate ||
+		plan.Targets[0].Diff != redactedDiff {
+		t.Fatalf("plan must redact the diff of secret content: %+v", plan)
+	}
+}
+
+func TestPipelineRemoteFaults(t *testing.T) {
+	e := newRemoteEnv(t, remotePipeline("app", "web"))
+	ctx := context.Background()
+
+	// 5xx可重试，状态不变
+	e.server.Inject(csfake.Fault{Op: "CreateConfig", Times: 1, Status: http.StatusServiceUnavailable})
+	if result := e.reconcilePipeline(t, "app"); result.RequeueAfter == 0 {
+		t.Errorf("retryable failure was not requeued: %+v", result)
+	}
+	e.expectState(t, "5xx", csfake.State{Configs: map[string]string{}, Groups: map[string][]string{}})
+
+	// 响应丢失时配置已写入，重新下发覆盖相同的配置
+	e.server.Inject(csfake.Fault{Op: "ApplyConfigToAgentGroup", Times: 1, DropResponse: true})
+	e.reconcilePipeline(t, "app")
+	e.reconcilePipeline(t, "app")
+	want := csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	}
+	e.expectState(t, "dropped response", want)
+	pipeline := &v1alpha1.Pipeline{}
+	if err := e.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app"}, pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if !pipeline.Status.Success {
+		t.Errorf("status after retry = %+v", pipeline.Status)
+	}
+
+	// 错误的响应码不可重试，等待spec变化
+	e.server.Inject(csfake.Fault{Op: "CreateConfig", Code: http.StatusBadRequest})
+	e.update(t, pipeline, func() { pipeline.Spec.Content = remoteUpdated })
+	if result := e.reconcilePipeline(t, "app"); result.RequeueAfter != 0 {
+		t.Errorf("terminal failure was requeued: %+v", result)
+	}
+	if err := e.Get(ctx, client.ObjectKeyFromObject(pipeline), pipeline); err != nil {
+		t.Fatal(err)
+	}
+	if pipeline.Status.Success || !strings.Contains(pipeline.Status.Conditions[0].Message, "injected fault") {
+		t.Errorf("status after wrong code = %+v", pipeline.Status)
+	}
+	e.expectState(t, "wrong code", want)
+}
+
+func TestAgentGroupRemoteState(t *testing.T) {
+	group := &v1alpha1.AgentGroup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       v1alpha1.AgentGroupSpec{Name: "web", Description: "web nodes", Tags: []string{"env=prod"}},
+	}
+	e := newRemoteEnv(t, group, remotePipeline("app", "web"), remotePipeline("api", ""))
+
+	// AgentGroup CR存在时Pipeline只下发配置，关联由AgentGroup控制器维护
+	e.reconcilePipeline(t, "app")
+	e.reconcilePipeline(t, "api")
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "create", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent), "api": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+	if remote, _ := e.server.AgentGroup("web"); remote.Description != "web nodes" {
+		t.Errorf("remote group = %+v", remote)
+	}
+
+	e.update(t, group, func() {
+		group.Spec.Description = "all web nodes"
+		group.Spec.Configs = []string{"api"}
+	})
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "update", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent), "api": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"api", "app"}},
+	})
+	if remote, _ := e.server.AgentGroup("web"); remote.Description != "all web nodes" {
+		t.Errorf("remote group = %+v", remote)
+	}
+
+	// Pipeline改组后AgentGroup控制器移除关联
+	app := &v1alpha1.Pipeline{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app"}}
+	e.update(t, app, func() { app.Spec.AgentGroup = "" })
+	e.reconcilePipeline(t, "app")
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "regroup", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent), "api": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"api"}},
+	})
+
+	e.update(t, group, func() { group.Spec.Name = "frontend" })
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "rename", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent), "api": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"frontend": {"api"}},
+	})
+
+	if err := e.Delete(context.Background(), group); err != nil {
+		t.Fatal(err)
+	}
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "delete", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent), "api": canonical(t, remoteContent)},
+		Groups:  map[string][]string{},
+	})
+}
+
+func TestAgentGroupKeepsDryRunAssociation(t *testing.T) {
+	group := &v1alpha1.AgentGroup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       v1alpha1.AgentGroupSpec{Name: "web"},
+	}
+	app := remotePipeline("app", "web")
+	e := newRemoteEnv(t, group, app)
+	e.reconcilePipeline(t, "app")
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "create", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+
+	// dryRun期间只计算计划，仍在运行的配置保持与原Agent组的关联
+	e.server.ResetRequests()
+	e.update(t, app, func() {
+		app.Spec.DryRun = true
+		app.Spec.AgentGroup = "db"
+	})
+	e.reconcilePipeline(t, "app")
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "dry run", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+	if n := e.server.Count("RemoveConfigFromAgentGroup"); n != 0 {
+		t.Errorf("dry run unbound the live config: %v", e.server.Requests())
+	}
+
+	// 退出dryRun后才按spec改组
+	e.update(t, app, func() { app.Spec.DryRun = false })
+	e.reconcilePipeline(t, "app")
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "apply", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {}, "db": {"app"}},
+	})
+}
+
+func TestAgentGroupDeleteKeepsGroupOfOtherAgentGroups(t *testing.T) {
+	group := func(name string) *v1alpha1.AgentGroup {
+		return &v1alpha1.AgentGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       v1alpha1.AgentGroupSpec{Name: "web"},
+		}
+	}
+	web, copied := group("web"), group("web-copy")
+	e := newRemoteEnv(t, web, copied)
+	e.reconcileAgentGroup(t, "web")
+	e.reconcileAgentGroup(t, "web-copy")
+	want := csfake.State{Configs: map[string]string{}, Groups: map[string][]string{"web": {}}}
+	e.expectState(t, "create", want)
+
+	// 另一个AgentGroup仍声明同名的Agent组，删除时保留
+	if err := e.Delete(context.Background(), copied); err != nil {
+		t.Fatal(err)
+	}
+	e.reconcileAgentGroup(t, "web-copy")
+	e.expectState(t, "delete copy", want)
+	if n := e.server.Count("DeleteAgentGroup"); n != 0 {
+		t.Errorf("deleting one declaration removed the shared agent group: %v", e.server.Requests())
+	}
+
+	if err := e.Delete(context.Background(), web); err != nil {
+		t.Fatal(err)
+	}
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "delete last", csfake.State{Configs: map[string]string{}, Groups: map[string][]string{}})
+}
+
+func TestAgentGroupFindsConfigServerByLabel(t *testing.T) {
+	group := &v1alpha1.AgentGroup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       v1alpha1.AgentGroupSpec{Name: "web"},
+	}
+	e := newRemoteEnv(t, group, remotePipeline("app", "web"))
+
+	// 与Pipeline控制器一样按标签查找ConfigMap，不依赖ConfigMap的名称
+	ctx := context.Background()
+	configMap := &corev1.ConfigMap{}
+	if err := e.Get(ctx, client.ObjectKey{Namespace: configMapNamespace, Name: configMapName}, configMap); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Delete(ctx, configMap); err != nil {
+		t.Fatal(err)
+	}
+	renamed := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: configMapNamespace, Name: "config-server-endpoint",
+			Labels: configMap.Labels},
+		Data: configMap.Data,
+	}
+	if err := e.Create(ctx, renamed); err != nil {
+		t.Fatal(err)
+	}
+
+	e.reconcilePipeline(t, "app")
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "create", csfake.State{
+		Configs: map[string]string{"app": canonical(t, remoteContent)},
+		Groups:  map[string][]string{"web": {"app"}},
+	})
+}
+
+func TestAgentGroupRemoteFaults(t *testing.T) {
+	group := &v1alpha1.AgentGroup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec:       v1alpha1.AgentGroupSpec{Name: "web"},
+	}
+	e := newRemoteEnv(t, group)
+
+	// 被拒绝的请求不可重试，Config-Server保持不变
+	e.server.Inject(csfake.Fault{Op: "CreateAgentGroup", Code: http.StatusBadRequest})
+	e.reconcileAgentGroup(t, "web")
+	e.expectState(t, "rejected", csfake.State{Configs: map[string]string{}, Groups: map[string][]string{}})
+	if err := e.Get(context.Background(), client.ObjectKeyFromObject(group), group); err != nil {
+		t.Fatal(err)
+	}
+	if group.Status.Success {
+		t.Errorf("status after a rejected request = %+v", group.Status)
+	}
+}
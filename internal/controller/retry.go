@@ -0,0 +1,129 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/backoff"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/metrics"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/tracing"
+)
+
+const (
+	retryBaseDelay = time.Second * 5
+	retryMaxDelay  = time.Minute * 5
+	retryJitter    = 0.2
+)
+
+// retrier 为每个对象维护独立的指数退避，由Reconciler内嵌使用
+type retrier struct {
+	once    sync.Once
+	tracker *backoff.Tracker
+}
+
+func (r *retrier) backoff() *backoff.Tracker {
+	r.once.Do(func() {
+		r.tracker = backoff.NewTracker(retryBaseDelay, retryMaxDelay, retryJitter)
+	})
+	return r.tracker
+}
+
+// retryResult 根据错误类型决定重新入队的时间：可重试的错误按退避重新入队，不可重试的错误不再入队
+func (r *retrier) retryResult(ctx context.Context, obj client.Object, err error) ctrl.Result {
+	key := retryKey(obj)
+	kind := reflect.TypeOf(obj).Elem().Name()
+	retryable := configserver.IsRetryable(err)
+	metrics.ObserveRetry(kind, retryable)
+
+	var result ctrl.Result
+	if retryable {
+		result.RequeueAfter = r.backoff().Next(key)
+	}
+	tracing.RecordRetry(ctx, kind, client.ObjectKeyFromObject(obj), r.backoff().Failures(key), retryable,
+		result.RequeueAfter.String(), err)
+	if !retryable {
+		r.backoff().Reset(key)
+	}
+	return result
+}
+
+// retrySucceeded 成功后清除对象的退避记录
+func (r *retrier) retrySucceeded(obj client.Object) {
+	r.backoff().Reset(retryKey(obj))
+}
+
+// retryKey 每个Reconciler持有独立的retrier，按namespace/name区分对象即可
+func retryKey(obj client.Object) string {
+	return client.ObjectKeyFromObject(obj).String()
+}
+
+// configServerRegistry 未配置注册表时返回默认注册表
+func configServerRegistry(registry *configserver.Registry) *configserver.Registry {
+	if registry == nil {
+		return configserver.DefaultRegistry
+	}
+	return registry
+}
+
+// configServerClient 返回Config-Server地址对应的共享客户端，未配置注册表时使用默认注册表
+func configServerClient(registry *configserver.Registry, baseURL string) *configserver.ConfigServerClient {
+	return configServerRegistry(registry).Client(baseURL)
+}
+
+// logRetry 记录失败。熔断期间的失败只在调试级别输出，避免重复的错误日志
+func logRetry(log logr.Logger, err error, msg string, keysAndValues ...interface{}) {
+	if errors.Is(err, configserver.ErrCircuitOpen) {
+		log.V(1).Info(msg+", config server circuit is open", keysAndValues...)
+		return
+	}
+	log.Error(err, msg, keysAndValues...)
+}
+
+// failureCondition 构造失败的Condition，Reason区分可重试与不可重试
+func failureCondition(conditionType string, generation int64, err error) metav1.Condition {
+	reason := emus.ConditionReasonRetrying
+	if !configserver.IsRetryable(err) {
+		reason = emus.ConditionReasonTerminalError
+	}
+	return metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            err.Error(),
+	}
+}
+
+// terminalForGeneration 判断当前generation是否已因不可重试的错误放弃
+func terminalForGeneration(conditions []metav1.Condition, conditionType string, generation int64) bool {
+	c := meta.FindStatusCondition(conditions, conditionType)
+	return c != nil && c.Status == metav1.ConditionFalse &&
+		c.Reason == emus.ConditionReasonTerminalError && c.ObservedGeneration == generation
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/sharding"
+)
+
+// owns 未启用分片时所有对象都由当前副本处理
+func owns(shard *sharding.Coordinator, obj client.Object) bool {
+	return shard == nil || shard.OwnsObject(obj)
+}
+
+// ownsKey 同owns，用于只有请求key的场景
+func ownsKey(shard *sharding.Coordinator, key client.ObjectKey) bool {
+	return shard == nil || shard.Owns(key.Namespace+"/"+key.Name)
+}
+
+// shardForOptions 启用分片时For只接收当前副本负责的对象的事件
+func shardForOptions(shard *sharding.Coordinator) []builder.ForOption {
+	if shard == nil {
+		return nil
+	}
+	return []builder.ForOption{builder.WithPredicates(shard.Predicate())}
+}
+
+// watchShard 启用分片时控制器在所有副本上运行，分配稳定后重新入队list返回的对象以接手新分配到的对象
+func watchShard(b *builder.Builder, shard *sharding.Coordinator, log logr.Logger,
+	list func(ctx context.Context) ([]client.Object, error)) *builder.Builder {
+	if shard == nil {
+		return b
+	}
+	events := make(chan event.GenericEvent)
+	shard.OnRebalance(func(ctx context.Context) {
+		objs, err := list(ctx)
+		if err != nil {
+			log.Error(err, "Failed to list objects after shard rebalance")
+			return
+		}
+		for _, obj := range objs {
+			if !shard.OwnsObject(obj) {
+				continue
+			}
+			select {
+			case events <- event.GenericEvent{Object: obj}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	return b.
+		WatchesRawSource(source.Channel(events, &handler.EnqueueRequestForObject{})).
+		WithOptions(crcontroller.Options{NeedLeaderElection: ptr.To(false)})
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/workload"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// WorkloadPipelineReconciler generates Pipelines from the log annotations of a workload kind
+type WorkloadPipelineReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	Event  record.EventRecorder
+	// Kind is the workload kind handled by this reconciler: Deployment, StatefulSet, DaemonSet or Pod
+	Kind string
+	// Reader reads the ReplicaSet owning a Pod without caching them, defaults to Client
+	Reader client.Reader
+}
+
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=pipelines,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods;namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *WorkloadPipelineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues(strings.ToLower(r.Kind), req.NamespacedName)
+
+	obj, err := r.newObject()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if errors.IsNotFound(err) {
+			// 生成的Pipeline带有ownerReference，由垃圾回收清理
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	var desired []*v1alpha1.Pipeline
+	enabled, err := r.namespaceEnabled(ctx, req.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	owned := false
+	if enabled {
+		if owned, err = r.ownedByWorkload(ctx, obj); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if enabled && !owned {
+		desired, err = workload.BuildPipelines(r.source(obj))
+		if err != nil {
+			log.Error(err, "Invalid log annotations")
+			r.Event.Event(obj, corev1.EventTypeWarning, "InvalidLogAnnotation", err.Error())
+			return ctrl.Result{}, nil
+		}
+	}
+
+	keep := make(map[string]bool, len(desired))
+	for _, pipeline := range desired {
+		keep[pipeline.Name] = true
+		if err := r.applyGenerated(ctx, obj, pipeline); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// 删除注解中已不存在的Pipeline
+	var existing v1alpha1.PipelineList
+	if err := r.List(ctx, &existing, client.InNamespace(req.Namespace),
+		client.MatchingLabels(workload.Labels(r.Kind, req.Name))); err != nil {
+		return ctrl.Result{}, err
+	}
+	for i := range existing.Items {
+		pipeline := &existing.Items[i]
+		if keep[pipeline.Name] {
+			continue
+		}
+		log.Info("Deleting stale generated pipeline", "pipeline", pipeline.Name)
+		if err := r.Delete(ctx, pipeline); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// applyGenerated 创建或更新生成的Pipeline，保留Pipeline控制器维护的finalizer与状态
+func (r *WorkloadPipelineReconciler) applyGenerated(ctx context.Context, owner client.Object, desired *v1alpha1.Pipeline) error {
+	pipeline := &v1alpha1.Pipeline{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, pipeline, func() error {
+		if pipeline.Labels == nil {
+			pipeline.Labels = map[string]string{}
+		}
+		for k, v := range desired.Labels {
+			pipeline.Labels[k] = v
+		}
+		pipeline.Spec = desired.Spec
+		return controllerutil.SetControllerReference(owner, pipeline, r.Scheme)
+	})
+	return err
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WorkloadPipelineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	obj, err := r.newObject()
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(strings.ToLower(r.Kind)+"-pipeline").
+		For(obj, builder.WithPredicates(predicate.Or(
+			predicate.AnnotationChangedPredicate{},
+			predicate.GenerationChangedPredicate{},
+			predicate.LabelChangedPredicate{},
+		))).
+		Owns(&v1alpha1.Pipeline{}).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.mapNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		Complete(r)
+}
+
+// SetupWorkloadPipelineControllers 为所有支持的工作负载类型注册控制器
+func SetupWorkloadPipelineControllers(mgr ctrl.Manager, log logr.Logger) error {
+	for _, kind := range []string{"Deployment", "StatefulSet", "DaemonSet", "Pod"} {
+		if err := (&WorkloadPipelineReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Log:    log.WithName(kind),
+			Event:  mgr.GetEventRecorderFor("WorkloadPipeline"),
+			Kind:   kind,
+			Reader: mgr.GetAPIReader(),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to set up %s pipeline controller: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// newObject 创建对应类型的空对象
+func (r *WorkloadPipelineReconciler) newObject() (client.Object, error) {
+	switch r.Kind {
+	case "Deployment":
+		return &appsv1.Deployment{}, nil
+	case "StatefulSet":
+		return &appsv1.StatefulSet{}, nil
+	case "DaemonSet":
+		return &appsv1.DaemonSet{}, nil
+	case "Pod":
+		return &corev1.Pod{}, nil
+	}
+	return nil, fmt.Errorf("unsupported workload kind %q", r.Kind)
+}
+
+// source 提取生成Pipeline所需的信息，工作负载上的注解优先于Pod模板上的注解
+func (r *WorkloadPipelineReconciler) source(obj client.Object) workload.Source {
+	var template *corev1.PodTemplateSpec
+	podLabels := obj.GetLabels()
+	switch o := obj.(type) {
+	case *appsv1.Deployment:
+		template = &o.Spec.Template
+		podLabels = selectorLabels(o.Spec.Selector, template.Labels)
+	case *appsv1.StatefulSet:
+		template = &o.Spec.Template
+		podLabels = selectorLabels(o.Spec.Selector, template.Labels)
+	case *appsv1.DaemonSet:
+		template = &o.Spec.Template
+		podLabels = selectorLabels(o.Spec.Selector, template.Labels)
+	}
+
+	annotations := map[string]string{}
+	if template != nil {
+		for k, v := range template.Annotations {
+			annotations[k] = v
+		}
+	}
+	for k, v := range obj.GetAnnotations() {
+		annotations[k] = v
+	}
+
+	return workload.Source{
+		Kind:        r.Kind,
+		Name:        obj.GetName(),
+		Namespace:   obj.GetNamespace(),
+		PodLabels:   podLabels,
+		Annotations: annotations,
+	}
+}
+
+// ownedByWorkload Pod由已支持的工作负载管理时，由工作负载生成Pipeline。
+// ReplicaSet只在属于Deployment时跳过，单独创建的ReplicaSet没有对应的控制器，其Pod各自生成Pipeline
+func (r *WorkloadPipelineReconciler) ownedByWorkload(ctx context.Context, obj client.Object) (bool, error) {
+	if r.Kind != "Pod" {
+		return false, nil
+	}
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil {
+		return false, nil
+	}
+	switch owner.Kind {
+	case "StatefulSet", "DaemonSet":
+		return true, nil
+	case "ReplicaSet":
+		return r.deploymentReplicaSet(ctx, obj, owner.Name)
+	}
+	return false, nil
+}
+
+// deploymentReplicaSet 检查Pod所属的ReplicaSet是否由Deployment管理
+func (r *WorkloadPipelineReconciler) deploymentReplicaSet(ctx context.Context, pod client.Object, name string) (bool, error) {
+	rs := &appsv1.ReplicaSet{}
+	if err := r.reader().Get(ctx, client.ObjectKey{Namespace: pod.GetNamespace(), Name: name}, rs); err != nil {
+		if !errors.IsNotFound(err) {
+			return false, err
+		}
+		// ReplicaSet已删除时按pod-template-hash标签判断，Deployment创建的Pod都带有该标签
+		_, ok := pod.GetLabels()[appsv1.DefaultDeploymentUniqueLabelKey]
+		return ok, nil
+	}
+	parent := metav1.GetControllerOf(rs)
+	return parent != nil && parent.Kind == "Deployment", nil
+}
+
+func (r *WorkloadPipelineReconciler) reader() client.Reader {
+	if r.Reader != nil {
+		return r.Reader
+	}
+	return r.Client
+}
+
+// namespaceEnabled 检查命名空间是否开启了注解采集
+func (r *WorkloadPipelineReconciler) namespaceEnabled(ctx context.Context, name string) (bool, error) {
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return ns.Labels[v1alpha1.LabelLogsEnabled] == "true", nil
+}
+
+// mapNamespace 命名空间标签变化时重新处理其中的工作负载
+func (r *WorkloadPipelineReconciler) mapNamespace(ctx context.Context, ns client.Object) []reconcile.Request {
+	list, err := r.newList()
+	if err != nil {
+		return nil
+	}
+	if err := r.List(ctx, list, client.InNamespace(ns.GetName())); err != nil {
+		r.Log.Error(err, "Failed to list workloads", "namespace", ns.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	_ = meta.EachListItem(list, func(o runtime.Object) error {
+		obj, ok := o.(client.Object)
+		if !ok {
+			return nil
+		}
+		if _, ok := r.source(obj).Annotations[v1alpha1.AnnotationLogs]; ok {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+		}
+		return nil
+	})
+	return requests
+}
+
+// newList 创建对应类型的列表对象
+func (r *WorkloadPipelineReconciler) newList() (client.ObjectList, error) {
+	switch r.Kind {
+	case "Deployment":
+		return &appsv1.DeploymentList{}, nil
+	case "StatefulSet":
+		return &appsv1.StatefulSetList{}, nil
+	case "DaemonSet":
+		return &appsv1.DaemonSetList{}, nil
+	case "Pod":
+		return &corev1.PodList{}, nil
+	}
+	return nil, fmt.Errorf("unsupported workload kind %q", r.Kind)
+}
+
+// selectorLabels 优先使用selector中的标签，避免模板中易变的标签影响匹配
+func selectorLabels(selector *metav1.LabelSelector, template map[string]string) map[string]string {
+	if selector != nil && len(selector.MatchLabels) > 0 {
+		return selector.MatchLabels
+	}
+	return template
+}
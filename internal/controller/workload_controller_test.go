@@ -0,0 +1,63 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestPodOwnedByWorkload(t *testing.T) {
+	controlledBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: ptr.To(true)}}
+	}
+	c, _ := newFakeClient(t,
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web-7d9f",
+			OwnerReferences: controlledBy("Deployment", "web")}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "batch"}},
+	)
+	r := &WorkloadPipelineReconciler{Client: c, Kind: "Pod"}
+
+	for _, tc := range []struct {
+		name   string
+		owners []metav1.OwnerReference
+		labels map[string]string
+		want   bool
+	}{
+		{name: "deployment", owners: controlledBy("ReplicaSet", "web-7d9f"), want: true},
+		{name: "standalone replicaset", owners: controlledBy("ReplicaSet", "batch"), want: false},
+		// ReplicaSet已删除时按pod-template-hash判断
+		{name: "deleted deployment replicaset", owners: controlledBy("ReplicaSet", "api-5c8b"),
+			labels: map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "5c8b"}, want: true},
+		{name: "deleted standalone replicaset", owners: controlledBy("ReplicaSet", "gone"), want: false},
+		{name: "statefulset", owners: controlledBy("StatefulSet", "db"), want: true},
+		{name: "job", owners: controlledBy("Job", "report"), want: false},
+		{name: "bare pod", want: false},
+	} {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "p",
+			OwnerReferences: tc.owners, Labels: tc.labels}}
+		got, err := r.ownedByWorkload(context.Background(), pod)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: ownedByWorkload = %v, %v, want %v", tc.name, got, err, tc.want)
+		}
+	}
+}
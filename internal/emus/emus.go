@@ -14,3 +14,54 @@ const AgentGroupStatusFailed = "Failed"
 
 // AgentGroupStatusSuccess AgentGroup 创建成功
 const AgentGroupStatusSuccess = "Success"
+
+// AgentGroupConditionSynced AgentGroup 的关联与 Config-Server 一致
+const AgentGroupConditionSynced = "Synced"
+
+// AgentGroupConditionConflict AgentGroup 的关联存在冲突
+const AgentGroupConditionConflict = "Conflict"
+
+// AgentGroupConditionMissingConfigs spec.configs 引用了不存在的 Pipeline
+const AgentGroupConditionMissingConfigs = "MissingConfigs"
+
+// PipelineConditionReady Pipeline 已下发到 Config-Server
+const PipelineConditionReady = "Ready"
+
+// ConditionReasonRetrying 可重试的失败，按指数退避重新入队
+const ConditionReasonRetrying = "Retrying"
+
+// ConditionReasonTerminalError 不可重试的失败（4xx、配置内容无效），修改spec前不再重试
+const ConditionReasonTerminalError = "TerminalError"
+
+// ConditionReasonNoTargets configServerSelector 没有选中任何 ConfigServer
+const ConditionReasonNoTargets = "NoTargets"
+
+// ConditionReasonAdopted 从 Config-Server 接管且 spec 未修改，不下发到 Config-Server
+const ConditionReasonAdopted = "Adopted"
+
+// PipelineConditionPlanned spec.dryRun 的 Pipeline 已计算出下发计划
+const PipelineConditionPlanned = "Planned"
+
+// ConditionReasonDryRun spec.dryRun 的 Pipeline 只计算下发计划，不下发到 Config-Server
+const ConditionReasonDryRun = "DryRun"
+
+// PipelineConditionProfilesUpToDate Pipeline 的配置包含引用的 ProcessorChain 与 FlusherProfile 的当前内容
+const PipelineConditionProfilesUpToDate = "ProfilesUpToDate"
+
+// ConditionReasonUpdatePending updatePolicy 为 Manual，引用的 profile 的变化等待 spec 修改或 resync 后下发
+const ConditionReasonUpdatePending = "UpdatePending"
+
+// PipelineTestConditionPassed PipelineTest 的输出与期望一致
+const PipelineTestConditionPassed = "Passed"
+
+// ConditionReasonOutputMatched 模拟输出与期望的日志一致
+const ConditionReasonOutputMatched = "OutputMatched"
+
+// ConditionReasonOutputMismatch 模拟输出与期望的日志不一致
+const ConditionReasonOutputMismatch = "OutputMismatch"
+
+// ConditionReasonPipelineNotFound 引用的 Pipeline 不存在
+const ConditionReasonPipelineNotFound = "PipelineNotFound"
+
+// ConditionReasonSimulationFailed 配置无法渲染或处理插件无法模拟
+const ConditionReasonSimulationFailed = "SimulationFailed"
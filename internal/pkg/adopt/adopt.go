@@ -0,0 +1,265 @@
+package adopt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+// Source Config-Server上已有的配置与Agent组
+type Source struct {
+	// Endpoint 被接管的Config-Server地址
+	Endpoint string
+	// Configs 包含内容的配置
+	Configs []configserver.ConfigDetail
+	// Groups Agent组，Configs为已关联的配置
+	Groups []configserver.AgentGroup
+}
+
+// Options 生成资源的选项
+type Options struct {
+	// Namespace 生成的Pipeline与AgentGroup所在的命名空间
+	Namespace string
+	// ExistingConfigs 已有Pipeline声明的配置名，这些配置不再接管
+	ExistingConfigs map[string]bool
+	// ExistingGroups 已有AgentGroup声明的组名，这些组不再接管
+	ExistingGroups map[string]bool
+}
+
+// Result 接管生成的资源
+type Result struct {
+	Pipelines   []v1alpha1.Pipeline
+	AgentGroups []v1alpha1.AgentGroup
+	// Skipped 已由集群内资源声明而跳过的配置与组，格式为config/<name>或group/<name>
+	Skipped []string
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Fetch 读取Config-Server上的全部配置内容、Agent组及其关联的配置
+func Fetch(ctx context.Context, c *configserver.ConfigServerClient, endpoint string) (Source, error) {
+	src := Source{Endpoint: endpoint}
+	configs, err := c.ListConfigs(ctx)
+	if err != nil {
+		return src, err
+	}
+	for _, config := range configs {
+		detail, err := c.GetConfig(ctx, config.Name)
+		if err != nil {
+			return src, err
+		}
+		if detail.Name == "" {
+			detail.Name = config.Name
+		}
+		src.Configs = append(src.Configs, *detail)
+	}
+
+	groups, err := c.ListAgentGroups(ctx)
+	if err != nil {
+		return src, err
+	}
+	for _, group := range groups {
+		applied, err := c.GetAppliedConfigsForAgentGroup(ctx, group.Name)
+		if err != nil {
+			return src, err
+		}
+		group.Configs = applied
+		src.Groups = append(src.Groups, group)
+	}
+	return src, nil
+}
+
+// Build 为尚未被集群内资源声明的配置与Agent组生成标记为已接管的Pipeline与AgentGroup
+func Build(src Source, opts Options) (Result, error) {
+	var result Result
+	pipelineNames, groupNames := map[string]bool{}, map[string]bool{}
+
+	configs := append([]configserver.ConfigDetail(nil), src.Configs...)
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	for _, config := range configs {
+		if opts.ExistingConfigs[config.Name] {
+			result.Skipped = append(result.Skipped, "config/"+config.Name)
+			continue
+		}
+		content, err := renderContent(config.Content)
+		if err != nil {
+			return result, fmt.Errorf("config %s: %w", config.Name, err)
+		}
+		result.Pipelines = append(result.Pipelines, v1alpha1.Pipeline{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "Pipeline"},
+			ObjectMeta: objectMeta(uniqueName("pipeline", config.Name, pipelineNames), opts.Namespace, src.Endpoint),
+			Spec: v1alpha1.PipelineSpec{
+				Name:    config.Name,
+				Content: content,
+			},
+		})
+	}
+
+	groups := append([]configserver.AgentGroup(nil), src.Groups...)
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	for _, group := range groups {
+		if opts.ExistingGroups[group.Name] {
+			result.Skipped = append(result.Skipped, "group/"+group.Name)
+			continue
+		}
+		configs := append([]string(nil), group.Configs...)
+		sort.Strings(configs)
+		result.AgentGroups = append(result.AgentGroups, v1alpha1.AgentGroup{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "AgentGroup"},
+			ObjectMeta: objectMeta(uniqueName("agentgroup", group.Name, groupNames), opts.Namespace, src.Endpoint),
+			Spec: v1alpha1.AgentGroupSpec{
+				Name:        group.Name,
+				Description: group.Description,
+				Tags:        group.Tags,
+				Configs:     configs,
+			},
+		})
+	}
+	return result, nil
+}
+
+// Existing 返回集群内Pipeline与AgentGroup已声明的配置名与组名
+func Existing(ctx context.Context, c client.Reader) (configs, groups map[string]bool, err error) {
+	var pipelines v1alpha1.PipelineList
+	if err := c.List(ctx, &pipelines); err != nil {
+		return nil, nil, err
+	}
+	var agentGroups v1alpha1.AgentGroupList
+	if err := c.List(ctx, &agentGroups); err != nil {
+		return nil, nil, err
+	}
+	configs, groups = map[string]bool{}, map[string]bool{}
+	for _, p := range pipelines.Items {
+		configs[p.Spec.Name] = true
+	}
+	for _, g := range agentGroups.Items {
+		groups[g.Spec.Name] = true
+	}
+	return configs, groups, nil
+}
+
+// Apply 创建接管生成的资源，已存在的同名资源跳过，返回创建的资源
+func Apply(ctx context.Context, c client.Client, result Result) ([]string, error) {
+	var created []string
+	create := func(obj client.Object, kind string) error {
+		if err := c.Create(ctx, obj); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to create %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+		}
+		created = append(created, kind+"/"+obj.GetName())
+		return nil
+	}
+	for i := range result.Pipelines {
+		if err := create(&result.Pipelines[i], "Pipeline"); err != nil {
+			return created, err
+		}
+	}
+	for i := range result.AgentGroups {
+		if err := create(&result.AgentGroups[i], "AgentGroup"); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// Manifests 将生成的资源输出为多文档YAML
+func Manifests(result Result) ([]byte, error) {
+	var buf bytes.Buffer
+	write := func(obj interface{}) error {
+		out, err := sigsyaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("---\n")
+		buf.Write(out)
+		return nil
+	}
+	for i := range result.Pipelines {
+		if err := write(manifest(&result.Pipelines[i], result.Pipelines[i].Spec)); err != nil {
+			return nil, err
+		}
+	}
+	for i := range result.AgentGroups {
+		if err := write(manifest(&result.AgentGroups[i], result.AgentGroups[i].Spec)); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// manifest 只输出apiVersion、kind、metadata与spec，省略空的status与creationTimestamp
+func manifest(obj client.Object, spec interface{}) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"name":        obj.GetName(),
+		"labels":      obj.GetLabels(),
+		"annotations": obj.GetAnnotations(),
+	}
+	if obj.GetNamespace() != "" {
+		metadata["namespace"] = obj.GetNamespace()
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return map[string]interface{}{
+		"apiVersion": gvk.GroupVersion().String(),
+		"kind":       gvk.Kind,
+		"metadata":   metadata,
+		"spec":       spec,
+	}
+}
+
+func objectMeta(name, namespace, endpoint string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{v1alpha1.LabelManagedBy: v1alpha1.ManagedByAdoption},
+		Annotations: map[string]string{
+			v1alpha1.AnnotationAdopted: endpoint,
+		},
+	}
+}
+
+// renderContent Config-Server返回的内容可能是YAML字符串，也可能是JSON对象
+func renderContent(content interface{}) (string, error) {
+	switch c := content.(type) {
+	case nil:
+		return "", fmt.Errorf("config has no content")
+	case string:
+		return c, nil
+	default:
+		out, err := yaml.Marshal(c)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+}
+
+// uniqueName 将配置名或组名转换为合法且不重复的资源名
+func uniqueName(fallback, name string, used map[string]bool) string {
+	base := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(base) > 240 {
+		base = strings.TrimRight(base[:240], "-")
+	}
+	if base == "" {
+		base = fallback
+	}
+	candidate := base
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+	used[candidate] = true
+	return candidate
+}
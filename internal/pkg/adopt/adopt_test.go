@@ -0,0 +1,131 @@
+package adopt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+func TestBuild(t *testing.T) {
+	src := Source{
+		Endpoint: "http://config-server:8899",
+		Configs: []configserver.ConfigDetail{
+			{Name: "nginx_access", Content: map[string]interface{}{"inputs": []interface{}{map[string]interface{}{"Type": "input_file"}}}},
+			{Name: "Nginx.Access", Content: "inputs:\n  - Type: input_file\n"},
+			{Name: "declared", Content: "inputs: []\n"},
+		},
+		Groups: []configserver.AgentGroup{
+			{Name: "web", Tags: []string{"web"}, Configs: []string{"nginx_access", "Nginx.Access"}},
+			{Name: "default"},
+		},
+	}
+	result, err := Build(src, Options{
+		Namespace:       "logging",
+		ExistingConfigs: map[string]bool{"declared": true},
+		ExistingGroups:  map[string]bool{"default": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Pipelines) != 2 {
+		t.Fatalf("got %d pipelines, want 2", len(result.Pipelines))
+	}
+	// 排序后Nginx.Access在前，两者转换后的资源名冲突
+	if got := result.Pipelines[0].Name + "," + result.Pipelines[1].Name; got != "nginx-access,nginx-access-2" {
+		t.Errorf("pipeline names = %s", got)
+	}
+	for _, p := range result.Pipelines {
+		if p.Namespace != "logging" || p.Annotations[v1alpha1.AnnotationAdopted] != src.Endpoint {
+			t.Errorf("pipeline %s is not marked as adopted in logging: %v", p.Name, p.Annotations)
+		}
+		if !strings.Contains(p.Spec.Content, "Type: input_file") {
+			t.Errorf("pipeline %s content = %q", p.Name, p.Spec.Content)
+		}
+	}
+
+	if len(result.AgentGroups) != 1 || result.AgentGroups[0].Spec.Name != "web" {
+		t.Fatalf("agent groups = %+v", result.AgentGroups)
+	}
+	if got := strings.Join(result.AgentGroups[0].Spec.Configs, ","); got != "Nginx.Access,nginx_access" {
+		t.Errorf("agent group configs = %s", got)
+	}
+	if got := strings.Join(result.Skipped, ","); got != "config/declared,group/default" {
+		t.Errorf("skipped = %s", got)
+	}
+}
+
+func TestBuildWithoutContent(t *testing.T) {
+	_, err := Build(Source{Configs: []configserver.ConfigDetail{{Name: "empty"}}}, Options{})
+	if err == nil {
+		t.Fatal("a config without content must fail")
+	}
+}
+
+func TestManifests(t *testing.T) {
+	result, err := Build(Source{
+		Endpoint: "http://config-server:8899",
+		Configs:  []configserver.ConfigDetail{{Name: "app", Content: "inputs: []\n"}},
+		Groups:   []configserver.AgentGroup{{Name: "app", Configs: []string{"app"}}},
+	}, Options{Namespace: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Manifests(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifests := string(out)
+	for _, want := range []string{
+		"kind: Pipeline",
+		"kind: AgentGroup",
+		"apiVersion: loongcollector.infraflow.co/v1alpha1",
+		v1alpha1.AnnotationAdopted + ": http://config-server:8899",
+	} {
+		if !strings.Contains(manifests, want) {
+			t.Errorf("manifests do not contain %q:\n%s", want, manifests)
+		}
+	}
+	if strings.Contains(manifests, "status:") || strings.Contains(manifests, "creationTimestamp") {
+		t.Errorf("manifests must only contain metadata and spec:\n%s", manifests)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data interface{}
+		switch r.URL.Path {
+		case "/User/ListConfigs":
+			data = []map[string]string{{"name": "app"}}
+		case "/User/GetConfig/app":
+			data = map[string]interface{}{"name": "app", "content": map[string]interface{}{"enable": true}}
+		case "/User/ListAgentGroups":
+			data = []map[string]string{{"name": "web"}}
+		case "/User/GetAppliedConfigsForAgentGroup/web":
+			data = []string{"app"}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "message": "ok", "data": data})
+	}))
+	defer server.Close()
+
+	src, err := Fetch(context.Background(), configserver.NewConfigServerClient(server.URL, nil, ""), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(src.Configs) != 1 || src.Configs[0].Content == nil {
+		t.Fatalf("configs = %+v", src.Configs)
+	}
+	if len(src.Groups) != 1 || strings.Join(src.Groups[0].Configs, ",") != "app" {
+		t.Fatalf("groups = %+v", src.Groups)
+	}
+}
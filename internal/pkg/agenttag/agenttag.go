@@ -0,0 +1,74 @@
+package agenttag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NodeTag 节点上的LoongCollector DaemonSet上报的标签
+func NodeTag(nodeName string) string {
+	return v1alpha1.NodeTagPrefix + nodeName
+}
+
+// PodTag 返回采集该Pod日志的agent的标签：已注入sidecar时为sidecar的标签，否则为所在节点agent的标签
+func PodTag(pod *corev1.Pod) string {
+	if pod.Annotations[v1alpha1.AnnotationInjected] == "true" {
+		return pod.Annotations[v1alpha1.AnnotationSidecarTag]
+	}
+	if pod.Spec.NodeName == "" {
+		return ""
+	}
+	return NodeTag(pod.Spec.NodeName)
+}
+
+// Resolve 计算AgentGroup的完整标签集合：spec.tags与选择器匹配到的agent标签的并集
+func Resolve(ctx context.Context, c client.Reader, group *v1alpha1.AgentGroup) ([]string, error) {
+	tags := map[string]struct{}{}
+	for _, tag := range group.Spec.Tags {
+		tags[tag] = struct{}{}
+	}
+
+	if group.Spec.NodeSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(group.Spec.NodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nodeSelector: %w", err)
+		}
+		var nodes corev1.NodeList
+		if err := c.List(ctx, &nodes, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to list nodes: %w", err)
+		}
+		for _, node := range nodes.Items {
+			tags[NodeTag(node.Name)] = struct{}{}
+		}
+	}
+
+	if group.Spec.PodSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(group.Spec.PodSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid podSelector: %w", err)
+		}
+		var pods corev1.PodList
+		if err := c.List(ctx, &pods, client.InNamespace(group.Namespace),
+			client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
+		}
+		for i := range pods.Items {
+			if tag := PodTag(&pods.Items[i]); tag != "" {
+				tags[tag] = struct{}{}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(tags))
+	for tag := range tags {
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,60 @@
+package agenttag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestResolve(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	objs := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-1", Labels: map[string]string{"pool": "gpu"}}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cpu-1", Labels: map[string]string{"pool": "cpu"}}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "shop", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: "cpu-1"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "shop", Labels: map[string]string{"app": "web"},
+				Annotations: map[string]string{
+					v1alpha1.AnnotationInjected:   "true",
+					v1alpha1.AnnotationSidecarTag: "sidecar-shop.web",
+				}},
+			Spec: corev1.PodSpec{NodeName: "serverless"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-3", Namespace: "other", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: "gpu-1"},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()
+
+	group := &v1alpha1.AgentGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "g", Namespace: "shop"},
+		Spec: v1alpha1.AgentGroupSpec{
+			Name:         "g",
+			Tags:         []string{"manual"},
+			NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "gpu"}},
+			PodSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+
+	tags, err := Resolve(context.Background(), c, group)
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected := []string{"manual", "node-cpu-1", "node-gpu-1", "sidecar-shop.web"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected %v, got %v", expected, tags)
+	}
+}
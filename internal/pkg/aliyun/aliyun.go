@@ -0,0 +1,297 @@
+package aliyun
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// 可以迁移的阿里云日志服务CRD
+var (
+	// PipelineConfigGVK iLogtail/LoongCollector的采集配置AliyunPipelineConfig
+	PipelineConfigGVK = schema.GroupVersionKind{Group: "telemetry.alibabacloud.com", Version: "v1alpha1", Kind: "AliyunPipelineConfig"}
+	// ClusterPipelineConfigGVK 集群级的ClusterAliyunPipelineConfig
+	ClusterPipelineConfigGVK = schema.GroupVersionKind{Group: "telemetry.alibabacloud.com", Version: "v1alpha1", Kind: "ClusterAliyunPipelineConfig"}
+	// LogConfigGVK 旧版的采集配置AliyunLogConfig
+	LogConfigGVK = schema.GroupVersionKind{Group: "log.alibabacloud.com", Version: "v1alpha1", Kind: "AliyunLogConfig"}
+)
+
+// GVKs 迁移时读取的种类
+var GVKs = []schema.GroupVersionKind{PipelineConfigGVK, ClusterPipelineConfigGVK, LogConfigGVK}
+
+// Options 生成资源的选项
+type Options struct {
+	// Namespace 生成的Pipeline与AgentGroup所在的命名空间，为空时使用源对象的命名空间，集群级的源对象使用default
+	Namespace string
+}
+
+// Result 迁移生成的资源与报告
+type Result struct {
+	Pipelines   []v1alpha1.Pipeline
+	AgentGroups []v1alpha1.AgentGroup
+	Report      Report
+}
+
+// ReadFiles 读取YAML文件中可以迁移的对象，"-"为标准输入，其他种类的对象被忽略
+func ReadFiles(files []string) ([]unstructured.Unstructured, error) {
+	var objs []unstructured.Unstructured
+	for _, file := range files {
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return nil, err
+		}
+		reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+		for {
+			doc, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			var obj map[string]interface{}
+			if err := sigsyaml.Unmarshal(doc, &obj); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			if obj == nil {
+				continue
+			}
+			u := unstructured.Unstructured{Object: obj}
+			if u.GetKind() == "List" {
+				items, _, _ := unstructured.NestedSlice(obj, "items")
+				for _, item := range items {
+					if m, ok := item.(map[string]interface{}); ok && migratable(m) {
+						objs = append(objs, unstructured.Unstructured{Object: m})
+					}
+				}
+				continue
+			}
+			if migratable(obj) {
+				objs = append(objs, u)
+			}
+		}
+	}
+	return objs, nil
+}
+
+// migratable 判断对象是否是可以迁移的种类
+func migratable(obj map[string]interface{}) bool {
+	u := unstructured.Unstructured{Object: obj}
+	gvk := u.GroupVersionKind()
+	for _, known := range GVKs {
+		if gvk.Group == known.Group && gvk.Kind == known.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// List 读取集群中可以迁移的对象。集群中没有安装的CRD被跳过，返回的notes说明跳过的种类
+func List(ctx context.Context, c client.Reader) ([]unstructured.Unstructured, []string, error) {
+	var objs []unstructured.Unstructured
+	var notes []string
+	for _, gvk := range GVKs {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := c.List(ctx, list); err != nil {
+			if meta.IsNoMatchError(err) {
+				notes = append(notes, fmt.Sprintf("%s is not installed in the cluster", gvk.Kind))
+				continue
+			}
+			return nil, notes, fmt.Errorf("failed to list %s: %w", gvk.Kind, err)
+		}
+		objs = append(objs, list.Items...)
+	}
+	return objs, notes, nil
+}
+
+// Convert 把AliyunPipelineConfig与AliyunLogConfig转换为Pipeline，并为它们引用的机器组生成AgentGroup
+func Convert(objs []unstructured.Unstructured, opts Options) (Result, error) {
+	// 配置名冲突时以较新的AliyunPipelineConfig为准，因此按GVKs中的顺序转换
+	order := map[string]int{}
+	for i, gvk := range GVKs {
+		order[gvk.Kind] = i
+	}
+	sorted := append([]unstructured.Unstructured(nil), objs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ki, kj := order[sorted[i].GetKind()], order[sorted[j].GetKind()]; ki != kj {
+			return ki < kj
+		}
+		return sourceName(&sorted[i]) < sourceName(&sorted[j])
+	})
+
+	m := &migration{opts: opts, pipelineNames: map[string]map[string]bool{}, configs: map[string]string{}, groups: map[string]*group{}}
+	for i := range sorted {
+		obj := &sorted[i]
+		var err error
+		switch obj.GetKind() {
+		case PipelineConfigGVK.Kind, ClusterPipelineConfigGVK.Kind:
+			err = m.pipelineConfig(obj)
+		case LogConfigGVK.Kind:
+			err = m.logConfig(obj)
+		default:
+			err = fmt.Errorf("unsupported kind %s", obj.GetKind())
+		}
+		if err != nil {
+			return m.result, fmt.Errorf("%s: %w", sourceName(obj), err)
+		}
+	}
+	m.agentGroups()
+	return m.result, nil
+}
+
+// migration 一次迁移的状态
+type migration struct {
+	opts   Options
+	result Result
+	// pipelineNames 每个命名空间中已使用的Pipeline名
+	pipelineNames map[string]map[string]bool
+	// configs 已生成的配置名及其源对象，Config-Server中配置名全局唯一
+	configs map[string]string
+	// groups 按机器组名记录引用它的配置
+	groups map[string]*group
+}
+
+// group 被采集配置引用的机器组
+type group struct {
+	namespace string
+	configs   []string
+	sources   []string
+}
+
+// namespace 返回源对象生成的资源所在的命名空间
+func (m *migration) namespace(obj *unstructured.Unstructured) string {
+	switch {
+	case m.opts.Namespace != "":
+		return m.opts.Namespace
+	case obj.GetNamespace() != "":
+		return obj.GetNamespace()
+	default:
+		return "default"
+	}
+}
+
+// addPipeline 记录生成的Pipeline及其报告。配置名已被其他源对象使用时不生成
+func (m *migration) addPipeline(obj *unstructured.Unstructured, entry *Entry, spec v1alpha1.PipelineSpec, groups []string) {
+	source := sourceName(obj)
+	if other, ok := m.configs[spec.Name]; ok {
+		entry.lossy("metadata.name", "", fmt.Sprintf("config name %s is already used by %s, not migrated", spec.Name, other))
+		m.result.Report.Entries = append(m.result.Report.Entries, *entry)
+		return
+	}
+	m.configs[spec.Name] = source
+
+	ns := m.namespace(obj)
+	if m.pipelineNames[ns] == nil {
+		m.pipelineNames[ns] = map[string]bool{}
+	}
+	name := uniqueName(obj.GetName(), m.pipelineNames[ns])
+	entry.Target = fmt.Sprintf("Pipeline/%s/%s", ns, name)
+	m.result.Pipelines = append(m.result.Pipelines, v1alpha1.Pipeline{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "Pipeline"},
+		ObjectMeta: objectMeta(name, ns, source),
+		Spec:       spec,
+	})
+	for _, name := range groups {
+		g, ok := m.groups[name]
+		if !ok {
+			g = &group{namespace: ns}
+			m.groups[name] = g
+		}
+		g.configs = append(g.configs, spec.Name)
+		g.sources = append(g.sources, source)
+	}
+	m.result.Report.Entries = append(m.result.Report.Entries, *entry)
+}
+
+// agentGroups 为每个机器组生成声明其配置的AgentGroup
+func (m *migration) agentGroups() {
+	names := make([]string, 0, len(m.groups))
+	for name := range m.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	used := map[string]map[string]bool{}
+	for _, name := range names {
+		g := m.groups[name]
+		if used[g.namespace] == nil {
+			used[g.namespace] = map[string]bool{}
+		}
+		resource := uniqueName(name, used[g.namespace])
+		configs := append([]string(nil), g.configs...)
+		sort.Strings(configs)
+		m.result.AgentGroups = append(m.result.AgentGroups, v1alpha1.AgentGroup{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: "AgentGroup"},
+			ObjectMeta: objectMeta(resource, g.namespace, strings.Join(g.sources, ",")),
+			Spec: v1alpha1.AgentGroupSpec{
+				Name:        name,
+				Description: "Migrated from the SLS machine group " + name,
+				Configs:     configs,
+			},
+		})
+		entry := Entry{Source: "MachineGroup/" + name, Target: fmt.Sprintf("AgentGroup/%s/%s", g.namespace, resource)}
+		entry.mapped("name", "spec.name", "")
+		entry.mapped("(referencing configs)", "spec.configs", strings.Join(configs, ", "))
+		entry.lossy("(machine identity)", "", "the IP or custom identifiers of the machine group are not migrated, "+
+			"set spec.nodeSelector or spec.podSelector to choose the agents of the group")
+		m.result.Report.Entries = append(m.result.Report.Entries, entry)
+	}
+}
+
+func objectMeta(name, namespace, source string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Labels:      map[string]string{v1alpha1.LabelManagedBy: v1alpha1.ManagedByMigration},
+		Annotations: map[string]string{v1alpha1.AnnotationMigratedFrom: source},
+	}
+}
+
+// sourceName 返回源对象在报告与注解中的名称，格式为<种类>/<命名空间>/<名称>，集群级对象没有命名空间
+func sourceName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetKind() + "/" + obj.GetName()
+	}
+	return fmt.Sprintf("%s/%s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// uniqueName 将名称转换为合法且不重复的资源名
+func uniqueName(name string, used map[string]bool) string {
+	base := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(base) > 240 {
+		base = strings.TrimRight(base[:240], "-")
+	}
+	if base == "" {
+		base = "migrated"
+	}
+	candidate := base
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+	used[candidate] = true
+	return candidate
+}
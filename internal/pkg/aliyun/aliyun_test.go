@@ -0,0 +1,193 @@
+package aliyun
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+const sources = `apiVersion: telemetry.alibabacloud.com/v1alpha1
+kind: AliyunPipelineConfig
+metadata:
+  name: nginx-access
+  namespace: web
+spec:
+  project:
+    name: k8s-log-demo
+  logstores:
+    - name: nginx
+      ttl: 30
+  machineGroups:
+    - name: web-nodes
+  config:
+    sample: "127.0.0.1 GET /"
+    inputs:
+      - Type: input_file
+        FilePaths: [/var/log/nginx/access.log]
+    flushers:
+      - Type: flusher_sls
+        Logstore: nginx
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ignored
+---
+apiVersion: log.alibabacloud.com/v1alpha1
+kind: AliyunLogConfig
+metadata:
+  name: app-log
+  namespace: web
+spec:
+  project: k8s-log-demo
+  logstore: app
+  shardCount: 2
+  lifeCycle: 90
+  machineGroups: [web-nodes]
+  logtailConfig:
+    inputType: file
+    configName: app_log
+    inputDetail:
+      logType: common_reg_log
+      logPath: /var/log/app
+      filePattern: "*.log"
+      dockerFile: true
+      dockerIncludeLabel:
+        app: shop
+      logBeginRegex: '\d+-\d+-\d+.*'
+      regex: '(\S+) (\S+) (.*)'
+      key: [time, level, msg]
+      timeFormat: "%Y-%m-%d"
+      timeKey: time
+      filterKey: [level]
+      filterRegex: [ERROR|WARN]
+      topicFormat: none
+---
+apiVersion: log.alibabacloud.com/v1alpha1
+kind: AliyunLogConfig
+metadata:
+  name: stdout
+  namespace: web
+spec:
+  logstore: stdout
+  logtailConfig:
+    inputType: plugin
+    configName: nginx-access
+    inputDetail:
+      plugin:
+        inputs:
+          - type: service_docker_stdout
+            detail:
+              Stderr: true
+---
+apiVersion: log.alibabacloud.com/v1alpha1
+kind: AliyunLogConfig
+metadata:
+  name: binlog
+spec:
+  logstore: mysql
+  logtailConfig:
+    inputType: binlog
+`
+
+func readSources(t *testing.T) Result {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "sources.yaml")
+	if err := os.WriteFile(file, []byte(sources), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	objs, err := ReadFiles([]string{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 4 {
+		t.Fatalf("read %d objects, want 4", len(objs))
+	}
+	result, err := Convert(objs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestConvert(t *testing.T) {
+	result := readSources(t)
+
+	if len(result.Pipelines) != 2 {
+		t.Fatalf("got %d pipelines, want 2", len(result.Pipelines))
+	}
+	// AliyunPipelineConfig先于AliyunLogConfig转换
+	nginx, app := result.Pipelines[0], result.Pipelines[1]
+	if app.Name != "app-log" || app.Namespace != "web" || app.Spec.Name != "app_log" {
+		t.Errorf("app pipeline = %s/%s %s", app.Namespace, app.Name, app.Spec.Name)
+	}
+	if nginx.Spec.Name != "nginx-access" || strings.Contains(nginx.Spec.Content, "sample") {
+		t.Errorf("nginx pipeline = %s %q", nginx.Spec.Name, nginx.Spec.Content)
+	}
+	if got := nginx.Annotations[v1alpha1.AnnotationMigratedFrom]; got != "AliyunPipelineConfig/web/nginx-access" {
+		t.Errorf("migrated-from = %s", got)
+	}
+	if nginx.Labels[v1alpha1.LabelManagedBy] != v1alpha1.ManagedByMigration {
+		t.Errorf("labels = %v", nginx.Labels)
+	}
+	if string(app.Spec.LogStores.Raw) != `[{"name":"app","shardCount":2,"ttl":90}]` {
+		t.Errorf("logStores = %s", app.Spec.LogStores.Raw)
+	}
+
+	var config map[string]interface{}
+	if err := sigsyaml.Unmarshal([]byte(app.Spec.Content), &config); err != nil {
+		t.Fatal(err)
+	}
+	input := config["inputs"].([]interface{})[0].(map[string]interface{})
+	if input["FilePaths"].([]interface{})[0] != "/var/log/app/*.log" || input["EnableContainerDiscovery"] != true {
+		t.Errorf("input = %v", input)
+	}
+	var types []string
+	for _, p := range config["processors"].([]interface{}) {
+		types = append(types, p.(map[string]interface{})["Type"].(string))
+	}
+	if got := strings.Join(types, ","); got !=
+		"processor_parse_regex_native,processor_parse_timestamp_native,processor_filter_regex_native" {
+		t.Errorf("processors = %s", got)
+	}
+	for _, p := range render.Lint(app.Spec.Content) {
+		if p.Severity == render.SeverityError {
+			t.Errorf("migrated config: %s", p)
+		}
+	}
+
+	if len(result.AgentGroups) != 1 || result.AgentGroups[0].Spec.Name != "web-nodes" {
+		t.Fatalf("agent groups = %+v", result.AgentGroups)
+	}
+	if got := strings.Join(result.AgentGroups[0].Spec.Configs, ","); got != "app_log,nginx-access" {
+		t.Errorf("agent group configs = %s", got)
+	}
+}
+
+func TestReport(t *testing.T) {
+	result := readSources(t)
+	text := result.Report.Text()
+	for _, want := range []string{
+		"AliyunLogConfig/binlog -> (not migrated)",
+		`! spec.logtailConfig.inputType -> (dropped): input type "binlog" is not supported`,
+		"! spec.config.sample -> (dropped)",
+		"! spec.logtailConfig.inputDetail.topicFormat -> (dropped)",
+		// 插件采集的配置名与AliyunPipelineConfig重复
+		"AliyunLogConfig/web/stdout -> (not migrated)",
+		"! metadata.name -> (dropped): config name nginx-access is already used by AliyunPipelineConfig/web/nginx-access",
+		"MachineGroup/web-nodes -> AgentGroup/web/web-nodes",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report does not contain %q:\n%s", want, text)
+		}
+	}
+	if _, err := result.Report.JSON(); err != nil {
+		t.Fatal(err)
+	}
+}
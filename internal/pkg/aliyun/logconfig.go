@@ -0,0 +1,268 @@
+package aliyun
+
+import (
+	"fmt"
+	"path"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// fileParsers AliyunLogConfig的logType对应的解析插件
+var fileParsers = map[string]string{
+	"common_reg_log": "processor_parse_regex_native",
+	"json_log":       "processor_parse_json_native",
+	"delimiter_log":  "processor_parse_delimiter_native",
+	"apsara_log":     "processor_parse_apsara_native",
+}
+
+// containerFilters dockerInclude*/dockerExclude*对应的ContainerFilters字段
+var containerFilters = map[string]string{
+	"dockerIncludeLabel": "IncludeContainerLabel",
+	"dockerExcludeLabel": "ExcludeContainerLabel",
+	"dockerIncludeEnv":   "IncludeEnv",
+	"dockerExcludeEnv":   "ExcludeEnv",
+}
+
+// logConfig 转换AliyunLogConfig。文件采集的inputDetail转换为input_file与解析插件，
+// 插件采集的inputDetail.plugin转换为同名插件，并追加写入原日志库的flusher_sls
+func (m *migration) logConfig(obj *unstructured.Unstructured) error {
+	entry := Entry{Source: sourceName(obj)}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	logtail, _ := spec["logtailConfig"].(map[string]interface{})
+	detail, _ := logtail["inputDetail"].(map[string]interface{})
+
+	name, _ := logtail["configName"].(string)
+	if name != "" {
+		entry.mapped("spec.logtailConfig.configName", "spec.name", "")
+	} else {
+		name = obj.GetName()
+		entry.mapped("metadata.name", "spec.name", "spec.logtailConfig.configName is not set")
+	}
+	project, _ := spec["project"].(string)
+	logstore, _ := spec["logstore"].(string)
+
+	var config map[string]interface{}
+	switch inputType, _ := logtail["inputType"].(string); inputType {
+	case "file":
+		config = fileConfig(detail, &entry)
+	case "plugin":
+		config = pluginConfig(detail, &entry)
+	default:
+		entry.lossy("spec.logtailConfig.inputType", "", fmt.Sprintf("input type %q is not supported, not migrated", inputType))
+		m.result.Report.Entries = append(m.result.Report.Entries, entry)
+		return nil
+	}
+	if _, ok := config["flushers"]; !ok {
+		flusher := map[string]interface{}{"Type": "flusher_sls", "Logstore": logstore}
+		if project != "" {
+			flusher["Project"] = project
+		}
+		config["flushers"] = []interface{}{flusher}
+		entry.mapped("spec.logstore", "flushers[0].Logstore", "flusher_sls added to keep writing to the logstore")
+	}
+	content, err := sigsyaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	out := v1alpha1.PipelineSpec{Name: name, Content: string(content)}
+
+	logStore := map[string]interface{}{"name": logstore}
+	var groups []string
+	for _, key := range sortedKeys(spec) {
+		value := spec[key]
+		switch key {
+		case "logtailConfig", "logstore":
+		case "project":
+			out.Project, err = rawExtension(map[string]interface{}{"name": value})
+			entry.mapped("spec.project", "spec.project.name", slsNote)
+		case "shardCount":
+			logStore["shardCount"] = value
+			entry.mapped("spec.shardCount", "spec.logStores[0].shardCount", slsNote)
+		case "lifeCycle":
+			logStore["ttl"] = value
+			entry.mapped("spec.lifeCycle", "spec.logStores[0].ttl", slsNote)
+		case "machineGroups":
+			var list []interface{}
+			values, _ := value.([]interface{})
+			for _, v := range values {
+				if group, ok := v.(string); ok {
+					groups = append(groups, group)
+					list = append(list, map[string]interface{}{"name": group})
+				}
+			}
+			out.MachineGroups, err = rawExtension(list)
+			entry.mapped("spec.machineGroups", "spec.machineGroups", slsNote)
+		default:
+			entry.lossy("spec."+key, "", "no equivalent in Pipeline")
+		}
+		if err != nil {
+			return fmt.Errorf("spec.%s: %w", key, err)
+		}
+	}
+	if logstore != "" {
+		if out.LogStores, err = rawExtension([]interface{}{logStore}); err != nil {
+			return err
+		}
+		entry.mapped("spec.logstore", "spec.logStores[0].name", slsNote)
+	}
+	m.addPipeline(obj, &entry, out, groups)
+	return nil
+}
+
+// fileConfig 把文件采集的inputDetail转换为采集配置
+func fileConfig(detail map[string]interface{}, entry *Entry) map[string]interface{} {
+	const prefix = "spec.logtailConfig.inputDetail."
+	input := map[string]interface{}{"Type": "input_file"}
+	var processors []interface{}
+	var parser map[string]interface{}
+	filters := map[string]interface{}{}
+
+	logPath, _ := detail["logPath"].(string)
+	pattern, _ := detail["filePattern"].(string)
+	if pattern == "" {
+		pattern = "*"
+	}
+	if depth, ok := detail["maxDepth"].(float64); ok && depth > 0 {
+		input["FilePaths"] = []interface{}{path.Join(logPath, "**", pattern)}
+		input["MaxDirSearchDepth"] = depth
+	} else {
+		input["FilePaths"] = []interface{}{path.Join(logPath, pattern)}
+	}
+
+	for _, key := range sortedKeys(detail) {
+		value := detail[key]
+		switch key {
+		case "logPath", "filePattern", "maxDepth":
+			entry.mapped(prefix+key, "inputs[0].FilePaths", "")
+		case "logType":
+			logType, _ := value.(string)
+			plugin, ok := fileParsers[logType]
+			if !ok {
+				entry.lossy(prefix+key, "", fmt.Sprintf("log type %q has no parser, logs are collected as plain text", logType))
+				continue
+			}
+			parser = map[string]interface{}{"Type": plugin, "SourceKey": "content"}
+			entry.mapped(prefix+key, "processors[0].Type", plugin)
+		case "dockerFile":
+			input["EnableContainerDiscovery"] = value
+			entry.mapped(prefix+key, "inputs[0].EnableContainerDiscovery", "")
+		case "dockerIncludeLabel", "dockerExcludeLabel", "dockerIncludeEnv", "dockerExcludeEnv":
+			filters[containerFilters[key]] = value
+			entry.mapped(prefix+key, "inputs[0].ContainerFilters."+containerFilters[key], "")
+		case "logBeginRegex":
+			input["Multiline"] = map[string]interface{}{"Mode": "custom", "StartPattern": value}
+			entry.mapped(prefix+key, "inputs[0].Multiline.StartPattern", "")
+		case "fileEncoding":
+			input["FileEncoding"] = value
+			entry.mapped(prefix+key, "inputs[0].FileEncoding", "")
+		case "regex", "key", "separator", "quote", "timeFormat", "timeKey", "filterKey", "filterRegex":
+			// 在解析插件与过滤插件中处理
+		default:
+			entry.lossy(prefix+key, "", "no equivalent in input_file")
+		}
+	}
+	if len(filters) > 0 {
+		input["ContainerFilters"] = filters
+	}
+
+	if parser != nil {
+		fields := map[string]string{"regex": "Regex", "key": "Keys", "separator": "Separator", "quote": "Quote"}
+		for _, key := range []string{"key", "quote", "regex", "separator"} {
+			if value, ok := detail[key]; ok {
+				parser[fields[key]] = value
+				entry.mapped(prefix+key, "processors[0]."+fields[key], "")
+			}
+		}
+		processors = append(processors, parser)
+	} else {
+		for _, key := range []string{"key", "quote", "regex", "separator"} {
+			if _, ok := detail[key]; ok {
+				entry.lossy(prefix+key, "", "logType has no parser")
+			}
+		}
+	}
+
+	timeFormat, hasFormat := detail["timeFormat"].(string)
+	timeKey, hasKey := detail["timeKey"].(string)
+	switch {
+	case hasFormat && hasKey:
+		processors = append(processors, map[string]interface{}{
+			"Type":         "processor_parse_timestamp_native",
+			"SourceKey":    timeKey,
+			"SourceFormat": timeFormat,
+		})
+		entry.mapped(prefix+"timeFormat", "processor_parse_timestamp_native.SourceFormat", "")
+		entry.mapped(prefix+"timeKey", "processor_parse_timestamp_native.SourceKey", "")
+	case hasFormat:
+		entry.lossy(prefix+"timeFormat", "", "timeKey is not set, the collection time is used")
+	case hasKey:
+		entry.lossy(prefix+"timeKey", "", "timeFormat is not set, the collection time is used")
+	}
+
+	filterKeys, _ := detail["filterKey"].([]interface{})
+	filterRegex, _ := detail["filterRegex"].([]interface{})
+	if len(filterKeys) > 0 && len(filterKeys) == len(filterRegex) {
+		include := map[string]interface{}{}
+		for i, key := range filterKeys {
+			if k, ok := key.(string); ok {
+				include[k] = filterRegex[i]
+			}
+		}
+		processors = append(processors, map[string]interface{}{
+			"Type":    "processor_filter_regex_native",
+			"Include": include,
+		})
+		entry.mapped(prefix+"filterKey", "processor_filter_regex_native.Include", "")
+		entry.mapped(prefix+"filterRegex", "processor_filter_regex_native.Include", "")
+	} else if len(filterKeys) > 0 || len(filterRegex) > 0 {
+		entry.lossy(prefix+"filterKey", "", "filterKey and filterRegex differ in length, no filter is applied")
+	}
+
+	config := map[string]interface{}{"inputs": []interface{}{input}}
+	if len(processors) > 0 {
+		config["processors"] = processors
+	}
+	return config
+}
+
+// pluginConfig 把插件采集的inputDetail.plugin转换为采集配置，插件的detail展开为插件的参数
+func pluginConfig(detail map[string]interface{}, entry *Entry) map[string]interface{} {
+	const prefix = "spec.logtailConfig.inputDetail."
+	config := map[string]interface{}{}
+	plugin, _ := detail["plugin"].(map[string]interface{})
+	for _, key := range sortedKeys(plugin) {
+		list, ok := plugin[key].([]interface{})
+		if !ok || (key != "inputs" && key != "processors" && key != "flushers" && key != "aggregators") {
+			entry.lossy(prefix+"plugin."+key, "", "unknown plugin section")
+			continue
+		}
+		var plugins []interface{}
+		for i, item := range list {
+			p, _ := item.(map[string]interface{})
+			typ, _ := p["type"].(string)
+			if typ == "" {
+				entry.lossy(fmt.Sprintf("%splugin.%s[%d]", prefix, key, i), "", "plugin without type")
+				continue
+			}
+			out := map[string]interface{}{"Type": typ}
+			params, _ := p["detail"].(map[string]interface{})
+			for k, v := range params {
+				out[k] = v
+			}
+			plugins = append(plugins, out)
+		}
+		if len(plugins) > 0 {
+			config[key] = plugins
+			entry.mapped(prefix+"plugin."+key, key, "")
+		}
+	}
+	for _, key := range sortedKeys(detail) {
+		if key != "plugin" {
+			entry.lossy(prefix+key, "", "ignored by plugin input configs")
+		}
+	}
+	return config
+}
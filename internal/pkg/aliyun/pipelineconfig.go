@@ -0,0 +1,102 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// slsNote 说明保留的日志服务字段不会被Operator创建
+const slsNote = "kept for the Config-Server, the operator does not create SLS resources"
+
+// pipelineConfig 转换AliyunPipelineConfig与ClusterAliyunPipelineConfig。
+// spec.config本身就是LoongCollector的采集配置，除sample外原样保留
+func (m *migration) pipelineConfig(obj *unstructured.Unstructured) error {
+	entry := Entry{Source: sourceName(obj)}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+
+	config, ok := spec["config"].(map[string]interface{})
+	if !ok {
+		entry.lossy("spec.config", "", "missing or not an object, not migrated")
+		m.result.Report.Entries = append(m.result.Report.Entries, entry)
+		return nil
+	}
+	config = runtime.DeepCopyJSON(config)
+	if _, ok := config["sample"]; ok {
+		delete(config, "sample")
+		entry.lossy("spec.config.sample", "", "sample logs are only used by the SLS console")
+	}
+	content, err := sigsyaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	out := v1alpha1.PipelineSpec{Name: obj.GetName(), Content: string(content)}
+	entry.mapped("metadata.name", "spec.name", "")
+	entry.mapped("spec.config", "spec.content", "")
+
+	var groups []string
+	for _, key := range sortedKeys(spec) {
+		value := spec[key]
+		switch key {
+		case "config":
+		case "project":
+			out.Project, err = rawExtension(value)
+			entry.mapped("spec.project", "spec.project", slsNote)
+		case "logstores":
+			out.LogStores, err = rawExtension(value)
+			entry.mapped("spec.logstores", "spec.logStores", slsNote)
+		case "machineGroups":
+			out.MachineGroups, err = rawExtension(value)
+			entry.mapped("spec.machineGroups", "spec.machineGroups", slsNote)
+			groups = machineGroupNames(value)
+		case "enableUpgradeOverride":
+			out.EnableUpgradeOverride, _ = value.(bool)
+			entry.mapped("spec.enableUpgradeOverride", "spec.enableUpgradeOverride", "")
+		default:
+			entry.lossy("spec."+key, "", "no equivalent in Pipeline")
+		}
+		if err != nil {
+			return fmt.Errorf("spec.%s: %w", key, err)
+		}
+	}
+	m.addPipeline(obj, &entry, out, groups)
+	return nil
+}
+
+// machineGroupNames 返回machineGroups中的机器组名
+func machineGroupNames(value interface{}) []string {
+	var names []string
+	list, _ := value.([]interface{})
+	for _, item := range list {
+		if group, ok := item.(map[string]interface{}); ok {
+			if name, ok := group["name"].(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func rawExtension(value interface{}) (runtime.RawExtension, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return runtime.RawExtension{}, err
+	}
+	return runtime.RawExtension{Raw: data}, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
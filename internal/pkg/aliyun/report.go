@@ -0,0 +1,87 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Report 迁移报告，记录每个源对象的字段映射与有损转换
+type Report struct {
+	Entries []Entry `json:"entries"`
+	// Notes 与单个对象无关的说明，例如集群中没有安装的CRD
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Entry 一个源对象的迁移结果，Target为空表示没有生成资源
+type Entry struct {
+	Source   string    `json:"source"`
+	Target   string    `json:"target,omitempty"`
+	Mappings []Mapping `json:"mappings"`
+}
+
+// Mapping 一个字段的映射，To为空表示字段被丢弃
+type Mapping struct {
+	From  string `json:"from"`
+	To    string `json:"to,omitempty"`
+	Lossy bool   `json:"lossy,omitempty"`
+	Note  string `json:"note,omitempty"`
+}
+
+func (e *Entry) mapped(from, to, note string) {
+	e.Mappings = append(e.Mappings, Mapping{From: from, To: to, Note: note})
+}
+
+func (e *Entry) lossy(from, to, note string) {
+	e.Mappings = append(e.Mappings, Mapping{From: from, To: to, Lossy: true, Note: note})
+}
+
+// Lossy 返回有损转换的数量
+func (r *Report) Lossy() int {
+	n := 0
+	for _, e := range r.Entries {
+		for _, m := range e.Mappings {
+			if m.Lossy {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Text 以文本格式输出报告，有损的映射以"!"标记
+func (r *Report) Text() string {
+	var b strings.Builder
+	for _, note := range r.Notes {
+		fmt.Fprintf(&b, "note: %s\n", note)
+	}
+	for _, e := range r.Entries {
+		target := e.Target
+		if target == "" {
+			target = "(not migrated)"
+		}
+		fmt.Fprintf(&b, "%s -> %s\n", e.Source, target)
+		for _, m := range e.Mappings {
+			mark := " "
+			if m.Lossy {
+				mark = "!"
+			}
+			to := m.To
+			if to == "" {
+				to = "(dropped)"
+			}
+			fmt.Fprintf(&b, "  %s %s -> %s", mark, m.From, to)
+			if m.Note != "" {
+				fmt.Fprintf(&b, ": %s", m.Note)
+			}
+			b.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&b, "%d objects, %d lossy conversions\n", len(r.Entries), r.Lossy())
+	return b.String()
+}
+
+// JSON 以JSON格式输出报告
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
@@ -0,0 +1,109 @@
+package association
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// Result AgentGroup期望关联的配置及检查结果
+type Result struct {
+	// Desired 期望关联到Agent组的配置，spec.configs与绑定到该组的Pipeline的并集
+	Desired []string
+	// Missing spec.configs中引用但没有对应Pipeline的配置
+	Missing []string
+	// Conflicts 无法确定归属的冲突描述
+	Conflicts []string
+}
+
+// Compute 计算AgentGroup的期望关联。pipelines与groups为集群中全部的Pipeline与AgentGroup
+func Compute(group *v1alpha1.AgentGroup, pipelines []v1alpha1.Pipeline, groups []v1alpha1.AgentGroup) Result {
+	var result Result
+
+	// 配置名到Pipeline的映射，同名Pipeline视为冲突
+	owners := map[string][]*v1alpha1.Pipeline{}
+	for i := range pipelines {
+		p := &pipelines[i]
+		if p.DeletionTimestamp != nil || p.Spec.Name == "" {
+			continue
+		}
+		owners[p.Spec.Name] = append(owners[p.Spec.Name], p)
+	}
+
+	desired := map[string]struct{}{}
+	for _, name := range group.Spec.Configs {
+		desired[name] = struct{}{}
+		if len(owners[name]) == 0 {
+			result.Missing = append(result.Missing, name)
+		}
+	}
+	for name, ps := range owners {
+		for _, p := range ps {
+			if p.Spec.AgentGroup == group.Spec.Name {
+				desired[name] = struct{}{}
+			}
+		}
+	}
+
+	for name := range desired {
+		if ps := owners[name]; len(ps) > 1 {
+			result.Conflicts = append(result.Conflicts,
+				fmt.Sprintf("config %q is declared by %d pipelines: %s", name, len(ps), pipelineKeys(ps)))
+		}
+	}
+	for i := range groups {
+		other := &groups[i]
+		if other.UID == group.UID || other.DeletionTimestamp != nil || other.Spec.Name != group.Spec.Name {
+			continue
+		}
+		result.Conflicts = append(result.Conflicts,
+			fmt.Sprintf("agent group %q is also declared by %s/%s", group.Spec.Name, other.Namespace, other.Name))
+	}
+
+	result.Desired = sortedKeys(desired)
+	sort.Strings(result.Missing)
+	sort.Strings(result.Conflicts)
+	return result
+}
+
+// Diff 比较期望与实际的关联，返回需要新增与移除的配置
+func Diff(desired, actual []string) (add, remove []string) {
+	actualSet := make(map[string]struct{}, len(actual))
+	for _, name := range actual {
+		actualSet[name] = struct{}{}
+	}
+	desiredSet := make(map[string]struct{}, len(desired))
+	for _, name := range desired {
+		desiredSet[name] = struct{}{}
+		if _, ok := actualSet[name]; !ok {
+			add = append(add, name)
+		}
+	}
+	for _, name := range actual {
+		if _, ok := desiredSet[name]; !ok {
+			remove = append(remove, name)
+		}
+	}
+	sort.Strings(add)
+	sort.Strings(remove)
+	return add, remove
+}
+
+func pipelineKeys(ps []*v1alpha1.Pipeline) string {
+	keys := make([]string, 0, len(ps))
+	for _, p := range ps {
+		keys = append(keys, p.Namespace+"/"+p.Name)
+	}
+	sort.Strings(keys)
+	return fmt.Sprint(keys)
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
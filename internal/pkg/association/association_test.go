@@ -0,0 +1,62 @@
+package association
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func pipeline(namespace, name, config, group string) v1alpha1.Pipeline {
+	return v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec:       v1alpha1.PipelineSpec{Name: config, AgentGroup: group},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	group := &v1alpha1.AgentGroup{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ops", Name: "web", UID: "1"},
+		Spec:       v1alpha1.AgentGroupSpec{Name: "web", Configs: []string{"nginx", "manual"}},
+	}
+	pipelines := []v1alpha1.Pipeline{
+		pipeline("ops", "nginx", "nginx", ""),
+		pipeline("shop", "app", "app-logs", "web"),
+		pipeline("shop", "other", "other-logs", "db"),
+		pipeline("a", "dup", "dup", "web"),
+		pipeline("b", "dup", "dup", ""),
+	}
+	groups := []v1alpha1.AgentGroup{
+		*group,
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web2", UID: "2"}, Spec: v1alpha1.AgentGroupSpec{Name: "web"}},
+	}
+
+	result := Compute(group, pipelines, groups)
+
+	if expected := []string{"app-logs", "dup", "manual", "nginx"}; !reflect.DeepEqual(result.Desired, expected) {
+		t.Errorf("desired: expected %v, got %v", expected, result.Desired)
+	}
+	if expected := []string{"manual"}; !reflect.DeepEqual(result.Missing, expected) {
+		t.Errorf("missing: expected %v, got %v", expected, result.Missing)
+	}
+	if len(result.Conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %v", result.Conflicts)
+	}
+	if !strings.Contains(result.Conflicts[0], "shop/web2") || !strings.Contains(result.Conflicts[1], `"dup"`) {
+		t.Errorf("unexpected conflicts %v", result.Conflicts)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	add, remove := Diff([]string{"a", "b", "c"}, []string{"c", "d"})
+	if !reflect.DeepEqual(add, []string{"a", "b"}) || !reflect.DeepEqual(remove, []string{"d"}) {
+		t.Errorf("unexpected diff add=%v remove=%v", add, remove)
+	}
+
+	add, remove = Diff(nil, nil)
+	if add != nil || remove != nil {
+		t.Errorf("expected empty diff, got add=%v remove=%v", add, remove)
+	}
+}
@@ -0,0 +1,61 @@
+package backoff
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Tracker 按对象记录连续失败次数，计算带抖动的指数退避时间
+type Tracker struct {
+	base   time.Duration
+	max    time.Duration
+	jitter float64
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+// NewTracker 创建退避计算器。第n次失败的退避时间为base*2^(n-1)，不超过max，并叠加±jitter比例的随机抖动
+func NewTracker(base, max time.Duration, jitter float64) *Tracker {
+	return &Tracker{
+		base:     base,
+		max:      max,
+		jitter:   jitter,
+		failures: map[string]int{},
+	}
+}
+
+// Next 记录一次失败并返回下一次重试前的等待时间
+func (t *Tracker) Next(key string) time.Duration {
+	t.mu.Lock()
+	t.failures[key]++
+	n := t.failures[key]
+	t.mu.Unlock()
+
+	delay := t.base
+	for i := 1; i < n && delay < t.max; i++ {
+		delay *= 2
+	}
+	if delay > t.max {
+		delay = t.max
+	}
+	if t.jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * t.jitter * float64(delay))
+	}
+	return delay
+}
+
+// Failures 返回对象当前的连续失败次数
+func (t *Tracker) Failures(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.failures[key]
+}
+
+// Reset 成功或放弃重试后清除对象的失败记录
+func (t *Tracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
@@ -0,0 +1,53 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDoublesUpToMax(t *testing.T) {
+	tracker := NewTracker(time.Second, 10*time.Second, 0)
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := tracker.Next("a"); got != w {
+			t.Fatalf("attempt %d: got %v, want %v", i+1, got, w)
+		}
+	}
+	if got := tracker.Failures("a"); got != len(want) {
+		t.Fatalf("failures: got %d, want %d", got, len(want))
+	}
+}
+
+func TestKeysAreIndependent(t *testing.T) {
+	tracker := NewTracker(time.Second, time.Minute, 0)
+
+	tracker.Next("a")
+	tracker.Next("a")
+	if got := tracker.Next("b"); got != time.Second {
+		t.Fatalf("got %v, want %v", got, time.Second)
+	}
+}
+
+func TestReset(t *testing.T) {
+	tracker := NewTracker(time.Second, time.Minute, 0)
+
+	tracker.Next("a")
+	tracker.Next("a")
+	tracker.Reset("a")
+	if got := tracker.Next("a"); got != time.Second {
+		t.Fatalf("got %v, want %v", got, time.Second)
+	}
+}
+
+func TestJitterStaysInRange(t *testing.T) {
+	tracker := NewTracker(time.Second, time.Minute, 0.2)
+
+	for i := 0; i < 100; i++ {
+		tracker.Reset("a")
+		got := tracker.Next("a")
+		if got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("got %v, want within 20%% of 1s", got)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package batchsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+func pipeline(name, content, group, appliedContent, endpoint string) v1alpha1.Pipeline {
+	return v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1alpha1.PipelineSpec{Name: name, Content: content, AgentGroup: group},
+		Status: v1alpha1.PipelineStatus{
+			LastAppliedConfig: v1alpha1.LastAppliedConfig{Content: appliedContent, Endpoint: endpoint},
+		},
+	}
+}
+
+func configNames(ps []*v1alpha1.Pipeline) []string {
+	names := []string{}
+	for _, p := range ps {
+		names = append(names, p.Spec.Name)
+	}
+	return names
+}
+
+func TestCompute(t *testing.T) {
+	const endpoint = "http://config-server:8899"
+	pipelines := []v1alpha1.Pipeline{
+		pipeline("in-sync", "a", "default", "a", endpoint),
+		pipeline("changed", "b", "default", "old", endpoint),
+		pipeline("missing-remote", "c", "", "c", endpoint),
+		pipeline("moved-endpoint", "d", "", "d", "http://old:8899"),
+		pipeline("new-group", "e", "new", "e", endpoint),
+		pipeline("managed", "f", "managed", "", endpoint),
+		pipeline("terminal", "g", "", "", endpoint),
+		// 来自Secret的配置只记录了哈希
+		pipeline("secret-in-sync", "h", "", "", endpoint),
+		pipeline("secret-changed", "i", "", "", endpoint),
+	}
+	sum := sha256.Sum256([]byte("h"))
+	pipelines[7].Status.ContentHash = hex.EncodeToString(sum[:])
+	pipelines[8].Status.ContentHash = hex.EncodeToString(sum[:])
+	actual := State{
+		Endpoint: endpoint,
+		Configs:  []string{"in-sync", "changed", "moved-endpoint", "new-group", "secret-in-sync", "secret-changed"},
+		Groups:   []string{"default", "managed"},
+		Applied:  map[string][]string{"default": {"in-sync"}},
+	}
+
+	plan := Compute(pipelines, map[string]bool{"managed": true}, actual, func(p *v1alpha1.Pipeline) bool {
+		return p.Name == "terminal"
+	})
+
+	if got, want := configNames(plan.Configs), []string{"changed", "managed", "missing-remote", "moved-endpoint", "secret-changed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("configs = %v, want %v", got, want)
+	}
+	if want := []string{"new"}; !reflect.DeepEqual(plan.Groups, want) {
+		t.Errorf("groups = %v, want %v", plan.Groups, want)
+	}
+	wantAssociations := []Association{{Config: "changed", Group: "default"}, {Config: "new-group", Group: "new"}}
+	if !reflect.DeepEqual(plan.Associations, wantAssociations) {
+		t.Errorf("associations = %v, want %v", plan.Associations, wantAssociations)
+	}
+	if got := ReferencedGroups(pipelines, map[string]bool{"managed": true}); !reflect.DeepEqual(got, []string{"default", "new"}) {
+		t.Errorf("referenced groups = %v", got)
+	}
+}
+
+type fakeExecutor struct {
+	mu       sync.Mutex
+	calls    []string
+	inFlight atomic.Int32
+	peak     atomic.Int32
+	fail     map[string]bool
+}
+
+func (f *fakeExecutor) do(call string) error {
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	for {
+		peak := f.peak.Load()
+		if n <= peak || f.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+	if f.fail[call] {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func (f *fakeExecutor) ApplyConfig(_ context.Context, p *v1alpha1.Pipeline) error {
+	return f.do("config/" + p.Spec.Name)
+}
+
+func (f *fakeExecutor) CreateGroup(_ context.Context, group string) error {
+	return f.do("group/" + group)
+}
+
+func (f *fakeExecutor) Associate(_ context.Context, config, group string) error {
+	return f.do("association/" + group + "/" + config)
+}
+
+func TestExecuteBoundsConcurrency(t *testing.T) {
+	var plan Plan
+	for i := 0; i < 40; i++ {
+		p := pipeline(fmt.Sprintf("p%02d", i), "x", "", "", "")
+		plan.Configs = append(plan.Configs, &p)
+	}
+	executor := &fakeExecutor{}
+
+	result := Execute(context.Background(), plan, executor, Options{Concurrency: 4})
+
+	if len(result.Applied) != 40 || len(result.Failed) != 0 {
+		t.Fatalf("applied %d, failed %v", len(result.Applied), result.Failed)
+	}
+	if peak := executor.peak.Load(); peak > 4 {
+		t.Fatalf("peak concurrency %d, want at most 4", peak)
+	}
+}
+
+func TestExecuteRespectsQPS(t *testing.T) {
+	var plan Plan
+	for i := 0; i < 6; i++ {
+		plan.Groups = append(plan.Groups, fmt.Sprintf("g%d", i))
+	}
+	start := time.Now()
+	Execute(context.Background(), plan, &fakeExecutor{}, Options{Concurrency: 1, QPS: 20})
+
+	// 首个请求使用初始令牌，其余5个请求至少需要250ms
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("6 requests at 20 QPS took %v", elapsed)
+	}
+}
+
+func TestExecuteSkipsAssociationsOfFailedConfigs(t *testing.T) {
+	ok := pipeline("ok", "x", "g", "", "")
+	bad := pipeline("bad", "x", "g", "", "")
+	plan := Plan{
+		Configs:      []*v1alpha1.Pipeline{&ok, &bad},
+		Associations: []Association{{Config: "ok", Group: "g"}, {Config: "bad", Group: "g"}},
+	}
+	executor := &fakeExecutor{fail: map[string]bool{"config/bad": true}}
+
+	result := Execute(context.Background(), plan, executor, Options{Concurrency: 2})
+
+	if _, ok := result.Failed["config/bad"]; !ok {
+		t.Errorf("expected config/bad to fail, got %v", result.Failed)
+	}
+	if _, ok := result.Failed["association/g/bad"]; !ok {
+		t.Errorf("expected association/g/bad to be skipped, got %v", result.Failed)
+	}
+	for _, call := range executor.calls {
+		if call == "association/g/bad" {
+			t.Fatal("association of a failed config was sent")
+		}
+	}
+	if len(result.Applied) != 1 || result.Applied[0].Spec.Name != "ok" {
+		t.Errorf("applied = %v", configNames(result.Applied))
+	}
+}
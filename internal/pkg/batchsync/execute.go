@@ -0,0 +1,110 @@
+package batchsync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// Executor 执行变更的Config-Server操作
+type Executor interface {
+	ApplyConfig(ctx context.Context, pipeline *v1alpha1.Pipeline) error
+	CreateGroup(ctx context.Context, group string) error
+	Associate(ctx context.Context, config, group string) error
+}
+
+// Options 执行变更的并发与限速
+type Options struct {
+	// Concurrency 同时进行的请求数
+	Concurrency int
+	// QPS 每秒请求数上限，0表示不限速
+	QPS float64
+}
+
+// Result 执行结果
+type Result struct {
+	// Applied 成功下发的配置
+	Applied []*v1alpha1.Pipeline
+	// Failed 失败的操作及原因
+	Failed map[string]error
+}
+
+// Execute 按顺序执行三个阶段：创建Agent组、下发配置、建立关联。每个阶段内以有限并发执行，
+// 所有请求共享同一个限速器。单个操作失败不会中断其余操作，下发失败的配置不会建立关联
+func Execute(ctx context.Context, plan Plan, executor Executor, opts Options) Result {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	limit := rate.Inf
+	burst := concurrency
+	if opts.QPS > 0 {
+		limit = rate.Limit(opts.QPS)
+	}
+	limiter := rate.NewLimiter(limit, burst)
+
+	result := Result{Failed: map[string]error{}}
+	var mu sync.Mutex
+	fail := func(key string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Failed[key] = err
+	}
+
+	run := func(n int, fn func(ctx context.Context, i int) (string, error)) {
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(concurrency)
+		for i := 0; i < n; i++ {
+			g.Go(func() error {
+				if err := limiter.Wait(gctx); err != nil {
+					return err
+				}
+				if key, err := fn(gctx, i); err != nil {
+					fail(key, err)
+				}
+				return nil
+			})
+		}
+		// 只有ctx结束时返回错误，此时剩余操作不再执行
+		_ = g.Wait()
+	}
+
+	run(len(plan.Groups), func(ctx context.Context, i int) (string, error) {
+		group := plan.Groups[i]
+		return "group/" + group, executor.CreateGroup(ctx, group)
+	})
+
+	failedConfigs := map[string]bool{}
+	run(len(plan.Configs), func(ctx context.Context, i int) (string, error) {
+		p := plan.Configs[i]
+		if err := executor.ApplyConfig(ctx, p); err != nil {
+			mu.Lock()
+			failedConfigs[p.Spec.Name] = true
+			mu.Unlock()
+			return "config/" + p.Spec.Name, err
+		}
+		mu.Lock()
+		result.Applied = append(result.Applied, p)
+		mu.Unlock()
+		return "", nil
+	})
+
+	run(len(plan.Associations), func(ctx context.Context, i int) (string, error) {
+		a := plan.Associations[i]
+		key := fmt.Sprintf("association/%s/%s", a.Group, a.Config)
+		mu.Lock()
+		skip := failedConfigs[a.Config]
+		mu.Unlock()
+		if skip {
+			return key, fmt.Errorf("config %s was not applied", a.Config)
+		}
+		return key, executor.Associate(ctx, a.Config, a.Group)
+	})
+
+	return result
+}
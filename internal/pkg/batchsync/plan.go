@@ -0,0 +1,126 @@
+package batchsync
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// Association 需要建立的配置与Agent组的关联
+type Association struct {
+	Config string
+	Group  string
+}
+
+// Plan 一次批量同步需要执行的变更
+type Plan struct {
+	// Configs 需要创建或更新的配置
+	Configs []*v1alpha1.Pipeline
+	// Groups 需要创建的Agent组
+	Groups []string
+	// Associations 需要新增的关联
+	Associations []Association
+}
+
+// Empty 判断是否没有任何变更
+func (p Plan) Empty() bool {
+	return len(p.Configs) == 0 && len(p.Groups) == 0 && len(p.Associations) == 0
+}
+
+// State Config-Server上的实际状态
+type State struct {
+	// Endpoint Config-Server地址
+	Endpoint string
+	// Configs 已存在的配置名
+	Configs []string
+	// Groups 已存在的Agent组
+	Groups []string
+	// Applied 每个Agent组已关联的配置
+	Applied map[string][]string
+}
+
+// Compute 一次性比较全部Pipeline与Config-Server的实际状态。managedGroups为存在AgentGroup CR的组，
+// 其关联由AgentGroup控制器维护；skip返回true的Pipeline不参与同步
+func Compute(pipelines []v1alpha1.Pipeline, managedGroups map[string]bool, actual State,
+	skip func(*v1alpha1.Pipeline) bool) Plan {
+	remoteConfigs := toSet(actual.Configs)
+	remoteGroups := toSet(actual.Groups)
+	applied := map[string]map[string]struct{}{}
+	for group, configs := range actual.Applied {
+		applied[group] = toSet(configs)
+	}
+
+	var plan Plan
+	groups := map[string]struct{}{}
+	for i := range pipelines {
+		p := &pipelines[i]
+		if p.DeletionTimestamp != nil || p.Spec.Name == "" || (skip != nil && skip(p)) {
+			continue
+		}
+
+		_, exists := remoteConfigs[p.Spec.Name]
+		if !exists || contentChanged(p) || p.Status.LastAppliedConfig.Endpoint != actual.Endpoint {
+			plan.Configs = append(plan.Configs, p)
+		}
+
+		group := p.Spec.AgentGroup
+		if group == "" || managedGroups[group] {
+			continue
+		}
+		if _, ok := remoteGroups[group]; !ok {
+			groups[group] = struct{}{}
+		}
+		if _, ok := applied[group][p.Spec.Name]; !ok {
+			plan.Associations = append(plan.Associations, Association{Config: p.Spec.Name, Group: group})
+		}
+	}
+
+	for group := range groups {
+		plan.Groups = append(plan.Groups, group)
+	}
+	sort.Strings(plan.Groups)
+	sort.Slice(plan.Configs, func(i, j int) bool { return plan.Configs[i].Spec.Name < plan.Configs[j].Spec.Name })
+	sort.Slice(plan.Associations, func(i, j int) bool {
+		if plan.Associations[i].Group != plan.Associations[j].Group {
+			return plan.Associations[i].Group < plan.Associations[j].Group
+		}
+		return plan.Associations[i].Config < plan.Associations[j].Config
+	})
+	return plan
+}
+
+// ReferencedGroups 返回Pipeline引用且不由AgentGroup CR管理的Agent组，用于获取实际关联
+func ReferencedGroups(pipelines []v1alpha1.Pipeline, managedGroups map[string]bool) []string {
+	groups := map[string]struct{}{}
+	for _, p := range pipelines {
+		if p.Spec.AgentGroup != "" && !managedGroups[p.Spec.AgentGroup] {
+			groups[p.Spec.AgentGroup] = struct{}{}
+		}
+	}
+	result := make([]string, 0, len(groups))
+	for group := range groups {
+		result = append(result, group)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// contentChanged 检查配置内容是否与上次下发的不同。来自Secret的配置没有记录内容，比较status.contentHash
+func contentChanged(p *v1alpha1.Pipeline) bool {
+	last := p.Status.LastAppliedConfig.Content
+	if last != "" || p.Status.ContentHash == "" {
+		return last != p.Spec.Content
+	}
+	sum := sha256.Sum256([]byte(p.Spec.Content))
+	return hex.EncodeToString(sum[:]) != p.Status.ContentHash
+}
+
+func toSet(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
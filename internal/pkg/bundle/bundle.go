@@ -0,0 +1,327 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+const (
+	// FormatVersion 归档格式的版本，格式不兼容变化时递增
+	FormatVersion = "loongcollector.infraflow.co/bundle/v1"
+	// Extension 归档文件的扩展名
+	Extension = ".tar.gz"
+
+	manifestFile = "bundle.json"
+	contentDir   = "content"
+)
+
+// Bundle 导出的Pipeline、AgentGroup及其关联
+type Bundle struct {
+	Version   string    `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Namespaces 导出的命名空间，为空表示全部命名空间
+	Namespaces  []string     `json:"namespaces,omitempty"`
+	Pipelines   []Pipeline   `json:"pipelines"`
+	AgentGroups []AgentGroup `json:"agentGroups"`
+	// Associations Agent组名到关联的配置名：AgentGroup的spec.configs与绑定到该组的Pipeline的并集
+	Associations map[string][]string `json:"associations"`
+	// ConfigMaps 与 Secrets 为Pipeline的spec.contentFrom引用的对象
+	ConfigMaps []ConfigMap `json:"configMaps,omitempty"`
+	Secrets    []Secret    `json:"secrets,omitempty"`
+	// Profiles 为Pipeline引用的ProcessorChain与FlusherProfile
+	Profiles []Profile `json:"profiles,omitempty"`
+	// Templates 为Pipeline引用的PipelineTemplate
+	Templates []PipelineTemplate `json:"pipelineTemplates,omitempty"`
+	// Warnings 导出时无法渲染配置等不影响恢复的问题
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ExportOptions 导出选项
+type ExportOptions struct {
+	// Namespaces 导出的命名空间，为空表示全部命名空间
+	Namespaces []string
+	// IncludeSecrets 导出被引用的Secret的数据及包含Secret中的值的渲染配置，归档中将以明文保存这些值
+	IncludeSecrets bool
+}
+
+// Pipeline 导出的Pipeline，包含已下发的修订历史
+type Pipeline struct {
+	Namespace   string                      `json:"namespace"`
+	Name        string                      `json:"name"`
+	Labels      map[string]string           `json:"labels,omitempty"`
+	Annotations map[string]string           `json:"annotations,omitempty"`
+	Spec        v1alpha1.PipelineSpec       `json:"spec"`
+	History     []v1alpha1.PipelineRevision `json:"history,omitempty"`
+	// Content 渲染后的配置，只用于查看，恢复时按spec重新渲染。包含Secret中的值而未导出Secret时为空
+	Content string `json:"content,omitempty"`
+}
+
+// AgentGroup 导出的AgentGroup
+type AgentGroup struct {
+	Namespace   string                  `json:"namespace"`
+	Name        string                  `json:"name"`
+	Labels      map[string]string       `json:"labels,omitempty"`
+	Annotations map[string]string       `json:"annotations,omitempty"`
+	Spec        v1alpha1.AgentGroupSpec `json:"spec"`
+}
+
+// Export 读取opts.Namespaces中的Pipeline、AgentGroup及Pipeline引用的对象，未指定命名空间时读取全部命名空间
+func Export(ctx context.Context, c client.Reader, opts ExportOptions) (*Bundle, error) {
+	b := &Bundle{
+		Version:      FormatVersion,
+		CreatedAt:    time.Now().UTC(),
+		Namespaces:   opts.Namespaces,
+		Associations: map[string][]string{},
+	}
+	scopes := opts.Namespaces
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+	for _, ns := range scopes {
+		var pipelines v1alpha1.PipelineList
+		if err := c.List(ctx, &pipelines, client.InNamespace(ns)); err != nil {
+			return nil, fmt.Errorf("failed to list pipelines: %w", err)
+		}
+		for _, p := range pipelines.Items {
+			content, err := renderedContent(ctx, c, &p, opts)
+			if err != nil {
+				// 引用的对象缺失时仍导出spec，恢复后由控制器报告
+				b.Warnings = append(b.Warnings, fmt.Sprintf("pipeline %s/%s: content not rendered: %v", p.Namespace, p.Name, err))
+			}
+			b.Pipelines = append(b.Pipelines, Pipeline{
+				Namespace:   p.Namespace,
+				Name:        p.Name,
+				Labels:      p.Labels,
+				Annotations: portableAnnotations(p.Annotations),
+				Spec:        p.Spec,
+				History:     p.Status.History,
+				Content:     content,
+			})
+		}
+		var groups v1alpha1.AgentGroupList
+		if err := c.List(ctx, &groups, client.InNamespace(ns)); err != nil {
+			return nil, fmt.Errorf("failed to list agent groups: %w", err)
+		}
+		for _, g := range groups.Items {
+			b.AgentGroups = append(b.AgentGroups, AgentGroup{
+				Namespace:   g.Namespace,
+				Name:        g.Name,
+				Labels:      g.Labels,
+				Annotations: portableAnnotations(g.Annotations),
+				Spec:        g.Spec,
+			})
+		}
+	}
+	b.sort()
+	b.Associations = associations(b)
+	if err := exportSources(ctx, c, b, opts); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// associations 计算每个Agent组应关联的配置
+func associations(b *Bundle) map[string][]string {
+	sets := map[string]map[string]bool{}
+	add := func(group, config string) {
+		if sets[group] == nil {
+			sets[group] = map[string]bool{}
+		}
+		sets[group][config] = true
+	}
+	for _, g := range b.AgentGroups {
+		for _, config := range g.Spec.Configs {
+			add(g.Spec.Name, config)
+		}
+	}
+	for _, p := range b.Pipelines {
+		if p.Spec.AgentGroup != "" {
+			add(p.Spec.AgentGroup, p.Spec.Name)
+		}
+	}
+	result := map[string][]string{}
+	for group, configs := range sets {
+		for config := range configs {
+			result[group] = append(result[group], config)
+		}
+		sort.Strings(result[group])
+	}
+	return result
+}
+
+func (b *Bundle) sort() {
+	sort.Slice(b.Pipelines, func(i, j int) bool {
+		return key(b.Pipelines[i].Namespace, b.Pipelines[i].Name) < key(b.Pipelines[j].Namespace, b.Pipelines[j].Name)
+	})
+	sort.Slice(b.AgentGroups, func(i, j int) bool {
+		return key(b.AgentGroups[i].Namespace, b.AgentGroups[i].Name) < key(b.AgentGroups[j].Namespace, b.AgentGroups[j].Name)
+	})
+}
+
+func key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// portableAnnotations 去掉与源集群相关的注解：接管标记与kubectl记录的上次配置。
+// 恢复后的资源需要下发到新的Config-Server
+func portableAnnotations(annotations map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range annotations {
+		if k == v1alpha1.AnnotationAdopted || k == "kubectl.kubernetes.io/last-applied-configuration" {
+			continue
+		}
+		result[k] = v
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// Write 写入tar.gz归档：bundle.json保存全部内容，content/<namespace>/<name>.yaml保存渲染后的配置内容便于查看，
+// 没有渲染出的配置时不写入
+func Write(w io.Writer, b *Bundle) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(tw, manifestFile, manifest, b.CreatedAt); err != nil {
+		return err
+	}
+	for _, p := range b.Pipelines {
+		if p.Content == "" {
+			continue
+		}
+		name := path.Join(contentDir, p.Namespace, p.Name+".yaml")
+		if err := writeFile(tw, name, []byte(p.Content), b.CreatedAt); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Read 读取Write写入的归档
+func Read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("invalid bundle: %s not found", manifestFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle: %w", err)
+		}
+		if header.Name != manifestFile {
+			continue
+		}
+		var b Bundle
+		if err := json.NewDecoder(tr).Decode(&b); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", manifestFile, err)
+		}
+		if b.Version != FormatVersion {
+			return nil, fmt.Errorf("unsupported bundle version %q, want %q", b.Version, FormatVersion)
+		}
+		return &b, nil
+	}
+}
+
+// WriteFile 先写入临时文件再重命名，避免中断时留下不完整的归档
+func WriteFile(name string, b *Bundle) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".bundle-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := Write(tmp, b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// ReadFile 读取归档文件
+func ReadFile(name string) (*Bundle, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// FileName 返回prefix在t时刻的归档文件名，按文件名排序即按时间排序
+func FileName(prefix string, t time.Time) string {
+	return fmt.Sprintf("%s-%s%s", prefix, t.UTC().Format("20060102T150405Z"), Extension)
+}
+
+// Prune 删除dir中由FileName生成的prefix的旧归档，只保留最新的keep个，返回删除的文件
+func Prune(dir, prefix string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var archives []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, prefix+"-") && strings.HasSuffix(name, Extension) {
+			archives = append(archives, name)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(archives)))
+	if len(archives) <= keep {
+		return nil, nil
+	}
+	var removed []string
+	for _, name := range archives[keep:] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
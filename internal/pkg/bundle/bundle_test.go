@@ -0,0 +1,532 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/contentfrom"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func sourceObjects() []client.Object {
+	return []client.Object{
+		&v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "logging",
+				Name:      "nginx",
+				Labels:    map[string]string{"app": "nginx"},
+				Annotations: map[string]string{
+					v1alpha1.AnnotationAdopted: "http://old:8899",
+				},
+			},
+			Spec: v1alpha1.PipelineSpec{Name: "nginx", Content: "inputs:\n  - Type: input_file\n", AgentGroup: "web"},
+			Status: v1alpha1.PipelineStatus{History: []v1alpha1.PipelineRevision{
+				{Generation: 1, ContentHash: "abc", Endpoint: "http://old:8899"},
+			}},
+		},
+		&v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "ignored"},
+			Spec:       v1alpha1.PipelineSpec{Name: "ignored", Content: "inputs: []\n"},
+		},
+		&v1alpha1.AgentGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web"},
+			Spec:       v1alpha1.AgentGroupSpec{Name: "web", Configs: []string{"static"}},
+		},
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	reader := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(sourceObjects()...).Build()
+	b, err := Export(context.Background(), reader, ExportOptions{Namespaces: []string{"logging"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Pipelines) != 1 || len(b.AgentGroups) != 1 {
+		t.Fatalf("got %d pipelines and %d agent groups, want 1 and 1", len(b.Pipelines), len(b.AgentGroups))
+	}
+	if _, ok := b.Pipelines[0].Annotations[v1alpha1.AnnotationAdopted]; ok {
+		t.Error("adopted annotation must not be exported")
+	}
+	if want := []string{"nginx", "static"}; !reflect.DeepEqual(b.Associations["web"], want) {
+		t.Errorf("associations = %v, want %v", b.Associations["web"], want)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Pipelines, b.Pipelines) || !reflect.DeepEqual(got.Associations, b.Associations) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got, b)
+	}
+}
+
+func TestReadRejectsUnknownVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, &Bundle{Version: "loongcollector.infraflow.co/bundle/v0"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(&buf); err == nil || !strings.Contains(err.Error(), "unsupported bundle version") {
+		t.Fatalf("got %v, want unsupported version error", err)
+	}
+}
+
+func TestRestoreIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	reader := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(sourceObjects()...).Build()
+	b, err := Export(ctx, reader, ExportOptions{Namespaces: []string{"logging"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := fake.NewClientBuilder().WithScheme(newScheme(t)).
+		WithStatusSubresource(&v1alpha1.Pipeline{}).Build()
+	opts := RestoreOptions{Namespace: "restored"}
+
+	plan, err := Restore(ctx, target, b, RestoreOptions{Namespace: "restored", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions(plan) != "AgentGroup=create Pipeline=create" {
+		t.Fatalf("dry run plan = %s", actions(plan))
+	}
+	var pipelines v1alpha1.PipelineList
+	if err := target.List(ctx, &pipelines); err != nil || len(pipelines.Items) != 0 {
+		t.Fatalf("dry run must not create objects, got %d, %v", len(pipelines.Items), err)
+	}
+
+	if _, err := Restore(ctx, target, b, opts); err != nil {
+		t.Fatal(err)
+	}
+	var restored v1alpha1.Pipeline
+	if err := target.Get(ctx, client.ObjectKey{Namespace: "restored", Name: "nginx"}, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Spec.Content != b.Pipelines[0].Spec.Content || len(restored.Status.History) != 1 {
+		t.Fatalf("unexpected restored pipeline %+v", restored)
+	}
+
+	changes, err := Restore(ctx, target, b, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions(changes) != "AgentGroup=unchanged Pipeline=unchanged" {
+		t.Fatalf("second restore = %s", actions(changes))
+	}
+
+	restored.Spec.Content = "inputs: []\n"
+	if err := target.Update(ctx, &restored); err != nil {
+		t.Fatal(err)
+	}
+	changes, err = Restore(ctx, target, b, RestoreOptions{Namespace: "restored", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions(changes) != "AgentGroup=unchanged Pipeline=update" {
+		t.Fatalf("drifted restore = %s", actions(changes))
+	}
+	if diff := changes[1].Diff; !strings.Contains(diff, "+    - Type: input_file") {
+		t.Fatalf("unexpected diff:\n%s", diff)
+	}
+}
+
+func actions(changes []Change) string {
+	var parts []string
+	for _, c := range changes {
+		parts = append(parts, c.Kind+"="+string(c.Action))
+	}
+	return strings.Join(parts, " ")
+}
+
+const secretFlusher = "flushers:\n  - Type: flusher_sls\n    AccessKeySecret: s3cr3t\n"
+
+func contentSourceObjects() []client.Object {
+	return []client.Object{
+		&v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web"},
+			Spec: v1alpha1.PipelineSpec{Name: "web", ContentFrom: &v1alpha1.PipelineContentSource{
+				Fragments: []v1alpha1.PipelineContentFragment{
+					{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "inputs"}, Key: "pipeline.yaml"}},
+					{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "sls"}, Key: "flusher.yaml"}},
+				},
+			}},
+			Status: v1alpha1.PipelineStatus{ContentFromSecret: true, ContentHash: "abc"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "inputs"},
+			Data:       map[string]string{"pipeline.yaml": "inputs:\n  - Type: input_file\n"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "sls"},
+			Data:       map[string][]byte{"flusher.yaml": []byte(secretFlusher), "unused": []byte("x")},
+		},
+	}
+}
+
+// archiveFiles 返回归档中的文件
+func archiveFiles(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(content)
+	}
+}
+
+func TestExportRedactsSecrets(t *testing.T) {
+	ctx := context.Background()
+	reader := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(contentSourceObjects()...).Build()
+	b, err := Export(ctx, reader, ExportOptions{Namespaces: []string{"logging"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.ConfigMaps) != 1 || b.ConfigMaps[0].Data["pipeline.yaml"] == "" {
+		t.Fatalf("referenced configmap must be exported, got %+v", b.ConfigMaps)
+	}
+	want := []Secret{{Namespace: "logging", Name: "sls", Redacted: true, Keys: []string{"flusher.yaml"}}}
+	if !reflect.DeepEqual(b.Secrets, want) {
+		t.Fatalf("secrets = %+v, want %+v", b.Secrets, want)
+	}
+	if b.Pipelines[0].Content != "" {
+		t.Fatalf("content with secret values must not be exported, got %q", b.Pipelines[0].Content)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	files := archiveFiles(t, buf.Bytes())
+	if _, ok := files["content/logging/web.yaml"]; ok {
+		t.Error("content file must be skipped when the content is redacted")
+	}
+	if strings.Contains(files[manifestFile], "s3cr3t") {
+		t.Error("secret values leaked into the bundle")
+	}
+
+	target := fake.NewClientBuilder().WithScheme(newScheme(t)).WithStatusSubresource(&v1alpha1.Pipeline{}).Build()
+	changes, err := Restore(ctx, target, b, RestoreOptions{Namespace: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(changes); got != "ConfigMap=create Secret=missing Pipeline=create" {
+		t.Fatalf("restore = %s", got)
+	}
+}
+
+func TestExportWithSecretsRestoresRenderedContent(t *testing.T) {
+	ctx := context.Background()
+	reader := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(contentSourceObjects()...).Build()
+	exported, err := Export(ctx, reader, ExportOptions{Namespaces: []string{"logging"}, IncludeSecrets: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := exported.Pipelines[0].Content
+	if !strings.Contains(rendered, "input_file") || !strings.Contains(rendered, "flusher_sls") {
+		t.Fatalf("content must be rendered from the referenced objects, got %q", rendered)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, exported); err != nil {
+		t.Fatal(err)
+	}
+	if files := archiveFiles(t, buf.Bytes()); files["content/logging/web.yaml"] != rendered {
+		t.Fatalf("content file = %q, want %q", files["content/logging/web.yaml"], rendered)
+	}
+	b, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := fake.NewClientBuilder().WithScheme(newScheme(t)).WithStatusSubresource(&v1alpha1.Pipeline{}).Build()
+	changes, err := Restore(ctx, target, b, RestoreOptions{Namespace: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(changes); got != "ConfigMap=create Secret=create Pipeline=create" {
+		t.Fatalf("restore = %s", got)
+	}
+	// 恢复后的Pipeline按恢复的对象渲染出相同的配置
+	var restored v1alpha1.Pipeline
+	if err := target.Get(ctx, client.ObjectKey{Namespace: "restored", Name: "web"}, &restored); err != nil {
+		t.Fatal(err)
+	}
+	content, err := contentfrom.Resolve(ctx, target, &restored)
+	if err != nil || content != rendered {
+		t.Fatalf("restored content = %q, %v, want %q", content, err, rendered)
+	}
+
+	changes, err = Restore(ctx, target, b, RestoreOptions{Namespace: "restored", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(changes); got != "ConfigMap=unchanged Secret=unchanged Pipeline=unchanged" {
+		t.Fatalf("second restore = %s", got)
+	}
+}
+
+func TestExportRestoresProfiles(t *testing.T) {
+	ctx := context.Background()
+	reader := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+		&v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "app"},
+			Spec: v1alpha1.PipelineSpec{
+				Name:               "app",
+				Content:            "inputs:\n  - Type: input_file\n",
+				ProcessorChainRefs: []v1alpha1.ProcessorChainReference{{Name: "parse"}},
+				FlusherProfileRefs: []v1alpha1.FlusherProfileReference{{Kind: profile.KindClusterFlusherProfile, Name: "kafka"}},
+			},
+		},
+		&v1alpha1.ProcessorChain{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "parse"},
+			Spec: v1alpha1.ProcessorChainSpec{Processors: []runtime.RawExtension{
+				{Raw: []byte(`{"Type":"processor_parse_json_native"}`)}}},
+		},
+		&v1alpha1.ClusterFlusherProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka"},
+			Spec: v1alpha1.FlusherProfileSpec{Flushers: []runtime.RawExtension{
+				{Raw: []byte(`{"Type":"flusher_kafka_v2"}`)}}},
+		},
+	).Build()
+	exported, err := Export(ctx, reader, ExportOptions{Namespaces: []string{"logging"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := exported.Pipelines[0].Content
+	if !strings.Contains(rendered, "processor_parse_json_native") || !strings.Contains(rendered, "flusher_kafka_v2") {
+		t.Fatalf("content must include the profiles, got %q", rendered)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, exported); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := fake.NewClientBuilder().WithScheme(newScheme(t)).WithStatusSubresource(&v1alpha1.Pipeline{}).Build()
+	changes, err := Restore(ctx, target, b, RestoreOptions{Namespace: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(changes); got != "ClusterFlusherProfile=create ProcessorChain=create Pipeline=create" {
+		t.Fatalf("restore = %s", got)
+	}
+	var restored v1alpha1.Pipeline
+	if err := target.Get(ctx, client.ObjectKey{Namespace: "restored", Name: "app"}, &restored); err != nil {
+		t.Fatal(err)
+	}
+	content, _, err := profile.Render(ctx, target, &restored, restored.Spec.Content)
+	if err != nil || content != rendered {
+		t.Fatalf("restored content = %q, %v, want %q", content, err, rendered)
+	}
+
+	changes, err = Restore(ctx, target, b, RestoreOptions{Namespace: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(changes); got != "ClusterFlusherProfile=unchanged ProcessorChain=unchanged Pipeline=unchanged" {
+		t.Fatalf("second restore = %s", got)
+	}
+}
+
+func TestExportRestoresTemplates(t *testing.T) {
+	ctx := context.Background()
+	reader := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+		&v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "nginx"},
+			Spec: v1alpha1.PipelineSpec{Name: "nginx", TemplateRef: &v1alpha1.PipelineTemplateReference{
+				Name:       "file",
+				Parameters: map[string]apiextensionsv1.JSON{"path": {Raw: []byte(`"/var/log/nginx/access.log"`)}},
+			}},
+		},
+		&v1alpha1.PipelineTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "file"},
+			Spec: v1alpha1.PipelineTemplateSpec{
+				Version:    "1.0.0",
+				Parameters: []v1alpha1.PipelineTemplateParameter{{Name: "path", Required: true}},
+				Content:    "inputs:\n  - Type: input_file\n    FilePaths: [{{ .Params.path }}]\n",
+			},
+		},
+	).Build()
+	exported, err := Export(ctx, reader, ExportOptions{Namespaces: []string{"logging"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered := exported.Pipelines[0].Content
+	if !strings.Contains(rendered, "/var/log/nginx/access.log") {
+		t.Fatalf("content must be rendered from the template, got %q", rendered)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, exported); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := fake.NewClientBuilder().WithScheme(newScheme(t)).WithStatusSubresource(&v1alpha1.Pipeline{}).Build()
+	changes, err := Restore(ctx, target, b, RestoreOptions{Namespace: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(changes); got != "PipelineTemplate=create Pipeline=create" {
+		t.Fatalf("restore = %s", got)
+	}
+	var restored v1alpha1.Pipeline
+	if err := target.Get(ctx, client.ObjectKey{Namespace: "restored", Name: "nginx"}, &restored); err != nil {
+		t.Fatal(err)
+	}
+	content, err := contentfrom.Resolve(ctx, target, &restored)
+	if err != nil || content != rendered {
+		t.Fatalf("restored content = %q, %v, want %q", content, err, rendered)
+	}
+
+	changes, err = Restore(ctx, target, b, RestoreOptions{Namespace: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(changes); got != "PipelineTemplate=unchanged Pipeline=unchanged" {
+		t.Fatalf("second restore = %s", got)
+	}
+}
+
+func TestExportConvertsSourceFormat(t *testing.T) {
+	ctx := context.Background()
+	reader := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+		&v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web"},
+			Spec: v1alpha1.PipelineSpec{
+				Name:         "web",
+				SourceFormat: v1alpha1.SourceFormatFluentBit,
+				Content:      "[INPUT]\n    Name tail\n    Path /var/log/web.log\n\n[OUTPUT]\n    Name stdout\n    Match *\n",
+			},
+		},
+		&v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "loki"},
+			Spec: v1alpha1.PipelineSpec{
+				Name:         "loki",
+				SourceFormat: v1alpha1.SourceFormatFluentBit,
+				Content:      "[INPUT]\n    Name tail\n    Path /var/log/web.log\n\n[OUTPUT]\n    Name loki\n    Match *\n",
+			},
+		},
+	).Build()
+	exported, err := Export(ctx, reader, ExportOptions{Namespaces: []string{"logging"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 按名称排序，loki在前
+	if exported.Pipelines[0].Content != "" || len(exported.Warnings) != 1 || !strings.Contains(exported.Warnings[0], "loki") {
+		t.Fatalf("unconvertible content must be reported as a warning, got %q, %v",
+			exported.Pipelines[0].Content, exported.Warnings)
+	}
+	converted := exported.Pipelines[1].Content
+	if !strings.Contains(converted, "input_file") || strings.Contains(converted, "[INPUT]") {
+		t.Fatalf("content must be converted to a LoongCollector configuration, got %q", converted)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, exported); err != nil {
+		t.Fatal(err)
+	}
+	if files := archiveFiles(t, buf.Bytes()); files["content/logging/web.yaml"] != converted {
+		t.Fatalf("content file = %q, want %q", files["content/logging/web.yaml"], converted)
+	}
+	b, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := fake.NewClientBuilder().WithScheme(newScheme(t)).WithStatusSubresource(&v1alpha1.Pipeline{}).Build()
+	if _, err := Restore(ctx, target, b, RestoreOptions{Namespace: "restored"}); err != nil {
+		t.Fatal(err)
+	}
+	// 恢复后的Pipeline保留源格式，转换出相同的配置
+	var restored v1alpha1.Pipeline
+	if err := target.Get(ctx, client.ObjectKey{Namespace: "restored", Name: "web"}, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Spec.SourceFormat != v1alpha1.SourceFormatFluentBit {
+		t.Fatalf("source format = %q", restored.Spec.SourceFormat)
+	}
+	content, err := contentfrom.Resolve(ctx, target, &restored)
+	if err != nil || content != converted {
+		t.Fatalf("restored content = %q, %v, want %q", content, err, converted)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		name := FileName("nightly", start.Add(time.Duration(i)*time.Hour))
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other-20200101T000000Z.tar.gz"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Prune(dir, "nightly", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{FileName("nightly", start.Add(time.Hour)), FileName("nightly", start)}
+	if !reflect.DeepEqual(removed, want) {
+		t.Fatalf("removed %v, want %v", removed, want)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Fatalf("got %d files left, want 3", len(entries))
+	}
+}
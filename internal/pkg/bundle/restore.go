@@ -0,0 +1,358 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/textdiff"
+)
+
+// Action 恢复时对单个资源执行的操作
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+	// ActionMissing 归档中没有数据的Secret在集群中不存在，需要手动创建
+	ActionMissing Action = "missing"
+)
+
+// Change 恢复一个资源的结果，DryRun时为预期结果
+type Change struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Action    Action
+	// Diff 集群中的spec与归档中spec的统一格式差异，仅ActionUpdate时非空
+	Diff string
+}
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	// Namespace 非空时把所有资源恢复到该命名空间
+	Namespace string
+	// DryRun 只计算变化，不修改集群
+	DryRun bool
+}
+
+// Restore 将归档中的资源创建或更新到集群，重复执行结果相同。Pipeline引用的对象先于Pipeline恢复，
+// 没有数据的Secret只检查是否存在。修订历史只在新建Pipeline时恢复，尽力而为，失败不影响恢复结果
+func Restore(ctx context.Context, c client.Client, b *Bundle, opts RestoreOptions) ([]Change, error) {
+	var changes []Change
+	namespaces := map[string]bool{}
+	ensureNamespace := func(name string) error {
+		if opts.DryRun || namespaces[name] {
+			return nil
+		}
+		namespaces[name] = true
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if err := c.Create(ctx, ns); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create namespace %s: %w", name, err)
+		}
+		return nil
+	}
+
+	for _, entry := range b.ConfigMaps {
+		namespace := targetNamespace(entry.Namespace, opts)
+		if err := ensureNamespace(namespace); err != nil {
+			return changes, err
+		}
+		desired := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: entry.Name, Labels: entry.Labels},
+			Data:       entry.Data,
+		}
+		change, err := restoreObject(ctx, c, "ConfigMap", desired, &corev1.ConfigMap{}, opts.DryRun,
+			func(existing client.Object) (interface{}, interface{}) {
+				return existing.(*corev1.ConfigMap).Data, desired.Data
+			},
+			func(existing client.Object) {
+				existing.(*corev1.ConfigMap).Data = desired.Data
+			})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+
+	for _, entry := range b.Secrets {
+		namespace := targetNamespace(entry.Namespace, opts)
+		if entry.Redacted {
+			change, err := checkSecret(ctx, c, namespace, entry.Name)
+			if err != nil {
+				return changes, err
+			}
+			changes = append(changes, change)
+			continue
+		}
+		if err := ensureNamespace(namespace); err != nil {
+			return changes, err
+		}
+		desired := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: entry.Name, Labels: entry.Labels},
+			Type:       entry.Type,
+			Data:       entry.Data,
+		}
+		change, err := restoreObject(ctx, c, "Secret", desired, &corev1.Secret{}, opts.DryRun,
+			func(existing client.Object) (interface{}, interface{}) {
+				return existing.(*corev1.Secret).Data, desired.Data
+			},
+			func(existing client.Object) {
+				existing.(*corev1.Secret).Data = desired.Data
+			})
+		// 不在diff中输出Secret的值
+		change.Diff = ""
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+
+	for _, entry := range b.Profiles {
+		// 集群级的profile不属于任何命名空间
+		namespace := entry.Namespace
+		if namespace != "" {
+			namespace = targetNamespace(namespace, opts)
+			if err := ensureNamespace(namespace); err != nil {
+				return changes, err
+			}
+		}
+		change, err := restoreProfile(ctx, c, entry, namespace, opts.DryRun)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+
+	for _, entry := range b.Templates {
+		desired := &v1alpha1.PipelineTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: entry.Name, Labels: entry.Labels},
+			Spec:       entry.Spec,
+		}
+		change, err := restoreObject(ctx, c, "PipelineTemplate", desired, &v1alpha1.PipelineTemplate{}, opts.DryRun,
+			func(existing client.Object) (interface{}, interface{}) {
+				return decoded(existing.(*v1alpha1.PipelineTemplate).Spec), decoded(desired.Spec)
+			},
+			func(existing client.Object) {
+				existing.(*v1alpha1.PipelineTemplate).Spec = desired.Spec
+			})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+
+	for _, entry := range b.AgentGroups {
+		namespace := targetNamespace(entry.Namespace, opts)
+		if err := ensureNamespace(namespace); err != nil {
+			return changes, err
+		}
+		desired := &v1alpha1.AgentGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        entry.Name,
+				Labels:      entry.Labels,
+				Annotations: portableAnnotations(entry.Annotations),
+			},
+			Spec: entry.Spec,
+		}
+		change, err := restoreObject(ctx, c, "AgentGroup", desired, &v1alpha1.AgentGroup{}, opts.DryRun,
+			func(existing client.Object) (interface{}, interface{}) {
+				return existing.(*v1alpha1.AgentGroup).Spec, desired.Spec
+			},
+			func(existing client.Object) {
+				existing.(*v1alpha1.AgentGroup).Spec = desired.Spec
+			})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, change)
+	}
+
+	for _, entry := range b.Pipelines {
+		namespace := targetNamespace(entry.Namespace, opts)
+		if err := ensureNamespace(namespace); err != nil {
+			return changes, err
+		}
+		desired := &v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        entry.Name,
+				Labels:      entry.Labels,
+				Annotations: portableAnnotations(entry.Annotations),
+			},
+			Spec: entry.Spec,
+		}
+		change, err := restoreObject(ctx, c, "Pipeline", desired, &v1alpha1.Pipeline{}, opts.DryRun,
+			func(existing client.Object) (interface{}, interface{}) {
+				// 模板参数是原样保存的JSON
+				return decoded(existing.(*v1alpha1.Pipeline).Spec), decoded(desired.Spec)
+			},
+			func(existing client.Object) {
+				existing.(*v1alpha1.Pipeline).Spec = desired.Spec
+			})
+		if err != nil {
+			return changes, err
+		}
+		if change.Action == ActionCreate && !opts.DryRun && len(entry.History) > 0 {
+			desired.Status.History = entry.History
+			_ = c.Status().Update(ctx, desired)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// restoreProfile 恢复一个profile
+func restoreProfile(ctx context.Context, c client.Client, entry Profile, namespace string, dryRun bool) (Change, error) {
+	meta := metav1.ObjectMeta{Namespace: namespace, Name: entry.Name, Labels: entry.Labels}
+	var desired, existing client.Object
+	var spec func(obj client.Object) interface{}
+	var setSpec func(obj client.Object)
+	switch {
+	case entry.Kind == profile.KindProcessorChain && entry.ProcessorChain != nil:
+		desired = &v1alpha1.ProcessorChain{ObjectMeta: meta, Spec: *entry.ProcessorChain}
+		existing = &v1alpha1.ProcessorChain{}
+		spec = func(obj client.Object) interface{} { return obj.(*v1alpha1.ProcessorChain).Spec }
+		setSpec = func(obj client.Object) { obj.(*v1alpha1.ProcessorChain).Spec = *entry.ProcessorChain }
+	case entry.Kind == profile.KindClusterProcessorChain && entry.ProcessorChain != nil:
+		desired = &v1alpha1.ClusterProcessorChain{ObjectMeta: meta, Spec: *entry.ProcessorChain}
+		existing = &v1alpha1.ClusterProcessorChain{}
+		spec = func(obj client.Object) interface{} { return obj.(*v1alpha1.ClusterProcessorChain).Spec }
+		setSpec = func(obj client.Object) { obj.(*v1alpha1.ClusterProcessorChain).Spec = *entry.ProcessorChain }
+	case entry.Kind == profile.KindFlusherProfile && entry.FlusherProfile != nil:
+		desired = &v1alpha1.FlusherProfile{ObjectMeta: meta, Spec: *entry.FlusherProfile}
+		existing = &v1alpha1.FlusherProfile{}
+		spec = func(obj client.Object) interface{} { return obj.(*v1alpha1.FlusherProfile).Spec }
+		setSpec = func(obj client.Object) { obj.(*v1alpha1.FlusherProfile).Spec = *entry.FlusherProfile }
+	case entry.Kind == profile.KindClusterFlusherProfile && entry.FlusherProfile != nil:
+		desired = &v1alpha1.ClusterFlusherProfile{ObjectMeta: meta, Spec: *entry.FlusherProfile}
+		existing = &v1alpha1.ClusterFlusherProfile{}
+		spec = func(obj client.Object) interface{} { return obj.(*v1alpha1.ClusterFlusherProfile).Spec }
+		setSpec = func(obj client.Object) { obj.(*v1alpha1.ClusterFlusherProfile).Spec = *entry.FlusherProfile }
+	default:
+		return Change{Kind: entry.Kind, Namespace: namespace, Name: entry.Name},
+			fmt.Errorf("invalid profile %s %s in bundle", entry.Kind, entry.Name)
+	}
+	// 插件是原样保存的JSON，按解码后的值比较
+	return restoreObject(ctx, c, entry.Kind, desired, existing, dryRun,
+		func(existing client.Object) (interface{}, interface{}) {
+			return decoded(spec(existing)), decoded(spec(desired))
+		},
+		setSpec)
+}
+
+// decoded 返回v编码为JSON再解码后的值，用于比较含有原样保存的JSON的spec，忽略格式差异
+func decoded(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// checkSecret 检查没有导出数据的Secret是否已存在
+func checkSecret(ctx context.Context, c client.Client, namespace, name string) (Change, error) {
+	change := Change{Kind: "Secret", Namespace: namespace, Name: name, Action: ActionUnchanged}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &corev1.Secret{}); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return change, fmt.Errorf("failed to get Secret %s/%s: %w", namespace, name, err)
+		}
+		change.Action = ActionMissing
+	}
+	return change, nil
+}
+
+func targetNamespace(namespace string, opts RestoreOptions) string {
+	if opts.Namespace != "" {
+		return opts.Namespace
+	}
+	return namespace
+}
+
+// restoreObject 不存在时创建desired；存在时比较spec，不同则用update覆盖spec并合并标签与注解
+func restoreObject(ctx context.Context, c client.Client, kind string, desired, existing client.Object, dryRun bool,
+	specs func(existing client.Object) (interface{}, interface{}), update func(existing client.Object)) (Change, error) {
+	change := Change{Kind: kind, Namespace: desired.GetNamespace(), Name: desired.GetName()}
+
+	err := c.Get(ctx, client.ObjectKeyFromObject(desired), existing)
+	if apierrors.IsNotFound(err) {
+		change.Action = ActionCreate
+		if dryRun {
+			return change, nil
+		}
+		if err := c.Create(ctx, desired); err != nil {
+			return change, fmt.Errorf("failed to create %s %s/%s: %w", kind, change.Namespace, change.Name, err)
+		}
+		return change, nil
+	}
+	if err != nil {
+		return change, fmt.Errorf("failed to get %s %s/%s: %w", kind, change.Namespace, change.Name, err)
+	}
+
+	current, wanted := specs(existing)
+	labels := mergeMap(existing.GetLabels(), desired.GetLabels())
+	annotations := mergeMap(existing.GetAnnotations(), desired.GetAnnotations())
+	if equality.Semantic.DeepEqual(current, wanted) &&
+		equality.Semantic.DeepEqual(labels, existing.GetLabels()) &&
+		equality.Semantic.DeepEqual(annotations, existing.GetAnnotations()) {
+		change.Action = ActionUnchanged
+		return change, nil
+	}
+
+	change.Action = ActionUpdate
+	change.Diff = specDiff(change, current, wanted)
+	if dryRun {
+		return change, nil
+	}
+	update(existing)
+	existing.SetLabels(labels)
+	existing.SetAnnotations(annotations)
+	if err := c.Update(ctx, existing); err != nil {
+		return change, fmt.Errorf("failed to update %s %s/%s: %w", kind, change.Namespace, change.Name, err)
+	}
+	return change, nil
+}
+
+// mergeMap 返回base合并overlay后的结果，二者都为空时返回base
+func mergeMap(base, overlay map[string]string) map[string]string {
+	if len(overlay) == 0 {
+		return base
+	}
+	result := make(map[string]string, len(base)+len(overlay))
+	for k, v := range base {
+		result[k] = v
+	}
+	for k, v := range overlay {
+		result[k] = v
+	}
+	return result
+}
+
+// specDiff 以YAML形式比较spec，配置内容按行展开便于阅读
+func specDiff(change Change, current, wanted interface{}) string {
+	from, err := yaml.Marshal(current)
+	if err != nil {
+		return ""
+	}
+	to, err := yaml.Marshal(wanted)
+	if err != nil {
+		return ""
+	}
+	name := fmt.Sprintf("%s %s/%s", change.Kind, change.Namespace, change.Name)
+	return textdiff.Unified(name+" (cluster)", name+" (bundle)", string(from), string(to), 3)
+}
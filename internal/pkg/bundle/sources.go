@@ -0,0 +1,227 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/contentfrom"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/profile"
+)
+
+// ConfigMap 被Pipeline的spec.contentFrom引用的ConfigMap
+type ConfigMap struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// Secret 被Pipeline的spec.contentFrom引用的Secret。默认不导出数据，只记录被引用的键，恢复前需要手动创建
+type Secret struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Type      corev1.SecretType `json:"type,omitempty"`
+	Data      map[string][]byte `json:"data,omitempty"`
+	// Redacted 为true时没有导出数据，Keys为被Pipeline引用的键
+	Redacted bool     `json:"redacted,omitempty"`
+	Keys     []string `json:"keys,omitempty"`
+}
+
+// Profile 被Pipeline引用的ProcessorChain、ClusterProcessorChain、FlusherProfile或ClusterFlusherProfile，
+// 集群级的profile没有命名空间。ProcessorChain与FlusherProfile按Kind设置其一
+type Profile struct {
+	Kind           string                       `json:"kind"`
+	Namespace      string                       `json:"namespace,omitempty"`
+	Name           string                       `json:"name"`
+	Labels         map[string]string            `json:"labels,omitempty"`
+	ProcessorChain *v1alpha1.ProcessorChainSpec `json:"processorChain,omitempty"`
+	FlusherProfile *v1alpha1.FlusherProfileSpec `json:"flusherProfile,omitempty"`
+}
+
+// PipelineTemplate 被Pipeline的spec.templateRef引用的集群级PipelineTemplate
+type PipelineTemplate struct {
+	Name   string                        `json:"name"`
+	Labels map[string]string             `json:"labels,omitempty"`
+	Spec   v1alpha1.PipelineTemplateSpec `json:"spec"`
+}
+
+// exportSources 导出Pipeline引用的ConfigMap、Secret、profile与PipelineTemplate。可选引用的对象不存在时跳过，
+// 其他被引用的对象不存在时同样跳过，恢复后由控制器报告
+func exportSources(ctx context.Context, c client.Reader, b *Bundle, opts ExportOptions) error {
+	configMaps := map[string]bool{}
+	secretKeys := map[string]map[string]bool{}
+	profiles := map[string]bool{}
+	templates := map[string]bool{}
+	for _, p := range b.Pipelines {
+		if ref := p.Spec.TemplateRef; ref != nil {
+			templates[ref.Name] = true
+		}
+		for _, ref := range profile.References(&v1alpha1.Pipeline{Spec: p.Spec}) {
+			kind, name, _ := strings.Cut(ref, "/")
+			namespace := ""
+			if profile.Namespaced(kind) {
+				namespace = p.Namespace
+			}
+			profiles[kind+"/"+key(namespace, name)] = true
+		}
+
+		from := p.Spec.ContentFrom
+		if from == nil || p.Spec.TemplateRef != nil {
+			continue
+		}
+		add := func(configMap *corev1.ConfigMapKeySelector, secret *corev1.SecretKeySelector) {
+			switch {
+			case configMap != nil:
+				configMaps[key(p.Namespace, configMap.Name)] = true
+			case secret != nil:
+				k := key(p.Namespace, secret.Name)
+				if secretKeys[k] == nil {
+					secretKeys[k] = map[string]bool{}
+				}
+				secretKeys[k][secret.Key] = true
+			}
+		}
+		add(from.ConfigMapKeyRef, from.SecretKeyRef)
+		for _, fragment := range from.Fragments {
+			add(fragment.ConfigMapKeyRef, fragment.SecretKeyRef)
+		}
+	}
+
+	for _, k := range sortedKeys(configMaps) {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, splitKey(k), cm); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get configmap %s: %w", k, err)
+		}
+		b.ConfigMaps = append(b.ConfigMaps, ConfigMap{
+			Namespace: cm.Namespace,
+			Name:      cm.Name,
+			Labels:    cm.Labels,
+			Data:      cm.Data,
+		})
+	}
+
+	for _, k := range sortedKeys(secretKeys) {
+		ref := splitKey(k)
+		if !opts.IncludeSecrets {
+			b.Secrets = append(b.Secrets, Secret{
+				Namespace: ref.Namespace,
+				Name:      ref.Name,
+				Redacted:  true,
+				Keys:      sortedKeys(secretKeys[k]),
+			})
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, ref, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get secret %s: %w", k, err)
+		}
+		b.Secrets = append(b.Secrets, Secret{
+			Namespace: secret.Namespace,
+			Name:      secret.Name,
+			Labels:    secret.Labels,
+			Type:      secret.Type,
+			Data:      secret.Data,
+		})
+	}
+
+	for _, k := range sortedKeys(profiles) {
+		kind, rest, _ := strings.Cut(k, "/")
+		entry, err := getProfile(ctx, c, kind, splitKey(rest))
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get %s %s: %w", kind, rest, err)
+		}
+		b.Profiles = append(b.Profiles, entry)
+	}
+
+	for _, name := range sortedKeys(templates) {
+		tpl := &v1alpha1.PipelineTemplate{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, tpl); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get pipelinetemplate %s: %w", name, err)
+		}
+		b.Templates = append(b.Templates, PipelineTemplate{Name: tpl.Name, Labels: tpl.Labels, Spec: tpl.Spec})
+	}
+	return nil
+}
+
+// getProfile 读取一个profile
+func getProfile(ctx context.Context, c client.Reader, kind string, ref client.ObjectKey) (Profile, error) {
+	entry := Profile{Kind: kind, Namespace: ref.Namespace, Name: ref.Name}
+	var obj client.Object
+	switch kind {
+	case profile.KindProcessorChain:
+		chain := &v1alpha1.ProcessorChain{}
+		obj, entry.ProcessorChain = chain, &chain.Spec
+	case profile.KindClusterProcessorChain:
+		chain := &v1alpha1.ClusterProcessorChain{}
+		obj, entry.ProcessorChain = chain, &chain.Spec
+	case profile.KindFlusherProfile:
+		flushers := &v1alpha1.FlusherProfile{}
+		obj, entry.FlusherProfile = flushers, &flushers.Spec
+	case profile.KindClusterFlusherProfile:
+		flushers := &v1alpha1.ClusterFlusherProfile{}
+		obj, entry.FlusherProfile = flushers, &flushers.Spec
+	default:
+		return entry, fmt.Errorf("unknown profile kind %q", kind)
+	}
+	if err := c.Get(ctx, ref, obj); err != nil {
+		return entry, err
+	}
+	entry.Labels = obj.GetLabels()
+	return entry, nil
+}
+
+// renderedContent 返回Pipeline下发的配置：上次下发的内容，未记录时按引用的对象重新渲染。
+// 配置包含Secret中的值而未导出Secret时返回空
+func renderedContent(ctx context.Context, c client.Reader, p *v1alpha1.Pipeline, opts ExportOptions) (string, error) {
+	readsSecret, err := contentfrom.ReadsSecret(ctx, c, p)
+	if err != nil {
+		return "", err
+	}
+	if readsSecret && !opts.IncludeSecrets {
+		return "", nil
+	}
+	if last := p.Status.LastAppliedConfig.Content; last != "" {
+		return last, nil
+	}
+	content, err := contentfrom.Resolve(ctx, c, p)
+	if err != nil {
+		return "", err
+	}
+	content, _, err = profile.Render(ctx, c, p, content)
+	return content, err
+}
+
+// splitKey 把key的结果还原为对象的namespace与name
+func splitKey(k string) client.ObjectKey {
+	namespace, name, _ := strings.Cut(k, "/")
+	return client.ObjectKey{Namespace: namespace, Name: name}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,174 @@
+package configserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器打开时请求不会发往Config-Server
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State 熔断器状态
+type State int
+
+// States 全部熔断器状态
+var States = []State{StateClosed, StateOpen, StateHalfOpen}
+
+const (
+	// StateClosed 正常放行请求
+	StateClosed State = iota
+	// StateOpen 连续失败达到阈值，拒绝请求，等待定时探测
+	StateOpen
+	// StateHalfOpen 正在探测Config-Server是否恢复
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker 单个Config-Server端点的熔断器。连续失败threshold次后打开，
+// 打开后每隔interval执行一次probe，探测成功后关闭
+type Breaker struct {
+	threshold int
+	interval  time.Duration
+	probe     func(ctx context.Context) error
+
+	mu            sync.Mutex
+	state         State
+	failures      int
+	timer         *time.Timer
+	onStateChange func(State)
+}
+
+// NewBreaker 创建熔断器，probe为空时打开interval后直接进入关闭状态重新放行
+func NewBreaker(threshold int, interval time.Duration, probe func(ctx context.Context) error) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		threshold: threshold,
+		interval:  interval,
+		probe:     probe,
+	}
+}
+
+// OnStateChange 设置状态切换的回调，回调在持有锁时调用，不能再访问熔断器
+func (b *Breaker) OnStateChange(fn func(State)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onStateChange = fn
+}
+
+// Allow 熔断器关闭时返回nil，否则返回ErrCircuitOpen
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != StateClosed {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// State 返回当前状态
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// RecordSuccess 记录一次成功，清零失败计数并关闭熔断器
+func (b *Breaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.closeLocked()
+}
+
+// RecordFailure 记录一次失败，连续失败达到阈值时打开熔断器
+func (b *Breaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == StateClosed && b.failures >= b.threshold {
+		b.openLocked()
+	}
+}
+
+// Stop 停止定时探测
+func (b *Breaker) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
+
+func (b *Breaker) setStateLocked(state State) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(state)
+	}
+}
+
+func (b *Breaker) openLocked() {
+	b.setStateLocked(StateOpen)
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.timer = time.AfterFunc(b.interval, b.runProbe)
+}
+
+func (b *Breaker) closeLocked() {
+	b.setStateLocked(StateClosed)
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
+
+// runProbe 定时探测：进入半开状态执行probe，成功则关闭，失败则重新打开等待下一次探测
+func (b *Breaker) runProbe() {
+	b.mu.Lock()
+	if b.state != StateOpen {
+		b.mu.Unlock()
+		return
+	}
+	b.setStateLocked(StateHalfOpen)
+	b.timer = nil
+	b.mu.Unlock()
+
+	var err error
+	if b.probe != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), b.interval)
+		err = b.probe(ctx)
+		cancel()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != StateHalfOpen {
+		return
+	}
+	if err != nil {
+		b.openLocked()
+		return
+	}
+	b.failures = 0
+	b.closeLocked()
+}
@@ -0,0 +1,162 @@
+package configserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := NewBreaker(3, time.Hour, nil)
+	defer b.Stop()
+
+	for i := 0; i < 2; i++ {
+		b.RecordFailure()
+		if err := b.Allow(); err != nil {
+			t.Fatalf("failure %d: breaker opened too early", i+1)
+		}
+	}
+	b.RecordFailure()
+	if !errors.Is(b.Allow(), ErrCircuitOpen) {
+		t.Fatalf("expected the breaker to be open, state %s", b.State())
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := NewBreaker(2, time.Hour, nil)
+	defer b.Stop()
+
+	b.RecordFailure()
+	b.RecordSuccess()
+	b.RecordFailure()
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+}
+
+func TestBreakerProbeClosesOnRecovery(t *testing.T) {
+	var healthy atomic.Bool
+	b := NewBreaker(1, 10*time.Millisecond, func(context.Context) error {
+		if healthy.Load() {
+			return nil
+		}
+		return errors.New("down")
+	})
+	defer b.Stop()
+
+	b.RecordFailure()
+	time.Sleep(50 * time.Millisecond)
+	if b.State() == StateClosed {
+		t.Fatal("breaker closed while the probe is failing")
+	}
+
+	healthy.Store(true)
+	waitForState(t, b, StateClosed)
+}
+
+func TestRegistrySharesClientAndFailsFast(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	registry := NewRegistry(RegistryOptions{FailureThreshold: 2, ProbeInterval: time.Hour})
+	defer registry.Stop()
+	registry.SetDefault(server.URL)
+	if registry.Client(server.URL) != registry.Client(server.URL) {
+		t.Fatal("expected the client to be shared per endpoint")
+	}
+
+	client := registry.Client(server.URL)
+	for i := 0; i < 2; i++ {
+		if err := client.DeleteConfig(context.Background(), "a"); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if err := registry.HealthCheck(nil); err == nil {
+		t.Fatal("expected the health check to fail while the breaker is open")
+	}
+
+	err := client.DeleteConfig(context.Background(), "a")
+	if !errors.Is(err, ErrCircuitOpen) || !IsRetryable(err) {
+		t.Fatalf("expected a retryable ErrCircuitOpen, got %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected 2 calls to reach the server, got %d", got)
+	}
+}
+
+func TestRegistryClientErrorsDoNotOpen(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	registry := NewRegistry(RegistryOptions{FailureThreshold: 1, ProbeInterval: time.Hour})
+	defer registry.Stop()
+	client := registry.Client(server.URL)
+	for i := 0; i < 3; i++ {
+		_ = client.DeleteConfig(context.Background(), "a")
+	}
+	if err := registry.HealthCheck(nil); err != nil {
+		t.Fatalf("4xx responses must not open the breaker: %v", err)
+	}
+}
+
+func TestRegistryHealthCheckOnlyChecksDefault(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	registry := NewRegistry(RegistryOptions{FailureThreshold: 1, ProbeInterval: time.Hour})
+	defer registry.Stop()
+	registry.SetDefault("http://default")
+	_ = registry.Client(down.URL).DeleteConfig(context.Background(), "a")
+	if registry.States()[down.URL] != StateOpen {
+		t.Fatalf("expected the breaker of %s to open", down.URL)
+	}
+	if err := registry.HealthCheck(nil); err != nil {
+		t.Fatalf("a remote endpoint must not affect the health check: %v", err)
+	}
+}
+
+func TestRegistryEvictsIdleEndpoints(t *testing.T) {
+	now := time.Now()
+	registry := NewRegistry(RegistryOptions{ProbeInterval: time.Hour, IdleTimeout: time.Minute})
+	defer registry.Stop()
+	registry.now = func() time.Time { return now }
+	registry.SetDefault("http://default")
+	registry.Client("http://default")
+	remote := registry.Client("http://remote")
+	registry.Client("http://used")
+
+	now = now.Add(2 * time.Minute)
+	registry.Client("http://used")
+	registry.evictIdle()
+	states := registry.States()
+	if _, ok := states["http://remote"]; ok || len(states) != 2 {
+		t.Fatalf("expected only the idle remote endpoint to be evicted, got %v", states)
+	}
+	if registry.Client("http://remote") == remote {
+		t.Error("expected a new client after eviction")
+	}
+}
+
+func waitForState(t *testing.T, b *Breaker, want State) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if b.State() == want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("breaker state %s, want %s", b.State(), want)
+}
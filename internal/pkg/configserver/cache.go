@@ -0,0 +1,168 @@
+package configserver
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL 缓存条目的最长有效期，超过后读取时重新获取
+const DefaultCacheTTL = 2 * time.Minute
+
+// cache 缓存Agent组、配置列表与Agent组已关联的配置。写操作使相关条目失效，
+// 由Registry定期刷新，条目超过ttl时在读取时重新获取。
+// 每类条目有一个代数，失效时加一；获取开始后代数变化的结果可能早于写操作，丢弃而不写入缓存
+type cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu         sync.Mutex
+	groups     *cacheEntry[[]AgentGroup]
+	configs    *cacheEntry[[]ConfigDetail]
+	applied    map[string]*cacheEntry[[]string]
+	groupsGen  uint64
+	configsGen uint64
+	appliedGen map[string]uint64
+}
+
+type cacheEntry[T any] struct {
+	value   T
+	fetched time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &cache{
+		ttl:        ttl,
+		now:        time.Now,
+		applied:    map[string]*cacheEntry[[]string]{},
+		appliedGen: map[string]uint64{},
+	}
+}
+
+// load 返回未过期的缓存值，否则调用fetch并在代数未变化时写入缓存。get、set与gen在持有锁时调用
+func load[T any](c *cache, get func() *cacheEntry[T], set func(*cacheEntry[T]), gen func() uint64,
+	fetch func() (T, error)) (T, error) {
+	c.mu.Lock()
+	if e := get(); e != nil && c.now().Sub(e.fetched) < c.ttl {
+		value := e.value
+		c.mu.Unlock()
+		return value, nil
+	}
+	started := gen()
+	c.mu.Unlock()
+
+	fetched := c.now()
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+	c.mu.Lock()
+	if gen() == started {
+		set(&cacheEntry[T]{value: value, fetched: fetched})
+	}
+	c.mu.Unlock()
+	return value, nil
+}
+
+func (c *cache) agentGroups(fetch func() ([]AgentGroup, error)) ([]AgentGroup, error) {
+	groups, err := load(c,
+		func() *cacheEntry[[]AgentGroup] { return c.groups },
+		func(e *cacheEntry[[]AgentGroup]) { c.groups = e },
+		func() uint64 { return c.groupsGen },
+		fetch)
+	return append([]AgentGroup(nil), groups...), err
+}
+
+func (c *cache) configList(fetch func() ([]ConfigDetail, error)) ([]ConfigDetail, error) {
+	configs, err := load(c,
+		func() *cacheEntry[[]ConfigDetail] { return c.configs },
+		func(e *cacheEntry[[]ConfigDetail]) { c.configs = e },
+		func() uint64 { return c.configsGen },
+		fetch)
+	return append([]ConfigDetail(nil), configs...), err
+}
+
+func (c *cache) appliedConfigs(group string, fetch func() ([]string, error)) ([]string, error) {
+	configs, err := load(c,
+		func() *cacheEntry[[]string] { return c.applied[group] },
+		func(e *cacheEntry[[]string]) { c.applied[group] = e },
+		func() uint64 { return c.appliedGen[group] },
+		fetch)
+	return append([]string(nil), configs...), err
+}
+
+// appliedGroups 返回已缓存关联配置的Agent组，用于定期刷新
+func (c *cache) appliedGroups() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	groups := make([]string, 0, len(c.applied))
+	for group := range c.applied {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func (c *cache) invalidateGroups() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.groups = nil
+	c.groupsGen++
+}
+
+func (c *cache) invalidateConfigs() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configs = nil
+	c.configsGen++
+}
+
+func (c *cache) invalidateApplied(group string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.applied, group)
+	c.appliedGen[group]++
+}
+
+// refresh 绕过缓存重新获取所有条目，刷新期间读取仍返回旧值。获取期间失效的条目不写入
+func (c *cache) refresh(ctx context.Context, client *ConfigServerClient) error {
+	c.mu.Lock()
+	groupsGen, configsGen := c.groupsGen, c.configsGen
+	c.mu.Unlock()
+	fetched := c.now()
+	groups, err := client.listAgentGroups(ctx)
+	if err != nil {
+		return err
+	}
+	configs, err := client.listConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.groupsGen == groupsGen {
+		c.groups = &cacheEntry[[]AgentGroup]{value: groups, fetched: fetched}
+	}
+	if c.configsGen == configsGen {
+		c.configs = &cacheEntry[[]ConfigDetail]{value: configs, fetched: fetched}
+	}
+	c.mu.Unlock()
+
+	for _, group := range c.appliedGroups() {
+		c.mu.Lock()
+		gen := c.appliedGen[group]
+		c.mu.Unlock()
+		fetched := c.now()
+		applied, err := client.getAppliedConfigsForAgentGroup(ctx, group)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		if c.appliedGen[group] == gen {
+			c.applied[group] = &cacheEntry[[]string]{value: applied, fetched: fetched}
+		}
+		c.mu.Unlock()
+	}
+	return nil
+}
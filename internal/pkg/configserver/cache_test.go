@@ -0,0 +1,196 @@
+package configserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingServer 记录每个接口的请求次数
+type countingServer struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *countingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint := endpointName(r.URL.Path)
+	s.mu.Lock()
+	s.calls[endpoint]++
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch endpoint {
+	case "ListAgentGroups":
+		fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":[{"name":"default"}]}`)
+	case "ListConfigs":
+		fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":[{"name":"nginx"}]}`)
+	case "GetAppliedConfigsForAgentGroup":
+		fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":["nginx"]}`)
+	default:
+		fmt.Fprint(w, `{"code":200,"message":"ACCEPT"}`)
+	}
+}
+
+func (s *countingServer) count(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[endpoint]
+}
+
+func newCachedClient(t *testing.T) (*ConfigServerClient, *countingServer) {
+	t.Helper()
+	counter := &countingServer{calls: map[string]int{}}
+	server := httptest.NewServer(counter)
+	t.Cleanup(server.Close)
+
+	registry := NewRegistry(RegistryOptions{RefreshInterval: time.Hour})
+	t.Cleanup(registry.Stop)
+	return registry.Client(server.URL), counter
+}
+
+func TestCacheServesRepeatedReads(t *testing.T) {
+	client, counter := newCachedClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		groups, err := client.ListAgentGroups(ctx)
+		if err != nil || len(groups) != 1 || groups[0].Name != "default" {
+			t.Fatalf("unexpected result %v, %v", groups, err)
+		}
+		if _, err := client.GetAppliedConfigsForAgentGroup(ctx, "default"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.ListConfigs(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, endpoint := range []string{"ListAgentGroups", "GetAppliedConfigsForAgentGroup", "ListConfigs"} {
+		if got := counter.count(endpoint); got != 1 {
+			t.Errorf("%s called %d times, want 1", endpoint, got)
+		}
+	}
+}
+
+func TestCacheInvalidatedOnWrites(t *testing.T) {
+	client, counter := newCachedClient(t)
+	ctx := context.Background()
+
+	_, _ = client.ListAgentGroups(ctx)
+	_, _ = client.GetAppliedConfigsForAgentGroup(ctx, "default")
+	_, _ = client.GetAppliedConfigsForAgentGroup(ctx, "other")
+	_, _ = client.ListConfigs(ctx)
+
+	if err := client.ApplyConfigToAgentGroup(ctx, "nginx", "default"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DeleteConfig(ctx, "nginx"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = client.ListAgentGroups(ctx)
+	_, _ = client.GetAppliedConfigsForAgentGroup(ctx, "default")
+	_, _ = client.GetAppliedConfigsForAgentGroup(ctx, "other")
+	_, _ = client.ListConfigs(ctx)
+
+	if got := counter.count("ListAgentGroups"); got != 2 {
+		t.Errorf("ListAgentGroups called %d times, want 2", got)
+	}
+	if got := counter.count("ListConfigs"); got != 2 {
+		t.Errorf("ListConfigs called %d times, want 2", got)
+	}
+	// 只有被修改的组重新获取
+	if got := counter.count("GetAppliedConfigsForAgentGroup"); got != 3 {
+		t.Errorf("GetAppliedConfigsForAgentGroup called %d times, want 3", got)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	client, counter := newCachedClient(t)
+	ctx := context.Background()
+	now := time.Now()
+	client.cache.now = func() time.Time { return now }
+
+	_, _ = client.ListAgentGroups(ctx)
+	now = now.Add(client.cache.ttl + time.Second)
+	_, _ = client.ListAgentGroups(ctx)
+
+	if got := counter.count("ListAgentGroups"); got != 2 {
+		t.Errorf("ListAgentGroups called %d times, want 2", got)
+	}
+}
+
+func TestRefreshReloadsKnownEntries(t *testing.T) {
+	client, counter := newCachedClient(t)
+	ctx := context.Background()
+
+	_, _ = client.GetAppliedConfigsForAgentGroup(ctx, "default")
+	if err := client.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.ListAgentGroups(ctx)
+	_, _ = client.ListConfigs(ctx)
+	_, _ = client.GetAppliedConfigsForAgentGroup(ctx, "default")
+
+	got := []string{}
+	for _, endpoint := range []string{"ListAgentGroups", "ListConfigs", "GetAppliedConfigsForAgentGroup"} {
+		got = append(got, fmt.Sprintf("%s=%d", endpoint, counter.count(endpoint)))
+	}
+	want := "ListAgentGroups=1 ListConfigs=1 GetAppliedConfigsForAgentGroup=2"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("got %s, want %s", strings.Join(got, " "), want)
+	}
+}
+
+func TestUncachedClientAlwaysFetches(t *testing.T) {
+	counter := &countingServer{calls: map[string]int{}}
+	server := httptest.NewServer(counter)
+	defer server.Close()
+
+	client := NewConfigServerClient(server.URL, nil, "default")
+	_, _ = client.ListAgentGroups(context.Background())
+	_, _ = client.ListAgentGroups(context.Background())
+	if got := counter.count("ListAgentGroups"); got != 2 {
+		t.Fatalf("ListAgentGroups called %d times, want 2", got)
+	}
+}
+
+func TestCacheDropsFetchesRacingInvalidation(t *testing.T) {
+	c := newCache(time.Hour)
+	fetches := 0
+	// 获取期间发生写操作，返回的是写之前的状态
+	stale := func() ([]string, error) {
+		fetches++
+		c.invalidateApplied("web")
+		return []string{"old"}, nil
+	}
+	if got, _ := c.appliedConfigs("web", stale); len(got) != 1 || got[0] != "old" {
+		t.Fatalf("appliedConfigs = %v", got)
+	}
+	fresh := func() ([]string, error) {
+		fetches++
+		return []string{"new"}, nil
+	}
+	if got, _ := c.appliedConfigs("web", fresh); len(got) != 1 || got[0] != "new" {
+		t.Fatalf("the racing fetch was cached: %v", got)
+	}
+	if got, _ := c.appliedConfigs("web", fresh); got[0] != "new" || fetches != 2 {
+		t.Fatalf("expected the fresh result to be cached, got %v after %d fetches", got, fetches)
+	}
+
+	if _, err := c.agentGroups(func() ([]AgentGroup, error) {
+		c.invalidateGroups()
+		return []AgentGroup{{Name: "old"}}, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.groups != nil {
+		t.Errorf("the racing agent group fetch was cached: %v", c.groups.value)
+	}
+}
@@ -3,11 +3,12 @@ package configserver
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
-	"gopkg.in/yaml.v2"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -15,39 +16,52 @@ import (
 type ConfigServerClient struct {
 	client    *resty.Client
 	namespace string
+	// cache 为空时每次读取都请求Config-Server
+	cache *cache
 }
 
-// NewConfigServerClient creates a new config server client
+// NewConfigServerClient creates a new config server client without a circuit breaker.
+// Controllers should use Registry.Client to share one client per endpoint.
 func NewConfigServerClient(baseURL string, kubernetesClient *client.Client, namespace string) *ConfigServerClient {
-	client := resty.New().
-		SetBaseURL(baseURL).
-		SetTimeout(10*time.Second).
-		SetHeader("Content-Type", "application/json").
-		SetRetryCount(3).
-		SetRetryWaitTime(1 * time.Second).
-		SetRetryMaxWaitTime(5 * time.Second)
-
 	return &ConfigServerClient{
-		client:    client,
+		client:    newRestyClient(baseURL),
 		namespace: namespace,
 	}
 }
 
+// NewConfigServerClientFor creates a config server client sending requests through httpClient,
+// e.g. one reaching Config-Server through the Kubernetes API server service proxy.
+func NewConfigServerClientFor(baseURL string, httpClient *http.Client) *ConfigServerClient {
+	return &ConfigServerClient{
+		client: configureRestyClient(resty.NewWithClient(httpClient), baseURL),
+	}
+}
+
+func newRestyClient(baseURL string) *resty.Client {
+	return configureRestyClient(resty.New(), baseURL)
+}
+
+func configureRestyClient(c *resty.Client, baseURL string) *resty.Client {
+	return c.
+		SetBaseURL(baseURL).
+		SetTimeout(10*time.Second).
+		SetHeader("Content-Type", "application/json").
+		OnBeforeRequest(startSpan).
+		OnSuccess(observeResponse).
+		OnSuccess(endSpanOnResponse).
+		OnError(observeError).
+		OnError(endSpanOnError)
+}
+
 // CreateConfig 创建配置
 func (a *ConfigServerClient) CreateConfig(ctx context.Context, pipeline *v1alpha1.Pipeline) error {
-	var config map[string]interface{}
-	var response response
+	defer a.invalidateConfigs()
 
-	if err := yaml.Unmarshal([]byte(pipeline.Spec.Content), &config); err != nil {
-		return fmt.Errorf("failed to parse YAML config: %v", err)
-	}
+	var response response
 
-	payload := map[string]interface{}{
-		"config_name": pipeline.Spec.Name,
-		"config_detail": map[string]interface{}{
-			"name":    pipeline.Spec.Name,
-			"content": config,
-		},
+	payload, err := render.Payload(pipeline.Spec.Name, pipeline.Spec.Content)
+	if err != nil {
+		return invalidContentError("CreateConfig", err)
 	}
 
 	resp, err := a.client.R().
@@ -57,15 +71,11 @@ func (a *ConfigServerClient) CreateConfig(ctx context.Context, pipeline *v1alpha
 		Post("/User/CreateConfig")
 
 	if err != nil {
-		return fmt.Errorf("failed to send request to configserver: %v", err)
-	}
-
-	if resp.StatusCode() != 200 {
-		return fmt.Errorf("configserver returned status %d: %s", resp.StatusCode(), resp.String())
+		return requestError("CreateConfig", err)
 	}
 
-	if response.Code != 200 {
-		return fmt.Errorf("configserver returned error: %s", response.Message)
+	if resp.StatusCode() != 200 || response.Code != 200 {
+		return responseError("CreateConfig", resp, response)
 	}
 
 	return nil
@@ -73,16 +83,18 @@ func (a *ConfigServerClient) CreateConfig(ctx context.Context, pipeline *v1alpha
 
 // DeleteConfig 从Config-Server删除配置
 func (a *ConfigServerClient) DeleteConfig(ctx context.Context, configName string) error {
+	defer a.invalidateConfigs()
+
 	resp, err := a.client.R().
 		SetContext(ctx).
 		Delete(fmt.Sprintf("/User/DeleteConfig/%s", configName))
 
 	if err != nil {
-		return fmt.Errorf("failed to send delete request to configserver: %v", err)
+		return requestError("DeleteConfig", err)
 	}
 
 	if resp.StatusCode() != 200 && resp.StatusCode() != 404 {
-		return fmt.Errorf("configserver returned status %d: %s", resp.StatusCode(), resp.String())
+		return statusError("DeleteConfig", resp)
 	}
 
 	return nil
@@ -90,19 +102,21 @@ func (a *ConfigServerClient) DeleteConfig(ctx context.Context, configName string
 
 // CreateAgentGroup creates a new agent group
 func (a *ConfigServerClient) CreateAgentGroup(ctx context.Context, group *AgentGroup) error {
+	defer a.invalidateGroups()
+
 	var response response
-	_, err := a.client.R().
+	resp, err := a.client.R().
 		SetContext(ctx).
 		SetBody(group).
 		SetResult(&response).
 		Post("/User/CreateAgentGroup")
 
 	if err != nil {
-		return fmt.Errorf("failed to send request to configserver: %v", err)
+		return requestError("CreateAgentGroup", err)
 	}
 
-	if response.Code != 200 || response.Message != "ACCEPT" {
-		return fmt.Errorf("configserver returned error: %s", response.Message)
+	if resp.StatusCode() != 200 || response.Code != 200 || response.Message != "ACCEPT" {
+		return responseError("CreateAgentGroup", resp, response)
 	}
 
 	return nil
@@ -110,20 +124,22 @@ func (a *ConfigServerClient) CreateAgentGroup(ctx context.Context, group *AgentG
 
 // UpdateAgentGroup updates an existing agent group
 func (a *ConfigServerClient) UpdateAgentGroup(ctx context.Context, group *AgentGroup) error {
+	defer a.invalidateGroups()
+
 	var response response
 
-	_, err := a.client.R().
+	resp, err := a.client.R().
 		SetContext(ctx).
 		SetBody(group).
 		SetResult(&response).
 		Put("/User/UpdateAgentGroup")
 
 	if err != nil {
-		return fmt.Errorf("failed to send request to configserver: %v", err)
+		return requestError("UpdateAgentGroup", err)
 	}
 
-	if response.Code != 200 || response.Message != "ACCEPT" {
-		return fmt.Errorf("configserver returned error: %s", response.Message)
+	if resp.StatusCode() != 200 || response.Code != 200 || response.Message != "ACCEPT" {
+		return responseError("UpdateAgentGroup", resp, response)
 	}
 
 	return nil
@@ -131,16 +147,18 @@ func (a *ConfigServerClient) UpdateAgentGroup(ctx context.Context, group *AgentG
 
 // DeleteAgentGroup deletes an agent group
 func (a *ConfigServerClient) DeleteAgentGroup(ctx context.Context, groupName string) error {
+	defer a.invalidateGroup(groupName)
+
 	resp, err := a.client.R().
 		SetContext(ctx).
 		Delete(fmt.Sprintf("/User/DeleteAgentGroup/%s", groupName))
 
 	if err != nil {
-		return fmt.Errorf("failed to send delete request to configserver: %v", err)
+		return requestError("DeleteAgentGroup", err)
 	}
 
 	if resp.StatusCode() != 200 && resp.StatusCode() != 404 {
-		return fmt.Errorf("configserver returned status %d: %s", resp.StatusCode(), resp.String())
+		return statusError("DeleteAgentGroup", resp)
 	}
 
 	return nil
@@ -148,24 +166,26 @@ func (a *ConfigServerClient) DeleteAgentGroup(ctx context.Context, groupName str
 
 // ApplyConfigToAgentGroup 将配置应用到Agent组
 func (a *ConfigServerClient) ApplyConfigToAgentGroup(ctx context.Context, configName, groupName string) error {
+	defer a.invalidateGroup(groupName)
+
 	var response response
 	payload := map[string]string{
 		"config_name": configName,
 		"group_name":  groupName,
 	}
 
-	_, err := a.client.R().
+	resp, err := a.client.R().
 		SetContext(ctx).
 		SetBody(payload).
 		SetResult(&response).
 		Post("/User/ApplyConfigToAgentGroup")
 
 	if err != nil {
-		return fmt.Errorf("failed to send request to configserver: %v", err)
+		return requestError("ApplyConfigToAgentGroup", err)
 	}
 
-	if response.Code != 200 || response.Message != "ACCEPT" {
-		return fmt.Errorf("configserver returned error: %s", response.Message)
+	if resp.StatusCode() != 200 || response.Code != 200 || response.Message != "ACCEPT" {
+		return responseError("ApplyConfigToAgentGroup", resp, response)
 	}
 
 	return nil
@@ -173,41 +193,193 @@ func (a *ConfigServerClient) ApplyConfigToAgentGroup(ctx context.Context, config
 
 // RemoveConfigFromAgentGroup 从Agent组中移除配置
 func (a *ConfigServerClient) RemoveConfigFromAgentGroup(ctx context.Context, configName, groupName string) error {
+	defer a.invalidateGroup(groupName)
+
 	resp, err := a.client.R().
 		SetContext(ctx).
 		Delete(fmt.Sprintf("/User/RemoveConfigFromAgentGroup/%s/%s", configName, groupName))
 
 	if err != nil {
-		return fmt.Errorf("failed to send delete request to configserver: %v", err)
+		return requestError("RemoveConfigFromAgentGroup", err)
 	}
 
 	if resp.StatusCode() != 200 && resp.StatusCode() != 404 {
-		return fmt.Errorf("configserver returned status %d: %s", resp.StatusCode(), resp.String())
+		return statusError("RemoveConfigFromAgentGroup", resp)
 	}
 
 	return nil
 }
 
-// ListAgentGroups 列出所有Agent组
+// ListAgentGroups 列出所有Agent组，优先读取缓存
 func (a *ConfigServerClient) ListAgentGroups(ctx context.Context) ([]AgentGroup, error) {
+	if a.cache == nil {
+		return a.listAgentGroups(ctx)
+	}
+	return a.cache.agentGroups(func() ([]AgentGroup, error) { return a.listAgentGroups(ctx) })
+}
+
+func (a *ConfigServerClient) listAgentGroups(ctx context.Context) ([]AgentGroup, error) {
 	var response struct {
-		Code       int          `json:"code"`
-		Message    string       `json:"message"`
+		response
 		AgentGroup []AgentGroup `json:"data"`
 	}
 
-	_, err := a.client.R().
+	resp, err := a.client.R().
 		SetContext(ctx).
 		SetResult(&response).
 		Get("/User/ListAgentGroups")
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request to configserver: %v", err)
+		return nil, requestError("ListAgentGroups", err)
 	}
 
-	if response.Code != 200 {
-		return nil, fmt.Errorf("configserver returned error: %s", response.Message)
+	if resp.StatusCode() != 200 || response.Code != 200 {
+		return nil, responseError("ListAgentGroups", resp, response.response)
 	}
 
 	return response.AgentGroup, nil
 }
+
+// GetAppliedConfigsForAgentGroup 获取Agent组已关联的配置，优先读取缓存
+func (a *ConfigServerClient) GetAppliedConfigsForAgentGroup(ctx context.Context, groupName string) ([]string, error) {
+	if a.cache == nil {
+		return a.getAppliedConfigsForAgentGroup(ctx, groupName)
+	}
+	return a.cache.appliedConfigs(groupName, func() ([]string, error) {
+		return a.getAppliedConfigsForAgentGroup(ctx, groupName)
+	})
+}
+
+func (a *ConfigServerClient) getAppliedConfigsForAgentGroup(ctx context.Context, groupName string) ([]string, error) {
+	var response struct {
+		response
+		ConfigNames []string `json:"data"`
+	}
+
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetResult(&response).
+		Get(fmt.Sprintf("/User/GetAppliedConfigsForAgentGroup/%s", groupName))
+
+	if err != nil {
+		return nil, requestError("GetAppliedConfigsForAgentGroup", err)
+	}
+
+	if resp.StatusCode() == 404 {
+		return nil, nil
+	}
+
+	if resp.StatusCode() != 200 || response.Code != 200 {
+		return nil, responseError("GetAppliedConfigsForAgentGroup", resp, response.response)
+	}
+
+	return response.ConfigNames, nil
+}
+
+// ListConfigs 列出所有配置，优先读取缓存
+func (a *ConfigServerClient) ListConfigs(ctx context.Context) ([]ConfigDetail, error) {
+	if a.cache == nil {
+		return a.listConfigs(ctx)
+	}
+	return a.cache.configList(func() ([]ConfigDetail, error) { return a.listConfigs(ctx) })
+}
+
+func (a *ConfigServerClient) listConfigs(ctx context.Context) ([]ConfigDetail, error) {
+	var response struct {
+		response
+		Configs []ConfigDetail `json:"data"`
+	}
+
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetResult(&response).
+		Get("/User/ListConfigs")
+
+	if err != nil {
+		return nil, requestError("ListConfigs", err)
+	}
+
+	if resp.StatusCode() != 200 || response.Code != 200 {
+		return nil, responseError("ListConfigs", resp, response.response)
+	}
+
+	return response.Configs, nil
+}
+
+// GetConfig 获取配置及其内容，不经过缓存
+func (a *ConfigServerClient) GetConfig(ctx context.Context, configName string) (*ConfigDetail, error) {
+	var response struct {
+		response
+		Config ConfigDetail `json:"data"`
+	}
+
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetResult(&response).
+		Get(fmt.Sprintf("/User/GetConfig/%s", configName))
+
+	if err != nil {
+		return nil, requestError("GetConfig", err)
+	}
+
+	if resp.StatusCode() != 200 || response.Code != 200 {
+		return nil, responseError("GetConfig", resp, response.response)
+	}
+
+	return &response.Config, nil
+}
+
+// ListAgents 列出Agent组内的Agent及其上报的配置状态，不经过缓存
+func (a *ConfigServerClient) ListAgents(ctx context.Context, groupName string) ([]Agent, error) {
+	var response struct {
+		response
+		Agents []Agent `json:"data"`
+	}
+
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetResult(&response).
+		Get(fmt.Sprintf("/User/ListAgents/%s", groupName))
+
+	if err != nil {
+		return nil, requestError("ListAgents", err)
+	}
+
+	if resp.StatusCode() == 404 {
+		return nil, nil
+	}
+
+	if resp.StatusCode() != 200 || response.Code != 200 {
+		return nil, responseError("ListAgents", resp, response.response)
+	}
+
+	return response.Agents, nil
+}
+
+// Refresh 绕过缓存重新获取Agent组、配置与已关联的配置，未开启缓存时不做任何事
+func (a *ConfigServerClient) Refresh(ctx context.Context) error {
+	if a.cache == nil {
+		return nil
+	}
+	return a.cache.refresh(ctx, a)
+}
+
+func (a *ConfigServerClient) invalidateConfigs() {
+	if a.cache != nil {
+		a.cache.invalidateConfigs()
+	}
+}
+
+func (a *ConfigServerClient) invalidateGroups() {
+	if a.cache != nil {
+		a.cache.invalidateGroups()
+	}
+}
+
+// invalidateGroup Agent组或其关联变化后，组列表与该组已关联的配置都需要重新获取
+func (a *ConfigServerClient) invalidateGroup(groupName string) {
+	if a.cache != nil {
+		a.cache.invalidateGroups()
+		a.cache.invalidateApplied(groupName)
+	}
+}
@@ -0,0 +1,59 @@
+package configserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateConfigSendsNestedContent(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"code":200,"message":"ACCEPT"}`)
+	}))
+	defer server.Close()
+
+	client := NewConfigServerClient(server.URL, nil, "default")
+	content := "inputs:\n  - Type: input_file\n    FilePaths: [/var/log/*.log]\nflushers:\n  - Type: flusher_stdout\n"
+	if err := client.CreateConfig(context.Background(), pipeline(content)); err != nil {
+		t.Fatal(err)
+	}
+	detail, _ := body["config_detail"].(map[string]interface{})
+	config, _ := detail["content"].(map[string]interface{})
+	inputs, _ := config["inputs"].([]interface{})
+	if len(inputs) != 1 || inputs[0].(map[string]interface{})["Type"] != "input_file" {
+		t.Fatalf("unexpected request body %v", body)
+	}
+}
+
+func TestListAgents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/User/ListAgents/web" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"code":200,"message":"ACCEPT","data":[
+			{"instance_id":"a-1","hostname":"node-1","configs":[{"name":"nginx","version":3,"status":"applied"}]}]}`)
+	}))
+	defer server.Close()
+
+	client := NewConfigServerClient(server.URL, nil, "default")
+	agents, err := client.ListAgents(context.Background(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 1 || agents[0].Hostname != "node-1" || agents[0].Configs[0].Version != 3 {
+		t.Fatalf("unexpected agents %+v", agents)
+	}
+	if agents, err := client.ListAgents(context.Background(), "missing"); err != nil || agents != nil {
+		t.Fatalf("missing group: got %v, %v", agents, err)
+	}
+}
@@ -0,0 +1,86 @@
+package configserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// Error Config-Server请求失败的错误，区分可重试与不可重试的失败
+type Error struct {
+	// Op 失败的接口，例如CreateConfig
+	Op string
+	// StatusCode HTTP状态码或响应体中的code，请求未发出时为0
+	StatusCode int
+	// Message 失败原因
+	Message string
+	// Retryable 是否值得重试：5xx、超时与网络错误可重试，4xx与无效的配置内容不可重试
+	Retryable bool
+	// Err 底层错误
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("configserver %s returned %d: %s", e.Op, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("configserver %s failed: %s", e.Op, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable 判断错误是否可重试。非Config-Server错误（例如访问Kubernetes API失败）视为可重试
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var csErr *Error
+	if errors.As(err, &csErr) {
+		return csErr.Retryable
+	}
+	return true
+}
+
+// retryableStatus 5xx、408与429可重试
+func retryableStatus(code int) bool {
+	return code >= http.StatusInternalServerError ||
+		code == http.StatusRequestTimeout ||
+		code == http.StatusTooManyRequests
+}
+
+// requestError 请求未能完成（连接失败、超时等），可重试
+func requestError(op string, err error) error {
+	return &Error{Op: op, Message: err.Error(), Retryable: true, Err: err}
+}
+
+// invalidContentError 配置内容无效，不可重试
+func invalidContentError(op string, err error) error {
+	return &Error{Op: op, Message: err.Error(), Err: err}
+}
+
+// statusError 根据HTTP状态码构造错误
+func statusError(op string, resp *resty.Response) error {
+	return &Error{
+		Op:         op,
+		StatusCode: resp.StatusCode(),
+		Message:    resp.String(),
+		Retryable:  retryableStatus(resp.StatusCode()),
+	}
+}
+
+// responseError 根据响应体中的code构造错误。HTTP状态码异常时以其为准，缺少code的响应视为可重试
+func responseError(op string, resp *resty.Response, r response) error {
+	if resp != nil && resp.StatusCode() != http.StatusOK {
+		return statusError(op, resp)
+	}
+	return &Error{
+		Op:         op,
+		StatusCode: r.Code,
+		Message:    r.Message,
+		Retryable:  r.Code == 0 || retryableStatus(r.Code),
+	}
+}
@@ -0,0 +1,102 @@
+package configserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		retryable bool
+	}{
+		{name: "server error", status: http.StatusInternalServerError, body: `{}`, retryable: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, body: ``, retryable: true},
+		{name: "too many requests", status: http.StatusTooManyRequests, body: ``, retryable: true},
+		{name: "bad request", status: http.StatusBadRequest, body: `{"code":400,"message":"bad"}`, retryable: false},
+		{name: "rejected in body", status: http.StatusOK, body: `{"code":400,"message":"invalid config"}`, retryable: false},
+		{name: "server error in body", status: http.StatusOK, body: `{"code":500,"message":"internal"}`, retryable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			client := NewConfigServerClient(server.URL, nil, "default")
+			err := client.CreateConfig(context.Background(), pipeline("a: b"))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			var csErr *Error
+			if !errors.As(err, &csErr) {
+				t.Fatalf("expected *Error, got %T", err)
+			}
+			if got := IsRetryable(err); got != tt.retryable {
+				t.Fatalf("IsRetryable() = %v, want %v (%v)", got, tt.retryable, err)
+			}
+		})
+	}
+}
+
+func TestInvalidContentIsTerminal(t *testing.T) {
+	client := NewConfigServerClient("http://127.0.0.1:0", nil, "default")
+	err := client.CreateConfig(context.Background(), pipeline("a: [b"))
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("expected a terminal error, got %v", err)
+	}
+}
+
+func TestTimeoutIsRetryable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewConfigServerClient(server.URL, nil, "default")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.DeleteConfig(ctx, "a")
+	if err == nil || !IsRetryable(err) {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+}
+
+func TestNonConfigServerErrorIsRetryable(t *testing.T) {
+	if !IsRetryable(errors.New("etcd unavailable")) {
+		t.Fatal("expected unknown errors to be retryable")
+	}
+	if IsRetryable(nil) {
+		t.Fatal("nil is not retryable")
+	}
+}
+
+func pipeline(content string) *v1alpha1.Pipeline {
+	return &v1alpha1.Pipeline{Spec: v1alpha1.PipelineSpec{Name: "test", Content: content}}
+}
+
+func TestEndpointName(t *testing.T) {
+	tests := map[string]string{
+		"http://config-server:8899/User/CreateConfig":       "CreateConfig",
+		"http://config-server:8899/User/DeleteConfig/nginx": "DeleteConfig",
+		"/User/RemoveConfigFromAgentGroup/nginx/default":    "RemoveConfigFromAgentGroup",
+		"http://config-server:8899/Agent/HeartBeat":         "unknown",
+	}
+	for in, want := range tests {
+		if got := endpointName(in); got != want {
+			t.Errorf("endpointName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
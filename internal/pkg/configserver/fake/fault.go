@@ -0,0 +1,96 @@
+package fake
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// Fault 注入到匹配请求的故障，多个字段可以组合，例如先延迟再返回错误
+type Fault struct {
+	// Op 匹配的接口，例如CreateConfig，为空时匹配所有接口
+	Op string
+	// Times 生效的次数，为0时一直生效直到ClearFaults
+	Times int
+	// Latency 处理请求前的延迟，客户端取消请求时提前结束
+	Latency time.Duration
+	// Status 以该HTTP状态码返回错误，不修改状态
+	Status int
+	// Code 以HTTP 200返回该响应体code，不修改状态
+	Code int
+	// DropResponse 处理请求并修改状态后断开连接，客户端收不到响应
+	DropResponse bool
+}
+
+// Inject 注入故障，按注入顺序匹配，每个请求只触发第一个匹配的故障
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults 移除所有故障
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// match 返回匹配op的故障并消耗一次次数
+func (s *Server) match(op string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if f.Op != "" && f.Op != op {
+			continue
+		}
+		matched := *f
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return &matched
+	}
+	return nil
+}
+
+func (s *Server) injectFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 路径为/User/<op>/<参数>...
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/User/"), "/")
+		f := s.match(parts[0])
+		if f == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if f.Latency > 0 {
+			timer := time.NewTimer(f.Latency)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+		switch {
+		case f.Status != 0:
+			s.record(parts[0], parts[1:]...)
+			reply(w, f.Status, "injected fault", nil)
+		case f.Code != 0:
+			s.record(parts[0], parts[1:]...)
+			writeJSON(w, http.StatusOK, map[string]interface{}{"code": f.Code, "message": "injected fault"})
+		case f.DropResponse:
+			next.ServeHTTP(httptest.NewRecorder(), r)
+			if hijacker, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hijacker.Hijack(); err == nil {
+					_ = conn.Close()
+				}
+			}
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,409 @@
+// Package fake 提供内存中的Config-Server，实现operator使用的全部HTTP接口，并支持注入延迟、错误码与丢失响应，
+// 供控制器的envtest与e2e测试断言Config-Server上的确切状态
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+// Server 内存中的Config-Server
+type Server struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// configs 配置名到配置内容，内容为CreateConfig请求中的对象
+	configs map[string]interface{}
+	groups  map[string]*group
+	agents  map[string][]configserver.Agent
+	faults  []*Fault
+	// requests 收到的请求，注入的故障也会记录
+	requests []string
+}
+
+type group struct {
+	configserver.AgentGroup
+	// applied 关联到该组的配置
+	applied map[string]bool
+}
+
+// State Config-Server上的配置与Agent组，可直接与期望值比较
+type State struct {
+	// Configs 配置名到键有序的YAML内容
+	Configs map[string]string
+	// Groups Agent组名到已关联的配置，按名称排序
+	Groups map[string][]string
+}
+
+// NewServer 启动内存中的Config-Server，使用完毕后调用Close
+func NewServer() *Server {
+	s := &Server{
+		configs: map[string]interface{}{},
+		groups:  map[string]*group{},
+		agents:  map[string][]configserver.Agent{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /User/CreateConfig", s.createConfig)
+	mux.HandleFunc("DELETE /User/DeleteConfig/{config}", s.deleteConfig)
+	mux.HandleFunc("GET /User/ListConfigs", s.listConfigs)
+	mux.HandleFunc("GET /User/GetConfig/{config}", s.getConfig)
+	mux.HandleFunc("POST /User/CreateAgentGroup", s.createAgentGroup)
+	mux.HandleFunc("PUT /User/UpdateAgentGroup", s.updateAgentGroup)
+	mux.HandleFunc("DELETE /User/DeleteAgentGroup/{group}", s.deleteAgentGroup)
+	mux.HandleFunc("GET /User/ListAgentGroups", s.listAgentGroups)
+	mux.HandleFunc("POST /User/ApplyConfigToAgentGroup", s.applyConfigToAgentGroup)
+	mux.HandleFunc("DELETE /User/RemoveConfigFromAgentGroup/{config}/{group}", s.removeConfigFromAgentGroup)
+	mux.HandleFunc("GET /User/GetAppliedConfigsForAgentGroup/{group}", s.getAppliedConfigs)
+	mux.HandleFunc("GET /User/ListAgents/{group}", s.listAgents)
+	s.Server = httptest.NewServer(s.injectFaults(mux))
+	return s
+}
+
+// State 返回当前的配置与Agent组
+func (s *Server) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := State{Configs: map[string]string{}, Groups: map[string][]string{}}
+	for name, content := range s.configs {
+		canonical, err := render.Canonical(content)
+		if err != nil {
+			canonical = fmt.Sprint(content)
+		}
+		state.Configs[name] = canonical
+	}
+	for name, g := range s.groups {
+		state.Groups[name] = sortedKeys(g.applied)
+	}
+	return state
+}
+
+// AgentGroup 返回Agent组，Configs为已关联的配置
+func (s *Server) AgentGroup(name string) (configserver.AgentGroup, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[name]
+	if !ok {
+		return configserver.AgentGroup{}, false
+	}
+	return g.view(), true
+}
+
+// Requests 返回收到的请求，例如"CreateConfig nginx"、"RemoveConfigFromAgentGroup nginx web"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Count 返回收到的op请求数
+func (s *Server) Count(op string) int {
+	n := 0
+	for _, req := range s.Requests() {
+		if req == op || strings.HasPrefix(req, op+" ") {
+			n++
+		}
+	}
+	return n
+}
+
+// ResetRequests 清空请求记录
+func (s *Server) ResetRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+// Reset 清空配置、Agent组、Agent、故障与请求记录
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs = map[string]interface{}{}
+	s.groups = map[string]*group{}
+	s.agents = map[string][]configserver.Agent{}
+	s.faults = nil
+	s.requests = nil
+}
+
+// AddConfig 直接写入一个配置，content为YAML
+func (s *Server) AddConfig(name, content string) error {
+	config, err := render.Parse(content)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs[name] = config
+	return nil
+}
+
+// AddAgentGroup 直接写入一个Agent组，Configs中的配置视为已关联
+func (s *Server) AddAgentGroup(ag configserver.AgentGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := &group{AgentGroup: ag, applied: map[string]bool{}}
+	for _, config := range ag.Configs {
+		g.applied[config] = true
+	}
+	g.Configs = nil
+	s.groups[ag.Name] = g
+}
+
+// SetAgents 设置Agent组内的Agent及其上报的配置状态
+func (s *Server) SetAgents(groupName string, agents ...configserver.Agent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agents[groupName] = agents
+}
+
+func (s *Server) createConfig(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ConfigName   string `json:"config_name"`
+		ConfigDetail struct {
+			Name    string      `json:"name"`
+			Content interface{} `json:"content"`
+		} `json:"config_detail"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ConfigName == "" {
+		s.record("CreateConfig")
+		reply(w, http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+	s.record("CreateConfig", body.ConfigName)
+	if body.ConfigDetail.Name != body.ConfigName || body.ConfigDetail.Content == nil {
+		reply(w, http.StatusBadRequest, "config_detail must hold the name and the content of the config", nil)
+		return
+	}
+	s.mu.Lock()
+	// 与Config-Server一致，已存在的配置被覆盖
+	s.configs[body.ConfigName] = body.ConfigDetail.Content
+	s.mu.Unlock()
+	accept(w, nil)
+}
+
+func (s *Server) deleteConfig(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("config")
+	s.record("DeleteConfig", name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.configs[name]; !ok {
+		reply(w, http.StatusNotFound, "config "+name+" not found", nil)
+		return
+	}
+	delete(s.configs, name)
+	// 删除的配置不再下发到任何Agent组
+	for _, g := range s.groups {
+		delete(g.applied, name)
+	}
+	accept(w, nil)
+}
+
+func (s *Server) listConfigs(w http.ResponseWriter, _ *http.Request) {
+	s.record("ListConfigs")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	configs := make([]configserver.ConfigDetail, 0, len(s.configs))
+	for _, name := range sortedKeys(s.configs) {
+		configs = append(configs, configserver.ConfigDetail{Name: name})
+	}
+	accept(w, configs)
+}
+
+func (s *Server) getConfig(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("config")
+	s.record("GetConfig", name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.configs[name]
+	if !ok {
+		reply(w, http.StatusNotFound, "config "+name+" not found", nil)
+		return
+	}
+	accept(w, configserver.ConfigDetail{Name: name, Content: content})
+}
+
+func (s *Server) createAgentGroup(w http.ResponseWriter, r *http.Request) {
+	ag, ok := s.decodeGroup(w, r, "CreateAgentGroup")
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.groups[ag.Name]; exists {
+		reply(w, http.StatusBadRequest, "agent group "+ag.Name+" already exists", nil)
+		return
+	}
+	s.groups[ag.Name] = &group{AgentGroup: ag, applied: map[string]bool{}}
+	accept(w, nil)
+}
+
+func (s *Server) updateAgentGroup(w http.ResponseWriter, r *http.Request) {
+	ag, ok := s.decodeGroup(w, r, "UpdateAgentGroup")
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, exists := s.groups[ag.Name]
+	if !exists {
+		reply(w, http.StatusNotFound, "agent group "+ag.Name+" not found", nil)
+		return
+	}
+	// 更新不改变已关联的配置
+	g.AgentGroup = ag
+	accept(w, nil)
+}
+
+// decodeGroup 解析请求中的Agent组，关联只能通过ApplyConfigToAgentGroup修改
+func (s *Server) decodeGroup(w http.ResponseWriter, r *http.Request, op string) (configserver.AgentGroup, bool) {
+	var ag configserver.AgentGroup
+	if err := json.NewDecoder(r.Body).Decode(&ag); err != nil || ag.Name == "" {
+		s.record(op)
+		reply(w, http.StatusBadRequest, "invalid request body", nil)
+		return ag, false
+	}
+	s.record(op, ag.Name)
+	ag.Configs = nil
+	return ag, true
+}
+
+func (s *Server) deleteAgentGroup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	s.record("DeleteAgentGroup", name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[name]; !ok {
+		reply(w, http.StatusNotFound, "agent group "+name+" not found", nil)
+		return
+	}
+	delete(s.groups, name)
+	accept(w, nil)
+}
+
+func (s *Server) listAgentGroups(w http.ResponseWriter, _ *http.Request) {
+	s.record("ListAgentGroups")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	groups := make([]configserver.AgentGroup, 0, len(s.groups))
+	for _, name := range sortedKeys(s.groups) {
+		groups = append(groups, s.groups[name].view())
+	}
+	accept(w, groups)
+}
+
+func (s *Server) applyConfigToAgentGroup(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ConfigName string `json:"config_name"`
+		GroupName  string `json:"group_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ConfigName == "" || body.GroupName == "" {
+		s.record("ApplyConfigToAgentGroup")
+		reply(w, http.StatusBadRequest, "invalid request body", nil)
+		return
+	}
+	s.record("ApplyConfigToAgentGroup", body.ConfigName, body.GroupName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[body.GroupName]
+	if !ok {
+		reply(w, http.StatusNotFound, "agent group "+body.GroupName+" not found", nil)
+		return
+	}
+	if _, ok := s.configs[body.ConfigName]; !ok {
+		reply(w, http.StatusNotFound, "config "+body.ConfigName+" not found", nil)
+		return
+	}
+	g.applied[body.ConfigName] = true
+	accept(w, nil)
+}
+
+func (s *Server) removeConfigFromAgentGroup(w http.ResponseWriter, r *http.Request) {
+	config, groupName := r.PathValue("config"), r.PathValue("group")
+	s.record("RemoveConfigFromAgentGroup", config, groupName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[groupName]
+	if !ok || !g.applied[config] {
+		reply(w, http.StatusNotFound, "config "+config+" is not applied to agent group "+groupName, nil)
+		return
+	}
+	delete(g.applied, config)
+	accept(w, nil)
+}
+
+func (s *Server) getAppliedConfigs(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	s.record("GetAppliedConfigsForAgentGroup", name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[name]
+	if !ok {
+		reply(w, http.StatusNotFound, "agent group "+name+" not found", nil)
+		return
+	}
+	accept(w, sortedKeys(g.applied))
+}
+
+func (s *Server) listAgents(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	s.record("ListAgents", name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[name]; !ok {
+		reply(w, http.StatusNotFound, "agent group "+name+" not found", nil)
+		return
+	}
+	agents := s.agents[name]
+	if agents == nil {
+		agents = []configserver.Agent{}
+	}
+	accept(w, agents)
+}
+
+func (s *Server) record(op string, args ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, strings.Join(append([]string{op}, args...), " "))
+}
+
+func (g *group) view() configserver.AgentGroup {
+	ag := g.AgentGroup
+	ag.Configs = sortedKeys(g.applied)
+	return ag
+}
+
+// accept 返回成功的响应，data为空时不返回data字段
+func accept(w http.ResponseWriter, data interface{}) {
+	reply(w, http.StatusOK, "ACCEPT", data)
+}
+
+// reply 以相同的HTTP状态码与响应体code返回
+func reply(w http.ResponseWriter, code int, message string, data interface{}) {
+	body := map[string]interface{}{"code": code, "message": message}
+	if data != nil {
+		body["data"] = data
+	}
+	writeJSON(w, code, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,155 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/configserver"
+)
+
+const content = "inputs:\n  - Type: input_file\n    FilePaths: [/var/log/app.log]\nflushers:\n  - Type: flusher_stdout\n"
+
+func pipeline(name string) *v1alpha1.Pipeline {
+	return &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1alpha1.PipelineSpec{Name: name, Content: content},
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	ctx := context.Background()
+	c := configserver.NewConfigServerClient(s.URL, nil, "")
+
+	if err := c.CreateConfig(ctx, pipeline("app")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateAgentGroup(ctx, &configserver.AgentGroup{Name: "web", Tags: []string{"env=prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateAgentGroup(ctx, &configserver.AgentGroup{Name: "web"}); err == nil {
+		t.Error("creating an existing agent group succeeded")
+	}
+	if err := c.ApplyConfigToAgentGroup(ctx, "app", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ApplyConfigToAgentGroup(ctx, "missing", "web"); err == nil {
+		t.Error("applying a missing config succeeded")
+	}
+	if err := c.UpdateAgentGroup(ctx, &configserver.AgentGroup{Name: "web", Description: "web nodes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := State{
+		Configs: map[string]string{"app": "flushers:\n- Type: flusher_stdout\ninputs:\n- FilePaths:\n  - /var/log/app.log\n  Type: input_file\n"},
+		Groups:  map[string][]string{"web": {"app"}},
+	}
+	if got := s.State(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("state = %#v, want %#v", got, want)
+	}
+	if group, _ := s.AgentGroup("web"); group.Description != "web nodes" || len(group.Tags) != 0 {
+		t.Errorf("updated group = %+v", group)
+	}
+
+	groups, err := c.ListAgentGroups(ctx)
+	if err != nil || len(groups) != 1 || !reflect.DeepEqual(groups[0].Configs, []string{"app"}) {
+		t.Errorf("ListAgentGroups = %+v, %v", groups, err)
+	}
+	if configs, err := c.ListConfigs(ctx); err != nil || len(configs) != 1 || configs[0].Name != "app" {
+		t.Errorf("ListConfigs = %+v, %v", configs, err)
+	}
+	if detail, err := c.GetConfig(ctx, "app"); err != nil || detail.Content == nil {
+		t.Errorf("GetConfig = %+v, %v", detail, err)
+	}
+	s.SetAgents("web", configserver.Agent{InstanceID: "a-1", Configs: []configserver.AgentConfigStatus{{Name: "app"}}})
+	if agents, err := c.ListAgents(ctx, "web"); err != nil || len(agents) != 1 {
+		t.Errorf("ListAgents = %+v, %v", agents, err)
+	}
+
+	if err := c.RemoveConfigFromAgentGroup(ctx, "app", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteConfig(ctx, "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteAgentGroup(ctx, "web"); err != nil {
+		t.Fatal(err)
+	}
+	// 不存在的对象删除成功，与客户端对404的处理一致
+	if err := c.DeleteConfig(ctx, "app"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.State(); len(got.Configs) != 0 || len(got.Groups) != 0 {
+		t.Errorf("state after delete = %+v", got)
+	}
+	if n := s.Count("DeleteConfig"); n != 2 {
+		t.Errorf("DeleteConfig requests = %d, want 2: %v", n, s.Requests())
+	}
+}
+
+func TestDeleteConfigRemovesAssociations(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	if err := s.AddConfig("app", content); err != nil {
+		t.Fatal(err)
+	}
+	s.AddAgentGroup(configserver.AgentGroup{Name: "web", Configs: []string{"app"}})
+	c := configserver.NewConfigServerClient(s.URL, nil, "")
+	if err := c.DeleteConfig(context.Background(), "app"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.State().Groups["web"]; len(got) != 0 {
+		t.Errorf("web configs = %v", got)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	ctx := context.Background()
+	c := configserver.NewConfigServerClient(s.URL, nil, "")
+
+	s.Inject(Fault{Op: "CreateConfig", Times: 1, Status: http.StatusServiceUnavailable})
+	err := c.CreateConfig(ctx, pipeline("app"))
+	if !configserver.IsRetryable(err) {
+		t.Fatalf("5xx: got %v, want a retryable error", err)
+	}
+	if len(s.State().Configs) != 0 {
+		t.Fatal("a failed request changed the state")
+	}
+	if err := c.CreateConfig(ctx, pipeline("app")); err != nil {
+		t.Fatalf("the fault did not expire: %v", err)
+	}
+
+	s.Inject(Fault{Op: "CreateAgentGroup", Code: http.StatusBadRequest})
+	var csErr *configserver.Error
+	if err := c.CreateAgentGroup(ctx, &configserver.AgentGroup{Name: "web"}); !errors.As(err, &csErr) ||
+		csErr.StatusCode != http.StatusBadRequest || csErr.Retryable {
+		t.Errorf("wrong code: got %v", err)
+	}
+	s.ClearFaults()
+
+	// 响应丢失时请求已经生效
+	s.Inject(Fault{Op: "DeleteConfig", Times: 1, DropResponse: true})
+	if err := c.DeleteConfig(ctx, "app"); !configserver.IsRetryable(err) {
+		t.Errorf("dropped response: got %v, want a retryable error", err)
+	}
+	if len(s.State().Configs) != 0 {
+		t.Error("the dropped request was not applied")
+	}
+
+	s.Inject(Fault{Latency: time.Second})
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.ListConfigs(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("latency: got %v, want a deadline error", err)
+	}
+}
@@ -0,0 +1,36 @@
+package configserver
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/kube"
+)
+
+const (
+	// DefaultURL is the Config-Server address used when the ConfigMap does not set one
+	DefaultURL = "http://config-server:8899"
+	// ConfigMapName is the name of the ConfigMap holding the Config-Server address
+	ConfigMapName = "config-server-config"
+	// ConfigMapNamespace is the namespace of the ConfigMap holding the Config-Server address
+	ConfigMapNamespace = "loongcollector-system"
+	// ConfigMapKey is the ConfigMap key of the Config-Server address
+	ConfigMapKey = "configServerURL"
+)
+
+// LookupURL 从带有app: config-server标签的ConfigMap读取Config-Server地址，未设置时返回DefaultURL
+func LookupURL(ctx context.Context, c client.Client) (string, error) {
+	configMap, err := kube.GetConfigMapByLabel(ctx, c, ConfigMapName,
+		ConfigMapNamespace, map[string]string{
+			"app": "config-server",
+		})
+	if err != nil {
+		return "", err
+	}
+
+	if url, ok := configMap.Data[ConfigMapKey]; ok && url != "" {
+		return url, nil
+	}
+	return DefaultURL, nil
+}
@@ -0,0 +1,62 @@
+package configserver
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/metrics"
+)
+
+// observeResponse 记录收到响应的请求
+func observeResponse(_ *resty.Client, resp *resty.Response) {
+	metrics.ObserveRequest(endpointName(resp.Request.URL), resultForStatus(resp.StatusCode()), resp.Time())
+}
+
+// observeError 记录未收到正常响应的请求
+func observeError(req *resty.Request, err error) {
+	var respErr *resty.ResponseError
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		metrics.ObserveRequest(endpointName(req.URL), metrics.ResultCircuitOpen, 0)
+	case errors.As(err, &respErr) && respErr.Response != nil:
+		observeResponse(nil, respErr.Response)
+	default:
+		var duration time.Duration
+		if !req.Time.IsZero() {
+			duration = time.Since(req.Time)
+		}
+		metrics.ObserveRequest(endpointName(req.URL), metrics.ResultError, duration)
+	}
+}
+
+func resultForStatus(code int) string {
+	switch {
+	case retryableStatus(code):
+		return metrics.ResultServerError
+	case code >= 400:
+		return metrics.ResultClientError
+	default:
+		return metrics.ResultSuccess
+	}
+}
+
+// endpointName 取/User/之后的接口名，例如/User/DeleteConfig/foo返回DeleteConfig，避免配置名进入标签
+func endpointName(rawURL string) string {
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+	const prefix = "/User/"
+	if i := strings.Index(path, prefix); i >= 0 {
+		path = path[i+len(prefix):]
+		if j := strings.Index(path, "/"); j >= 0 {
+			path = path[:j]
+		}
+		return path
+	}
+	return "unknown"
+}
@@ -0,0 +1,235 @@
+package configserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/metrics"
+)
+
+const (
+	// DefaultFailureThreshold 连续失败多少次后打开熔断器
+	DefaultFailureThreshold = 5
+	// DefaultProbeInterval 熔断器打开后探测Config-Server的间隔
+	DefaultProbeInterval = 30 * time.Second
+	// DefaultRefreshInterval 定期刷新缓存的间隔
+	DefaultRefreshInterval = time.Minute
+	// DefaultIdleTimeout 地址多久未使用后移除其客户端与熔断器
+	DefaultIdleTimeout = 30 * time.Minute
+)
+
+// RegistryOptions 客户端注册表的配置
+type RegistryOptions struct {
+	// FailureThreshold 连续失败多少次后打开熔断器
+	FailureThreshold int
+	// ProbeInterval 熔断器打开后探测Config-Server的间隔
+	ProbeInterval time.Duration
+	// RefreshInterval 定期刷新Agent组与配置缓存的间隔，缓存条目在两倍间隔后过期
+	RefreshInterval time.Duration
+	// IdleTimeout 地址多久未使用后移除其客户端与熔断器，默认地址不会被移除
+	IdleTimeout time.Duration
+}
+
+// Registry 按Config-Server地址共享客户端，每个地址持有独立的熔断器
+type Registry struct {
+	opts RegistryOptions
+
+	mu        sync.Mutex
+	endpoints map[string]*endpoint
+	// defaultURL 集群默认的Config-Server地址，就绪检查只关注该地址
+	defaultURL string
+	now        func() time.Time
+}
+
+type endpoint struct {
+	client   *ConfigServerClient
+	breaker  *Breaker
+	lastUsed time.Time
+}
+
+// DefaultRegistry 未显式配置注册表时使用的默认注册表
+var DefaultRegistry = NewRegistry(RegistryOptions{})
+
+// NewRegistry 创建客户端注册表
+func NewRegistry(opts RegistryOptions) *Registry {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = DefaultProbeInterval
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = DefaultRefreshInterval
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	return &Registry{
+		opts:      opts,
+		endpoints: map[string]*endpoint{},
+		now:       time.Now,
+	}
+}
+
+// SetDefault 记录从ConfigMap读取的集群默认Config-Server地址。
+// 其他地址（ConfigServer资源）的熔断器只通过指标与Pipeline状态报告，不影响就绪检查
+func (r *Registry) SetDefault(baseURL string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultURL = baseURL
+}
+
+// Client 返回指向baseURL的共享客户端
+func (r *Registry) Client(baseURL string) *ConfigServerClient {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ep, ok := r.endpoints[baseURL]; ok {
+		ep.lastUsed = r.now()
+		return ep.client
+	}
+
+	probeClient := newRestyClient(baseURL)
+	breaker := NewBreaker(r.opts.FailureThreshold, r.opts.ProbeInterval, func(ctx context.Context) error {
+		resp, err := probeClient.R().SetContext(ctx).Get("/User/ListAgentGroups")
+		if err != nil {
+			return err
+		}
+		if retryableStatus(resp.StatusCode()) {
+			return fmt.Errorf("configserver returned %d", resp.StatusCode())
+		}
+		return nil
+	})
+
+	stateNames := make([]string, 0, len(States))
+	for _, state := range States {
+		stateNames = append(stateNames, state.String())
+	}
+	metrics.ObserveBreakerState(baseURL, StateClosed.String(), stateNames)
+	breaker.OnStateChange(func(state State) {
+		metrics.ObserveBreakerState(baseURL, state.String(), stateNames)
+	})
+
+	rc := newRestyClient(baseURL).
+		OnBeforeRequest(func(_ *resty.Client, _ *resty.Request) error {
+			return breaker.Allow()
+		}).
+		OnSuccess(func(_ *resty.Client, resp *resty.Response) {
+			if retryableStatus(resp.StatusCode()) {
+				breaker.RecordFailure()
+				return
+			}
+			breaker.RecordSuccess()
+		}).
+		OnError(func(_ *resty.Request, err error) {
+			// 熔断拒绝与调用方取消不代表Config-Server不可用
+			if errors.Is(err, ErrCircuitOpen) || errors.Is(err, context.Canceled) {
+				return
+			}
+			breaker.RecordFailure()
+		})
+
+	ep := &endpoint{
+		client:   &ConfigServerClient{client: rc, cache: newCache(2 * r.opts.RefreshInterval)},
+		breaker:  breaker,
+		lastUsed: r.now(),
+	}
+	r.endpoints[baseURL] = ep
+	return ep.client
+}
+
+// States 返回每个Config-Server地址的熔断器状态
+func (r *Registry) States() map[string]State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make(map[string]State, len(r.endpoints))
+	for url, ep := range r.endpoints {
+		states[url] = ep.breaker.State()
+	}
+	return states
+}
+
+// HealthCheck 实现healthz.Checker，默认地址的熔断器未关闭时返回错误，其他地址的状态由熔断器指标报告。
+// 不用于/readyz，否则Config-Server故障会使副本不再就绪，sidecar注入webhook随之失效
+func (r *Registry) HealthCheck(_ *http.Request) error {
+	r.mu.Lock()
+	ep, ok := r.endpoints[r.defaultURL]
+	url := r.defaultURL
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if state := ep.breaker.State(); state != StateClosed {
+		return fmt.Errorf("configserver circuit breaker not closed: %s (%s)", url, state)
+	}
+	return nil
+}
+
+// Start 定期刷新所有已使用的Config-Server地址的缓存，直到ctx结束，实现manager.Runnable
+func (r *Registry) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.RefreshInterval)
+	defer ticker.Stop()
+	defer r.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.refresh(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection 每个副本各自维护缓存
+func (r *Registry) NeedLeaderElection() bool {
+	return false
+}
+
+// refresh 移除空闲的地址后刷新缓存，熔断器未关闭的地址跳过。刷新失败时保留旧值，条目过期后由读取重新获取
+func (r *Registry) refresh(ctx context.Context) {
+	r.evictIdle()
+
+	r.mu.Lock()
+	endpoints := make([]*endpoint, 0, len(r.endpoints))
+	for _, ep := range r.endpoints {
+		endpoints = append(endpoints, ep)
+	}
+	r.mu.Unlock()
+
+	for _, ep := range endpoints {
+		if ep.breaker.Allow() != nil {
+			continue
+		}
+		_ = ep.client.Refresh(ctx)
+	}
+}
+
+// evictIdle 移除超过IdleTimeout未使用的非默认地址，例如已删除或不再被选中的ConfigServer。
+// 再次使用时重新创建客户端，熔断器从关闭状态开始
+func (r *Registry) evictIdle() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	for url, ep := range r.endpoints {
+		if url == r.defaultURL || now.Sub(ep.lastUsed) < r.opts.IdleTimeout {
+			continue
+		}
+		ep.breaker.Stop()
+		delete(r.endpoints, url)
+		metrics.ForgetBreaker(url)
+	}
+}
+
+// Stop 停止所有熔断器的定时探测
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ep := range r.endpoints {
+		ep.breaker.Stop()
+	}
+}
@@ -0,0 +1,54 @@
+package configserver
+
+import (
+	"context"
+
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/tracing"
+)
+
+// clientSpanKey 标记由startSpan创建的span，避免结束调用方的span
+type clientSpanKey struct{}
+
+// startSpan 为每次请求创建客户端span，并通过W3C traceparent头传播到Config-Server
+func startSpan(_ *resty.Client, req *resty.Request) error {
+	endpoint := endpointName(req.URL)
+	ctx, span := tracing.Tracer().Start(req.Context(), "ConfigServer "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			tracing.AttrEndpoint.String(endpoint),
+			attribute.String("http.request.method", req.Method),
+		))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	req.SetContext(context.WithValue(ctx, clientSpanKey{}, span))
+	return nil
+}
+
+// endSpanOnResponse 收到响应后结束span，5xx标记为失败
+func endSpanOnResponse(_ *resty.Client, resp *resty.Response) {
+	span, ok := resp.Request.Context().Value(clientSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode()))
+	if retryableStatus(resp.StatusCode()) {
+		span.SetStatus(codes.Error, resp.Status())
+	}
+	span.End()
+}
+
+// endSpanOnError 请求失败后结束span
+func endSpanOnError(req *resty.Request, err error) {
+	span, ok := req.Context().Value(clientSpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	tracing.RecordError(span, err)
+	span.End()
+}
@@ -8,6 +8,34 @@ type AgentGroup struct {
 	Configs     []string `json:"configs,omitempty"`
 }
 
+// ConfigDetail represents a config stored in Config-Server
+type ConfigDetail struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Content is only returned by GetConfig, either as a YAML string or as a JSON object
+	Content interface{} `json:"content,omitempty"`
+}
+
+// Agent represents an agent connected to Config-Server
+type Agent struct {
+	InstanceID    string   `json:"instance_id"`
+	Hostname      string   `json:"hostname,omitempty"`
+	IP            string   `json:"ip,omitempty"`
+	Version       string   `json:"version,omitempty"`
+	RunningStatus string   `json:"running_status,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	// Configs is the status of each config reported by the agent in its last heartbeat
+	Configs []AgentConfigStatus `json:"configs,omitempty"`
+}
+
+// AgentConfigStatus represents the status of a config on an agent
+type AgentConfigStatus struct {
+	Name    string `json:"name"`
+	Version int64  `json:"version,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
 type response struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
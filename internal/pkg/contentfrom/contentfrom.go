@@ -0,0 +1,271 @@
+package contentfrom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/convert"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/pipelinetemplate"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+// ErrInvalid 配置来源无效（片段无法合并、Pipeline循环引用、模板参数无效、无法转换等），修改spec或被引用的对象前重试没有意义
+var ErrInvalid = errors.New("invalid content source")
+
+// maxDepth pipelineRef的最大嵌套层数
+const maxDepth = 8
+
+// 被引用对象的种类，References返回的索引值为"<种类>/<名称>"
+const (
+	KindConfigMap = "ConfigMap"
+	KindSecret    = "Secret"
+	KindPipeline  = "Pipeline"
+	// KindPipelineTemplate 集群级的PipelineTemplate，索引值不区分命名空间
+	KindPipelineTemplate = "PipelineTemplate"
+)
+
+// Reference 返回References中表示被引用对象的索引值
+func Reference(kind, name string) string {
+	return kind + "/" + name
+}
+
+// References 返回Pipeline的spec.contentFrom引用的同命名空间对象与spec.templateRef引用的PipelineTemplate，
+// 用于在被引用对象变化时找到Pipeline
+func References(pipeline *v1alpha1.Pipeline) []string {
+	var refs []string
+	if ref := pipeline.Spec.TemplateRef; ref != nil {
+		refs = append(refs, Reference(KindPipelineTemplate, ref.Name))
+	}
+	from := pipeline.Spec.ContentFrom
+	if from == nil {
+		return refs
+	}
+	add := func(configMap *corev1.ConfigMapKeySelector, secret *corev1.SecretKeySelector, p *corev1.LocalObjectReference) {
+		switch {
+		case configMap != nil:
+			refs = append(refs, Reference(KindConfigMap, configMap.Name))
+		case secret != nil:
+			refs = append(refs, Reference(KindSecret, secret.Name))
+		case p != nil:
+			refs = append(refs, Reference(KindPipeline, p.Name))
+		}
+	}
+	add(from.ConfigMapKeyRef, from.SecretKeyRef, nil)
+	for _, fragment := range from.Fragments {
+		add(fragment.ConfigMapKeyRef, fragment.SecretKeyRef, fragment.PipelineRef)
+	}
+	return refs
+}
+
+// Resolve 返回Pipeline的配置内容：spec.content，按spec.templateRef渲染的PipelineTemplate，
+// 或按spec.contentFrom从同命名空间的ConfigMap、Secret与Pipeline读取并合并的内容。
+// 设置了spec.sourceFormat时，合并前先把每个来源的配置转换为LoongCollector流水线配置
+func Resolve(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline) (string, error) {
+	return resolve(ctx, c, pipeline, []string{pipeline.Name})
+}
+
+// resolve 解析Pipeline的配置内容，chain为pipelineRef的引用链，用于发现循环引用
+func resolve(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline, chain []string) (string, error) {
+	if pipeline.Spec.TemplateRef != nil {
+		tpl, err := Template(ctx, c, pipeline)
+		if err != nil {
+			return "", err
+		}
+		content, err := pipelinetemplate.Render(tpl, pipeline)
+		if err != nil {
+			return "", fmt.Errorf("%w: pipelinetemplate %s: %v", ErrInvalid, tpl.Name, err)
+		}
+		return convertSource(pipeline, content)
+	}
+	from := pipeline.Spec.ContentFrom
+	if from == nil {
+		return convertSource(pipeline, pipeline.Spec.Content)
+	}
+	ns := pipeline.Namespace
+	switch {
+	case from.ConfigMapKeyRef != nil:
+		value, _, err := configMapKey(ctx, c, ns, from.ConfigMapKeyRef)
+		if err != nil {
+			return "", err
+		}
+		return convertSource(pipeline, value)
+	case from.SecretKeyRef != nil:
+		value, _, err := secretKey(ctx, c, ns, from.SecretKeyRef)
+		if err != nil {
+			return "", err
+		}
+		return convertSource(pipeline, value)
+	case len(from.Fragments) == 0:
+		return "", fmt.Errorf("%w: one of configMapKeyRef, secretKeyRef and fragments must be set", ErrInvalid)
+	}
+
+	fragments := make([]string, 0, len(from.Fragments))
+	for i, fragment := range from.Fragments {
+		value, found, err := resolveFragment(ctx, c, ns, fragment, chain)
+		if err != nil {
+			return "", fmt.Errorf("fragments[%d]: %w", i, err)
+		}
+		if !found {
+			continue
+		}
+		// pipelineRef引用的配置已按被引用Pipeline的格式转换
+		if fragment.PipelineRef == nil {
+			if value, err = convertSource(pipeline, value); err != nil {
+				return "", fmt.Errorf("fragments[%d]: %w", i, err)
+			}
+		}
+		fragments = append(fragments, value)
+	}
+	if len(fragments) == 0 {
+		return "", nil
+	}
+	merged, err := render.Merge(fragments)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return merged, nil
+}
+
+// convertSource 按spec.sourceFormat把其他日志采集器的配置转换为LoongCollector流水线配置，有无法转换的配置项时返回错误
+func convertSource(pipeline *v1alpha1.Pipeline, content string) (string, error) {
+	format := pipeline.Spec.SourceFormat
+	if format == "" || format == v1alpha1.SourceFormatLoongCollector || strings.TrimSpace(content) == "" {
+		return content, nil
+	}
+	result, err := convert.Convert(format, content)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalid, format, err)
+	}
+	if len(result.Unsupported) > 0 {
+		items := make([]string, 0, len(result.Unsupported))
+		for _, u := range result.Unsupported {
+			items = append(items, u.String())
+		}
+		return "", fmt.Errorf("%w: %s config has unsupported constructs: %s", ErrInvalid, format, strings.Join(items, "; "))
+	}
+	return result.Content, nil
+}
+
+// ReadsSecret 检查Pipeline的配置是否包含从Secret读取的内容，包括pipelineRef引用的Pipeline读取的Secret。
+// 循环引用与过深的嵌套由Resolve报告，这里忽略
+func ReadsSecret(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline) (bool, error) {
+	return readsSecret(ctx, c, pipeline, []string{pipeline.Name})
+}
+
+func readsSecret(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline, chain []string) (bool, error) {
+	from := pipeline.Spec.ContentFrom
+	if pipeline.Spec.TemplateRef != nil || from == nil {
+		return false, nil
+	}
+	if from.SecretKeyRef != nil {
+		return true, nil
+	}
+	for _, fragment := range from.Fragments {
+		if fragment.SecretKeyRef != nil {
+			return true, nil
+		}
+		if fragment.PipelineRef == nil || len(chain) > maxDepth {
+			continue
+		}
+		name := fragment.PipelineRef.Name
+		if slices.Contains(chain, name) {
+			continue
+		}
+		referenced := &v1alpha1.Pipeline{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: pipeline.Namespace, Name: name}, referenced); err != nil {
+			return false, fmt.Errorf("pipeline %s: %w", name, err)
+		}
+		if found, err := readsSecret(ctx, c, referenced, append(append([]string{}, chain...), name)); found || err != nil {
+			return found, err
+		}
+	}
+	return false, nil
+}
+
+// Template 读取Pipeline的spec.templateRef引用的PipelineTemplate
+func Template(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline) (*v1alpha1.PipelineTemplate, error) {
+	tpl := &v1alpha1.PipelineTemplate{}
+	if err := c.Get(ctx, client.ObjectKey{Name: pipeline.Spec.TemplateRef.Name}, tpl); err != nil {
+		return nil, fmt.Errorf("pipelinetemplate %s: %w", pipeline.Spec.TemplateRef.Name, err)
+	}
+	return tpl, nil
+}
+
+// resolveFragment 读取一个片段，可选的ConfigMap或Secret不存在时返回found为false
+func resolveFragment(ctx context.Context, c client.Reader, ns string, fragment v1alpha1.PipelineContentFragment,
+	chain []string) (string, bool, error) {
+	switch {
+	case fragment.ConfigMapKeyRef != nil:
+		return configMapKey(ctx, c, ns, fragment.ConfigMapKeyRef)
+	case fragment.SecretKeyRef != nil:
+		return secretKey(ctx, c, ns, fragment.SecretKeyRef)
+	case fragment.PipelineRef != nil:
+		name := fragment.PipelineRef.Name
+		for _, seen := range chain {
+			if seen == name {
+				return "", false, fmt.Errorf("%w: pipelineRef cycle %s", ErrInvalid, strings.Join(append(chain, name), " -> "))
+			}
+		}
+		if len(chain) > maxDepth {
+			return "", false, fmt.Errorf("%w: pipelineRef nested deeper than %d", ErrInvalid, maxDepth)
+		}
+		next := append(append([]string{}, chain...), name)
+		referenced := &v1alpha1.Pipeline{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, referenced); err != nil {
+			return "", false, fmt.Errorf("pipeline %s: %w", name, err)
+		}
+		value, err := resolve(ctx, c, referenced, next)
+		return value, true, err
+	default:
+		return fragment.Content, true, nil
+	}
+}
+
+// configMapKey 读取ConfigMap的键，selector为可选时ConfigMap或键不存在返回found为false
+func configMapKey(ctx context.Context, c client.Reader, ns string, selector *corev1.ConfigMapKeySelector) (string, bool, error) {
+	optional := selector.Optional != nil && *selector.Optional
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: selector.Name}, cm); err != nil {
+		if apierrors.IsNotFound(err) && optional {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("configmap %s: %w", selector.Name, err)
+	}
+	if value, ok := cm.Data[selector.Key]; ok {
+		return value, true, nil
+	}
+	if value, ok := cm.BinaryData[selector.Key]; ok {
+		return string(value), true, nil
+	}
+	if optional {
+		return "", false, nil
+	}
+	return "", false, fmt.Errorf("configmap %s has no key %s", selector.Name, selector.Key)
+}
+
+// secretKey 读取Secret的键，selector为可选时Secret或键不存在返回found为false
+func secretKey(ctx context.Context, c client.Reader, ns string, selector *corev1.SecretKeySelector) (string, bool, error) {
+	optional := selector.Optional != nil && *selector.Optional
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: selector.Name}, secret); err != nil {
+		if apierrors.IsNotFound(err) && optional {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("secret %s: %w", selector.Name, err)
+	}
+	if value, ok := secret.Data[selector.Key]; ok {
+		return string(value), true, nil
+	}
+	if optional {
+		return "", false, nil
+	}
+	return "", false, fmt.Errorf("secret %s has no key %s", selector.Name, selector.Key)
+}
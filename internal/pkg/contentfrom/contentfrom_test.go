@@ -0,0 +1,219 @@
+package contentfrom
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+func newReader(t *testing.T, objs ...client.Object) client.Reader {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func pipeline(name string, from *v1alpha1.PipelineContentSource) *v1alpha1.Pipeline {
+	return &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: name},
+		Spec:       v1alpha1.PipelineSpec{Name: name, ContentFrom: from},
+	}
+}
+
+func configMapRef(name, key string) *corev1.ConfigMapKeySelector {
+	return &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+}
+
+func TestResolveInlineContent(t *testing.T) {
+	p := &v1alpha1.Pipeline{Spec: v1alpha1.PipelineSpec{Name: "inline", Content: "inputs: []\n"}}
+	content, err := Resolve(context.Background(), newReader(t), p)
+	if err != nil || content != "inputs: []\n" {
+		t.Fatalf("got %q, %v", content, err)
+	}
+}
+
+func TestResolveConfigMapAndSecret(t *testing.T) {
+	reader := newReader(t,
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "nginx"},
+			Data: map[string]string{"pipeline.yaml": "inputs: []\n"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "sls"},
+			Data: map[string][]byte{"pipeline.yaml": []byte("flushers: []\n")}},
+	)
+	content, err := Resolve(context.Background(), reader, pipeline("nginx", &v1alpha1.PipelineContentSource{
+		ConfigMapKeyRef: configMapRef("nginx", "pipeline.yaml"),
+	}))
+	if err != nil || content != "inputs: []\n" {
+		t.Fatalf("configMapKeyRef: got %q, %v", content, err)
+	}
+	content, err = Resolve(context.Background(), reader, pipeline("sls", &v1alpha1.PipelineContentSource{
+		SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "sls"}, Key: "pipeline.yaml"},
+	}))
+	if err != nil || content != "flushers: []\n" {
+		t.Fatalf("secretKeyRef: got %q, %v", content, err)
+	}
+	if _, err := Resolve(context.Background(), reader, pipeline("missing", &v1alpha1.PipelineContentSource{
+		ConfigMapKeyRef: configMapRef("nginx", "other.yaml"),
+	})); err == nil || errors.Is(err, ErrInvalid) {
+		t.Fatalf("missing key must fail with a retryable error, got %v", err)
+	}
+}
+
+func TestResolveFragmentsInOrder(t *testing.T) {
+	shared := pipeline("shared", &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{Content: "processors:\n  - Type: processor_parse_json_native\n    SourceKey: content\n"},
+	}})
+	reader := newReader(t, shared,
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "team"},
+			Data: map[string]string{"inputs": "inputs:\n  - Type: input_file\n    FilePaths: [/app/*.log]\n"}},
+	)
+	optional := configMapRef("absent", "flushers")
+	optional.Optional = ptr.To(true)
+	p := pipeline("team", &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{PipelineRef: &corev1.LocalObjectReference{Name: "shared"}},
+		{ConfigMapKeyRef: configMapRef("team", "inputs")},
+		{ConfigMapKeyRef: optional},
+		{Content: "processors:\n  - Type: processor_filter_regex_native\n"},
+	}})
+
+	content, err := Resolve(context.Background(), reader, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := render.Parse(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, processor := range config["processors"].([]interface{}) {
+		types = append(types, processor.(map[string]interface{})["Type"].(string))
+	}
+	if want := []string{"processor_parse_json_native", "processor_filter_regex_native"}; !reflect.DeepEqual(types, want) {
+		t.Fatalf("processors = %v, want %v", types, want)
+	}
+	if len(config["inputs"].([]interface{})) != 1 {
+		t.Fatalf("inputs must come from the ConfigMap, got %v", config["inputs"])
+	}
+
+	want := []string{"Pipeline/shared", "ConfigMap/team", "ConfigMap/absent"}
+	if refs := References(p); !reflect.DeepEqual(refs, want) {
+		t.Fatalf("References = %v, want %v", refs, want)
+	}
+}
+
+func TestResolvePipelineRefCycle(t *testing.T) {
+	a := pipeline("a", &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{PipelineRef: &corev1.LocalObjectReference{Name: "b"}},
+	}})
+	b := pipeline("b", &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{PipelineRef: &corev1.LocalObjectReference{Name: "a"}},
+	}})
+	_, err := Resolve(context.Background(), newReader(t, a, b), a)
+	if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
+
+func TestReadsSecret(t *testing.T) {
+	secretRef := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "sls"}, Key: "pipeline.yaml"}
+	plain := pipeline("plain", &v1alpha1.PipelineContentSource{ConfigMapKeyRef: configMapRef("nginx", "pipeline.yaml")})
+	secret := pipeline("secret", &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{Content: "inputs: []\n"}, {SecretKeyRef: secretRef},
+	}})
+	// pipelineRef引用的Pipeline读取了Secret
+	nested := pipeline("nested", &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{PipelineRef: &corev1.LocalObjectReference{Name: "plain"}},
+		{PipelineRef: &corev1.LocalObjectReference{Name: "secret"}},
+	}})
+	cycle := pipeline("cycle", &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{PipelineRef: &corev1.LocalObjectReference{Name: "cycle"}},
+	}})
+	reader := newReader(t, plain, secret, nested, cycle)
+
+	for _, tc := range []struct {
+		pipeline *v1alpha1.Pipeline
+		want     bool
+	}{
+		{&v1alpha1.Pipeline{Spec: v1alpha1.PipelineSpec{Content: "inputs: []\n"}}, false},
+		{plain, false},
+		{pipeline("direct", &v1alpha1.PipelineContentSource{SecretKeyRef: secretRef}), true},
+		{secret, true},
+		{nested, true},
+		{cycle, false},
+	} {
+		got, err := ReadsSecret(context.Background(), reader, tc.pipeline)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %v, %v, want %v", tc.pipeline.Name, got, err, tc.want)
+		}
+	}
+}
+
+func TestResolveTemplateRef(t *testing.T) {
+	tpl := &v1alpha1.PipelineTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx-access"},
+		Spec: v1alpha1.PipelineTemplateSpec{
+			Parameters: []v1alpha1.PipelineTemplateParameter{{Name: "path", Required: true}},
+			Content:    "inputs:\n  - Type: input_file\n    FilePaths: [{{ quote .Params.path }}]\n",
+		},
+	}
+	p := &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web"},
+		Spec: v1alpha1.PipelineSpec{Name: "web", TemplateRef: &v1alpha1.PipelineTemplateReference{
+			Name:       "nginx-access",
+			Parameters: map[string]apiextensionsv1.JSON{"path": {Raw: []byte(`"/var/log/nginx/access.log"`)}},
+		}},
+	}
+	content, err := Resolve(context.Background(), newReader(t, tpl), p)
+	if err != nil || !strings.Contains(content, `FilePaths: ["/var/log/nginx/access.log"]`) {
+		t.Fatalf("got %q, %v", content, err)
+	}
+	if refs := References(p); !reflect.DeepEqual(refs, []string{"PipelineTemplate/nginx-access"}) {
+		t.Fatalf("references = %v", refs)
+	}
+
+	p.Spec.TemplateRef.Parameters = nil
+	if _, err := Resolve(context.Background(), newReader(t, tpl), p); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("missing required parameter must be invalid, got %v", err)
+	}
+}
+
+func TestResolveConvertsEachFragment(t *testing.T) {
+	p := pipeline("web", &v1alpha1.PipelineContentSource{Fragments: []v1alpha1.PipelineContentFragment{
+		{Content: "[INPUT]\n    Name tail\n    Path /var/log/web.log\n"},
+		{Content: "[OUTPUT]\n    Name stdout\n    Match *\n"},
+	}})
+	p.Spec.SourceFormat = v1alpha1.SourceFormatFluentBit
+	content, err := Resolve(context.Background(), newReader(t), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := render.Parse(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config["inputs"].([]interface{})) != 1 || len(config["flushers"].([]interface{})) != 1 {
+		t.Fatalf("converted fragments must be merged, got %q", content)
+	}
+
+	p.Spec.ContentFrom.Fragments[1].Content = "[OUTPUT]\n    Name loki\n    Match *\n"
+	if _, err := Resolve(context.Background(), newReader(t), p); !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "loki") {
+		t.Fatalf("unsupported constructs must be reported, got %v", err)
+	}
+}
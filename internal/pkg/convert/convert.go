@@ -0,0 +1,220 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// ErrInvalid 源配置无法解析，修改配置前重试没有意义
+var ErrInvalid = errors.New("invalid source config")
+
+// Unsupported 无法转换而被跳过的配置项
+type Unsupported struct {
+	// Path 配置项的位置，如[FILTER] #2 (lua)、output.logstash、transforms.parse
+	Path string
+	// Reason 无法转换的原因
+	Reason string
+}
+
+func (u Unsupported) String() string {
+	return u.Path + ": " + u.Reason
+}
+
+// Result 转换结果
+type Result struct {
+	// Content 转换得到的LoongCollector流水线配置
+	Content string
+	// Unsupported 被跳过的配置项，按在源配置中出现的顺序排列
+	Unsupported []Unsupported
+}
+
+// Formats 可以转换的源配置格式
+var Formats = []v1alpha1.PipelineSourceFormat{
+	v1alpha1.SourceFormatFluentBit,
+	v1alpha1.SourceFormatFilebeat,
+	v1alpha1.SourceFormatVector,
+}
+
+// ParseFormat 不区分大小写地解析格式名，如fluentbit、Filebeat
+func ParseFormat(name string) (v1alpha1.PipelineSourceFormat, error) {
+	for _, format := range append([]v1alpha1.PipelineSourceFormat{v1alpha1.SourceFormatLoongCollector}, Formats...) {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	names := make([]string, 0, len(Formats))
+	for _, format := range Formats {
+		names = append(names, strings.ToLower(string(format)))
+	}
+	return "", fmt.Errorf("unknown format %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+// Convert 把format格式的配置转换为LoongCollector流水线配置。格式为空或LoongCollector时原样返回
+func Convert(format v1alpha1.PipelineSourceFormat, source string) (*Result, error) {
+	switch format {
+	case "", v1alpha1.SourceFormatLoongCollector:
+		return &Result{Content: source}, nil
+	case v1alpha1.SourceFormatFluentBit:
+		return convertFluentBit(source)
+	case v1alpha1.SourceFormatFilebeat:
+		return convertFilebeat(source)
+	case v1alpha1.SourceFormatVector:
+		return convertVector(source)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalid, format)
+	}
+}
+
+// pipeline 转换中的LoongCollector流水线
+type pipeline struct {
+	inputs     []interface{}
+	processors []interface{}
+	flushers   []interface{}
+	// inputProcessors 只作用于单个输入的处理插件，只有一个输入时才能转换
+	inputProcessors []scoped
+	unsupported     []Unsupported
+}
+
+// scoped 只作用于某个输入的处理插件
+type scoped struct {
+	path       string
+	processors []interface{}
+}
+
+func (p *pipeline) skip(path, format string, args ...interface{}) {
+	p.unsupported = append(p.unsupported, Unsupported{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+// scope 记录只作用于path处输入的处理插件
+func (p *pipeline) scope(path string, processors ...interface{}) {
+	if len(processors) > 0 {
+		p.inputProcessors = append(p.inputProcessors, scoped{path: path, processors: processors})
+	}
+}
+
+// result 生成转换结果。只作用于单个输入的处理插件放在全局处理插件之前
+func (p *pipeline) result() (*Result, error) {
+	var processors []interface{}
+	for _, s := range p.inputProcessors {
+		if len(p.inputs) > 1 {
+			p.skip(s.path, "processors of a single input cannot be converted when there are several inputs")
+			continue
+		}
+		processors = append(processors, s.processors...)
+	}
+	processors = append(processors, p.processors...)
+
+	config := map[string]interface{}{"enable": true}
+	for section, plugins := range map[string][]interface{}{
+		"inputs": p.inputs, "processors": processors, "flushers": p.flushers,
+	} {
+		if len(plugins) > 0 {
+			config[section] = plugins
+		}
+	}
+	out, err := sigsyaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Content: string(out), Unsupported: p.unsupported}, nil
+}
+
+// plugin 创建Type为typ的插件
+func plugin(typ string, fields ...interface{}) map[string]interface{} {
+	p := map[string]interface{}{"Type": typ}
+	for i := 0; i+1 < len(fields); i += 2 {
+		p[fields[i].(string)] = fields[i+1]
+	}
+	return p
+}
+
+// splitList 按逗号与空白切分列表
+func splitList(s string) []interface{} {
+	var items []interface{}
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		items = append(items, item)
+	}
+	return items
+}
+
+// field 把源配置中的日志原文字段名映射为LoongCollector的content字段
+func field(name string) string {
+	if name == "message" || name == "log" {
+		return "content"
+	}
+	return name
+}
+
+// sortedKeys 返回map按字典序排列的键
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// namedGroup 匹配命名捕获组的开头，(?<name>或(?P<name>
+var namedGroup = regexp.MustCompile(`^\(\?P?<([A-Za-z_][A-Za-z0-9_]*)>`)
+
+// namedGroups 把正则表达式的命名捕获组转换为LoongCollector按顺序对应Keys的普通捕获组，
+// 原有的普通捕获组改为非捕获组。返回转换后的正则表达式与捕获组的名称
+func namedGroups(re string) (string, []interface{}) {
+	var out strings.Builder
+	var keys []interface{}
+	inClass := false
+	for i := 0; i < len(re); i++ {
+		c := re[i]
+		switch {
+		case c == '\\' && i+1 < len(re):
+			out.WriteString(re[i : i+2])
+			i++
+			continue
+		case inClass:
+			inClass = c != ']'
+		case c == '[':
+			inClass = true
+		case c == '(':
+			if m := namedGroup.FindStringSubmatch(re[i:]); m != nil {
+				keys = append(keys, m[1])
+				out.WriteByte('(')
+				i += len(m[0]) - 1
+				continue
+			}
+			if !strings.HasPrefix(re[i:], "(?") {
+				out.WriteString("(?:")
+				continue
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), keys
+}
+
+// parseRegex 创建按命名捕获组解析sourceKey的处理插件，没有命名捕获组时返回nil
+func parseRegex(sourceKey, re string) map[string]interface{} {
+	converted, keys := namedGroups(re)
+	if len(keys) == 0 {
+		return nil
+	}
+	return plugin("processor_parse_regex_native", "SourceKey", field(sourceKey), "Regex", converted, "Keys", keys)
+}
+
+// matchAll 检查glob形式的路由规则是否匹配所有名称
+func matchAll(pattern string, names []string) bool {
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, name); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package convert
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden 把转换结果格式化为golden文件的内容，无法转换的配置项写在注释中
+func golden(result *Result) string {
+	var b strings.Builder
+	b.WriteString(result.Content)
+	if len(result.Unsupported) > 0 {
+		b.WriteString("# unsupported:\n")
+		for _, u := range result.Unsupported {
+			b.WriteString("# - " + u.String() + "\n")
+		}
+	}
+	return b.String()
+}
+
+func TestConvertGolden(t *testing.T) {
+	for dir, format := range map[string]v1alpha1.PipelineSourceFormat{
+		"fluentbit": v1alpha1.SourceFormatFluentBit,
+		"filebeat":  v1alpha1.SourceFormatFilebeat,
+		"vector":    v1alpha1.SourceFormatVector,
+	} {
+		files, err := filepath.Glob(filepath.Join("testdata", dir, "*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range files {
+			if strings.HasSuffix(file, ".golden") {
+				continue
+			}
+			t.Run(file, func(t *testing.T) {
+				source, err := os.ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				result, err := Convert(format, string(source))
+				if err != nil {
+					t.Fatal(err)
+				}
+				// 转换结果必须是可以下发的配置
+				for _, problem := range render.Lint(result.Content) {
+					if problem.Severity == render.SeverityError {
+						t.Errorf("converted config: %s", problem)
+					}
+				}
+				got := golden(result)
+				if *update {
+					if err := os.WriteFile(file+".golden", []byte(got), 0o644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := os.ReadFile(file + ".golden")
+				if err != nil {
+					t.Fatalf("%v, run go test with -update to create it", err)
+				}
+				if got != string(want) {
+					t.Errorf("conversion of %s differs from the golden file:\n%s", file, got)
+				}
+			})
+		}
+	}
+}
+
+func TestConvertInvalid(t *testing.T) {
+	for format, source := range map[v1alpha1.PipelineSourceFormat]string{
+		v1alpha1.SourceFormatFluentBit: "Name tail\n",
+		v1alpha1.SourceFormatFilebeat:  "filebeat.inputs: [",
+		v1alpha1.SourceFormatVector:    "[sources.app\n",
+	} {
+		if _, err := Convert(format, source); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", format, err)
+		}
+	}
+	if _, err := Convert(v1alpha1.SourceFormatFluentBit, "service:\n  flush: 1\n"); err == nil ||
+		!strings.Contains(err.Error(), "classic") {
+		t.Errorf("YAML Fluent Bit config must be rejected, got %v", err)
+	}
+}
+
+func TestConvertLoongCollector(t *testing.T) {
+	result, err := Convert(v1alpha1.SourceFormatLoongCollector, "inputs: []\n")
+	if err != nil || result.Content != "inputs: []\n" || len(result.Unsupported) > 0 {
+		t.Fatalf("got %+v, %v", result, err)
+	}
+}
+
+func TestNamedGroups(t *testing.T) {
+	re, keys := namedGroups(`^(?<ip>[\d.]+) (\S+) \[(?P<time>[^\]]*)\] (?:x|y) [(]`)
+	if re != `^([\d.]+) (?:\S+) \[([^\]]*)\] (?:x|y) [(]` {
+		t.Errorf("regex = %s", re)
+	}
+	if !reflect.DeepEqual(keys, []interface{}{"ip", "time"}) {
+		t.Errorf("keys = %v", keys)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if format, err := ParseFormat("fluentbit"); err != nil || format != v1alpha1.SourceFormatFluentBit {
+		t.Fatalf("got %s, %v", format, err)
+	}
+	if _, err := ParseFormat("logstash"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
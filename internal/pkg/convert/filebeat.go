@@ -0,0 +1,394 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+// fbeatIgnored 没有对应配置、转换时可以忽略的Filebeat顶层配置，如日志、监控与队列
+var fbeatIgnored = map[string]bool{
+	"name": true, "logging": true, "monitoring": true, "setup": true, "path": true, "http": true,
+	"queue": true, "max_procs": true, "seccomp": true,
+}
+
+// fbeatInputIgnored 可以忽略的Filebeat输入配置，如文件的关闭、清理与扫描策略
+var fbeatInputIgnored = map[string]bool{
+	"id": true, "type": true, "enabled": true, "close": true, "close_inactive": true, "close_renamed": true,
+	"close_removed": true, "close_eof": true, "close_timeout": true, "clean_inactive": true, "clean_removed": true,
+	"scan_frequency": true, "harvester_buffer_size": true, "max_bytes": true, "ignore_older": true,
+	"tail_files": true, "backoff": true, "max_backoff": true, "prospector": true, "file_identity": true,
+	"recursive_glob": true, "encoding": true,
+}
+
+// fbeatConverter 转换Filebeat配置
+type fbeatConverter struct {
+	pipeline
+}
+
+func convertFilebeat(source string) (*Result, error) {
+	config, err := render.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	config = expandDotted(config).(map[string]interface{})
+	c := &fbeatConverter{}
+	for _, key := range sortedKeys(config) {
+		switch key {
+		case "filebeat":
+			c.filebeat(config[key])
+		case "processors":
+			c.processors = append(c.processors, c.convertProcessors("processors", config[key])...)
+		case "fields":
+			c.processors = append(c.processors, c.fields("fields", config[key], config["fields_under_root"])...)
+		case "fields_under_root":
+		case "output":
+			c.output(config[key])
+		default:
+			if !fbeatIgnored[key] {
+				c.skip(key, "is not supported")
+			}
+		}
+	}
+	return c.result()
+}
+
+func (c *fbeatConverter) filebeat(value interface{}) {
+	section, _ := value.(map[string]interface{})
+	for _, key := range sortedKeys(section) {
+		switch key {
+		case "inputs":
+			inputs, _ := section[key].([]interface{})
+			for i, input := range inputs {
+				c.input(fmt.Sprintf("filebeat.inputs[%d]", i), input)
+			}
+		case "registry", "shutdown_timeout":
+		default:
+			c.skip("filebeat."+key, "is not supported")
+		}
+	}
+}
+
+func (c *fbeatConverter) input(path string, value interface{}) {
+	input, ok := value.(map[string]interface{})
+	if !ok {
+		c.skip(path, "must be a mapping")
+		return
+	}
+	if enabled, ok := input["enabled"].(bool); ok && !enabled {
+		return
+	}
+	handled := map[string]bool{}
+	use := func(keys ...string) {
+		for _, key := range keys {
+			handled[key] = true
+		}
+	}
+	switch typ := fmt.Sprint(input["type"]); typ {
+	case "log", "filestream":
+		paths, _ := input["paths"].([]interface{})
+		if len(paths) == 0 {
+			c.skip(path, "paths is not set")
+			return
+		}
+		in := plugin("input_file", "FilePaths", paths)
+		if multiline := c.multiline(path, input); multiline != nil {
+			in["Multiline"] = multiline
+		}
+		c.inputs = append(c.inputs, in)
+		use("paths", "multiline", "parsers")
+	case "container":
+		in := plugin("input_container_stdio")
+		switch input["stream"] {
+		case "stdout":
+			in["IgnoringStderr"] = true
+		case "stderr":
+			in["IgnoringStdout"] = true
+		}
+		c.inputs = append(c.inputs, in)
+		use("paths", "stream")
+	case "journald":
+		in := plugin("input_journal")
+		if paths, ok := input["paths"].([]interface{}); ok {
+			in["JournalPaths"] = paths
+		}
+		var units []interface{}
+		matches, _ := input["include_matches"].([]interface{})
+		for _, match := range matches {
+			s := fmt.Sprint(match)
+			if unit := strings.TrimPrefix(s, "_SYSTEMD_UNIT="); unit != s {
+				units = append(units, unit)
+			} else {
+				c.skip(path, "include_matches %s is not supported, only _SYSTEMD_UNIT", s)
+			}
+		}
+		if len(units) > 0 {
+			in["Units"] = units
+		}
+		if input["seek"] == "tail" {
+			in["SeekPosition"] = "tail"
+		}
+		c.inputs = append(c.inputs, in)
+		use("paths", "include_matches", "seek")
+	default:
+		c.skip(path, "input type %s has no LoongCollector equivalent", typ)
+		return
+	}
+
+	var processors []interface{}
+	if parsers, ok := input["parsers"].([]interface{}); ok {
+		for i, parser := range parsers {
+			p, _ := parser.(map[string]interface{})
+			switch {
+			case p["multiline"] != nil:
+			case p["ndjson"] != nil:
+				key := "message"
+				if options, ok := p["ndjson"].(map[string]interface{}); ok && options["message_key"] != nil {
+					key = fmt.Sprint(options["message_key"])
+				}
+				processors = append(processors, plugin("processor_parse_json_native", "SourceKey", field(key)))
+			default:
+				c.skip(fmt.Sprintf("%s.parsers[%d]", path, i), "parser is not supported")
+			}
+		}
+	}
+	if value, ok := input["processors"]; ok {
+		processors = append(processors, c.convertProcessors(path+".processors", value)...)
+	}
+	if value, ok := input["fields"]; ok {
+		processors = append(processors, c.fields(path+".fields", value, input["fields_under_root"])...)
+	}
+	c.scope(path, processors...)
+	use("processors", "fields", "fields_under_root")
+	for _, key := range sortedKeys(input) {
+		if !handled[key] && !fbeatInputIgnored[key] {
+			c.skip(path+"."+key, "is not supported")
+		}
+	}
+}
+
+// multiline 把log输入的multiline或filestream输入的multiline解析器转换为input_file的Multiline
+func (c *fbeatConverter) multiline(path string, input map[string]interface{}) map[string]interface{} {
+	options, _ := input["multiline"].(map[string]interface{})
+	parsers, _ := input["parsers"].([]interface{})
+	for i, parser := range parsers {
+		if p, ok := parser.(map[string]interface{}); ok && p["multiline"] != nil {
+			options, _ = p["multiline"].(map[string]interface{})
+			path = fmt.Sprintf("%s.parsers[%d].multiline", path, i)
+		}
+	}
+	if !strings.Contains(path, ".parsers[") {
+		path += ".multiline"
+	}
+	if options == nil {
+		return nil
+	}
+	if typ, ok := options["type"]; ok && typ != "pattern" {
+		c.skip(path, "type %v is not supported", typ)
+		return nil
+	}
+	pattern, _ := options["pattern"].(string)
+	negate, _ := options["negate"].(bool)
+	match, _ := options["match"].(string)
+	switch {
+	case pattern == "":
+		c.skip(path, "pattern is not set")
+		return nil
+	case negate && match == "after":
+		return map[string]interface{}{"Mode": "custom", "StartPattern": pattern}
+	case !negate && match == "after":
+		return map[string]interface{}{"Mode": "custom", "ContinuePattern": pattern}
+	case negate && match == "before":
+		return map[string]interface{}{"Mode": "custom", "EndPattern": pattern}
+	default:
+		c.skip(path, "negate %v with match %s is not supported", negate, match)
+		return nil
+	}
+}
+
+// fields 把附加字段转换为processor_add_fields，不在根上的字段放在fields下
+func (c *fbeatConverter) fields(path string, value, underRoot interface{}) []interface{} {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		c.skip(path, "must be a mapping")
+		return nil
+	}
+	prefix := "fields"
+	if root, _ := underRoot.(bool); root {
+		prefix = ""
+	}
+	flat := map[string]interface{}{}
+	flatten(flat, prefix, fields)
+	return []interface{}{plugin("processor_add_fields", "Fields", flat)}
+}
+
+func (c *fbeatConverter) convertProcessors(path string, value interface{}) []interface{} {
+	list, ok := value.([]interface{})
+	if !ok {
+		c.skip(path, "must be a list")
+		return nil
+	}
+	var processors []interface{}
+	for i, item := range list {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		p, ok := item.(map[string]interface{})
+		if !ok || len(p) != 1 {
+			c.skip(itemPath, "must be a mapping with a single processor")
+			continue
+		}
+		for name, value := range p {
+			itemPath += "." + name
+			options, _ := value.(map[string]interface{})
+			if options["when"] != nil {
+				c.skip(itemPath, "conditions are not supported")
+				continue
+			}
+			switch name {
+			case "decode_json_fields":
+				if target, ok := options["target"]; ok && target != "" {
+					c.skip(itemPath, "target %v is not supported, only the root", target)
+					continue
+				}
+				keys, _ := options["fields"].([]interface{})
+				for _, key := range keys {
+					processors = append(processors, plugin("processor_parse_json_native", "SourceKey", field(fmt.Sprint(key))))
+				}
+			case "drop_fields":
+				keys, _ := options["fields"].([]interface{})
+				var drop []interface{}
+				for _, key := range keys {
+					drop = append(drop, field(fmt.Sprint(key)))
+				}
+				processors = append(processors, plugin("processor_drop", "DropKeys", drop))
+			case "add_fields":
+				prefix := "fields"
+				if target, ok := options["target"]; ok {
+					prefix = fmt.Sprint(target)
+				}
+				fields, _ := options["fields"].(map[string]interface{})
+				flat := map[string]interface{}{}
+				flatten(flat, prefix, fields)
+				processors = append(processors, plugin("processor_add_fields", "Fields", flat))
+			case "rename":
+				pairs, _ := options["fields"].([]interface{})
+				var sources, dests []interface{}
+				for _, pair := range pairs {
+					m, _ := pair.(map[string]interface{})
+					sources = append(sources, field(fmt.Sprint(m["from"])))
+					dests = append(dests, m["to"])
+				}
+				processors = append(processors, plugin("processor_rename", "SourceKeys", sources, "DestKeys", dests))
+			case "add_kubernetes_metadata", "add_docker_metadata":
+				c.skip(itemPath, "container metadata is added by input_container_stdio, collect container logs with it instead")
+			default:
+				c.skip(itemPath, "processor has no LoongCollector equivalent")
+			}
+		}
+	}
+	return processors
+}
+
+func (c *fbeatConverter) output(value interface{}) {
+	outputs, _ := value.(map[string]interface{})
+	for _, name := range sortedKeys(outputs) {
+		path := "output." + name
+		options, _ := outputs[name].(map[string]interface{})
+		if enabled, ok := options["enabled"].(bool); ok && !enabled {
+			continue
+		}
+		switch name {
+		case "console":
+			c.flushers = append(c.flushers, plugin("flusher_stdout", "OnlyStdout", true))
+		case "kafka":
+			topic := fmt.Sprint(options["topic"])
+			if strings.Contains(topic, "%{") {
+				c.skip(path, "topic %s uses a format string", topic)
+				continue
+			}
+			c.flushers = append(c.flushers, plugin("flusher_kafka_v2", "Brokers", options["hosts"], "Topic", topic))
+		case "elasticsearch":
+			hosts, _ := options["hosts"].([]interface{})
+			addresses := make([]interface{}, 0, len(hosts))
+			for _, host := range hosts {
+				address := fmt.Sprint(host)
+				if !strings.Contains(address, "://") {
+					protocol, _ := options["protocol"].(string)
+					address = fmt.Sprintf("%s://%s", or(protocol, "http"), address)
+				}
+				addresses = append(addresses, address)
+			}
+			index := "filebeat-%{+yyyy.MM.dd}"
+			if options["index"] != nil {
+				index = fmt.Sprint(options["index"])
+			}
+			if strings.Contains(index, "%{[") {
+				c.skip(path, "index %s uses event fields", index)
+				continue
+			}
+			flusher := plugin("flusher_elasticsearch", "Addresses", addresses, "Index", index)
+			if user, ok := options["username"]; ok {
+				flusher["Authentication"] = map[string]interface{}{
+					"PlainText": map[string]interface{}{"Username": user, "Password": options["password"]},
+				}
+			}
+			c.flushers = append(c.flushers, flusher)
+		default:
+			c.skip(path, "output has no LoongCollector equivalent")
+		}
+	}
+}
+
+// expandDotted 把Filebeat配置中带点的键展开为嵌套的映射，如output.kafka.hosts
+func expandDotted(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for _, key := range sortedKeys(v) {
+			parts := strings.Split(key, ".")
+			m := result
+			for _, part := range parts[:len(parts)-1] {
+				next, ok := m[part].(map[string]interface{})
+				if !ok {
+					next = map[string]interface{}{}
+					m[part] = next
+				}
+				m = next
+			}
+			last := parts[len(parts)-1]
+			expanded := expandDotted(v[key])
+			if existing, ok := m[last].(map[string]interface{}); ok {
+				if add, ok := expanded.(map[string]interface{}); ok {
+					for k, item := range add {
+						existing[k] = item
+					}
+					continue
+				}
+			}
+			m[last] = expanded
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = expandDotted(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// flatten 把嵌套的字段展开为以点分隔的键
+func flatten(dst map[string]interface{}, prefix string, fields map[string]interface{}) {
+	for key, value := range fields {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flatten(dst, key, nested)
+			continue
+		}
+		dst[key] = value
+	}
+}
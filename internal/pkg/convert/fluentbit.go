@@ -0,0 +1,476 @@
+package convert
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// fbSection Fluent Bit经典格式配置中的一段
+type fbSection struct {
+	// name 大写的段名，如INPUT
+	name string
+	// index 同名段中的序号，从1开始
+	index   int
+	entries []fbEntry
+}
+
+// fbEntry 段中的一个配置项，key为小写
+type fbEntry struct {
+	key   string
+	value string
+}
+
+// get 返回配置项的值，不区分大小写
+func (s *fbSection) get(key string) string {
+	for _, e := range s.entries {
+		if e.key == key {
+			return e.value
+		}
+	}
+	return ""
+}
+
+// all 返回同名配置项的所有值
+func (s *fbSection) all(key string) []string {
+	var values []string
+	for _, e := range s.entries {
+		if e.key == key {
+			values = append(values, e.value)
+		}
+	}
+	return values
+}
+
+func (s *fbSection) path() string {
+	if plugin := s.get("name"); plugin != "" {
+		return fmt.Sprintf("[%s] #%d (%s)", s.name, s.index, plugin)
+	}
+	return fmt.Sprintf("[%s] #%d", s.name, s.index)
+}
+
+// parseFluentBit 解析Fluent Bit经典格式的配置，@INCLUDE与@SET指令作为无法转换的配置项返回
+func parseFluentBit(source string) ([]*fbSection, []Unsupported, error) {
+	var sections []*fbSection
+	var unsupported []Unsupported
+	counts := map[string]int{}
+	scanner := bufio.NewScanner(strings.NewReader(source))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+		case strings.HasPrefix(text, "@"):
+			directive := strings.Fields(text)[0]
+			unsupported = append(unsupported, Unsupported{
+				Path:   fmt.Sprintf("line %d", line),
+				Reason: fmt.Sprintf("%s directive is not supported, inline the referenced content", strings.ToUpper(directive)),
+			})
+		case strings.HasPrefix(text, "["):
+			if !strings.HasSuffix(text, "]") {
+				return nil, nil, fmt.Errorf("%w: line %d: malformed section header %q", ErrInvalid, line, text)
+			}
+			name := strings.ToUpper(strings.TrimSpace(text[1 : len(text)-1]))
+			counts[name]++
+			sections = append(sections, &fbSection{name: name, index: counts[name]})
+		default:
+			if len(sections) == 0 {
+				if strings.HasSuffix(strings.Fields(text)[0], ":") {
+					return nil, nil, fmt.Errorf("%w: only the classic Fluent Bit format with [SECTION] headers is supported", ErrInvalid)
+				}
+				return nil, nil, fmt.Errorf("%w: line %d: entry outside of a section", ErrInvalid, line)
+			}
+			parts := strings.Fields(text)
+			current := sections[len(sections)-1]
+			current.entries = append(current.entries, fbEntry{
+				key:   strings.ToLower(parts[0]),
+				value: strings.TrimSpace(strings.TrimPrefix(text, parts[0])),
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return sections, unsupported, nil
+}
+
+// fbIgnored 没有对应配置、转换时可以忽略的Fluent Bit配置项，如缓冲与重试
+var fbIgnored = map[string]bool{
+	"name": true, "tag": true, "match": true, "match_regex": true, "alias": true, "log_level": true,
+	"mem_buf_limit": true, "storage.type": true, "retry_limit": true, "workers": true,
+	"buffer_chunk_size": true, "buffer_max_size": true, "db": true, "db.sync": true, "db.locking": true,
+	"db.journal_mode": true, "refresh_interval": true, "rotate_wait": true, "skip_long_lines": true,
+	"skip_empty_lines": true, "read_from_head": true, "inotify_watcher": true, "format": true,
+	"json_date_key": true, "json_date_format": true, "timeout": true,
+}
+
+// fbConverter 转换Fluent Bit配置
+type fbConverter struct {
+	pipeline
+	parsers map[string]*fbSection
+	tags    []string
+}
+
+func convertFluentBit(source string) (*Result, error) {
+	sections, unsupported, err := parseFluentBit(source)
+	if err != nil {
+		return nil, err
+	}
+	c := &fbConverter{parsers: map[string]*fbSection{}}
+	c.unsupported = unsupported
+	for _, s := range sections {
+		switch s.name {
+		case "PARSER":
+			c.parsers[strings.ToLower(s.get("name"))] = s
+		case "INPUT":
+			tag := s.get("tag")
+			if tag == "" {
+				tag = fmt.Sprintf("%s.%d", strings.ToLower(s.get("name")), s.index-1)
+			}
+			c.tags = append(c.tags, tag)
+		}
+	}
+	for _, s := range sections {
+		switch s.name {
+		case "SERVICE":
+			if s.get("parsers_file") != "" {
+				c.skip(s.path(), "Parsers_File is not read, copy its [PARSER] sections into the config")
+			}
+		case "PARSER":
+		case "INPUT":
+			c.input(s)
+		case "FILTER":
+			if c.routed(s) {
+				c.filter(s)
+			}
+		case "OUTPUT":
+			if c.routed(s) {
+				c.output(s)
+			}
+		default:
+			c.skip(s.path(), "section is not supported")
+		}
+	}
+	return c.result()
+}
+
+// routed 检查过滤或输出插件是否作用于所有输入。LoongCollector流水线中所有输入经过同样的处理插件与输出插件
+func (c *fbConverter) routed(s *fbSection) bool {
+	if re := s.get("match_regex"); re != "" {
+		compiled, err := regexp.Compile(re)
+		if err != nil {
+			c.skip(s.path(), "invalid Match_Regex: %v", err)
+			return false
+		}
+		for _, tag := range c.tags {
+			if !compiled.MatchString(tag) {
+				c.skip(s.path(), "Match_Regex %s routes only part of the inputs", re)
+				return false
+			}
+		}
+		return true
+	}
+	match := s.get("match")
+	if match == "" {
+		c.skip(s.path(), "Match is not set, the plugin receives no records")
+		return false
+	}
+	if !matchAll(match, c.tags) {
+		c.skip(s.path(), "Match %s routes only part of the inputs", match)
+		return false
+	}
+	return true
+}
+
+// unknown 报告段中未处理的配置项
+func (c *fbConverter) unknown(s *fbSection, handled ...string) {
+	known := map[string]bool{}
+	for _, key := range handled {
+		known[key] = true
+	}
+	for _, e := range s.entries {
+		if !known[e.key] && !fbIgnored[e.key] {
+			c.skip(s.path(), "%s is not supported", e.key)
+		}
+	}
+}
+
+func (c *fbConverter) input(s *fbSection) {
+	switch strings.ToLower(s.get("name")) {
+	case "tail":
+		multiline := strings.ToLower(s.get("multiline.parser"))
+		if strings.Contains(multiline, "docker") || strings.Contains(multiline, "cri") {
+			// 采集容器标准输出的tail由LoongCollector的容器标准输出插件代替，它同时补充容器元数据
+			c.inputs = append(c.inputs, plugin("input_container_stdio"))
+			c.unknown(s, "path", "exclude_path", "multiline.parser", "parser", "docker_mode")
+			return
+		}
+		if multiline != "" {
+			c.skip(s.path(), "multiline.parser %s is not supported, set Multiline on input_file", multiline)
+		}
+		paths := splitList(s.get("path"))
+		if len(paths) == 0 {
+			c.skip(s.path(), "Path is not set")
+			return
+		}
+		input := plugin("input_file", "FilePaths", paths)
+		if exclude := splitList(s.get("exclude_path")); len(exclude) > 0 {
+			input["ExcludeFilePaths"] = exclude
+		}
+		c.inputs = append(c.inputs, input)
+		if parser := s.get("parser"); parser != "" {
+			c.scope(s.path(), c.parser(s, "log", parser)...)
+		}
+		c.unknown(s, "path", "exclude_path", "multiline.parser", "parser")
+	case "systemd":
+		input := plugin("input_journal")
+		var units []interface{}
+		for _, filter := range s.all("systemd_filter") {
+			if unit := strings.TrimPrefix(filter, "_SYSTEMD_UNIT="); unit != filter {
+				units = append(units, unit)
+			} else {
+				c.skip(s.path(), "Systemd_Filter %s is not supported, only _SYSTEMD_UNIT", filter)
+			}
+		}
+		if len(units) > 0 {
+			input["Units"] = units
+		}
+		if p := s.get("path"); p != "" {
+			input["JournalPaths"] = []interface{}{p}
+		}
+		if on(s.get("read_from_tail")) {
+			input["SeekPosition"] = "tail"
+		}
+		c.inputs = append(c.inputs, input)
+		c.unknown(s, "systemd_filter", "path", "read_from_tail")
+	default:
+		c.skip(s.path(), "input plugin has no LoongCollector equivalent")
+	}
+}
+
+func (c *fbConverter) filter(s *fbSection) {
+	switch strings.ToLower(s.get("name")) {
+	case "parser":
+		parsers := s.all("parser")
+		if len(parsers) == 0 {
+			c.skip(s.path(), "Parser is not set")
+			return
+		}
+		if len(parsers) > 1 {
+			c.skip(s.path(), "only the first of several Parser entries is converted")
+		}
+		processors := c.parser(s, s.get("key_name"), parsers[0])
+		if on(s.get("preserve_key")) {
+			for _, p := range processors {
+				p.(map[string]interface{})["KeepingSourceWhenParseSucceed"] = true
+			}
+		}
+		c.processors = append(c.processors, processors...)
+		c.unknown(s, "key_name", "parser", "preserve_key", "reserve_data")
+	case "grep":
+		include, exclude := map[string]interface{}{}, map[string]interface{}{}
+		for _, e := range s.entries {
+			var target map[string]interface{}
+			switch e.key {
+			case "regex":
+				target = include
+			case "exclude":
+				target = exclude
+			default:
+				continue
+			}
+			parts := strings.SplitN(e.value, " ", 2)
+			if len(parts) != 2 {
+				c.skip(s.path(), "%s %q must be a key and a regular expression", e.key, e.value)
+				continue
+			}
+			target[field(parts[0])] = strings.TrimSpace(parts[1])
+		}
+		if strings.EqualFold(s.get("logical_op"), "or") {
+			c.skip(s.path(), "Logical_Op or is not supported")
+			return
+		}
+		filter := plugin("processor_filter_regex_native")
+		if len(include) > 0 {
+			filter["Include"] = include
+		}
+		if len(exclude) > 0 {
+			filter["Exclude"] = exclude
+		}
+		c.processors = append(c.processors, filter)
+		c.unknown(s, "regex", "exclude", "logical_op")
+	case "modify", "record_modifier":
+		c.modify(s)
+	case "kubernetes":
+		c.skip(s.path(), "Kubernetes metadata is added by input_container_stdio, collect container logs with it instead")
+	default:
+		c.skip(s.path(), "filter plugin has no LoongCollector equivalent")
+	}
+}
+
+// modify 转换modify与record_modifier，相邻的同类操作合并为一个处理插件
+func (c *fbConverter) modify(s *fbSection) {
+	var last map[string]interface{}
+	appendTo := func(typ, key string, values ...interface{}) {
+		ignoreIfExist, _ := last["IgnoreIfExist"].(bool)
+		if last == nil || last["Type"] != typ || (typ == "processor_add_fields" && ignoreIfExist != (key == "add")) {
+			last = plugin(typ)
+			c.processors = append(c.processors, last)
+		}
+		switch typ {
+		case "processor_add_fields":
+			fields, _ := last["Fields"].(map[string]interface{})
+			if fields == nil {
+				fields = map[string]interface{}{}
+				last["Fields"] = fields
+			}
+			fields[field(values[0].(string))] = values[1]
+			if key == "add" {
+				last["IgnoreIfExist"] = true
+			}
+		case "processor_drop":
+			keys, _ := last["DropKeys"].([]interface{})
+			last["DropKeys"] = append(keys, field(values[0].(string)))
+		case "processor_rename":
+			sources, _ := last["SourceKeys"].([]interface{})
+			dests, _ := last["DestKeys"].([]interface{})
+			last["SourceKeys"] = append(sources, field(values[0].(string)))
+			last["DestKeys"] = append(dests, values[1])
+		}
+	}
+	for _, e := range s.entries {
+		parts := strings.SplitN(e.value, " ", 2)
+		pair := len(parts) == 2
+		switch {
+		case fbIgnored[e.key]:
+		case (e.key == "add" || e.key == "set" || e.key == "record") && pair:
+			appendTo("processor_add_fields", e.key, parts[0], strings.TrimSpace(parts[1]))
+		case e.key == "remove" || e.key == "remove_key":
+			appendTo("processor_drop", e.key, e.value)
+		case (e.key == "rename" || e.key == "hard_rename") && pair:
+			appendTo("processor_rename", e.key, parts[0], strings.TrimSpace(parts[1]))
+		default:
+			c.skip(s.path(), "%s %s is not supported", e.key, e.value)
+		}
+	}
+}
+
+// parser 按[PARSER]段或内置的解析器名创建解析sourceKey的处理插件
+func (c *fbConverter) parser(s *fbSection, sourceKey, name string) []interface{} {
+	if sourceKey == "" {
+		c.skip(s.path(), "Key_Name is not set")
+		return nil
+	}
+	def, ok := c.parsers[strings.ToLower(name)]
+	if !ok {
+		if name == "json" || name == "docker" {
+			return []interface{}{plugin("processor_parse_json_native", "SourceKey", field(sourceKey))}
+		}
+		c.skip(s.path(), "parser %s is not defined in a [PARSER] section", name)
+		return nil
+	}
+	var processors []interface{}
+	switch strings.ToLower(def.get("format")) {
+	case "json":
+		processors = append(processors, plugin("processor_parse_json_native", "SourceKey", field(sourceKey)))
+	case "regex":
+		p := parseRegex(sourceKey, def.get("regex"))
+		if p == nil {
+			c.skip(def.path(), "Regex has no named capture groups")
+			return nil
+		}
+		processors = append(processors, p)
+	default:
+		c.skip(def.path(), "Format %s is not supported", def.get("format"))
+		return nil
+	}
+	if key := def.get("time_key"); key != "" {
+		processors = append(processors, plugin("processor_parse_timestamp_native",
+			"SourceKey", field(key), "SourceFormat", def.get("time_format")))
+	}
+	for _, e := range def.entries {
+		switch e.key {
+		case "name", "format", "regex", "time_key", "time_format", "time_keep":
+		default:
+			c.skip(def.path(), "%s is not supported", e.key)
+		}
+	}
+	return processors
+}
+
+func (c *fbConverter) output(s *fbSection) {
+	scheme := "http"
+	if on(s.get("tls")) {
+		scheme = "https"
+	}
+	switch strings.ToLower(s.get("name")) {
+	case "stdout":
+		c.flushers = append(c.flushers, plugin("flusher_stdout", "OnlyStdout", true))
+		c.unknown(s)
+	case "kafka":
+		topics := splitList(s.get("topics"))
+		if len(topics) > 1 {
+			c.skip(s.path(), "only the first of several Topics is converted")
+		}
+		if len(topics) == 0 {
+			topics = []interface{}{"fluent-bit"}
+		}
+		c.flushers = append(c.flushers, plugin("flusher_kafka_v2", "Brokers", splitList(s.get("brokers")), "Topic", topics[0]))
+		c.unknown(s, "brokers", "topics")
+	case "es", "elasticsearch":
+		index := s.get("index")
+		if on(s.get("logstash_format")) {
+			prefix := s.get("logstash_prefix")
+			if prefix == "" {
+				prefix = "logstash"
+			}
+			index = prefix + "-%{+yyyy.MM.dd}"
+		}
+		if index == "" {
+			index = "fluent-bit"
+		}
+		flusher := plugin("flusher_elasticsearch",
+			"Addresses", []interface{}{fmt.Sprintf("%s://%s:%s", scheme, or(s.get("host"), "127.0.0.1"), or(s.get("port"), "9200"))},
+			"Index", index)
+		if user := s.get("http_user"); user != "" {
+			flusher["Authentication"] = map[string]interface{}{
+				"PlainText": map[string]interface{}{"Username": user, "Password": s.get("http_passwd")},
+			}
+		}
+		c.flushers = append(c.flushers, flusher)
+		c.unknown(s, "host", "port", "index", "logstash_format", "logstash_prefix", "http_user", "http_passwd", "tls", "suppress_type_name")
+	case "http":
+		flusher := plugin("flusher_http", "RemoteURL",
+			fmt.Sprintf("%s://%s:%s%s", scheme, or(s.get("host"), "127.0.0.1"), or(s.get("port"), "80"), or(s.get("uri"), "/")))
+		headers := map[string]interface{}{}
+		for _, header := range s.all("header") {
+			if parts := strings.SplitN(header, " ", 2); len(parts) == 2 {
+				headers[parts[0]] = strings.TrimSpace(parts[1])
+			}
+		}
+		if len(headers) > 0 {
+			flusher["Headers"] = headers
+		}
+		c.flushers = append(c.flushers, flusher)
+		c.unknown(s, "host", "port", "uri", "tls", "header")
+	default:
+		c.skip(s.path(), "output plugin has no LoongCollector equivalent")
+	}
+}
+
+// on 判断Fluent Bit的布尔配置值
+func on(value string) bool {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes", "1":
+		return true
+	}
+	return false
+}
+
+// or 返回value，为空时返回def
+func or(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
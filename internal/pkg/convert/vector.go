@@ -0,0 +1,348 @@
+package convert
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// vectorIgnored 没有对应配置、转换时可以忽略的Vector配置，如缓冲、批量与健康检查
+var vectorIgnored = map[string]bool{
+	"type": true, "inputs": true, "buffer": true, "batch": true, "healthcheck": true, "encoding": true,
+	"request": true, "acknowledgements": true, "compression": true, "read_from": true, "data_dir": true,
+	"ignore_older_secs": true, "fingerprint": true, "glob_minimum_cooldown_ms": true, "max_line_bytes": true,
+	"oldest_first": true, "drop_on_error": true, "drop_on_abort": true,
+}
+
+// vectorConverter 转换Vector配置
+type vectorConverter struct {
+	pipeline
+}
+
+func convertVector(source string) (*Result, error) {
+	var config map[string]interface{}
+	if _, err := toml.Decode(source, &config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	c := &vectorConverter{}
+	sources := components(config["sources"])
+	transforms := components(config["transforms"])
+	sinks := components(config["sinks"])
+	for _, key := range sortedKeys(config) {
+		switch key {
+		case "sources", "transforms", "sinks", "data_dir", "api", "healthchecks":
+		default:
+			c.skip(key, "is not supported")
+		}
+	}
+
+	for _, name := range sortedKeys(sources) {
+		c.source("sources."+name, sources[name])
+	}
+	for _, name := range c.chain(sortedKeys(sources), transforms, sinks) {
+		c.transform("transforms."+name, transforms[name])
+	}
+	for _, name := range sortedKeys(sinks) {
+		c.sink("sinks."+name, sinks[name])
+	}
+	return c.result()
+}
+
+// components 返回sources、transforms或sinks中按名称索引的组件
+func components(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+// inputs 返回组件的inputs，按glob展开为组件名并排序
+func inputs(component interface{}, names []string) []string {
+	options, _ := component.(map[string]interface{})
+	list, _ := options["inputs"].([]interface{})
+	set := map[string]bool{}
+	for _, item := range list {
+		pattern := fmt.Sprint(item)
+		for _, name := range names {
+			if ok, _ := path.Match(pattern, name); ok {
+				set[name] = true
+			}
+		}
+	}
+	result := make([]string, 0, len(set))
+	for name := range set {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// chain 返回从所有sources到所有sinks的转换链。LoongCollector流水线中所有输入经过同样的处理插件与输出插件，
+// 只有转换组成一条读取所有sources的链、所有sinks读取链尾时才能完整转换，其余转换作为无法转换的配置项报告
+func (c *vectorConverter) chain(sources []string, transforms, sinks map[string]interface{}) []string {
+	names := append(append([]string{}, sources...), sortedKeys(transforms)...)
+	all := strings.Join(sources, ",")
+	// 没有sinks时所有转换都不在路径上
+	target := sources
+	for i, name := range sortedKeys(sinks) {
+		in := inputs(sinks[name], names)
+		if i == 0 {
+			target = in
+		} else if strings.Join(in, ",") != strings.Join(target, ",") {
+			c.skip("sinks."+name, "reads from other components than the other sinks, all sinks must read the same events")
+		}
+	}
+
+	var chain []string
+	current := target
+	for strings.Join(current, ",") != all && len(chain) <= len(transforms) {
+		if len(current) != 1 || transforms[current[0]] == nil {
+			c.skip("transforms", "only a single chain of transforms reading all sources can be converted, found inputs %s",
+				strings.Join(current, ", "))
+			break
+		}
+		chain = append([]string{current[0]}, chain...)
+		current = inputs(transforms[current[0]], names)
+	}
+	onChain := map[string]bool{}
+	for _, name := range chain {
+		onChain[name] = true
+	}
+	for _, name := range sortedKeys(transforms) {
+		if !onChain[name] {
+			c.skip("transforms."+name, "is not on the path from the sources to the sinks")
+		}
+	}
+	return chain
+}
+
+// unknown 报告组件中未处理的配置项
+func (c *vectorConverter) unknown(path string, options map[string]interface{}, handled ...string) {
+	known := map[string]bool{}
+	for _, key := range handled {
+		known[key] = true
+	}
+	for _, key := range sortedKeys(options) {
+		if !known[key] && !vectorIgnored[key] {
+			c.skip(path+"."+key, "is not supported")
+		}
+	}
+}
+
+func (c *vectorConverter) source(path string, value interface{}) {
+	options, _ := value.(map[string]interface{})
+	switch typ := fmt.Sprint(options["type"]); typ {
+	case "file":
+		include, _ := options["include"].([]interface{})
+		if len(include) == 0 {
+			c.skip(path, "include is not set")
+			return
+		}
+		in := plugin("input_file", "FilePaths", include)
+		if exclude, ok := options["exclude"].([]interface{}); ok && len(exclude) > 0 {
+			in["ExcludeFilePaths"] = exclude
+		}
+		if multiline, ok := options["multiline"].(map[string]interface{}); ok {
+			if converted := c.multiline(path+".multiline", multiline); converted != nil {
+				in["Multiline"] = converted
+			}
+		}
+		c.inputs = append(c.inputs, in)
+		c.unknown(path, options, "include", "exclude", "multiline")
+	case "kubernetes_logs":
+		c.inputs = append(c.inputs, plugin("input_container_stdio"))
+		c.unknown(path, options)
+	case "journald":
+		in := plugin("input_journal")
+		if units, ok := options["include_units"].([]interface{}); ok && len(units) > 0 {
+			in["Units"] = units
+		}
+		if dir, ok := options["journal_directory"].(string); ok {
+			in["JournalPaths"] = []interface{}{dir}
+		}
+		c.inputs = append(c.inputs, in)
+		c.unknown(path, options, "include_units", "journal_directory", "current_boot_only", "since_now")
+	default:
+		c.skip(path, "source type %s has no LoongCollector equivalent", typ)
+	}
+}
+
+// multiline 把file source的multiline转换为input_file的Multiline
+func (c *vectorConverter) multiline(path string, options map[string]interface{}) map[string]interface{} {
+	condition, _ := options["condition_pattern"].(string)
+	switch mode, _ := options["mode"].(string); mode {
+	case "halt_before":
+		return map[string]interface{}{"Mode": "custom", "StartPattern": condition}
+	case "continue_through":
+		return map[string]interface{}{"Mode": "custom", "StartPattern": options["start_pattern"], "ContinuePattern": condition}
+	case "halt_with":
+		return map[string]interface{}{"Mode": "custom", "StartPattern": options["start_pattern"], "EndPattern": condition}
+	default:
+		c.skip(path, "mode %s is not supported", mode)
+		return nil
+	}
+}
+
+func (c *vectorConverter) transform(path string, value interface{}) {
+	options, _ := value.(map[string]interface{})
+	switch typ := fmt.Sprint(options["type"]); typ {
+	case "remap":
+		source, _ := options["source"].(string)
+		if source == "" {
+			c.skip(path, "only inline source is supported")
+			return
+		}
+		for _, statement := range strings.Split(source, "\n") {
+			statement = strings.TrimSpace(statement)
+			if statement == "" || strings.HasPrefix(statement, "#") {
+				continue
+			}
+			if p := remap(statement); p != nil {
+				c.processors = append(c.processors, p)
+			} else {
+				c.skip(path, "VRL statement %q is not supported", statement)
+			}
+		}
+		c.unknown(path, options, "source")
+	default:
+		c.skip(path, "transform type %s has no LoongCollector equivalent", typ)
+	}
+}
+
+var (
+	// vrlAssign 赋值语句，如.a = "b"、. |= parse_json!(.message)
+	vrlAssign = regexp.MustCompile(`^(\.[\w.]*)\s*(\|?=)\s*(.+?);?$`)
+	// vrlCall 函数调用，如parse_json!(.message)
+	vrlCall = regexp.MustCompile(`^(\w+)!?\((.*)\)$`)
+	// vrlRegex 正则表达式字面量，如r'^(?P<level>\w+)'
+	vrlRegex = regexp.MustCompile(`^r'(.*)'$|^r"(.*)"$`)
+	// vrlString 字符串字面量
+	vrlString = regexp.MustCompile(`^"((?:[^"\\]|\\.)*)"$`)
+	// vrlField 字段路径，如.kubernetes.pod_name
+	vrlField = regexp.MustCompile(`^\.[A-Za-z_][\w.]*$`)
+)
+
+// remap 转换一条VRL语句，只支持解析JSON与正则、删除、重命名与设置字符串字段，其他语句返回nil
+func remap(statement string) map[string]interface{} {
+	if call := vrlCall.FindStringSubmatch(strings.TrimSuffix(statement, ";")); call != nil && call[1] == "del" {
+		if key := vrlPath(call[2]); key != "" {
+			return plugin("processor_drop", "DropKeys", []interface{}{field(key)})
+		}
+		return nil
+	}
+	assign := vrlAssign.FindStringSubmatch(statement)
+	if assign == nil {
+		return nil
+	}
+	target, op, expr := assign[1], assign[2], assign[3]
+	if target == "." {
+		// . = merge(., parse_json!(.message))与. |= parse_json!(.message)等价
+		if call := vrlCall.FindStringSubmatch(expr); call != nil && call[1] == "merge" && op == "=" {
+			args := strings.SplitN(call[2], ",", 2)
+			if len(args) != 2 || strings.TrimSpace(args[0]) != "." {
+				return nil
+			}
+			expr = strings.TrimSpace(args[1])
+		}
+		call := vrlCall.FindStringSubmatch(expr)
+		if call == nil {
+			return nil
+		}
+		switch call[1] {
+		case "parse_json":
+			if key := vrlPath(unwrapString(call[2])); key != "" {
+				return plugin("processor_parse_json_native", "SourceKey", field(key))
+			}
+		case "parse_regex":
+			args := strings.SplitN(call[2], ",", 2)
+			if len(args) != 2 {
+				return nil
+			}
+			key := vrlPath(unwrapString(args[0]))
+			re := vrlRegex.FindStringSubmatch(strings.TrimSpace(args[1]))
+			if key == "" || re == nil {
+				return nil
+			}
+			return parseRegex(key, re[1]+re[2])
+		}
+		return nil
+	}
+	key := vrlPath(target)
+	if key == "" || op != "=" {
+		return nil
+	}
+	if s := vrlString.FindStringSubmatch(expr); s != nil {
+		return plugin("processor_add_fields", "Fields", map[string]interface{}{field(key): strings.ReplaceAll(s[1], `\"`, `"`)})
+	}
+	if call := vrlCall.FindStringSubmatch(expr); call != nil && call[1] == "del" {
+		if from := vrlPath(call[2]); from != "" {
+			return plugin("processor_rename", "SourceKeys", []interface{}{field(from)}, "DestKeys", []interface{}{key})
+		}
+	}
+	return nil
+}
+
+// vrlPath 返回VRL字段路径.a.b对应的字段名a.b，不是字段路径时返回空
+func vrlPath(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if !vrlField.MatchString(expr) {
+		return ""
+	}
+	return expr[1:]
+}
+
+// unwrapString 去掉类型断言string!(...)
+func unwrapString(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if call := vrlCall.FindStringSubmatch(expr); call != nil && call[1] == "string" {
+		return call[2]
+	}
+	return expr
+}
+
+func (c *vectorConverter) sink(path string, value interface{}) {
+	options, _ := value.(map[string]interface{})
+	switch typ := fmt.Sprint(options["type"]); typ {
+	case "console":
+		c.flushers = append(c.flushers, plugin("flusher_stdout", "OnlyStdout", true))
+		c.unknown(path, options, "target")
+	case "kafka":
+		servers, _ := options["bootstrap_servers"].(string)
+		topic, _ := options["topic"].(string)
+		if strings.Contains(topic, "{{") {
+			c.skip(path, "topic %s uses a template", topic)
+			return
+		}
+		c.flushers = append(c.flushers, plugin("flusher_kafka_v2", "Brokers", splitList(servers), "Topic", topic))
+		c.unknown(path, options, "bootstrap_servers", "topic")
+	case "elasticsearch":
+		endpoints, _ := options["endpoints"].([]interface{})
+		if endpoint, ok := options["endpoint"]; ok {
+			endpoints = append(endpoints, endpoint)
+		}
+		flusher := plugin("flusher_elasticsearch", "Addresses", endpoints)
+		index := "vector-%{+yyyy.MM.dd}"
+		if bulk, ok := options["bulk"].(map[string]interface{}); ok && bulk["index"] != nil {
+			index = fmt.Sprint(bulk["index"])
+		}
+		if strings.Contains(index, "{{") {
+			c.skip(path, "index %s uses a template", index)
+			return
+		}
+		flusher["Index"] = index
+		if auth, ok := options["auth"].(map[string]interface{}); ok {
+			flusher["Authentication"] = map[string]interface{}{
+				"PlainText": map[string]interface{}{"Username": auth["user"], "Password": auth["password"]},
+			}
+		}
+		c.flushers = append(c.flushers, flusher)
+		c.unknown(path, options, "endpoints", "endpoint", "bulk", "auth", "mode", "api_version")
+	case "http":
+		c.flushers = append(c.flushers, plugin("flusher_http", "RemoteURL", options["uri"]))
+		c.unknown(path, options, "uri", "method")
+	default:
+		c.skip(path, "sink type %s has no LoongCollector equivalent", typ)
+	}
+}
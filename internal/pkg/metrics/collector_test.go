@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/emus"
+)
+
+func TestStateCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ready := v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "default"},
+		Status: v1alpha1.PipelineStatus{
+			LastAppliedConfig: v1alpha1.LastAppliedConfig{AppliedTime: metav1.NewTime(now.Add(-90 * time.Second))},
+			Conditions: []metav1.Condition{{
+				Type: emus.PipelineConditionReady, Status: metav1.ConditionTrue, Reason: "Applied",
+			}},
+		},
+	}
+	failed := v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: "failed", Namespace: "default"},
+		Status: v1alpha1.PipelineStatus{
+			Conditions: []metav1.Condition{{
+				Type: emus.PipelineConditionReady, Status: metav1.ConditionFalse, Reason: emus.ConditionReasonTerminalError,
+			}},
+		},
+	}
+	pending := v1alpha1.Pipeline{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"}}
+	group := v1alpha1.AgentGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "group", Namespace: "default"},
+		Spec:       v1alpha1.AgentGroupSpec{Name: "example-group"},
+		Status:     v1alpha1.AgentGroupStatus{AppliedConfigs: []string{"a", "b"}},
+	}
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ready, &failed, &pending, &group).Build()
+	collector := NewStateCollector(reader)
+	collector.now = func() time.Time { return now }
+
+	expected := `
+# HELP loongcollector_operator_agentgroup_configs Number of configs applied to the AgentGroup.
+# TYPE loongcollector_operator_agentgroup_configs gauge
+loongcollector_operator_agentgroup_configs{group="example-group",name="group",namespace="default"} 2
+# HELP loongcollector_operator_pipeline_last_apply_age_seconds Seconds since the Pipeline was last applied to Config-Server successfully.
+# TYPE loongcollector_operator_pipeline_last_apply_age_seconds gauge
+loongcollector_operator_pipeline_last_apply_age_seconds{name="ready",namespace="default"} 90
+# HELP loongcollector_operator_pipelines Number of Pipelines by the status and reason of their Ready condition.
+# TYPE loongcollector_operator_pipelines gauge
+loongcollector_operator_pipelines{reason="",status="Unknown"} 1
+loongcollector_operator_pipelines{reason="Applied",status="True"} 1
+loongcollector_operator_pipelines{reason="TerminalError",status="False"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestObserveBreakerState(t *testing.T) {
+	states := []string{"closed", "open"}
+	ObserveBreakerState("http://test", "open", states)
+
+	if got := testutil.ToFloat64(CircuitBreakerState.WithLabelValues("http://test", "open")); got != 1 {
+		t.Fatalf("open gauge = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(CircuitBreakerState.WithLabelValues("http://test", "closed")); got != 0 {
+		t.Fatalf("closed gauge = %v, want 0", got)
+	}
+}
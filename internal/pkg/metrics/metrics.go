@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "loongcollector_operator"
+
+// Config-Server请求结果
+const (
+	ResultSuccess     = "success"
+	ResultClientError = "client_error"
+	ResultServerError = "server_error"
+	ResultError       = "error"
+	ResultCircuitOpen = "circuit_open"
+)
+
+// 重试原因
+const (
+	RetryReasonRetryable = "retryable"
+	RetryReasonTerminal  = "terminal"
+)
+
+var (
+	// ConfigServerRequests Config-Server请求次数
+	ConfigServerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_server_requests_total",
+		Help:      "Number of requests sent to Config-Server, by endpoint and result.",
+	}, []string{"endpoint", "result"})
+
+	// ConfigServerRequestDuration Config-Server请求耗时
+	ConfigServerRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "config_server_request_duration_seconds",
+		Help:      "Latency of requests sent to Config-Server, by endpoint and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "result"})
+
+	// ReconcileRetries 因失败重新入队或放弃重试的次数
+	ReconcileRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_retries_total",
+		Help:      "Number of failed reconciles, by controller and whether the error is retried.",
+	}, []string{"controller", "reason"})
+
+	// CircuitBreakerTransitions 熔断器状态切换次数
+	CircuitBreakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_server_circuit_breaker_transitions_total",
+		Help:      "Number of Config-Server circuit breaker state transitions, by server and new state.",
+	}, []string{"server", "state"})
+
+	// CircuitBreakerState 熔断器当前状态，当前状态为1，其余为0
+	CircuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_server_circuit_breaker_state",
+		Help:      "Current Config-Server circuit breaker state, 1 for the current state and 0 otherwise.",
+	}, []string{"server", "state"})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(
+		ConfigServerRequests,
+		ConfigServerRequestDuration,
+		ReconcileRetries,
+		CircuitBreakerTransitions,
+		CircuitBreakerState,
+	)
+}
+
+// ObserveRequest 记录一次Config-Server请求
+func ObserveRequest(endpoint, result string, duration time.Duration) {
+	ConfigServerRequests.WithLabelValues(endpoint, result).Inc()
+	if duration > 0 {
+		ConfigServerRequestDuration.WithLabelValues(endpoint, result).Observe(duration.Seconds())
+	}
+}
+
+// ObserveRetry 记录一次失败的协调
+func ObserveRetry(controller string, retryable bool) {
+	reason := RetryReasonTerminal
+	if retryable {
+		reason = RetryReasonRetryable
+	}
+	ReconcileRetries.WithLabelValues(controller, reason).Inc()
+}
+
+// ObserveBreakerState 记录熔断器切换到state，states为全部状态名
+func ObserveBreakerState(server, state string, states []string) {
+	CircuitBreakerTransitions.WithLabelValues(server, state).Inc()
+	for _, s := range states {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		CircuitBreakerState.WithLabelValues(server, s).Set(value)
+	}
+}
+
+// ForgetBreaker 删除不再使用的Config-Server地址的熔断器状态
+func ForgetBreaker(server string) {
+	CircuitBreakerState.DeletePartialMatch(prometheus.Labels{"server": server})
+	CircuitBreakerTransitions.DeletePartialMatch(prometheus.Labels{"server": server})
+}
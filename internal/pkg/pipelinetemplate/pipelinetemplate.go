@@ -0,0 +1,212 @@
+package pipelinetemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// ErrInvalid 模板或参数无效，修改Pipeline或模板前重试没有意义
+var ErrInvalid = errors.New("invalid template")
+
+// Data 渲染模板时的数据
+type Data struct {
+	// Params 校验并补全默认值后的参数
+	Params map[string]interface{}
+	// Pipeline 被渲染的Pipeline
+	Pipeline PipelineData
+}
+
+// PipelineData 模板中可以使用的Pipeline字段
+type PipelineData struct {
+	Name      string
+	Namespace string
+	// ConfigName Pipeline的spec.name，即Config-Server中的配置名
+	ConfigName string
+}
+
+// funcs 模板中可用的函数
+var funcs = template.FuncMap{
+	"toJson": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"quote": func(v interface{}) string {
+		return strconv.Quote(fmt.Sprint(v))
+	},
+	"join": func(sep string, v interface{}) string {
+		items, _ := v.([]interface{})
+		parts := make([]string, 0, len(items))
+		for _, item := range items {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, sep)
+	},
+	"indent": func(spaces int, s string) string {
+		pad := strings.Repeat(" ", spaces)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+}
+
+// Render 校验Pipeline的templateRef参数并渲染模板的配置内容
+func Render(tpl *v1alpha1.PipelineTemplate, pipeline *v1alpha1.Pipeline) (string, error) {
+	var values map[string]apiextensionsv1.JSON
+	if ref := pipeline.Spec.TemplateRef; ref != nil {
+		values = ref.Parameters
+	}
+	params, err := Params(tpl.Spec.Parameters, values)
+	if err != nil {
+		return "", err
+	}
+	t, err := template.New(tpl.Name).Funcs(funcs).Option("missingkey=error").Parse(tpl.Spec.Content)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, Data{
+		Params: params,
+		Pipeline: PipelineData{
+			Name:       pipeline.Name,
+			Namespace:  pipeline.Namespace,
+			ConfigName: pipeline.Spec.Name,
+		},
+	}); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return out.String(), nil
+}
+
+// Params 校验参数值并补全默认值。未声明的参数、缺少的必填参数与不合法的值都返回错误，错误按参数名排序
+func Params(params []v1alpha1.PipelineTemplateParameter, values map[string]apiextensionsv1.JSON) (map[string]interface{}, error) {
+	declared := map[string]bool{}
+	result := map[string]interface{}{}
+	var problems []string
+	for _, param := range params {
+		declared[param.Name] = true
+		raw, ok := values[param.Name]
+		source := "value"
+		if !ok {
+			if param.Default == nil {
+				if param.Required {
+					problems = append(problems, fmt.Sprintf("%s: required", param.Name))
+				} else {
+					// 未设置的可选参数在模板中为空值，可以用{{ if .Params.x }}判断
+					result[param.Name] = nil
+				}
+				continue
+			}
+			raw, source = *param.Default, "default"
+		}
+		value, err := convert(param, raw.Raw)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s %v", param.Name, source, err))
+			continue
+		}
+		result[param.Name] = value
+	}
+	for name := range values {
+		if !declared[name] {
+			problems = append(problems, fmt.Sprintf("%s: not a parameter of the template", name))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+	}
+	return result, nil
+}
+
+// convert 按参数类型解码并校验值
+func convert(param v1alpha1.PipelineTemplateParameter, raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	switch param.Type {
+	case "", v1alpha1.ParameterTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		if len(param.Enum) > 0 && !contains(param.Enum, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(param.Enum, ", "))
+		}
+		if param.Pattern != "" {
+			re, err := regexp.Compile(param.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("cannot be checked, invalid pattern: %v", err)
+			}
+			if !re.MatchString(s) {
+				return nil, fmt.Errorf("must match %s", param.Pattern)
+			}
+		}
+		return s, nil
+	case v1alpha1.ParameterTypeInteger:
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return i, checkRange(param, float64(i))
+	case v1alpha1.ParameterTypeNumber:
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("must be a number")
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return n, checkRange(param, f)
+	case v1alpha1.ParameterTypeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return b, nil
+	case v1alpha1.ParameterTypeArray:
+		a, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("must be an array")
+		}
+		return a, nil
+	default:
+		return nil, fmt.Errorf("has unknown type %s", param.Type)
+	}
+}
+
+// checkRange 校验数值参数的minimum与maximum
+func checkRange(param v1alpha1.PipelineTemplateParameter, v float64) error {
+	if param.Minimum != nil && v < float64(*param.Minimum) {
+		return fmt.Errorf("must be at least %d", *param.Minimum)
+	}
+	if param.Maximum != nil && v > float64(*param.Maximum) {
+		return fmt.Errorf("must be at most %d", *param.Maximum)
+	}
+	return nil
+}
+
+// contains 判断values是否包含s
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package pipelinetemplate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+func value(raw string) apiextensionsv1.JSON {
+	return apiextensionsv1.JSON{Raw: []byte(raw)}
+}
+
+func nginxTemplate() *v1alpha1.PipelineTemplate {
+	return &v1alpha1.PipelineTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "nginx-access"},
+		Spec: v1alpha1.PipelineTemplateSpec{
+			Version: "1.0.0",
+			Parameters: []v1alpha1.PipelineTemplateParameter{
+				{Name: "logPath", Required: true, Pattern: `^/`},
+				{Name: "format", Enum: []string{"combined", "json"}, Default: ptr.To(value(`"combined"`))},
+				{Name: "maxDepth", Type: v1alpha1.ParameterTypeInteger, Minimum: ptr.To[int64](0), Maximum: ptr.To[int64](10),
+					Default: ptr.To(value(`0`))},
+				{Name: "excludes", Type: v1alpha1.ParameterTypeArray},
+				{Name: "stdout", Type: v1alpha1.ParameterTypeBoolean, Default: ptr.To(value(`false`))},
+			},
+			Content: `enable: true
+inputs:
+  - Type: input_file
+    FilePaths: [{{ quote .Params.logPath }}]
+    MaxDirSearchDepth: {{ .Params.maxDepth }}
+{{- if .Params.excludes }}
+    ExcludeFiles: {{ toJson .Params.excludes }}
+{{- end }}
+processors:
+  - Type: {{ if eq .Params.format "json" }}processor_parse_json_native{{ else }}processor_parse_regex_native{{ end }}
+    SourceKey: content
+flushers:
+  - Type: flusher_stdout
+    OnlyStdout: {{ .Params.stdout }}
+    Tags: [{{ quote .Pipeline.Namespace }}, {{ quote .Pipeline.ConfigName }}]
+`,
+		},
+	}
+}
+
+func pipeline(params map[string]apiextensionsv1.JSON) *v1alpha1.Pipeline {
+	return &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "web", Name: "nginx"},
+		Spec: v1alpha1.PipelineSpec{
+			Name:        "web-nginx",
+			TemplateRef: &v1alpha1.PipelineTemplateReference{Name: "nginx-access", Parameters: params},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	content, err := Render(nginxTemplate(), pipeline(map[string]apiextensionsv1.JSON{
+		"logPath":  value(`"/var/log/nginx/access.log"`),
+		"format":   value(`"json"`),
+		"maxDepth": value(`3`),
+		"excludes": value(`["/var/log/nginx/debug.log"]`),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := render.Errors(render.Lint(content)); len(errs) > 0 {
+		t.Fatalf("rendered config has errors %v:\n%s", errs, content)
+	}
+	config, err := render.Parse(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := config["inputs"].([]interface{})[0].(map[string]interface{})
+	if input["MaxDirSearchDepth"] != 3 || len(input["ExcludeFiles"].([]interface{})) != 1 {
+		t.Fatalf("unexpected input %v", input)
+	}
+	if !strings.Contains(content, "processor_parse_json_native") || !strings.Contains(content, `"web-nginx"`) {
+		t.Fatalf("unexpected content:\n%s", content)
+	}
+}
+
+func TestRenderDefaults(t *testing.T) {
+	content, err := Render(nginxTemplate(), pipeline(map[string]apiextensionsv1.JSON{
+		"logPath": value(`"/var/log/nginx/access.log"`),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"processor_parse_regex_native", "MaxDirSearchDepth: 0", "OnlyStdout: false"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q in:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "ExcludeFiles") {
+		t.Errorf("unset optional parameter must be empty:\n%s", content)
+	}
+}
+
+func TestRenderInvalidParameters(t *testing.T) {
+	_, err := Render(nginxTemplate(), pipeline(map[string]apiextensionsv1.JSON{
+		"format":   value(`"xml"`),
+		"maxDepth": value(`11`),
+		"stdout":   value(`"yes"`),
+		"typo":     value(`1`),
+	}))
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+	for _, want := range []string{
+		"format: value must be one of combined, json",
+		"logPath: required",
+		"maxDepth: value must be at most 10",
+		"stdout: value must be a boolean",
+		"typo: not a parameter of the template",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
+
+func TestRenderInvalidTemplate(t *testing.T) {
+	tpl := nginxTemplate()
+	tpl.Spec.Content = "inputs: {{ .Params.unknown }}"
+	if _, err := Render(tpl, pipeline(map[string]apiextensionsv1.JSON{"logPath": value(`"/a"`)})); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("undeclared parameter must fail, got %v", err)
+	}
+	tpl.Spec.Content = "inputs: {{ .Params.logPath"
+	if _, err := Render(tpl, pipeline(map[string]apiextensionsv1.JSON{"logPath": value(`"/a"`)})); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("unparsable template must fail, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package pipelinetest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/simulate"
+)
+
+// Outcome PipelineTest运行的结果
+type Outcome struct {
+	// Output 处理插件输出的日志
+	Output []simulate.Record
+	// Failures 输出与期望记录的差异以及没有通过的断言，为空表示测试通过
+	Failures []string
+	// Notes 没有被完整模拟的效果
+	Notes []string
+}
+
+// Passed 测试是否通过
+func (o *Outcome) Passed() bool {
+	return len(o.Failures) == 0
+}
+
+// Run 用配置内容的处理插件处理PipelineTest的样例日志，再与期望记录比较并检查断言。
+// 配置无效或处理插件无法模拟时返回simulate的错误
+func Run(content string, spec *v1alpha1.PipelineTestSpec) (*Outcome, error) {
+	records := make([]simulate.Record, 0, len(spec.Input)+len(spec.InputRecords))
+	for _, input := range spec.Input {
+		records = append(records, simulate.Record{"content": input})
+	}
+	for _, record := range spec.InputRecords {
+		records = append(records, record)
+	}
+	result, err := simulate.RunRecords(content, records)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := &Outcome{Output: result.Records, Notes: result.Notes}
+	// 只有断言时不比较期望记录，否则空的expected表示所有日志都被丢弃
+	if len(spec.Expected) > 0 || len(spec.Assertions) == 0 {
+		expected := make([]simulate.Record, 0, len(spec.Expected))
+		for _, record := range spec.Expected {
+			expected = append(expected, record)
+		}
+		outcome.Failures = simulate.Compare(result.Records, expected)
+	}
+	for i := range spec.Assertions {
+		prefix := fmt.Sprintf("assertions[%d]: ", i)
+		for _, failure := range check(&spec.Assertions[i], result.Records) {
+			outcome.Failures = append(outcome.Failures, prefix+failure)
+		}
+	}
+	return outcome, nil
+}
+
+// check 检查一条断言，返回没有通过的原因
+func check(a *v1alpha1.PipelineTestAssertion, records []simulate.Record) []string {
+	if a.Count != nil {
+		if int(*a.Count) != len(records) {
+			return []string{fmt.Sprintf("got %d records, want %d", len(records), *a.Count)}
+		}
+		return nil
+	}
+
+	var re *regexp.Regexp
+	if a.Matches != "" {
+		var err error
+		if re, err = regexp.Compile(`^(?:` + a.Matches + `)$`); err != nil {
+			return []string{fmt.Sprintf("matches is not a valid RE2 regular expression: %v", err)}
+		}
+	}
+	indexes := make([]int, 0, len(records))
+	if a.Record != nil {
+		if int(*a.Record) >= len(records) {
+			return []string{fmt.Sprintf("record %d missing, got %d records", *a.Record, len(records))}
+		}
+		indexes = append(indexes, int(*a.Record))
+	} else {
+		for i := range records {
+			indexes = append(indexes, i)
+		}
+	}
+
+	var failures []string
+	for _, i := range indexes {
+		value, ok := records[i][a.Field]
+		switch {
+		case a.Exists != nil && *a.Exists && !ok:
+			failures = append(failures, fmt.Sprintf("record %d: field %s missing", i, a.Field))
+		case a.Exists != nil && !*a.Exists && ok:
+			failures = append(failures, fmt.Sprintf("record %d: unexpected field %s = %q", i, a.Field, value))
+		case a.Exists != nil:
+		case !ok:
+			failures = append(failures, fmt.Sprintf("record %d: field %s missing", i, a.Field))
+		case a.Equals != nil && value != *a.Equals:
+			failures = append(failures, fmt.Sprintf("record %d: field %s = %q, want %q", i, a.Field, value, *a.Equals))
+		case re != nil && !re.MatchString(value):
+			failures = append(failures, fmt.Sprintf("record %d: field %s = %q, want match %q", i, a.Field, value, a.Matches))
+		}
+	}
+	return failures
+}
+
+// Blocking 返回开启了blockOnFailure的PipelineTest，按名称排序
+func Blocking(tests []v1alpha1.PipelineTest) []*v1alpha1.PipelineTest {
+	var blocking []*v1alpha1.PipelineTest
+	for i := range tests {
+		if tests[i].Spec.BlockOnFailure && tests[i].DeletionTimestamp == nil {
+			blocking = append(blocking, &tests[i])
+		}
+	}
+	sort.Slice(blocking, func(i, j int) bool { return blocking[i].Name < blocking[j].Name })
+	return blocking
+}
@@ -0,0 +1,119 @@
+package pipelinetest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/simulate"
+)
+
+const content = `processors:
+  - Type: processor_parse_regex_native
+    SourceKey: content
+    Regex: (\w+) (.*)
+    Keys: [level, msg]
+  - Type: processor_filter_regex_native
+    Include:
+      level: ERROR|WARN
+`
+
+func TestRun(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		spec     v1alpha1.PipelineTestSpec
+		failures []string
+	}{
+		{
+			name: "expected records",
+			spec: v1alpha1.PipelineTestSpec{
+				Input:    []string{"INFO started", "ERROR failed"},
+				Expected: []map[string]string{{"level": "ERROR", "msg": "failed"}},
+			},
+		},
+		{
+			name: "empty expected drops every log",
+			spec: v1alpha1.PipelineTestSpec{Input: []string{"ERROR failed"}},
+			failures: []string{
+				`record 0: unexpected {level: "ERROR", msg: "failed"}`,
+			},
+		},
+		{
+			name: "input records keep their fields",
+			spec: v1alpha1.PipelineTestSpec{
+				Input:        []string{"WARN slow"},
+				InputRecords: []map[string]string{{"content": "ERROR failed", "pod": "app-0"}},
+				Assertions: []v1alpha1.PipelineTestAssertion{
+					{Count: ptr.To(int32(2))},
+					{Field: "level", Matches: "ERROR|WARN"},
+					{Record: ptr.To(int32(1)), Field: "pod", Equals: ptr.To("app-0")},
+					{Record: ptr.To(int32(0)), Field: "pod", Exists: ptr.To(false)},
+					{Field: "content", Exists: ptr.To(false)},
+				},
+			},
+		},
+		{
+			name: "failed assertions",
+			spec: v1alpha1.PipelineTestSpec{
+				Input: []string{"WARN slow", "ERROR failed"},
+				Assertions: []v1alpha1.PipelineTestAssertion{
+					{Count: ptr.To(int32(1))},
+					{Field: "level", Matches: "ERR.*"},
+					{Record: ptr.To(int32(0)), Field: "msg", Equals: ptr.To("fast")},
+					{Record: ptr.To(int32(2)), Field: "msg", Exists: ptr.To(true)},
+					{Field: "pod", Exists: ptr.To(true)},
+					{Field: "msg", Matches: "("},
+				},
+			},
+			failures: []string{
+				"assertions[0]: got 2 records, want 1",
+				`assertions[1]: record 0: field level = "WARN", want match "ERR.*"`,
+				`assertions[2]: record 0: field msg = "slow", want "fast"`,
+				"assertions[3]: record 2 missing, got 2 records",
+				"assertions[4]: record 0: field pod missing",
+				"assertions[4]: record 1: field pod missing",
+				"assertions[5]: matches is not a valid RE2 regular expression",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			outcome, err := Run(content, &tc.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if outcome.Passed() != (len(tc.failures) == 0) || len(outcome.Failures) != len(tc.failures) {
+				t.Fatalf("failures = %q, want %q", outcome.Failures, tc.failures)
+			}
+			for i, want := range tc.failures {
+				if !strings.HasPrefix(outcome.Failures[i], want) {
+					t.Errorf("failure %d = %q, want %q", i, outcome.Failures[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunUnsupported(t *testing.T) {
+	spec := &v1alpha1.PipelineTestSpec{Input: []string{"x"}}
+	if _, err := Run("processors:\n  - Type: processor_desensitize\n", spec); !errors.Is(err, simulate.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	now := metav1.Now()
+	tests := []v1alpha1.PipelineTest{
+		{ObjectMeta: metav1.ObjectMeta{Name: "b"}, Spec: v1alpha1.PipelineTestSpec{BlockOnFailure: true}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "c"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: v1alpha1.PipelineTestSpec{BlockOnFailure: true}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "d", DeletionTimestamp: &now}, Spec: v1alpha1.PipelineTestSpec{BlockOnFailure: true}},
+	}
+	blocking := Blocking(tests)
+	if len(blocking) != 2 || blocking[0].Name != "a" || blocking[1].Name != "b" {
+		t.Errorf("blocking = %v", blocking)
+	}
+}
@@ -0,0 +1,164 @@
+package profile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+// ErrInvalid profile中的插件或被拼接的配置无效，修改spec或profile前重试没有意义
+var ErrInvalid = errors.New("invalid profile")
+
+// profile的种类
+const (
+	KindProcessorChain        = "ProcessorChain"
+	KindClusterProcessorChain = "ClusterProcessorChain"
+	KindFlusherProfile        = "FlusherProfile"
+	KindClusterFlusherProfile = "ClusterFlusherProfile"
+)
+
+// Reference 返回References中表示profile的索引值
+func Reference(kind, name string) string {
+	return kind + "/" + name
+}
+
+// Namespaced 判断该种类的profile是否与Pipeline在同一命名空间
+func Namespaced(kind string) bool {
+	return kind == KindProcessorChain || kind == KindFlusherProfile
+}
+
+// References 返回Pipeline引用的profile，格式为"<种类>/<名称>"，用于在profile变化时找到Pipeline
+func References(pipeline *v1alpha1.Pipeline) []string {
+	var refs []string
+	for _, ref := range pipeline.Spec.ProcessorChainRefs {
+		refs = append(refs, Reference(processorChainKind(ref), ref.Name))
+	}
+	for _, ref := range pipeline.Spec.FlusherProfileRefs {
+		refs = append(refs, Reference(flusherProfileKind(ref), ref.Name))
+	}
+	return refs
+}
+
+// Render 把Pipeline引用的ProcessorChain的processors与FlusherProfile的flushers按顺序拼接到content，
+// 返回拼接后的配置与引用的profile。没有引用时content原样返回
+func Render(ctx context.Context, c client.Reader, pipeline *v1alpha1.Pipeline, content string) (string, []v1alpha1.PipelineProfileStatus, error) {
+	if len(pipeline.Spec.ProcessorChainRefs) == 0 && len(pipeline.Spec.FlusherProfileRefs) == 0 {
+		return content, nil, nil
+	}
+	var statuses []v1alpha1.PipelineProfileStatus
+	processors := render.Insertion{Section: "processors"}
+	for _, ref := range pipeline.Spec.ProcessorChainRefs {
+		kind := processorChainKind(ref)
+		spec, err := getProcessorChain(ctx, c, kind, pipeline.Namespace, ref.Name)
+		if err != nil {
+			return "", nil, err
+		}
+		plugins, status, err := decodePlugins(kind, ref.Name, spec.Processors)
+		if err != nil {
+			return "", nil, err
+		}
+		if ref.Position == v1alpha1.PositionAfter {
+			processors.After = append(processors.After, plugins...)
+		} else {
+			processors.Before = append(processors.Before, plugins...)
+		}
+		statuses = append(statuses, status)
+	}
+	flushers := render.Insertion{Section: "flushers"}
+	for _, ref := range pipeline.Spec.FlusherProfileRefs {
+		kind := flusherProfileKind(ref)
+		spec, err := getFlusherProfile(ctx, c, kind, pipeline.Namespace, ref.Name)
+		if err != nil {
+			return "", nil, err
+		}
+		plugins, status, err := decodePlugins(kind, ref.Name, spec.Flushers)
+		if err != nil {
+			return "", nil, err
+		}
+		flushers.After = append(flushers.After, plugins...)
+		statuses = append(statuses, status)
+	}
+
+	rendered, err := render.Splice(content, processors, flushers)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return rendered, statuses, nil
+}
+
+// processorChainKind 引用的ProcessorChain种类，默认为ProcessorChain
+func processorChainKind(ref v1alpha1.ProcessorChainReference) string {
+	if ref.Kind == "" {
+		return KindProcessorChain
+	}
+	return ref.Kind
+}
+
+// flusherProfileKind 引用的FlusherProfile种类，默认为FlusherProfile
+func flusherProfileKind(ref v1alpha1.FlusherProfileReference) string {
+	if ref.Kind == "" {
+		return KindFlusherProfile
+	}
+	return ref.Kind
+}
+
+// getProcessorChain 读取Pipeline命名空间中的ProcessorChain或ClusterProcessorChain
+func getProcessorChain(ctx context.Context, c client.Reader, kind, ns, name string) (*v1alpha1.ProcessorChainSpec, error) {
+	if kind == KindClusterProcessorChain {
+		chain := &v1alpha1.ClusterProcessorChain{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, chain); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", kind, name, err)
+		}
+		return &chain.Spec, nil
+	}
+	chain := &v1alpha1.ProcessorChain{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, chain); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", kind, name, err)
+	}
+	return &chain.Spec, nil
+}
+
+// getFlusherProfile 读取Pipeline命名空间中的FlusherProfile或ClusterFlusherProfile
+func getFlusherProfile(ctx context.Context, c client.Reader, kind, ns, name string) (*v1alpha1.FlusherProfileSpec, error) {
+	if kind == KindClusterFlusherProfile {
+		profile := &v1alpha1.ClusterFlusherProfile{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, profile); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", kind, name, err)
+		}
+		return &profile.Spec, nil
+	}
+	profile := &v1alpha1.FlusherProfile{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, profile); err != nil {
+		return nil, fmt.Errorf("%s %s: %w", kind, name, err)
+	}
+	return &profile.Spec, nil
+}
+
+// decodePlugins 解码profile中的插件，并返回带有插件sha256的profile状态
+func decodePlugins(kind, name string, raw []runtime.RawExtension) ([]interface{}, v1alpha1.PipelineProfileStatus, error) {
+	status := v1alpha1.PipelineProfileStatus{Kind: kind, Name: name}
+	plugins := make([]interface{}, 0, len(raw))
+	for i, ext := range raw {
+		var plugin map[string]interface{}
+		if err := json.Unmarshal(ext.Raw, &plugin); err != nil || plugin == nil {
+			return nil, status, fmt.Errorf("%w: %s %s: plugin %d must be a mapping", ErrInvalid, kind, name, i)
+		}
+		plugins = append(plugins, plugin)
+	}
+	data, err := json.Marshal(plugins)
+	if err != nil {
+		return nil, status, err
+	}
+	sum := sha256.Sum256(data)
+	status.ContentHash = hex.EncodeToString(sum[:])
+	return plugins, status, nil
+}
@@ -0,0 +1,141 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+func newReader(t *testing.T, objs ...client.Object) client.Reader {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func raw(plugins ...string) []runtime.RawExtension {
+	exts := make([]runtime.RawExtension, 0, len(plugins))
+	for _, plugin := range plugins {
+		exts = append(exts, runtime.RawExtension{Raw: []byte(plugin)})
+	}
+	return exts
+}
+
+func profiles() []client.Object {
+	return []client.Object{
+		&v1alpha1.ProcessorChain{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "json"},
+			Spec:       v1alpha1.ProcessorChainSpec{Processors: raw(`{"Type":"processor_parse_json_native","SourceKey":"content"}`)},
+		},
+		&v1alpha1.ClusterProcessorChain{
+			ObjectMeta: metav1.ObjectMeta{Name: "drop-fields"},
+			Spec:       v1alpha1.ProcessorChainSpec{Processors: raw(`{"Type":"processor_drop","DropKeys":["password"]}`)},
+		},
+		&v1alpha1.ClusterFlusherProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka"},
+			Spec:       v1alpha1.FlusherProfileSpec{Flushers: raw(`{"Type":"flusher_kafka_v2","Brokers":["kafka:9092"]}`)},
+		},
+	}
+}
+
+func pipeline() *v1alpha1.Pipeline {
+	return &v1alpha1.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "web"},
+		Spec: v1alpha1.PipelineSpec{
+			Name: "web",
+			ProcessorChainRefs: []v1alpha1.ProcessorChainReference{
+				{Name: "json"},
+				{Kind: KindClusterProcessorChain, Name: "drop-fields", Position: v1alpha1.PositionAfter},
+			},
+			FlusherProfileRefs: []v1alpha1.FlusherProfileReference{{Kind: KindClusterFlusherProfile, Name: "kafka"}},
+		},
+	}
+}
+
+const content = `
+inputs:
+  - Type: input_file
+    FilePaths: [/app/*.log]
+processors:
+  - Type: processor_filter_regex_native
+`
+
+func TestRenderSplicesProfiles(t *testing.T) {
+	rendered, statuses, err := Render(context.Background(), newReader(t, profiles()...), pipeline(), content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := render.Parse(rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, section := range []string{"processors", "flushers"} {
+		for _, plugin := range config[section].([]interface{}) {
+			types = append(types, plugin.(map[string]interface{})["Type"].(string))
+		}
+	}
+	want := "processor_parse_json_native,processor_filter_regex_native,processor_drop,flusher_kafka_v2"
+	if got := strings.Join(types, ","); got != want {
+		t.Fatalf("plugins = %s, want %s", got, want)
+	}
+
+	if len(statuses) != 3 || statuses[0].Kind != KindProcessorChain || statuses[2].Name != "kafka" || statuses[0].ContentHash == "" {
+		t.Fatalf("unexpected statuses %+v", statuses)
+	}
+	wantRefs := []string{"ProcessorChain/json", "ClusterProcessorChain/drop-fields", "ClusterFlusherProfile/kafka"}
+	if refs := References(pipeline()); !reflect.DeepEqual(refs, wantRefs) {
+		t.Fatalf("References = %v, want %v", refs, wantRefs)
+	}
+}
+
+func TestRenderHashChangesWithProfile(t *testing.T) {
+	objs := profiles()
+	_, before, err := Render(context.Background(), newReader(t, objs...), pipeline(), content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs[0].(*v1alpha1.ProcessorChain).Spec.Processors = raw(`{"Type":"processor_parse_json_native","SourceKey":"message"}`)
+	_, after, err := Render(context.Background(), newReader(t, objs...), pipeline(), content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before[0].ContentHash == after[0].ContentHash || before[1].ContentHash != after[1].ContentHash {
+		t.Fatalf("only the changed profile hash must change: %+v -> %+v", before, after)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	p := pipeline()
+	if _, _, err := Render(context.Background(), newReader(t), p, content); err == nil || errors.Is(err, ErrInvalid) {
+		t.Fatalf("missing profile must fail with a retryable error, got %v", err)
+	}
+	invalid := &v1alpha1.ProcessorChain{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "logging", Name: "json"},
+		Spec:       v1alpha1.ProcessorChainSpec{Processors: raw(`"processor_parse_json_native"`)},
+	}
+	objs := append([]client.Object{invalid}, profiles()[1:]...)
+	if _, _, err := Render(context.Background(), newReader(t, objs...), p, content); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("non mapping plugin must be invalid, got %v", err)
+	}
+}
+
+func TestRenderWithoutRefsUnchanged(t *testing.T) {
+	p := &v1alpha1.Pipeline{Spec: v1alpha1.PipelineSpec{Name: "web"}}
+	rendered, statuses, err := Render(context.Background(), newReader(t), p, content)
+	if err != nil || rendered != content || statuses != nil {
+		t.Fatalf("got %q, %v, %v", rendered, statuses, err)
+	}
+}
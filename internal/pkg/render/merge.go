@@ -0,0 +1,98 @@
+package render
+
+import (
+	"fmt"
+
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Merge 按顺序合并配置片段：映射逐键合并，列表（如inputs、processors）依次追加，其他值由后面的片段覆盖。
+// 只有一个片段时原样返回
+func Merge(fragments []string) (string, error) {
+	if len(fragments) == 1 {
+		return fragments[0], nil
+	}
+	merged := map[string]interface{}{}
+	for i, fragment := range fragments {
+		config, err := Parse(fragment)
+		if err != nil {
+			return "", fmt.Errorf("fragment %d: %w", i, err)
+		}
+		merged = mergeMaps(merged, config)
+	}
+	out, err := sigsyaml.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// mergeMaps 把src合并到dst
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	for key, value := range src {
+		dst[key] = mergeValues(dst[key], value)
+	}
+	return dst
+}
+
+// mergeValues 合并同一个键的两个值
+func mergeValues(dst, src interface{}) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		if d, ok := dst.(map[string]interface{}); ok {
+			return mergeMaps(d, s)
+		}
+	case []interface{}:
+		if d, ok := dst.([]interface{}); ok {
+			return append(append([]interface{}{}, d...), s...)
+		}
+	}
+	return src
+}
+
+// Insertion 插入配置某个插件段的插件
+type Insertion struct {
+	// Section 插件段，如processors、flushers
+	Section string
+	// Before 放在原有插件之前的插件
+	Before []interface{}
+	// After 放在原有插件之后的插件
+	After []interface{}
+}
+
+// Splice 把插件插入配置的插件段。没有插件需要插入时原样返回
+func Splice(content string, insertions ...Insertion) (string, error) {
+	empty := true
+	for _, in := range insertions {
+		if len(in.Before) > 0 || len(in.After) > 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return content, nil
+	}
+	config, err := Parse(content)
+	if err != nil {
+		return "", err
+	}
+	for _, in := range insertions {
+		if len(in.Before) == 0 && len(in.After) == 0 {
+			continue
+		}
+		var existing []interface{}
+		if value, ok := config[in.Section]; ok && value != nil {
+			if existing, ok = value.([]interface{}); !ok {
+				return "", fmt.Errorf("%s must be a list, got %T", in.Section, value)
+			}
+		}
+		plugins := make([]interface{}, 0, len(in.Before)+len(existing)+len(in.After))
+		plugins = append(plugins, in.Before...)
+		plugins = append(plugins, existing...)
+		config[in.Section] = append(plugins, in.After...)
+	}
+	out, err := sigsyaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
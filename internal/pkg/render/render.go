@@ -0,0 +1,238 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// Severity 检查结果的级别
+type Severity string
+
+const (
+	// SeverityError 的配置无法下发
+	SeverityError Severity = "error"
+	// SeverityWarning 的配置可以下发，但LoongCollector可能不按预期运行
+	SeverityWarning Severity = "warning"
+)
+
+// Problem 配置内容中的一个问题
+type Problem struct {
+	// Path 问题所在的位置，如inputs[0].Type，为空表示整个配置
+	Path     string
+	Severity Severity
+	Message  string
+}
+
+func (p Problem) String() string {
+	if p.Path == "" {
+		return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Path, p.Message)
+}
+
+// pluginSections 由插件列表组成的配置段
+var pluginSections = []string{"inputs", "processors", "aggregators", "flushers", "extensions"}
+
+// knownKeys LoongCollector流水线配置的顶层字段
+var knownKeys = map[string]bool{
+	"enable": true, "global": true,
+	"inputs": true, "processors": true, "aggregators": true, "flushers": true, "extensions": true,
+}
+
+// Parse 解析YAML配置内容。yaml.v2解析出的map[interface{}]interface{}无法编码为JSON，统一转换为map[string]interface{}
+func Parse(content string) (map[string]interface{}, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal([]byte(content), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML config: %v", err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("config is empty")
+	}
+	converted, err := convert(raw, "")
+	if err != nil {
+		return nil, err
+	}
+	config, ok := converted.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config must be a mapping, got %T", converted)
+	}
+	return config, nil
+}
+
+// convert 递归地把map[interface{}]interface{}转换为map[string]interface{}
+func convert(v interface{}, path string) (interface{}, error) {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			converted, err := convert(item, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			converted, err := convert(item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Lint 离线检查配置内容，不访问Config-Server
+func Lint(content string) []Problem {
+	config, err := Parse(content)
+	if err != nil {
+		return []Problem{{Severity: SeverityError, Message: err.Error()}}
+	}
+
+	var problems []Problem
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !knownKeys[key] {
+			problems = append(problems, Problem{Path: key, Severity: SeverityWarning, Message: "unknown field"})
+		}
+	}
+	if enable, ok := config["enable"]; ok {
+		if _, isBool := enable.(bool); !isBool {
+			problems = append(problems, Problem{Path: "enable", Severity: SeverityError, Message: "must be a boolean"})
+		}
+	}
+	if global, ok := config["global"]; ok {
+		if _, isMap := global.(map[string]interface{}); !isMap {
+			problems = append(problems, Problem{Path: "global", Severity: SeverityError, Message: "must be a mapping"})
+		}
+	}
+
+	for _, section := range pluginSections {
+		value, ok := config[section]
+		if !ok {
+			continue
+		}
+		plugins, ok := value.([]interface{})
+		if !ok {
+			problems = append(problems, Problem{Path: section, Severity: SeverityError, Message: "must be a list of plugins"})
+			continue
+		}
+		for i, item := range plugins {
+			path := fmt.Sprintf("%s[%d]", section, i)
+			plugin, ok := item.(map[string]interface{})
+			if !ok {
+				problems = append(problems, Problem{Path: path, Severity: SeverityError, Message: "plugin must be a mapping"})
+				continue
+			}
+			pluginType, ok := plugin["Type"].(string)
+			if !ok || pluginType == "" {
+				problems = append(problems, Problem{Path: path + ".Type", Severity: SeverityError, Message: "plugin type is required"})
+				continue
+			}
+			if prefix := sectionPrefix(section); prefix != "" && !strings.HasPrefix(pluginType, prefix) {
+				problems = append(problems, Problem{Path: path + ".Type", Severity: SeverityWarning,
+					Message: fmt.Sprintf("%s is not a %s plugin", pluginType, strings.TrimSuffix(section, "s"))})
+			}
+		}
+	}
+
+	for _, section := range []string{"inputs", "flushers"} {
+		value, ok := config[section]
+		if plugins, isList := value.([]interface{}); !ok || (isList && len(plugins) == 0) {
+			problems = append(problems, Problem{Path: section, Severity: SeverityWarning, Message: "at least one plugin is expected"})
+		}
+	}
+	return problems
+}
+
+// sectionPrefix 插件类型名的约定前缀
+func sectionPrefix(section string) string {
+	switch section {
+	case "inputs":
+		return "input_"
+	case "processors":
+		return "processor_"
+	case "aggregators":
+		return "aggregator_"
+	case "flushers":
+		return "flusher_"
+	case "extensions":
+		return "ext_"
+	}
+	return ""
+}
+
+// Errors 只保留错误级别的问题
+func Errors(problems []Problem) []Problem {
+	var errs []Problem
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// Payload 返回CreateConfig请求体。配置内容存在错误级别的问题时返回错误
+func Payload(name, content string) (map[string]interface{}, error) {
+	if errs := Errors(Lint(content)); len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, p := range errs {
+			messages = append(messages, p.String())
+		}
+		return nil, fmt.Errorf("invalid config: %s", strings.Join(messages, "; "))
+	}
+	config, err := Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"config_name": name,
+		"config_detail": map[string]interface{}{
+			"name":    name,
+			"content": config,
+		},
+	}, nil
+}
+
+// Canonical 把配置内容转换为键有序的YAML，用于比较本地与Config-Server上的配置。
+// content为YAML字符串或Config-Server返回的JSON对象
+func Canonical(content interface{}) (string, error) {
+	var value interface{} = content
+	if s, ok := content.(string); ok {
+		config, err := Parse(s)
+		if err != nil {
+			return "", err
+		}
+		value = config
+	}
+	out, err := sigsyaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,220 @@
+package render
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseConvertsNestedMaps(t *testing.T) {
+	config, err := Parse(`
+inputs:
+  - Type: input_file
+    FilePaths: [/var/log/*.log]
+    Tags:
+      1: one
+flushers:
+  - Type: flusher_stdout
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// yaml.v2的map[interface{}]interface{}无法编码为JSON
+	out, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("parsed config must be JSON encodable: %v", err)
+	}
+	if !strings.Contains(string(out), `"Tags":{"1":"one"}`) {
+		t.Fatalf("unexpected JSON %s", out)
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "valid",
+			content: "enable: true\ninputs:\n  - Type: input_file\nflushers:\n  - Type: flusher_stdout\n",
+		},
+		{
+			name:    "invalid yaml",
+			content: "inputs: [",
+			want:    []string{"error: failed to parse YAML config"},
+		},
+		{
+			name:    "not a mapping",
+			content: "- a\n- b\n",
+			want:    []string{"error: config must be a mapping"},
+		},
+		{
+			name:    "missing sections",
+			content: "global: {}\n",
+			want:    []string{"warning: inputs: at least one plugin", "warning: flushers: at least one plugin"},
+		},
+		{
+			name: "bad plugins",
+			content: "inputs: input_file\nprocessors:\n  - processor_regex\n  - Regex: x\n" +
+				"flushers:\n  - Type: input_file\nfoo: 1\n",
+			want: []string{
+				"warning: foo: unknown field",
+				"error: inputs: must be a list of plugins",
+				"error: processors[0]: plugin must be a mapping",
+				"error: processors[1].Type: plugin type is required",
+				"warning: flushers[0].Type: input_file is not a flusher plugin",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := Lint(tt.content)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("got %v, want %v", problems, tt.want)
+			}
+			for i, p := range problems {
+				if !strings.HasPrefix(p.String(), tt.want[i]) {
+					t.Errorf("problem %d = %q, want prefix %q", i, p, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPayload(t *testing.T) {
+	if _, err := Payload("bad", "inputs: x\n"); err == nil || !strings.Contains(err.Error(), "inputs: must be a list") {
+		t.Fatalf("got %v, want an invalid config error", err)
+	}
+	payload, err := Payload("nginx", "inputs:\n  - Type: input_file\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"config_detail":{"content":{"inputs":[{"Type":"input_file"}]},"name":"nginx"},"config_name":"nginx"}`
+	if string(out) != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	local, err := Canonical("inputs:\n  - Type: input_file\n    FilePaths: [/a]\nenable: true\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := Canonical(map[string]interface{}{
+		"enable": true,
+		"inputs": []interface{}{map[string]interface{}{"FilePaths": []interface{}{"/a"}, "Type": "input_file"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if local != remote {
+		t.Fatalf("canonical forms differ:\n%s\n%s", local, remote)
+	}
+}
+
+func TestMergeAppendsListsAndOverridesScalars(t *testing.T) {
+	merged, err := Merge([]string{`
+enable: false
+global:
+  TopicType: none
+processors:
+  - Type: processor_parse_json_native
+    SourceKey: content
+`, `
+enable: true
+global:
+  DefaultLogQueueSize: 10
+inputs:
+  - Type: input_file
+    FilePaths: [/app/*.log]
+processors:
+  - Type: processor_filter_regex_native
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := Parse(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config["enable"] != true {
+		t.Fatalf("later fragment must override enable, got %v", config["enable"])
+	}
+	global := config["global"].(map[string]interface{})
+	if global["TopicType"] != "none" || global["DefaultLogQueueSize"] == nil {
+		t.Fatalf("global must be merged, got %v", global)
+	}
+	processors := config["processors"].([]interface{})
+	if len(processors) != 2 || processors[0].(map[string]interface{})["Type"] != "processor_parse_json_native" {
+		t.Fatalf("processors must be appended in order, got %v", processors)
+	}
+	if len(config["inputs"].([]interface{})) != 1 {
+		t.Fatalf("inputs must be kept, got %v", config["inputs"])
+	}
+}
+
+func TestMergeSingleFragmentUnchanged(t *testing.T) {
+	content := "inputs: []  # kept as is\n"
+	merged, err := Merge([]string{content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged != content {
+		t.Fatalf("single fragment must be returned as is, got %q", merged)
+	}
+}
+
+func TestMergeInvalidFragment(t *testing.T) {
+	if _, err := Merge([]string{"inputs: []", "- not a mapping"}); err == nil || !strings.Contains(err.Error(), "fragment 1") {
+		t.Fatalf("expected error for fragment 1, got %v", err)
+	}
+}
+
+func TestSplicePlugins(t *testing.T) {
+	spliced, err := Splice(`
+inputs:
+  - Type: input_file
+processors:
+  - Type: processor_filter_regex_native
+`, Insertion{
+		Section: "processors",
+		Before:  []interface{}{map[string]interface{}{"Type": "processor_parse_json_native"}},
+		After:   []interface{}{map[string]interface{}{"Type": "processor_drop"}},
+	}, Insertion{
+		Section: "flushers",
+		After:   []interface{}{map[string]interface{}{"Type": "flusher_kafka_v2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := Parse(spliced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, section := range []string{"processors", "flushers"} {
+		for _, plugin := range config[section].([]interface{}) {
+			types = append(types, plugin.(map[string]interface{})["Type"].(string))
+		}
+	}
+	want := "processor_parse_json_native,processor_filter_regex_native,processor_drop,flusher_kafka_v2"
+	if got := strings.Join(types, ","); got != want {
+		t.Fatalf("plugins = %s, want %s", got, want)
+	}
+}
+
+func TestSpliceNothingUnchanged(t *testing.T) {
+	content := "inputs: []  # kept as is\n"
+	spliced, err := Splice(content, Insertion{Section: "processors"})
+	if err != nil || spliced != content {
+		t.Fatalf("got %q, %v", spliced, err)
+	}
+	if _, err := Splice("processors: {}\n", Insertion{Section: "processors", After: []interface{}{"x"}}); err == nil {
+		t.Fatal("expected error for a processors mapping")
+	}
+}
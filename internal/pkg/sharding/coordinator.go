@@ -0,0 +1,315 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// LabelShardGroup 标记同一组分片副本的Lease
+	LabelShardGroup = "loongcollector.infraflow.co/shard-group"
+	// LeasePrefix 分片Lease的名称前缀
+	LeasePrefix = "loongcollector-operator-shard-"
+
+	// DefaultLeaseDuration Lease未续约多久后视为副本已离开
+	DefaultLeaseDuration = 15 * time.Second
+	// DefaultRenewInterval 续约与刷新成员的间隔
+	DefaultRenewInterval = 5 * time.Second
+)
+
+// Options 分片配置
+type Options struct {
+	// Identity 当前副本的标识，通常为Pod名称
+	Identity string
+	// Namespace Lease所在的命名空间
+	Namespace string
+	// Group 参与分片的副本组，同一组的副本共同分担全部对象
+	Group string
+	// LeaseDuration Lease未续约多久后视为副本已离开
+	LeaseDuration time.Duration
+	// RenewInterval 续约与刷新成员的间隔
+	RenewInterval time.Duration
+	// SettleDelay 成员变化后，新分配到的对象等待多久才开始处理，保证原副本已经放手。默认为两倍RenewInterval
+	SettleDelay time.Duration
+}
+
+// Coordinator 每个副本维护自己的Lease，根据存活的Lease构建一致性哈希环，决定当前副本负责哪些对象
+type Coordinator struct {
+	client client.Client
+	reader client.Reader
+	log    logr.Logger
+	opts   Options
+	now    func() time.Time
+
+	mu          sync.RWMutex
+	ready       bool
+	lastRenew   time.Time
+	current     *Ring
+	previous    *Ring
+	changedAt   time.Time
+	notified    bool
+	subscribers []func(ctx context.Context)
+}
+
+// NewCoordinator 创建分片协调器。c用于写Lease，reader用于直接读取Lease，避免在缓存中监听Lease
+func NewCoordinator(c client.Client, reader client.Reader, log logr.Logger, opts Options) (*Coordinator, error) {
+	if opts.Identity == "" {
+		return nil, fmt.Errorf("shard identity must not be empty")
+	}
+	if opts.Namespace == "" {
+		return nil, fmt.Errorf("shard namespace must not be empty")
+	}
+	if opts.Group == "" {
+		opts.Group = "default"
+	}
+	if opts.LeaseDuration <= 0 {
+		opts.LeaseDuration = DefaultLeaseDuration
+	}
+	if opts.RenewInterval <= 0 {
+		opts.RenewInterval = DefaultRenewInterval
+	}
+	if opts.SettleDelay <= 0 {
+		opts.SettleDelay = 2 * opts.RenewInterval
+	}
+	return &Coordinator{
+		client: c,
+		reader: reader,
+		log:    log,
+		opts:   opts,
+		now:    time.Now,
+	}, nil
+}
+
+// Identity 返回当前副本的标识
+func (c *Coordinator) Identity() string {
+	return c.opts.Identity
+}
+
+// Key 对象参与哈希的key
+func Key(obj client.Object) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// Owns 判断当前副本是否负责key。成员变化后的SettleDelay内，只有新旧环都归属当前副本的key才返回true，
+// 失去的key立即放手，得到的key等待原副本放手后再处理，保证每个对象同时只有一个写入方。
+// 续约失败时，在其他副本认为Lease过期之前放手全部key
+func (c *Coordinator) Owns(key string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.ready || c.leaseLapsed() || c.current.Owner(key) != c.opts.Identity {
+		return false
+	}
+	if c.previous != nil && c.now().Sub(c.changedAt) < c.opts.SettleDelay {
+		return c.previous.Owner(key) == c.opts.Identity
+	}
+	return true
+}
+
+// OwnsObject 判断当前副本是否负责obj
+func (c *Coordinator) OwnsObject(obj client.Object) bool {
+	return c.Owns(Key(obj))
+}
+
+// Predicate 只放行当前副本负责的对象的事件
+func (c *Coordinator) Predicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(c.OwnsObject)
+}
+
+// Members 返回当前的成员
+func (c *Coordinator) Members() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.current == nil {
+		return nil
+	}
+	return c.current.Members()
+}
+
+// OnRebalance 注册分配稳定后的回调，控制器在回调中重新入队对象以接手新分配到的对象。回调在独立的goroutine中执行，不会阻塞续约
+func (c *Coordinator) OnRebalance(fn func(ctx context.Context)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribers = append(c.subscribers, fn)
+}
+
+// Start 定期续约并刷新成员，直到ctx结束。退出时删除自己的Lease以便其他副本尽快接手，实现manager.Runnable
+func (c *Coordinator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.opts.RenewInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.tick(ctx); err != nil {
+			c.log.Error(err, "Failed to refresh shard membership")
+		}
+		select {
+		case <-ctx.Done():
+			c.release()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection 所有副本都参与分片
+func (c *Coordinator) NeedLeaderElection() bool {
+	return false
+}
+
+// leaseLapsed 返回距上次成功续约是否已接近LeaseDuration。留出一个RenewInterval的余量，
+// 其他副本最早在LeaseDuration之后接手，此时当前副本已经放手。调用方持有锁
+func (c *Coordinator) leaseLapsed() bool {
+	margin := c.opts.RenewInterval
+	if margin >= c.opts.LeaseDuration {
+		margin = c.opts.LeaseDuration / 2
+	}
+	return c.now().Sub(c.lastRenew) >= c.opts.LeaseDuration-margin
+}
+
+// tick 续约、刷新成员，并在分配稳定后通知订阅者
+func (c *Coordinator) tick(ctx context.Context) error {
+	renewed := c.now()
+	if err := c.renew(ctx); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if c.ready && c.leaseLapsed() {
+		// 其他副本可能已经接手，按新加入的副本重新等待分配稳定
+		c.log.Info("Shard lease renewed after it lapsed, rejoining")
+		c.ready = false
+		c.current = nil
+	}
+	c.lastRenew = renewed
+	c.mu.Unlock()
+
+	members, err := c.liveMembers(ctx)
+	if err != nil {
+		return err
+	}
+	c.update(members)
+
+	c.mu.Lock()
+	notify := !c.notified && c.now().Sub(c.changedAt) >= c.opts.SettleDelay
+	if notify {
+		c.notified = true
+		c.previous = nil
+	}
+	subscribers := append([]func(context.Context){}, c.subscribers...)
+	c.mu.Unlock()
+
+	if notify {
+		c.log.Info("Shard assignment settled", "members", members)
+		for _, fn := range subscribers {
+			go fn(ctx)
+		}
+	}
+	return nil
+}
+
+// update 成员变化时重建哈希环
+func (c *Coordinator) update(members []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current != nil && reflect.DeepEqual(c.current.Members(), members) {
+		return
+	}
+	c.log.Info("Shard members changed", "members", members)
+	if c.ready {
+		c.previous = c.current
+	} else {
+		// 新加入的副本不知道其他副本是否已经放手，同样等待分配稳定
+		var others []string
+		for _, member := range members {
+			if member != c.opts.Identity {
+				others = append(others, member)
+			}
+		}
+		c.previous = NewRing(others, DefaultVirtualNodes)
+	}
+	c.current = NewRing(members, DefaultVirtualNodes)
+	c.changedAt = c.now()
+	c.notified = false
+	c.ready = true
+}
+
+func (c *Coordinator) leaseName() string {
+	return LeasePrefix + c.opts.Group + "-" + c.opts.Identity
+}
+
+// renew 创建或续约当前副本的Lease
+func (c *Coordinator) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(c.now())
+	seconds := int32(c.opts.LeaseDuration / time.Second)
+	lease := &coordinationv1.Lease{}
+	err := c.reader.Get(ctx, client.ObjectKey{Namespace: c.opts.Namespace, Name: c.leaseName()}, lease)
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.leaseName(),
+				Namespace: c.opts.Namespace,
+				Labels:    map[string]string{LabelShardGroup: c.opts.Group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &c.opts.Identity,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		return c.client.Create(ctx, lease)
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.HolderIdentity = &c.opts.Identity
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+	return c.client.Update(ctx, lease)
+}
+
+// liveMembers 返回Lease未过期的副本
+func (c *Coordinator) liveMembers(ctx context.Context) ([]string, error) {
+	var leases coordinationv1.LeaseList
+	if err := c.reader.List(ctx, &leases, client.InNamespace(c.opts.Namespace),
+		client.MatchingLabels{LabelShardGroup: c.opts.Group}); err != nil {
+		return nil, err
+	}
+	return LiveMembers(leases.Items, c.now()), nil
+}
+
+// LiveMembers 返回在now时仍然有效的Lease的持有者
+func LiveMembers(leases []coordinationv1.Lease, now time.Time) []string {
+	var members []string
+	for _, lease := range leases {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || spec.RenewTime == nil {
+			continue
+		}
+		duration := DefaultLeaseDuration
+		if spec.LeaseDurationSeconds != nil {
+			duration = time.Duration(*spec.LeaseDurationSeconds) * time.Second
+		}
+		if spec.RenewTime.Add(duration).After(now) {
+			members = append(members, *spec.HolderIdentity)
+		}
+	}
+	return NewRing(members, 1).Members()
+}
+
+// release 删除自己的Lease
+func (c *Coordinator) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.RenewInterval)
+	defer cancel()
+	lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: c.opts.Namespace, Name: c.leaseName()}}
+	if err := c.client.Delete(ctx, lease); err != nil && !apierrors.IsNotFound(err) {
+		c.log.Error(err, "Failed to release shard lease")
+	}
+}
@@ -0,0 +1,235 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func keys(n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("ns-%d/pipeline-%d", i%7, i)
+	}
+	return out
+}
+
+func TestRingDistribution(t *testing.T) {
+	ring := NewRing([]string{"a", "b", "c"}, 0)
+	counts := map[string]int{}
+	for _, key := range keys(3000) {
+		counts[ring.Owner(key)]++
+	}
+	for _, member := range []string{"a", "b", "c"} {
+		if counts[member] < 700 || counts[member] > 1300 {
+			t.Errorf("member %s owns %d of 3000 keys, want about 1000", member, counts[member])
+		}
+	}
+}
+
+func TestRingStability(t *testing.T) {
+	before := NewRing([]string{"a", "b", "c"}, 0)
+	after := NewRing([]string{"a", "b", "c", "d"}, 0)
+	moved := 0
+	for _, key := range keys(3000) {
+		old, cur := before.Owner(key), after.Owner(key)
+		if old != cur {
+			moved++
+			if cur != "d" {
+				t.Fatalf("key %s moved from %s to %s, only moves to the new member are expected", key, old, cur)
+			}
+		}
+	}
+	if moved < 450 || moved > 1050 {
+		t.Errorf("%d of 3000 keys moved, want about 750", moved)
+	}
+}
+
+func TestRingEmpty(t *testing.T) {
+	if owner := NewRing(nil, 0).Owner("ns/name"); owner != "" {
+		t.Errorf("Owner() = %q on an empty ring", owner)
+	}
+}
+
+func lease(holder string, renew time.Time, seconds int32) coordinationv1.Lease {
+	return coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: LeasePrefix + holder, Namespace: "system"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(holder),
+			LeaseDurationSeconds: ptr.To(seconds),
+			RenewTime:            ptr.To(metav1.NewMicroTime(renew)),
+		},
+	}
+}
+
+func TestLiveMembers(t *testing.T) {
+	now := time.Now()
+	members := LiveMembers([]coordinationv1.Lease{
+		lease("b", now.Add(-5*time.Second), 15),
+		lease("a", now, 15),
+		lease("expired", now.Add(-20*time.Second), 15),
+		{Spec: coordinationv1.LeaseSpec{RenewTime: ptr.To(metav1.NewMicroTime(now))}},
+	}, now)
+	if fmt.Sprint(members) != "[a b]" {
+		t.Errorf("LiveMembers() = %v, want [a b]", members)
+	}
+}
+
+func newCoordinator(t *testing.T, c client.Client, identity string, now *time.Time) *Coordinator {
+	t.Helper()
+	coord, err := NewCoordinator(c, c, logr.Discard(), Options{
+		Identity:      identity,
+		Namespace:     "system",
+		RenewInterval: time.Second,
+		SettleDelay:   10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	coord.now = func() time.Time { return *now }
+	return coord
+}
+
+func TestCoordinatorOwnership(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := coordinationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx := context.Background()
+	now := time.Now()
+
+	a := newCoordinator(t, c, "a", &now)
+	if a.Owns("ns/name") {
+		t.Fatal("Owns() must be false before the membership is known")
+	}
+	if err := a.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	all := keys(300)
+	if a.Owns(all[0]) {
+		t.Fatal("Owns() must be false until the assignment settles")
+	}
+	now = now.Add(11 * time.Second)
+	if err := a.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range all {
+		if !a.Owns(key) {
+			t.Fatalf("single replica must own %s", key)
+		}
+	}
+
+	b := newCoordinator(t, c, "b", &now)
+	if err := b.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range all {
+		if b.Owns(key) {
+			t.Fatalf("b must not take over %s before a releases it", key)
+		}
+	}
+	if err := a.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ring := NewRing([]string{"a", "b"}, 0)
+	for _, key := range all {
+		if ring.Owner(key) == "b" && a.Owns(key) {
+			t.Fatalf("a must release %s as soon as b joins", key)
+		}
+		if a.Owns(key) && b.Owns(key) {
+			t.Fatalf("%s is owned by both replicas", key)
+		}
+	}
+
+	now = now.Add(11 * time.Second)
+	for _, coord := range []*Coordinator{a, b} {
+		if err := coord.tick(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range all {
+		if a.Owns(key) == b.Owns(key) {
+			t.Fatalf("%s must be owned by exactly one replica once settled", key)
+		}
+	}
+
+	b.release()
+	now = now.Add(time.Second)
+	if err := a.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(a.Members()) != "[a]" {
+		t.Errorf("Members() = %v after b left, want [a]", a.Members())
+	}
+}
+
+func TestCoordinatorReleasesKeysWhenRenewFails(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := coordinationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	partitioned := false
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if partitioned {
+				return fmt.Errorf("connection refused")
+			}
+			return c.Update(ctx, obj, opts...)
+		},
+	}).Build()
+	ctx := context.Background()
+	now := time.Now()
+
+	a := newCoordinator(t, c, "a", &now)
+	for i := 0; i < 2; i++ {
+		if err := a.tick(ctx); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(11 * time.Second)
+	}
+	key := keys(1)[0]
+	if !a.Owns(key) {
+		t.Fatal("single replica must own every key")
+	}
+
+	// 续约失败后仍在Lease有效期内
+	partitioned = true
+	if err := a.tick(ctx); err == nil {
+		t.Fatal("expected the renew to fail")
+	}
+	if !a.Owns(key) {
+		t.Fatal("a must keep its keys while the lease is still valid")
+	}
+	// 其他副本认为Lease过期之前放手
+	now = now.Add(DefaultLeaseDuration - time.Second)
+	if a.Owns(key) {
+		t.Fatal("a must release its keys before its lease expires")
+	}
+
+	// 恢复后按新加入的副本等待分配稳定
+	partitioned = false
+	if err := a.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if a.Owns(key) {
+		t.Fatal("a must wait for the assignment to settle after rejoining")
+	}
+	now = now.Add(11 * time.Second)
+	if err := a.tick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !a.Owns(key) {
+		t.Fatal("a must own its keys again once settled")
+	}
+}
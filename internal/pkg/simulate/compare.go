@@ -0,0 +1,66 @@
+package simulate
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Compare 按顺序比较模拟输出的日志与期望的日志，返回每处差异的说明，一致时返回空
+func Compare(got, want []Record) []string {
+	var diffs []string
+	for i := 0; i < len(got) || i < len(want); i++ {
+		switch {
+		case i >= len(got):
+			diffs = append(diffs, fmt.Sprintf("record %d: missing, want %s", i, format(want[i])))
+		case i >= len(want):
+			diffs = append(diffs, fmt.Sprintf("record %d: unexpected %s", i, format(got[i])))
+		default:
+			diffs = append(diffs, compareRecord(i, got[i], want[i])...)
+		}
+	}
+	return diffs
+}
+
+func compareRecord(i int, got, want Record) []string {
+	keys := make([]string, 0, len(got)+len(want))
+	for k := range got {
+		keys = append(keys, k)
+	}
+	for k := range want {
+		if _, ok := got[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var diffs []string
+	for _, k := range keys {
+		g, inGot := got[k]
+		w, inWant := want[k]
+		switch {
+		case !inGot:
+			diffs = append(diffs, fmt.Sprintf("record %d: field %s missing, want %q", i, k, w))
+		case !inWant:
+			diffs = append(diffs, fmt.Sprintf("record %d: unexpected field %s = %q", i, k, g))
+		case g != w:
+			diffs = append(diffs, fmt.Sprintf("record %d: field %s = %q, want %q", i, k, g, w))
+		}
+	}
+	return diffs
+}
+
+// format 以键有序的形式输出一条日志
+func format(r Record) string {
+	keys := make([]string, 0, len(r))
+	for k := range r {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := "{"
+	for i, k := range keys {
+		if i > 0 {
+			out += ", "
+		}
+		out += fmt.Sprintf("%s: %q", k, r[k])
+	}
+	return out + "}"
+}
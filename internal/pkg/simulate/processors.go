@@ -0,0 +1,597 @@
+package simulate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// nativeSource 原生解析插件对源字段的处理：解析后默认删除源字段，可以按参数保留或重命名
+type nativeSource struct {
+	key         string
+	renamed     string
+	keepFail    bool
+	keepSucceed bool
+}
+
+func newNativeSource(p params) (s nativeSource, err error) {
+	if s.key, err = p.required("SourceKey"); err != nil {
+		return s, err
+	}
+	if s.renamed, err = p.str("RenamedSourceKey", s.key); err != nil {
+		return s, err
+	}
+	if s.keepFail, err = p.boolean("KeepingSourceWhenParseFail", false); err != nil {
+		return s, err
+	}
+	s.keepSucceed, err = p.boolean("KeepingSourceWhenParseSucceed", false)
+	return s, err
+}
+
+// parse 用parse解析源字段，ok为false表示解析失败
+func (s nativeSource) parse(parse func(raw string) (fields map[string]string, ok bool)) processor {
+	return func(r Record) []Record {
+		raw, exists := r[s.key]
+		if !exists {
+			return []Record{r}
+		}
+		fields, ok := parse(raw)
+		delete(r, s.key)
+		if ok {
+			if s.keepSucceed {
+				r[s.renamed] = raw
+			}
+			for k, v := range fields {
+				r[k] = v
+			}
+		} else if s.keepFail {
+			r[s.renamed] = raw
+		}
+		return []Record{r}
+	}
+}
+
+// keyed 按Keys的顺序为解析出的值命名
+func keyed(keys, values []string) map[string]string {
+	fields := make(map[string]string, len(keys))
+	for i, key := range keys {
+		fields[key] = values[i]
+	}
+	return fields
+}
+
+func newParseRegexNative(p params) (processor, error) {
+	source, err := newNativeSource(p)
+	if err != nil {
+		return nil, err
+	}
+	re, keys, err := regexKeys(p, true)
+	if err != nil {
+		return nil, err
+	}
+	return source.parse(func(raw string) (map[string]string, bool) {
+		m := re.FindStringSubmatch(raw)
+		if m == nil {
+			return nil, false
+		}
+		return keyed(keys, m[1:]), true
+	}), nil
+}
+
+// regexKeys 读取Regex与Keys，捕获组的数量必须与Keys一致
+func regexKeys(p params, full bool) (*regexp.Regexp, []string, error) {
+	expr, err := p.required("Regex")
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := p.regex("Regex", expr, full)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys, err := p.list("Keys")
+	if err != nil {
+		return nil, nil, err
+	}
+	if re.NumSubexp() != len(keys) {
+		return nil, nil, p.invalid("Keys", "has %d keys for %d capture groups", len(keys), re.NumSubexp())
+	}
+	return re, keys, nil
+}
+
+// newRegex 扩展插件processor_regex，不匹配时日志保持不变
+func newRegex(p params) (processor, error) {
+	key, err := p.str("SourceKey", "content")
+	if err != nil {
+		return nil, err
+	}
+	full, err := p.boolean("FullMatch", true)
+	if err != nil {
+		return nil, err
+	}
+	keep, err := p.boolean("KeepSource", false)
+	if err != nil {
+		return nil, err
+	}
+	re, keys, err := regexKeys(p, full)
+	if err != nil {
+		return nil, err
+	}
+	return func(r Record) []Record {
+		m := re.FindStringSubmatch(r[key])
+		if _, exists := r[key]; !exists || m == nil {
+			return []Record{r}
+		}
+		if !keep {
+			delete(r, key)
+		}
+		for k, v := range keyed(keys, m[1:]) {
+			r[k] = v
+		}
+		return []Record{r}
+	}, nil
+}
+
+// decodeObject 解析JSON对象，数字保留原文
+func decodeObject(raw string) (map[string]interface{}, bool) {
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	var obj map[string]interface{}
+	if err := decoder.Decode(&obj); err != nil || obj == nil || decoder.More() {
+		return nil, false
+	}
+	return obj, true
+}
+
+// jsonValue 字符串原样保留，其他值编码为JSON
+func jsonValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func newParseJSONNative(p params) (processor, error) {
+	source, err := newNativeSource(p)
+	if err != nil {
+		return nil, err
+	}
+	return source.parse(func(raw string) (map[string]string, bool) {
+		obj, ok := decodeObject(raw)
+		if !ok {
+			return nil, false
+		}
+		fields := make(map[string]string, len(obj))
+		for k, v := range obj {
+			fields[k] = jsonValue(v)
+		}
+		return fields, true
+	}), nil
+}
+
+// newJSON 扩展插件processor_json，按ExpandDepth展开嵌套对象
+func newJSON(p params) (processor, error) {
+	key, err := p.required("SourceKey")
+	if err != nil {
+		return nil, err
+	}
+	keep, err := p.boolean("KeepSource", true)
+	if err != nil {
+		return nil, err
+	}
+	keepIfError, err := p.boolean("KeepSourceIfParseError", true)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := p.integer("ExpandDepth", 0)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := p.str("ExpandConnector", "_")
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := p.str("Prefix", "")
+	if err != nil {
+		return nil, err
+	}
+	sourcePrefix, err := p.boolean("UseSourceKeyAsPrefix", false)
+	if err != nil {
+		return nil, err
+	}
+	if sourcePrefix {
+		prefix += key + connector
+	}
+
+	var expand func(r Record, obj map[string]interface{}, name string, level int)
+	expand = func(r Record, obj map[string]interface{}, name string, level int) {
+		for k, v := range obj {
+			field := name + k
+			if nested, ok := v.(map[string]interface{}); ok && (depth <= 0 || level < depth) {
+				expand(r, nested, field+connector, level+1)
+				continue
+			}
+			r[prefix+field] = jsonValue(v)
+		}
+	}
+	return func(r Record) []Record {
+		raw, exists := r[key]
+		if !exists {
+			return []Record{r}
+		}
+		obj, ok := decodeObject(raw)
+		if !ok {
+			if !keepIfError {
+				delete(r, key)
+			}
+			return []Record{r}
+		}
+		if !keep {
+			delete(r, key)
+		}
+		expand(r, obj, "", 1)
+		return []Record{r}
+	}, nil
+}
+
+func newParseDelimiterNative(p params) (processor, error) {
+	source, err := newNativeSource(p)
+	if err != nil {
+		return nil, err
+	}
+	separator, err := p.required("Separator")
+	if err != nil {
+		return nil, err
+	}
+	quote, err := p.str("Quote", `"`)
+	if err != nil {
+		return nil, err
+	}
+	if len(quote) != 1 {
+		return nil, p.invalid("Quote", "must be a single character")
+	}
+	keys, err := p.list("Keys")
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, p.invalid("Keys", "is required")
+	}
+	shortened, err := p.boolean("AllowingShortenedFields", true)
+	if err != nil {
+		return nil, err
+	}
+	overflow, err := p.str("OverflowedFieldsTreatment", "extend")
+	if err != nil {
+		return nil, err
+	}
+	if overflow != "extend" && overflow != "keep" && overflow != "discard" {
+		return nil, p.invalid("OverflowedFieldsTreatment", "must be extend, keep or discard")
+	}
+
+	return source.parse(func(raw string) (map[string]string, bool) {
+		var values []string
+		if len(separator) == 1 {
+			var ok bool
+			if values, ok = splitQuoted(raw, separator[0], quote[0]); !ok {
+				return nil, false
+			}
+		} else {
+			values = strings.Split(raw, separator)
+		}
+		if len(values) < len(keys) {
+			if !shortened {
+				return nil, false
+			}
+			return keyed(keys[:len(values)], values), true
+		}
+		fields := keyed(keys, values)
+		extra := values[len(keys):]
+		switch {
+		case len(extra) == 0 || overflow == "discard":
+		case overflow == "keep":
+			fields["__column0__"] = strings.Join(extra, separator)
+		default:
+			for i, v := range extra {
+				fields[fmt.Sprintf("__column%d__", i)] = v
+			}
+		}
+		return fields, true
+	}), nil
+}
+
+// splitQuoted 按单字符分隔符切分，引号内的分隔符不切分，两个连续的引号表示一个引号
+func splitQuoted(raw string, separator, quote byte) ([]string, bool) {
+	var values []string
+	var field strings.Builder
+	quoted, start := false, true
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case quoted && c == quote && i+1 < len(raw) && raw[i+1] == quote:
+			field.WriteByte(quote)
+			i++
+		case quoted && c == quote:
+			quoted = false
+			if i+1 < len(raw) && raw[i+1] != separator {
+				return nil, false
+			}
+		case start && c == quote:
+			quoted = true
+			start = false
+		case !quoted && c == separator:
+			values = append(values, field.String())
+			field.Reset()
+			start = true
+		default:
+			field.WriteByte(c)
+			start = false
+		}
+	}
+	if quoted {
+		return nil, false
+	}
+	return append(values, field.String()), true
+}
+
+func newSplitLogString(p params) (processor, error) {
+	key, err := p.str("SourceKey", "content")
+	if err != nil {
+		return nil, err
+	}
+	char, err := p.str("SplitChar", "\n")
+	if err != nil {
+		return nil, err
+	}
+	return func(r Record) []Record {
+		raw, exists := r[key]
+		if !exists {
+			return []Record{r}
+		}
+		var out []Record
+		for _, part := range strings.Split(raw, char) {
+			if part == "" {
+				continue
+			}
+			split := r.clone()
+			split[key] = part
+			out = append(out, split)
+		}
+		return out
+	}, nil
+}
+
+func newSplitMultiline(p params) (processor, error) {
+	key, err := p.str("SourceKey", "content")
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMultiline(p)
+	if err != nil {
+		return nil, err
+	}
+	return func(r Record) []Record {
+		raw, exists := r[key]
+		if !exists {
+			return []Record{r}
+		}
+		var out []Record
+		for _, content := range m.split(strings.Split(raw, "\n")) {
+			split := r.clone()
+			split[key] = content
+			out = append(out, split)
+		}
+		return out
+	}, nil
+}
+
+// multiline 按行首、行中与行尾正则合并多行日志
+type multiline struct {
+	start, next, end *regexp.Regexp
+	discard          bool
+}
+
+// newMultiline 读取插件参数中的Multiline，只支持custom模式
+func newMultiline(p params) (*multiline, error) {
+	values, ok := p.values["Multiline"].(map[string]interface{})
+	if !ok {
+		return nil, p.invalid("Multiline", "must be an object")
+	}
+	mp := params{name: p.name + " Multiline", values: values}
+	mode, err := mp.str("Mode", "custom")
+	if err != nil {
+		return nil, err
+	}
+	if mode != "custom" {
+		return nil, fmt.Errorf("%s: mode %s: %w", mp.name, mode, ErrUnsupported)
+	}
+	m := &multiline{}
+	for _, pattern := range []struct {
+		key string
+		re  **regexp.Regexp
+	}{{"StartPattern", &m.start}, {"ContinuePattern", &m.next}, {"EndPattern", &m.end}} {
+		expr, err := mp.str(pattern.key, "")
+		if err != nil {
+			return nil, err
+		}
+		if expr != "" {
+			if *pattern.re, err = mp.regex(pattern.key, expr, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if m.start == nil && m.end == nil {
+		return nil, mp.invalid("StartPattern", "or EndPattern is required")
+	}
+	treatment, err := mp.str("UnmatchedContentTreatment", "single_line")
+	if err != nil {
+		return nil, err
+	}
+	m.discard = treatment == "discard"
+	return m, nil
+}
+
+// split 合并多行日志，不属于任何多行日志的行按UnmatchedContentTreatment单独成为日志或丢弃
+func (m *multiline) split(lines []string) []string {
+	var out, block []string
+	unmatched := func(line string) {
+		if !m.discard {
+			out = append(out, line)
+		}
+	}
+	flush := func() {
+		if len(block) > 0 {
+			out = append(out, strings.Join(block, "\n"))
+			block = nil
+		}
+	}
+	for _, line := range lines {
+		switch {
+		case m.start != nil && m.start.MatchString(line):
+			if m.end != nil && len(block) > 0 {
+				// 上一条日志没有遇到行尾，不是完整的多行日志
+				for _, l := range block {
+					unmatched(l)
+				}
+				block = nil
+			}
+			flush()
+			block = []string{line}
+		case m.start != nil && len(block) == 0:
+			unmatched(line)
+			continue
+		case m.next != nil && !m.next.MatchString(line) && (m.end == nil || !m.end.MatchString(line)):
+			flush()
+			unmatched(line)
+			continue
+		default:
+			block = append(block, line)
+		}
+		if m.end != nil && m.end.MatchString(line) {
+			flush()
+		}
+	}
+	if m.end != nil {
+		for _, l := range block {
+			unmatched(l)
+		}
+		return out
+	}
+	flush()
+	return out
+}
+
+func newDrop(p params) (processor, error) {
+	keys, err := p.list("DropKeys")
+	if err != nil {
+		return nil, err
+	}
+	return func(r Record) []Record {
+		for _, key := range keys {
+			delete(r, key)
+		}
+		return []Record{r}
+	}, nil
+}
+
+func newRename(p params) (processor, error) {
+	from, err := p.list("SourceKeys")
+	if err != nil {
+		return nil, err
+	}
+	to, err := p.list("DestKeys")
+	if err != nil {
+		return nil, err
+	}
+	if len(from) != len(to) {
+		return nil, p.invalid("DestKeys", "must have as many keys as SourceKeys")
+	}
+	return func(r Record) []Record {
+		for i, key := range from {
+			if v, ok := r[key]; ok {
+				delete(r, key)
+				r[to[i]] = v
+			}
+		}
+		return []Record{r}
+	}, nil
+}
+
+func newAddFields(p params) (processor, error) {
+	fields, err := p.object("Fields")
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, p.invalid("Fields", "is required")
+	}
+	ignoreIfExist, err := p.boolean("IgnoreIfExist", false)
+	if err != nil {
+		return nil, err
+	}
+	return func(r Record) []Record {
+		for k, v := range fields {
+			if _, exists := r[k]; exists && ignoreIfExist {
+				continue
+			}
+			r[k] = v
+		}
+		return []Record{r}
+	}, nil
+}
+
+// newFilterRegex 保留Include中的字段都匹配且Exclude中的字段都不匹配的日志，
+// 原生插件要求匹配整个值，扩展插件只要求部分匹配
+func newFilterRegex(full bool) factory {
+	return func(p params) (processor, error) {
+		compile := func(key string) (map[string]*regexp.Regexp, error) {
+			exprs, err := p.object(key)
+			if err != nil {
+				return nil, err
+			}
+			out := make(map[string]*regexp.Regexp, len(exprs))
+			for _, field := range sortedKeys(exprs) {
+				if out[field], err = p.regex(key+"."+field, exprs[field], full); err != nil {
+					return nil, err
+				}
+			}
+			return out, nil
+		}
+		include, err := compile("Include")
+		if err != nil {
+			return nil, err
+		}
+		exclude, err := compile("Exclude")
+		if err != nil {
+			return nil, err
+		}
+		return func(r Record) []Record {
+			for key, re := range include {
+				if v, ok := r[key]; !ok || !re.MatchString(v) {
+					return nil
+				}
+			}
+			for key, re := range exclude {
+				if v, ok := r[key]; ok && re.MatchString(v) {
+					return nil
+				}
+			}
+			return []Record{r}
+		}, nil
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
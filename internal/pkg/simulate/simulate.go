@@ -0,0 +1,289 @@
+package simulate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/infraflows/loongcollector-operator/internal/pkg/render"
+)
+
+// ErrUnsupported 配置中的处理插件无法在本地模拟
+var ErrUnsupported = errors.New("not supported by the simulator")
+
+// ErrInvalid 配置内容或插件参数无效
+var ErrInvalid = errors.New("invalid pipeline config")
+
+// Record 一条日志，字段与值都是字符串，原始日志在content字段中
+type Record map[string]string
+
+// Step 一个处理插件输出的日志
+type Step struct {
+	Processor string
+	Records   []Record
+}
+
+// Result 模拟的结果
+type Result struct {
+	// Records 所有处理插件输出的日志
+	Records []Record
+	// Steps 每个处理插件输出的日志，按processors中的顺序
+	Steps []Step
+	// Notes 没有被完整模拟的效果，例如日志时间
+	Notes []string
+}
+
+// processor 处理一条日志，返回处理后的日志，返回空表示日志被丢弃
+type processor func(r Record) []Record
+
+// factory 根据插件参数创建处理插件
+type factory func(p params) (processor, error)
+
+// factories 支持模拟的处理插件
+var factories = map[string]factory{
+	"processor_parse_regex_native":                newParseRegexNative,
+	"processor_regex":                             newRegex,
+	"processor_parse_json_native":                 newParseJSONNative,
+	"processor_json":                              newJSON,
+	"processor_parse_delimiter_native":            newParseDelimiterNative,
+	"processor_split_log_string_native":           newSplitLogString,
+	"processor_split_multiline_log_string_native": newSplitMultiline,
+	"processor_drop":                              newDrop,
+	"processor_rename":                            newRename,
+	"processor_add_fields":                        newAddFields,
+	"processor_filter_regex_native":               newFilterRegex(true),
+	"processor_filter_regex":                      newFilterRegex(false),
+}
+
+// notSimulated 不改变日志字段的处理插件及其没有被模拟的效果
+var notSimulated = map[string]string{
+	"processor_parse_timestamp_native": "the log time is not simulated",
+	"processor_gotime":                 "the log time is not simulated",
+}
+
+// Supported 返回支持模拟的处理插件
+func Supported() []string {
+	types := make([]string, 0, len(factories)+len(notSimulated))
+	for typ := range factories {
+		types = append(types, typ)
+	}
+	for typ := range notSimulated {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Run 用配置内容的processors处理inputs，每个input是一条日志的content。
+// 没有任何字段的日志被丢弃，和LoongCollector一致
+func Run(content string, inputs []string) (*Result, error) {
+	records := make([]Record, 0, len(inputs))
+	for _, input := range inputs {
+		records = append(records, Record{"content": input})
+	}
+	return RunRecords(content, records)
+}
+
+// RunRecords 用配置内容的processors处理已有字段的日志，records不会被修改
+func RunRecords(content string, records []Record) (*Result, error) {
+	config, err := render.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	list, ok := config["processors"].([]interface{})
+	if !ok && config["processors"] != nil {
+		return nil, fmt.Errorf("%w: processors must be a list", ErrInvalid)
+	}
+
+	result := &Result{}
+	for i, item := range list {
+		p, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: processors[%d] must be an object", ErrInvalid, i)
+		}
+		typ, _ := p["Type"].(string)
+		name := fmt.Sprintf("processors[%d] %s", i, typ)
+		if note, ok := notSimulated[typ]; ok {
+			result.Notes = append(result.Notes, name+": "+note)
+			result.Steps = append(result.Steps, Step{Processor: name, Records: records})
+			continue
+		}
+		newProcessor, ok := factories[typ]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", name, ErrUnsupported)
+		}
+		process, err := newProcessor(params{name: name, values: p})
+		if err != nil {
+			return nil, err
+		}
+		var out []Record
+		for _, r := range records {
+			for _, processed := range process(r.clone()) {
+				if len(processed) > 0 {
+					out = append(out, processed)
+				}
+			}
+		}
+		records = out
+		result.Steps = append(result.Steps, Step{Processor: name, Records: records})
+	}
+	result.Records = records
+	return result, nil
+}
+
+// Inputs 按配置的输入插件把样例文件切分为日志：有拆分插件时整个文件是一条日志，
+// 输入插件配置了Multiline时按行首正则合并多行，否则每个非空行是一条日志
+func Inputs(content, text string) ([]string, error) {
+	config, err := render.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	text = strings.TrimSuffix(text, "\n")
+	processors, _ := config["processors"].([]interface{})
+	for _, item := range processors {
+		if p, ok := item.(map[string]interface{}); ok {
+			if typ, _ := p["Type"].(string); strings.HasPrefix(typ, "processor_split_") {
+				return []string{text}, nil
+			}
+		}
+	}
+	lines := strings.Split(text, "\n")
+	inputs, _ := config["inputs"].([]interface{})
+	for i, item := range inputs {
+		in, ok := item.(map[string]interface{})
+		if !ok || in["Multiline"] == nil {
+			continue
+		}
+		m, err := newMultiline(params{name: fmt.Sprintf("inputs[%d]", i), values: in})
+		if err != nil {
+			return nil, err
+		}
+		return m.split(lines), nil
+	}
+	var records []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			records = append(records, line)
+		}
+	}
+	return records, nil
+}
+
+func (r Record) clone() Record {
+	out := make(Record, len(r))
+	for k, v := range r {
+		out[k] = v
+	}
+	return out
+}
+
+// params 插件参数
+type params struct {
+	name   string
+	values map[string]interface{}
+}
+
+func (p params) invalid(key, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s: %s %s", ErrInvalid, p.name, key, fmt.Sprintf(format, args...))
+}
+
+func (p params) str(key, def string) (string, error) {
+	v, ok := p.values[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", p.invalid(key, "must be a string")
+	}
+	return s, nil
+}
+
+func (p params) required(key string) (string, error) {
+	s, err := p.str(key, "")
+	if err == nil && s == "" {
+		err = p.invalid(key, "is required")
+	}
+	return s, err
+}
+
+func (p params) boolean(key string, def bool) (bool, error) {
+	v, ok := p.values[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, p.invalid(key, "must be a boolean")
+	}
+	return b, nil
+}
+
+func (p params) integer(key string, def int) (int, error) {
+	switch v := p.values[key].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	default:
+		return 0, p.invalid(key, "must be an integer")
+	}
+}
+
+func (p params) list(key string) ([]string, error) {
+	v, ok := p.values[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, p.invalid(key, "must be a list of strings")
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, p.invalid(key, "must be a list of strings")
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func (p params) object(key string) (map[string]string, error) {
+	v, ok := p.values[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, p.invalid(key, "must be a map of strings")
+	}
+	out := make(map[string]string, len(m))
+	for k, item := range m {
+		s, ok := item.(string)
+		if !ok {
+			return nil, p.invalid(key, "must be a map of strings")
+		}
+		out[k] = s
+	}
+	return out, nil
+}
+
+// regex 编译正则，full为true时要求匹配整个值，和LoongCollector原生插件一致
+func (p params) regex(key, expr string, full bool) (*regexp.Regexp, error) {
+	if full {
+		expr = `^(?:` + expr + `)$`
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, p.invalid(key, "is not a valid RE2 regular expression: %v", err)
+	}
+	return re, nil
+}
@@ -0,0 +1,195 @@
+package simulate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		processors string
+		inputs     []string
+		want       []Record
+	}{
+		{
+			name: "regex native",
+			processors: `
+  - Type: processor_parse_regex_native
+    SourceKey: content
+    Regex: (\S+) (\w+) (.*)
+    Keys: [time, level, msg]
+    KeepingSourceWhenParseFail: true
+    RenamedSourceKey: raw`,
+			inputs: []string{"2025-01-01 INFO started", "garbage"},
+			want:   []Record{{"time": "2025-01-01", "level": "INFO", "msg": "started"}, {"raw": "garbage"}},
+		},
+		{
+			name: "regex native drops unparsed logs",
+			processors: `
+  - Type: processor_parse_regex_native
+    SourceKey: content
+    Regex: (\d+)
+    Keys: [num]`,
+			inputs: []string{"12", "12a"},
+			want:   []Record{{"num": "12"}},
+		},
+		{
+			name: "regex extension",
+			processors: `
+  - Type: processor_regex
+    Regex: level=(\w+)
+    Keys: [level]
+    FullMatch: false
+    KeepSource: true`,
+			inputs: []string{"a level=WARN b", "none"},
+			want:   []Record{{"content": "a level=WARN b", "level": "WARN"}, {"content": "none"}},
+		},
+		{
+			name: "json native",
+			processors: `
+  - Type: processor_parse_json_native
+    SourceKey: content`,
+			inputs: []string{`{"msg":"ok","code":200,"tags":{"a":1}}`},
+			want:   []Record{{"msg": "ok", "code": "200", "tags": `{"a":1}`}},
+		},
+		{
+			name: "json extension expands nested objects",
+			processors: `
+  - Type: processor_json
+    SourceKey: content
+    KeepSource: false
+    ExpandDepth: 2
+    ExpandConnector: .`,
+			inputs: []string{`{"req":{"url":"/","hdr":{"ua":"x"}},"ok":true}`},
+			want:   []Record{{"req.url": "/", "req.hdr": `{"ua":"x"}`, "ok": "true"}},
+		},
+		{
+			name: "delimiter",
+			processors: `
+  - Type: processor_parse_delimiter_native
+    SourceKey: content
+    Separator: ","
+    Keys: [a, b]`,
+			inputs: []string{`1,"x,""y"""`, `1`, `1,2,3,4`},
+			want: []Record{
+				{"a": "1", "b": `x,"y"`},
+				{"a": "1"},
+				{"a": "1", "b": "2", "__column0__": "3", "__column1__": "4"},
+			},
+		},
+		{
+			name: "split multiline",
+			processors: `
+  - Type: processor_split_multiline_log_string_native
+    Multiline:
+      StartPattern: \d{4}-.*
+      UnmatchedContentTreatment: discard`,
+			inputs: []string{"orphan\n2025-01 boom\n  at a\n  at b\n2025-02 ok"},
+			want:   []Record{{"content": "2025-01 boom\n  at a\n  at b"}, {"content": "2025-02 ok"}},
+		},
+		{
+			name: "split lines",
+			processors: `
+  - Type: processor_split_log_string_native`,
+			inputs: []string{"a\n\nb"},
+			want:   []Record{{"content": "a"}, {"content": "b"}},
+		},
+		{
+			name: "drop rename add filter",
+			processors: `
+  - Type: processor_parse_json_native
+    SourceKey: content
+  - Type: processor_drop
+    DropKeys: [secret]
+  - Type: processor_rename
+    SourceKeys: [lvl]
+    DestKeys: [level]
+  - Type: processor_add_fields
+    Fields:
+      env: prod
+      level: DEBUG
+    IgnoreIfExist: true
+  - Type: processor_filter_regex_native
+    Include:
+      level: ERROR|WARN
+    Exclude:
+      msg: .*healthz.*`,
+			inputs: []string{
+				`{"lvl":"ERROR","msg":"failed","secret":"s"}`,
+				`{"lvl":"WARN","msg":"GET /healthz"}`,
+				`{"msg":"no level"}`,
+			},
+			want: []Record{{"level": "ERROR", "msg": "failed", "env": "prod"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Run("processors:"+tc.processors+"\n", tc.inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Records, tc.want) {
+				t.Errorf("got %v, want %v", result.Records, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	result, err := Run("processors:\n  - Type: processor_parse_timestamp_native\n    SourceKey: time\n", []string{"x"})
+	if err != nil || len(result.Notes) != 1 || len(result.Steps) != 1 {
+		t.Fatalf("timestamp processors must pass logs through with a note, got %+v, %v", result, err)
+	}
+	if _, err := Run("processors:\n  - Type: processor_desensitize\n", nil); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	for _, content := range []string{
+		"processors: {}\n",
+		"processors:\n  - Type: processor_parse_regex_native\n    SourceKey: content\n    Regex: (a)(b)\n    Keys: [a]\n",
+		"processors:\n  - Type: processor_parse_regex_native\n    SourceKey: content\n    Regex: (?=a)\n    Keys: []\n",
+	} {
+		if _, err := Run(content, nil); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: expected ErrInvalid, got %v", content, err)
+		}
+	}
+}
+
+func TestInputs(t *testing.T) {
+	text := "2025-01 a\n  at x\n2025-02 b\n"
+	for _, tc := range []struct {
+		content string
+		want    []string
+	}{
+		{"inputs:\n  - Type: input_file\n", []string{"2025-01 a", "  at x", "2025-02 b"}},
+		{"inputs:\n  - Type: input_file\n    Multiline:\n      StartPattern: \\d+-.*\n", []string{"2025-01 a\n  at x", "2025-02 b"}},
+		{"processors:\n  - Type: processor_split_log_string_native\n", []string{"2025-01 a\n  at x\n2025-02 b"}},
+	} {
+		got, err := Inputs(tc.content, text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %q, want %q", tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	got := []Record{{"level": "INFO", "msg": "a"}, {"msg": "b"}}
+	want := []Record{{"level": "WARN", "msg": "a", "env": "prod"}}
+	diffs := strings.Join(Compare(got, want), "\n")
+	for _, expected := range []string{
+		`record 0: field env missing, want "prod"`,
+		`record 0: field level = "INFO", want "WARN"`,
+		`record 1: unexpected {msg: "b"}`,
+	} {
+		if !strings.Contains(diffs, expected) {
+			t.Errorf("diffs do not contain %q:\n%s", expected, diffs)
+		}
+	}
+	if diffs := Compare(want, want); len(diffs) != 0 {
+		t.Errorf("equal records differ: %v", diffs)
+	}
+}
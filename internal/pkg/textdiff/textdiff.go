@@ -0,0 +1,98 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Unified 按行比较a与b，返回带有from/to文件头的统一格式差异，相同时返回空字符串。
+// 不做分块，变化的行前后各保留context行上下文
+func Unified(from, to, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	x, y := splitLines(a), splitLines(b)
+	ops := diff(x, y)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, to)
+	printed, skipped := false, false
+	for i, op := range ops {
+		if op.kind == ' ' && !nearChange(ops, i, context) {
+			skipped = true
+			continue
+		}
+		// 两段变化之间省略了相同的行
+		if printed && skipped {
+			out.WriteString("@@\n")
+		}
+		printed, skipped = true, false
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+type op struct {
+	kind byte
+	line string
+}
+
+// diff 基于最长公共子序列计算逐行的编辑
+func diff(x, y []string) []op {
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			ops = append(ops, op{' ', x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', x[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		ops = append(ops, op{'-', x[i]})
+	}
+	for ; j < len(y); j++ {
+		ops = append(ops, op{'+', y[j]})
+	}
+	return ops
+}
+
+// nearChange 判断第i个编辑前后context行内是否有变化
+func nearChange(ops []op, i, context int) bool {
+	for k := max(0, i-context); k <= min(len(ops)-1, i+context); k++ {
+		if ops[k].kind != ' ' {
+			return true
+		}
+	}
+	return false
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
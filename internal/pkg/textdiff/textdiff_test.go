@@ -0,0 +1,38 @@
+package textdiff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "inputs:\n  - Type: input_file\n    FilePaths:\n      - /var/log/a.log\nflushers:\n  - Type: flusher_stdout\n"
+	b := "inputs:\n  - Type: input_file\n    FilePaths:\n      - /var/log/b.log\nflushers:\n  - Type: flusher_stdout\n"
+	want := "--- old\n+++ new\n" +
+		"     FilePaths:\n" +
+		"-      - /var/log/a.log\n" +
+		"+      - /var/log/b.log\n" +
+		" flushers:\n"
+	if got := Unified("old", "new", a, b, 1); got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedEqual(t *testing.T) {
+	if got := Unified("a", "b", "same\n", "same\n", 3); got != "" {
+		t.Errorf("Unified() of equal texts = %q", got)
+	}
+}
+
+func TestUnifiedSeparatesHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n"
+	b := "0\n2\n3\n4\n5\n6\n8\n"
+	want := "--- a\n+++ b\n-1\n+0\n 2\n@@\n 6\n-7\n+8\n"
+	if got := Unified("a", "b", a, b, 1); got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedFromEmpty(t *testing.T) {
+	want := "--- a\n+++ b\n+x\n"
+	if got := Unified("a", "b", "", "x\n", 3); got != want {
+		t.Errorf("Unified() = %q, want %q", got, want)
+	}
+}
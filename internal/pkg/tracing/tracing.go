@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// InstrumentationName 本项目创建的span使用的tracer名称
+	InstrumentationName = "github.com/infraflows/loongcollector-operator"
+	// DefaultServiceName 默认的service.name
+	DefaultServiceName = "loongcollector-operator"
+)
+
+// span属性
+const (
+	AttrNamespace    = attribute.Key("k8s.namespace.name")
+	AttrKind         = attribute.Key("loongcollector.resource.kind")
+	AttrName         = attribute.Key("loongcollector.resource.name")
+	AttrEndpoint     = attribute.Key("loongcollector.configserver.endpoint")
+	AttrRetryable    = attribute.Key("loongcollector.retry.retryable")
+	AttrAttempt      = attribute.Key("loongcollector.retry.attempt")
+	AttrRequeueAfter = attribute.Key("loongcollector.retry.requeue_after")
+)
+
+// Options OTLP导出配置
+type Options struct {
+	// Endpoint OTLP gRPC地址，为空时不开启tracing
+	Endpoint string
+	// Insecure 不使用TLS连接Endpoint
+	Insecure bool
+	// SampleRatio 采样比例，0到1之间
+	SampleRatio float64
+	// ServiceName 上报的service.name
+	ServiceName string
+}
+
+// Setup 按配置安装全局TracerProvider与W3C traceparent传播器，返回的函数在退出前调用以刷新span
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporterOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+	if opts.Insecure {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	serviceName := opts.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer 返回本项目使用的tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// StartReconcile 为一次协调创建span，属性中记录Pipeline或AgentGroup的名称
+func StartReconcile(ctx context.Context, kind string, key types.NamespacedName) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "Reconcile "+kind,
+		trace.WithAttributes(
+			AttrKind.String(kind),
+			AttrNamespace.String(key.Namespace),
+			AttrName.String(key.Name),
+		))
+}
+
+// RecordRetry 为一次失败后的重试决定创建span
+func RecordRetry(ctx context.Context, kind string, key types.NamespacedName, attempt int, retryable bool,
+	requeueAfter string, err error) {
+	_, span := Tracer().Start(ctx, "Retry "+kind,
+		trace.WithAttributes(
+			AttrKind.String(kind),
+			AttrNamespace.String(key.Namespace),
+			AttrName.String(key.Name),
+			AttrAttempt.Int(attempt),
+			AttrRetryable.Bool(retryable),
+			AttrRequeueAfter.String(requeueAfter),
+		))
+	RecordError(span, err)
+	span.End()
+}
+
+// RecordError 将错误记录到span并标记为失败
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
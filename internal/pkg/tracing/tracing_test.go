@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func installRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestReconcileAndRetrySpans(t *testing.T) {
+	recorder := installRecorder(t)
+	key := types.NamespacedName{Namespace: "default", Name: "nginx"}
+
+	ctx, span := StartReconcile(context.Background(), "Pipeline", key)
+	RecordRetry(ctx, "Pipeline", key, 2, true, "10s", errors.New("unavailable"))
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	retry, reconcile := spans[0], spans[1]
+	if reconcile.Name() != "Reconcile Pipeline" || retry.Name() != "Retry Pipeline" {
+		t.Fatalf("unexpected span names %q, %q", reconcile.Name(), retry.Name())
+	}
+	if retry.Parent().SpanID() != reconcile.SpanContext().SpanID() {
+		t.Fatal("expected the retry span to be a child of the reconcile span")
+	}
+	if retry.Status().Code != codes.Error {
+		t.Fatalf("expected the retry span to record the error, got %v", retry.Status())
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range reconcile.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs[string(AttrKind)] != "Pipeline" || attrs[string(AttrName)] != "nginx" || attrs[string(AttrNamespace)] != "default" {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+}
+
+func TestSetupWithoutEndpointIsNoop(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,230 @@
+package workload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LogSource 描述注解中声明的一类日志
+type LogSource struct {
+	// Path 容器内的日志路径，支持通配符
+	Path string `json:"path"`
+	// Format 日志格式：raw、json、regex、delimiter
+	Format string `json:"format,omitempty"`
+	// Container 只采集指定容器，为空则采集全部容器
+	Container string `json:"container,omitempty"`
+	// Regex format=regex 时使用的正则
+	Regex string `json:"regex,omitempty"`
+	// Separator format=delimiter 时使用的分隔符
+	Separator string `json:"separator,omitempty"`
+	// Keys regex/delimiter 解析出的字段名
+	Keys []string `json:"keys,omitempty"`
+	// MultilineStart 多行日志的行首正则
+	MultilineStart string `json:"multilineStart,omitempty"`
+}
+
+// Source 生成Pipeline所需的工作负载信息
+type Source struct {
+	Kind      string
+	Name      string
+	Namespace string
+	// PodLabels 用于匹配容器的Pod标签
+	PodLabels map[string]string
+	// Annotations 工作负载上的注解
+	Annotations map[string]string
+}
+
+const (
+	FormatRaw       = "raw"
+	FormatJSON      = "json"
+	FormatRegex     = "regex"
+	FormatDelimiter = "delimiter"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ParseLogSources 解析日志注解
+func ParseLogSources(value string) ([]LogSource, error) {
+	var sources []LogSource
+	if err := json.Unmarshal([]byte(value), &sources); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", v1alpha1.AnnotationLogs, err)
+	}
+	for i, s := range sources {
+		if s.Path == "" {
+			return nil, fmt.Errorf("log source %d: path is required", i)
+		}
+		switch s.Format {
+		case "", FormatRaw, FormatJSON:
+		case FormatRegex:
+			if s.Regex == "" || len(s.Keys) == 0 {
+				return nil, fmt.Errorf("log source %d: regex format requires regex and keys", i)
+			}
+		case FormatDelimiter:
+			if s.Separator == "" || len(s.Keys) == 0 {
+				return nil, fmt.Errorf("log source %d: delimiter format requires separator and keys", i)
+			}
+		default:
+			return nil, fmt.Errorf("log source %d: unsupported format %q", i, s.Format)
+		}
+	}
+	return sources, nil
+}
+
+// BuildPipelines 根据工作负载注解生成Pipeline，未声明注解时返回空
+func BuildPipelines(src Source) ([]*v1alpha1.Pipeline, error) {
+	value, ok := src.Annotations[v1alpha1.AnnotationLogs]
+	if !ok || strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	sources, err := ParseLogSources(value)
+	if err != nil {
+		return nil, err
+	}
+
+	flusher := map[string]interface{}{"Type": "flusher_stdout", "OnlyStdout": true}
+	if raw, ok := src.Annotations[v1alpha1.AnnotationFlusher]; ok && raw != "" {
+		flusher = map[string]interface{}{}
+		if err := json.Unmarshal([]byte(raw), &flusher); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", v1alpha1.AnnotationFlusher, err)
+		}
+	}
+
+	pipelines := make([]*v1alpha1.Pipeline, 0, len(sources))
+	for i, s := range sources {
+		content, err := renderContent(src, s, flusher)
+		if err != nil {
+			return nil, err
+		}
+		pipelines = append(pipelines, &v1alpha1.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      PipelineName(src.Kind, src.Name, i),
+				Namespace: src.Namespace,
+				Labels:    Labels(src.Kind, src.Name),
+			},
+			Spec: v1alpha1.PipelineSpec{
+				Name:       ConfigName(src.Namespace, src.Kind, src.Name, i),
+				Content:    content,
+				AgentGroup: src.Annotations[v1alpha1.AnnotationAgentGroup],
+			},
+		})
+	}
+	return pipelines, nil
+}
+
+// Labels 返回生成的Pipeline所带的标签，也用于查找已生成的Pipeline
+func Labels(kind, name string) map[string]string {
+	return map[string]string{
+		v1alpha1.LabelManagedBy:  v1alpha1.ManagedByWorkload,
+		v1alpha1.LabelSourceKind: strings.ToLower(kind),
+		v1alpha1.LabelSourceName: truncate(name, 63),
+	}
+}
+
+// PipelineName 生成Pipeline资源名
+func PipelineName(kind, name string, index int) string {
+	return truncate(fmt.Sprintf("%s-%s", strings.ToLower(kind), name), 240) + fmt.Sprintf("-logs-%d", index)
+}
+
+// maxConfigNameLength 配置名的最大长度，与Kubernetes的DNS label一致，agent会用配置名命名本地文件
+const maxConfigNameLength = 63
+
+// ConfigName 生成Config-Server中的配置名，需在集群内唯一。超长时截断并追加完整名称的哈希，保持唯一
+func ConfigName(namespace, kind, name string, index int) string {
+	base := invalidNameChars.ReplaceAllString(strings.ToLower(fmt.Sprintf("%s-%s-%s", namespace, kind, name)), "-")
+	suffix := fmt.Sprintf("-%d", index)
+	if len(base)+len(suffix) <= maxConfigNameLength {
+		return base + suffix
+	}
+	sum := sha256.Sum256([]byte(base))
+	suffix = "-" + hex.EncodeToString(sum[:])[:8] + suffix
+	return truncate(base, maxConfigNameLength-len(suffix)) + suffix
+}
+
+// renderContent 生成LoongCollector采集配置
+func renderContent(src Source, s LogSource, flusher map[string]interface{}) (string, error) {
+	filters := yaml.MapSlice{
+		{Key: "K8sNamespaceRegex", Value: "^(" + regexp.QuoteMeta(src.Namespace) + ")$"},
+	}
+	if len(src.PodLabels) > 0 {
+		keys := make([]string, 0, len(src.PodLabels))
+		for k := range src.PodLabels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		labels := yaml.MapSlice{}
+		for _, k := range keys {
+			labels = append(labels, yaml.MapItem{Key: k, Value: src.PodLabels[k]})
+		}
+		filters = append(filters, yaml.MapItem{Key: "IncludeK8sLabel", Value: labels})
+	}
+	if s.Container != "" {
+		filters = append(filters, yaml.MapItem{Key: "K8sContainerRegex", Value: "^(" + regexp.QuoteMeta(s.Container) + ")$"})
+	}
+
+	input := yaml.MapSlice{
+		{Key: "Type", Value: "input_file"},
+		{Key: "FilePaths", Value: []string{s.Path}},
+		{Key: "EnableContainerDiscovery", Value: true},
+		{Key: "ContainerFilters", Value: filters},
+	}
+	if s.MultilineStart != "" {
+		input = append(input, yaml.MapItem{Key: "Multiline", Value: yaml.MapSlice{
+			{Key: "Mode", Value: "custom"},
+			{Key: "StartPattern", Value: s.MultilineStart},
+		}})
+	}
+
+	config := yaml.MapSlice{{Key: "enable", Value: true}, {Key: "inputs", Value: []interface{}{input}}}
+	if processor := buildProcessor(s); processor != nil {
+		config = append(config, yaml.MapItem{Key: "processors", Value: []interface{}{processor}})
+	}
+	config = append(config, yaml.MapItem{Key: "flushers", Value: []interface{}{flusher}})
+
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to render pipeline content: %w", err)
+	}
+	return string(out), nil
+}
+
+// buildProcessor 根据日志格式生成解析插件
+func buildProcessor(s LogSource) yaml.MapSlice {
+	switch s.Format {
+	case FormatJSON:
+		return yaml.MapSlice{
+			{Key: "Type", Value: "processor_parse_json_native"},
+			{Key: "SourceKey", Value: "content"},
+		}
+	case FormatRegex:
+		return yaml.MapSlice{
+			{Key: "Type", Value: "processor_parse_regex_native"},
+			{Key: "SourceKey", Value: "content"},
+			{Key: "Regex", Value: s.Regex},
+			{Key: "Keys", Value: s.Keys},
+		}
+	case FormatDelimiter:
+		return yaml.MapSlice{
+			{Key: "Type", Value: "processor_parse_delimiter_native"},
+			{Key: "SourceKey", Value: "content"},
+			{Key: "Separator", Value: s.Separator},
+			{Key: "Keys", Value: s.Keys},
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.TrimRight(s[:n], "-.")
+}
@@ -0,0 +1,106 @@
+package workload
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+	"gopkg.in/yaml.v2"
+)
+
+func TestBuildPipelines(t *testing.T) {
+	src := Source{
+		Kind:      "Deployment",
+		Name:      "web",
+		Namespace: "shop",
+		PodLabels: map[string]string{"app": "web"},
+		Annotations: map[string]string{
+			v1alpha1.AnnotationLogs:       `[{"path":"/app/*.log","format":"json","container":"app"},{"path":"/var/log/nginx/access.log"}]`,
+			v1alpha1.AnnotationAgentGroup: "default",
+		},
+	}
+
+	pipelines, err := BuildPipelines(src)
+	if err != nil {
+		t.Fatalf("BuildPipelines returned error: %v", err)
+	}
+	if len(pipelines) != 2 {
+		t.Fatalf("expected 2 pipelines, got %d", len(pipelines))
+	}
+
+	first := pipelines[0]
+	if first.Name != "deployment-web-logs-0" || first.Spec.Name != "shop-deployment-web-0" {
+		t.Errorf("unexpected names %q/%q", first.Name, first.Spec.Name)
+	}
+	if first.Spec.AgentGroup != "default" {
+		t.Errorf("expected agent group to be propagated, got %q", first.Spec.AgentGroup)
+	}
+	if first.Labels[v1alpha1.LabelSourceKind] != "deployment" || first.Labels[v1alpha1.LabelSourceName] != "web" {
+		t.Errorf("unexpected labels %v", first.Labels)
+	}
+
+	var content struct {
+		Inputs []struct {
+			FilePaths        []string               `yaml:"FilePaths"`
+			ContainerFilters map[string]interface{} `yaml:"ContainerFilters"`
+		} `yaml:"inputs"`
+		Processors []map[string]interface{} `yaml:"processors"`
+		Flushers   []map[string]interface{} `yaml:"flushers"`
+	}
+	if err := yaml.Unmarshal([]byte(first.Spec.Content), &content); err != nil {
+		t.Fatalf("generated content is not valid YAML: %v", err)
+	}
+	filters := content.Inputs[0].ContainerFilters
+	if filters["K8sNamespaceRegex"] != "^(shop)$" || filters["K8sContainerRegex"] != "^(app)$" {
+		t.Errorf("unexpected container filters %v", filters)
+	}
+	if len(content.Processors) != 1 || content.Processors[0]["Type"] != "processor_parse_json_native" {
+		t.Errorf("expected json processor, got %v", content.Processors)
+	}
+	if len(content.Flushers) != 1 || content.Flushers[0]["Type"] != "flusher_stdout" {
+		t.Errorf("expected default stdout flusher, got %v", content.Flushers)
+	}
+
+	if strings.Contains(pipelines[1].Spec.Content, "processors") {
+		t.Errorf("raw logs should not have processors:\n%s", pipelines[1].Spec.Content)
+	}
+}
+
+func TestBuildPipelinesWithoutAnnotation(t *testing.T) {
+	pipelines, err := BuildPipelines(Source{Kind: "Pod", Name: "p", Namespace: "ns"})
+	if err != nil || pipelines != nil {
+		t.Fatalf("expected no pipelines and no error, got %v, %v", pipelines, err)
+	}
+}
+
+func TestParseLogSourcesValidation(t *testing.T) {
+	cases := map[string]string{
+		"missing path":    `[{"format":"json"}]`,
+		"unknown format":  `[{"path":"/a.log","format":"xml"}]`,
+		"regex w/o keys":  `[{"path":"/a.log","format":"regex","regex":"(.*)"}]`,
+		"not a json list": `{"path":"/a.log"}`,
+	}
+	for name, value := range cases {
+		if _, err := ParseLogSources(value); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestConfigNameIsBounded(t *testing.T) {
+	if got := ConfigName("shop", "Deployment", "web", 0); got != "shop-deployment-web-0" {
+		t.Errorf("short names must be kept, got %q", got)
+	}
+
+	long := strings.Repeat("checkout-service-", 4)
+	first, second := ConfigName("shop", "Deployment", long+"a", 10), ConfigName("shop", "Deployment", long+"b", 10)
+	for _, name := range []string{first, second} {
+		if len(name) > maxConfigNameLength || !strings.HasPrefix(name, "shop-deployment-checkout-service") ||
+			!strings.HasSuffix(name, "-10") {
+			t.Errorf("unexpected config name %q (%d characters)", name, len(name))
+		}
+	}
+	if first == second {
+		t.Errorf("truncated names must stay unique, both are %q", first)
+	}
+}
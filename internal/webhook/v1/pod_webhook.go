@@ -0,0 +1,256 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var podlog = logf.Log.WithName("pod-resource")
+
+const (
+	// SidecarContainerName is the name of the injected LoongCollector container
+	SidecarContainerName = "loongcollector"
+	// LogVolumeName is the name of the emptyDir shared between the application and the sidecar
+	LogVolumeName = "loongcollector-logs"
+
+	defaultLogMountPath        = "/var/log/app"
+	defaultTagEnvName          = "ilogtail_tags"
+	defaultConfigServerEnvName = "ilogtail_configserver_address"
+)
+
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// SetupPodWebhookWithManager registers the webhook for Pod in the manager.
+func SetupPodWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&corev1.Pod{}).
+		WithDefaulter(&PodCustomDefaulter{Client: mgr.GetClient(), Reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups=loongcollector.infraflow.co,resources=injectionprofiles,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get
+
+// PodCustomDefaulter injects the LoongCollector sidecar into Pods annotated with
+// loongcollector.infraflow.co/inject=true.
+type PodCustomDefaulter struct {
+	Client client.Client
+	// Reader reads the ReplicaSet or Job owning a Pod without caching them, defaults to Client
+	Reader client.Reader
+}
+
+var _ webhook.CustomDefaulter = &PodCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Pod.
+func (d *PodCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("expected an Pod object but got %T", obj)
+	}
+	if pod.Annotations[v1alpha1.AnnotationInject] != "true" || pod.Annotations[v1alpha1.AnnotationInjected] == "true" {
+		return nil
+	}
+
+	namespace := pod.Namespace
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Namespace != "" {
+		namespace = req.Namespace
+	}
+
+	profileName := pod.Annotations[v1alpha1.AnnotationInjectionProfile]
+	if profileName == "" {
+		profileName = v1alpha1.DefaultInjectionProfile
+	}
+	profile := &v1alpha1.InjectionProfile{}
+	if err := d.Client.Get(ctx, client.ObjectKey{Name: profileName}, profile); err != nil {
+		return fmt.Errorf("failed to get InjectionProfile %q: %w", profileName, err)
+	}
+
+	tag := SidecarTag(pod, namespace, d.workloadName(ctx, pod, namespace))
+	podlog.Info("Injecting LoongCollector sidecar", "namespace", namespace, "pod", podName(pod),
+		"profile", profileName, "tag", tag)
+	InjectSidecar(pod, &profile.Spec, tag)
+	return nil
+}
+
+// SidecarTag 返回sidecar上报的标签sidecar-<namespace>.<name>，只有绑定到该标签的Pipeline才会下发到这个sidecar。
+// name默认为所属工作负载名，同一工作负载的Pod共用一个标签：准入时Pod通常只有generateName，
+// 按Pod生成的标签在每次滚动发布后都会变化，无法预先绑定Pipeline。
+// Pod可以通过sidecar-tag注解指定标签，但只能使用本命名空间的前缀，
+// 命名空间名不含"."，其他命名空间的Pod无法得到相同的标签，也就拿不到该命名空间的配置
+func SidecarTag(pod *corev1.Pod, namespace, workload string) string {
+	prefix := v1alpha1.SidecarTagPrefix + namespace + "."
+	if tag := pod.Annotations[v1alpha1.AnnotationSidecarTag]; tag != "" {
+		if name, ok := strings.CutPrefix(tag, prefix); ok && name != "" && tagName(name) == name {
+			return tag
+		}
+		podlog.Info("Ignoring a sidecar tag outside the namespace of the Pod", "namespace", namespace,
+			"pod", podName(pod), "tag", tag, "prefix", prefix)
+	}
+	return prefix + tagName(workload)
+}
+
+// tagName 把名称转换为标签中只含小写字母、数字与"-"的部分
+func tagName(name string) string {
+	return strings.Trim(invalidTagChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// workloadName 返回Pod所属工作负载的名称，使标签在滚动发布后保持不变：
+// ReplicaSet名带有pod-template-hash，取其所属的Deployment；CronJob创建的Job名带有时间，取CronJob；
+// 其他控制器取控制器名，没有控制器时取Pod名
+func (d *PodCustomDefaulter) workloadName(ctx context.Context, pod *corev1.Pod, namespace string) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return podName(pod)
+	}
+	var parent client.Object
+	switch owner.Kind {
+	case "ReplicaSet":
+		parent = &appsv1.ReplicaSet{}
+	case "Job":
+		parent = &batchv1.Job{}
+	default:
+		return owner.Name
+	}
+
+	if err := d.reader().Get(ctx, client.ObjectKey{Namespace: namespace, Name: owner.Name}, parent); err != nil {
+		podlog.Info("Failed to get the owner of the Pod, deriving the workload name from it",
+			"namespace", namespace, "owner", owner.Name, "error", err.Error())
+		// Deployment的Pod带有pod-template-hash标签，ReplicaSet名为<Deployment名>-<hash>
+		if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; owner.Kind == "ReplicaSet" && hash != "" {
+			return strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+		return owner.Name
+	}
+	if grandparent := metav1.GetControllerOf(parent); grandparent != nil &&
+		(grandparent.Kind == "Deployment" || grandparent.Kind == "CronJob") {
+		return grandparent.Name
+	}
+	return owner.Name
+}
+
+func (d *PodCustomDefaulter) reader() client.Reader {
+	if d.Reader != nil {
+		return d.Reader
+	}
+	return d.Client
+}
+
+// InjectSidecar 注入共享日志卷与LoongCollector sidecar，重复调用不会重复注入
+func InjectSidecar(pod *corev1.Pod, profile *v1alpha1.InjectionProfileSpec, tag string) {
+	mountPath := profile.LogMountPath
+	if mountPath == "" {
+		mountPath = defaultLogMountPath
+	}
+	tagEnv := profile.TagEnvName
+	if tagEnv == "" {
+		tagEnv = defaultTagEnvName
+	}
+	configServerEnv := profile.ConfigServerEnvName
+	if configServerEnv == "" {
+		configServerEnv = defaultConfigServerEnvName
+	}
+
+	if !hasVolume(pod, LogVolumeName) {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name:         LogVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+	}
+	mount := corev1.VolumeMount{Name: LogVolumeName, MountPath: mountPath}
+	for i := range pod.Spec.Containers {
+		if !hasMount(&pod.Spec.Containers[i], mountPath) {
+			pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, mount)
+		}
+	}
+
+	if !hasContainer(pod, SidecarContainerName) {
+		env := []corev1.EnvVar{{Name: tagEnv, Value: tag}}
+		if profile.ConfigServerURL != "" {
+			env = append(env, corev1.EnvVar{Name: configServerEnv, Value: profile.ConfigServerURL})
+		}
+		env = append(env, profile.Env...)
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name:            SidecarContainerName,
+			Image:           profile.Image,
+			ImagePullPolicy: profile.ImagePullPolicy,
+			Resources:       profile.Resources,
+			Env:             env,
+			VolumeMounts:    []corev1.VolumeMount{{Name: LogVolumeName, MountPath: mountPath, ReadOnly: true}},
+		})
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[v1alpha1.AnnotationSidecarTag] = tag
+	pod.Annotations[v1alpha1.AnnotationInjected] = "true"
+}
+
+// podName 创建时Pod名可能尚未生成，退化为generateName
+func podName(pod *corev1.Pod) string {
+	if pod.Name != "" {
+		return pod.Name
+	}
+	return strings.TrimSuffix(pod.GenerateName, "-")
+}
+
+func hasVolume(pod *corev1.Pod, name string) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMount(container *corev1.Container, path string) bool {
+	for _, m := range container.VolumeMounts {
+		if m.MountPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+func hasContainer(pod *corev1.Pod, name string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
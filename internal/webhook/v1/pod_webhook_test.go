@@ -0,0 +1,157 @@
+/*
+Copyright 2025 LoongCollector Sigs.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/infraflows/loongcollector-operator/api/v1alpha1"
+)
+
+var _ = Describe("Pod Webhook", func() {
+	var (
+		defaulter *PodCustomDefaulter
+		pod       *corev1.Pod
+	)
+	ownedBy := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: ptr.To(true)}}
+	}
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+
+		profile := &v1alpha1.InjectionProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.DefaultInjectionProfile},
+			Spec: v1alpha1.InjectionProfileSpec{
+				Image:           "loongcollector:latest",
+				ConfigServerURL: "http://config-server:8899",
+			},
+		}
+		replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "web-7d9f", Namespace: "shop", OwnerReferences: ownedBy("Deployment", "web")}}
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name: "report-29012345", Namespace: "shop", OwnerReferences: ownedBy("CronJob", "report")}}
+		defaulter = &PodCustomDefaulter{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(profile, replicaSet, job).Build(),
+		}
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "web-7d9f-",
+				Namespace:    "shop",
+				Annotations:  map[string]string{v1alpha1.AnnotationInject: "true"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+		}
+	})
+
+	Context("When creating a Pod under the Defaulting Webhook", func() {
+		It("Should inject the sidecar and the shared log volume", func() {
+			Expect(defaulter.Default(context.Background(), pod)).To(Succeed())
+
+			Expect(pod.Spec.Containers).To(HaveLen(2))
+			sidecar := pod.Spec.Containers[1]
+			Expect(sidecar.Name).To(Equal(SidecarContainerName))
+			Expect(sidecar.Image).To(Equal("loongcollector:latest"))
+			Expect(sidecar.Env).To(ContainElements(
+				corev1.EnvVar{Name: defaultTagEnvName, Value: "sidecar-shop.web-7d9f"},
+				corev1.EnvVar{Name: defaultConfigServerEnvName, Value: "http://config-server:8899"},
+			))
+			Expect(pod.Spec.Volumes).To(ContainElement(HaveField("Name", LogVolumeName)))
+			Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(
+				corev1.VolumeMount{Name: LogVolumeName, MountPath: defaultLogMountPath}))
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1alpha1.AnnotationSidecarTag, "sidecar-shop.web-7d9f"))
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1alpha1.AnnotationInjected, "true"))
+		})
+
+		It("Should name the sidecar tag after the owning workload", func() {
+			pod.OwnerReferences = ownedBy("ReplicaSet", "web-7d9f")
+			Expect(defaulter.Default(context.Background(), pod)).To(Succeed())
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1alpha1.AnnotationSidecarTag, "sidecar-shop.web"))
+
+			cronPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "report-29012345-", Namespace: "shop", OwnerReferences: ownedBy("Job", "report-29012345")}}
+			Expect(defaulter.workloadName(context.Background(), cronPod, "shop")).To(Equal("report"))
+
+			statefulPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name: "db-0", Namespace: "shop", OwnerReferences: ownedBy("StatefulSet", "db")}}
+			Expect(defaulter.workloadName(context.Background(), statefulPod, "shop")).To(Equal("db"))
+		})
+
+		It("Should strip the pod-template-hash when the ReplicaSet cannot be read", func() {
+			pod.GenerateName = "api-5c8b-"
+			pod.Labels = map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "5c8b"}
+			pod.OwnerReferences = ownedBy("ReplicaSet", "api-5c8b")
+			Expect(defaulter.Default(context.Background(), pod)).To(Succeed())
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1alpha1.AnnotationSidecarTag, "sidecar-shop.api"))
+		})
+
+		It("Should use the sidecar tag annotation under the namespace of the Pod", func() {
+			pod.Annotations[v1alpha1.AnnotationSidecarTag] = "sidecar-shop.payments"
+			Expect(defaulter.Default(context.Background(), pod)).To(Succeed())
+			Expect(pod.Spec.Containers[1].Env).To(ContainElement(
+				corev1.EnvVar{Name: defaultTagEnvName, Value: "sidecar-shop.payments"}))
+		})
+
+		It("Should ignore sidecar tags outside the namespace of the Pod", func() {
+			for _, tag := range []string{"payments", "sidecar-bank.payments", "sidecar-shop.", "sidecar-shop.a.b", "node-worker-1"} {
+				p := pod.DeepCopy()
+				p.Annotations[v1alpha1.AnnotationSidecarTag] = tag
+				Expect(defaulter.Default(context.Background(), p)).To(Succeed())
+				Expect(p.Annotations).To(HaveKeyWithValue(v1alpha1.AnnotationSidecarTag, "sidecar-shop.web-7d9f"), tag)
+			}
+		})
+
+		It("Should not take the agent group of generated Pipelines as the sidecar tag", func() {
+			pod.Annotations[v1alpha1.AnnotationAgentGroup] = "payments"
+			Expect(defaulter.Default(context.Background(), pod)).To(Succeed())
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1alpha1.AnnotationSidecarTag, "sidecar-shop.web-7d9f"))
+			Expect(pod.Annotations).To(HaveKeyWithValue(v1alpha1.AnnotationAgentGroup, "payments"))
+		})
+
+		It("Should not inject twice", func() {
+			Expect(defaulter.Default(context.Background(), pod)).To(Succeed())
+			Expect(defaulter.Default(context.Background(), pod)).To(Succeed())
+			InjectSidecar(pod, &v1alpha1.InjectionProfileSpec{Image: "loongcollector:latest"}, "payments")
+			Expect(pod.Spec.Containers).To(HaveLen(2))
+			Expect(pod.Spec.Volumes).To(HaveLen(1))
+		})
+
+		It("Should ignore Pods without the inject annotation", func() {
+			delete(pod.Annotations, v1alpha1.AnnotationInject)
+			Expect(defaulter.Default(context.Background(), pod)).To(Succeed())
+			Expect(pod.Spec.Containers).To(HaveLen(1))
+		})
+
+		It("Should fail when the profile does not exist", func() {
+			pod.Annotations[v1alpha1.AnnotationInjectionProfile] = "missing"
+			Expect(defaulter.Default(context.Background(), pod)).NotTo(Succeed())
+		})
+	})
+})